@@ -245,3 +245,16 @@ func (s *Step) String() string {
 	}
 	return "<unnamed>"
 }
+
+// Covers は抑制コメントが指定された行の指定されたルールの検出結果を抑制するかどうかを返します。
+func (s *Suppression) Covers(rule string, line int) bool {
+	if s.Kind != SuppressionKindFile && (line < s.StartLine || line > s.EndLine) {
+		return false
+	}
+	for _, r := range s.Rules {
+		if r == rule {
+			return true
+		}
+	}
+	return false
+}
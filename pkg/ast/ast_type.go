@@ -18,6 +18,9 @@ type String struct {
 	Quoted bool
 	// Literal represents the string is literal style in the YAML source.
 	Literal bool
+	// Indent is the indentation width of the content lines when the string is literal style.
+	// It is zero when the indentation is unknown, e.g. the string was not parsed from the source.
+	Indent int
 	// Pos is a position of the string in source.
 	Pos *Position
 
@@ -694,6 +697,117 @@ type Workflow struct {
 	Concurrency *Concurrency
 	// Jobs is mappings from job ID to the job object. Keys are in lower case since they are case-insensitive.
 	Jobs map[string]*Job
+	// Suppressions is a list of inline suppression comments found in the YAML file.
+	Suppressions []*Suppression
+	// Action is metadata of the action when the file is an action metadata file (action.yml). It is nil for workflow files.
+	// Jobs of the action contains a single pseudo job whose steps are "runs.steps" of the composite action.
+	Action *ActionMetadata
 	// BaseNode is a base node of the YAML file.
 	BaseNode *yaml.Node
 }
+
+// ActionMetadata is metadata of an action defined in action.yml.
+// *https://docs.github.com/en/actions/sharing-automations/creating-actions/metadata-syntax-for-github-actions
+type ActionMetadata struct {
+	// Name is name of the action.
+	Name *String
+	// Author is author of the action.
+	Author *String
+	// Description is description of the action.
+	Description *String
+	// Inputs is mapping from input ID to the input. Keys are in lower case since they are case-insensitive.
+	Inputs map[string]*ActionInput
+	// Outputs is mapping from output ID to the output. Keys are in lower case since they are case-insensitive.
+	Outputs map[string]*ActionOutput
+	// Runs is configuration of how the action runs.
+	Runs *ActionRuns
+	// Pos is a position in source.
+	Pos *Position
+}
+
+// ActionInput is an input of an action.
+// *https://docs.github.com/en/actions/sharing-automations/creating-actions/metadata-syntax-for-github-actions#inputs
+type ActionInput struct {
+	// Name is name of the input.
+	Name *String
+	// Description is description of the input.
+	Description *String
+	// Required is true when the input is required.
+	Required *Bool
+	// Default is default value of the input.
+	Default *String
+	// DeprecationMessage is a message shown when the deprecated input is used.
+	DeprecationMessage *String
+}
+
+// ActionOutput is an output of an action.
+// *https://docs.github.com/en/actions/sharing-automations/creating-actions/metadata-syntax-for-github-actions#outputs-for-composite-actions
+type ActionOutput struct {
+	// Name is name of the output.
+	Name *String
+	// Description is description of the output.
+	Description *String
+	// Value is value of the output. It is only available in composite actions.
+	Value *String
+}
+
+// ActionRuns is "runs" section of an action.
+// *https://docs.github.com/en/actions/sharing-automations/creating-actions/metadata-syntax-for-github-actions#runs
+type ActionRuns struct {
+	// Using is the runtime of the action such as "node20", "docker" or "composite".
+	Using *String
+	// Main is the entrypoint script of JavaScript action.
+	Main *String
+	// Pre is the script run before Main of JavaScript action.
+	Pre *String
+	// PreIf is the condition to run Pre.
+	PreIf *String
+	// Post is the script run after Main of JavaScript action.
+	Post *String
+	// PostIf is the condition to run Post.
+	PostIf *String
+	// Image is the Docker image or Dockerfile of Docker container action.
+	Image *String
+	// Entrypoint overrides ENTRYPOINT of the Docker image.
+	Entrypoint *String
+	// PreEntrypoint is the script run before Entrypoint of Docker container action.
+	PreEntrypoint *String
+	// PostEntrypoint is the script run after Entrypoint of Docker container action.
+	PostEntrypoint *String
+	// Args is arguments passed to the Docker container.
+	Args []*String
+	// Env is environment variables set in the Docker container.
+	Env *Env
+	// Steps is steps of the composite action.
+	Steps []*Step
+	// Pos is a position in source.
+	Pos *Position
+}
+
+// SuppressionKind はインライン抑制コメントの種類を表します。
+type SuppressionKind uint8
+
+const (
+	// SuppressionKindNextLine は "# sisakulint-disable-next-line" による次の行の抑制です。
+	SuppressionKindNextLine SuppressionKind = iota
+	// SuppressionKindBlock は "# sisakulint-disable" によるジョブやステップなどのブロック全体の抑制です。
+	SuppressionKindBlock
+	// SuppressionKindFile は "# sisakulint-disable-file" によるファイル全体の抑制です。
+	SuppressionKindFile
+)
+
+// Suppression は "# sisakulint-disable" 形式のコメントによる検出結果の抑制を表します。
+type Suppression struct {
+	// Kind は抑制の種類です。
+	Kind SuppressionKind
+	// Rules は抑制するルールの名前のリストです。
+	Rules []string
+	// Reason は抑制する理由です。"--" の後に記述されます。
+	Reason string
+	// StartLine は抑制する範囲の最初の行です。ファイル全体の抑制の場合は0です。
+	StartLine int
+	// EndLine は抑制する範囲の最後の行です。ファイル全体の抑制の場合は0です。
+	EndLine int
+	// Pos はコメントのソース内の位置です。
+	Pos *Position
+}
@@ -24,19 +24,57 @@ func NewActionListRule() *ActionList {
 }
 
 // isActionAllowed はアクションがルールに基づいて許可されているかチェック
-func (rule *ActionList) isActionAllowed(actionRef string) (bool, string) {
+// 禁止されている場合は理由と、blacklistにマッチした場合はそのエントリを返す
+func (rule *ActionList) isActionAllowed(actionRef string) (bool, string, *ActionListBlacklistEntry) {
 	// 設定がnilまたは空の場合は全て許可
-	if rule.userConfig == nil || len(rule.userConfig.actionListRegex) == 0 {
-		return true, ""
+	if rule.userConfig == nil || rule.userConfig.ActionList.isEmpty() {
+		return true, "", nil
+	}
+	list := &rule.userConfig.ActionList
+
+	// ブラックリストにマッチするならホワイトリストに関係なく禁止
+	for _, entry := range list.Blacklist {
+		if entry.regex != nil && entry.regex.MatchString(actionRef) {
+			reason := fmt.Sprintf("'%s' is in the blacklist (pattern '%s')", actionRef, entry.Pattern)
+			if entry.Reason != "" {
+				reason += ": " + entry.Reason
+			}
+			if entry.Advisory != "" {
+				reason += ". see " + entry.Advisory
+			}
+			return false, reason, entry
+		}
+	}
+
+	// ホワイトリストが設定されていない場合はブラックリスト以外を許可
+	if len(list.whitelistRegex) == 0 {
+		return true, "", nil
 	}
 
 	// ホワイトリストが設定されていて、マッチするなら許可
-	for _, pattern := range rule.userConfig.actionListRegex {
+	for _, pattern := range list.whitelistRegex {
 		if pattern.MatchString(actionRef) {
-			return true, ""
+			return true, "", nil
 		}
 	}
-	return false, fmt.Sprintf("action '%s' is not in the whitelist", actionRef)
+	return false, fmt.Sprintf("'%s' is not in the whitelist", actionRef), nil
+}
+
+// checkUsesはuses:の値を検査し、禁止されている場合はエラーを報告する
+// ブラックリストにマッチした場合は既知の危険な参照のため深刻度を上げて報告する
+func (rule *ActionList) checkUses(pos *ast.Position, uses *ast.String, kind, where string) {
+	if uses == nil || uses.Value == "" || strings.Contains(uses.Value, "${{") {
+		return
+	}
+	allowed, reason, blacklisted := rule.isActionAllowed(uses.Value)
+	if allowed {
+		return
+	}
+	if blacklisted != nil {
+		rule.ErrorfWithSeverity(pos, SeverityHigh, "%s %s in %s", kind, reason, where)
+		return
+	}
+	rule.Errorf(pos, "%s %s in %s", kind, reason, where)
 }
 
 // matchActionPattern はアクション参照がパターンにマッチするかチェック
@@ -51,15 +89,18 @@ func compileActionPattern(pattern string) (*regexp.Regexp, error) {
 	return re, nil
 }
 
-// VisitJobPre checks if actions used in job steps are allowed
+// VisitJobPre checks if actions, docker images and reusable workflows used in the job are allowed
 func (rule *ActionList) VisitJobPre(node *ast.Job) error {
+	if node.WorkflowCall != nil && node.WorkflowCall.Uses != nil {
+		rule.checkUses(node.WorkflowCall.Uses.Pos, node.WorkflowCall.Uses, "reusable workflow", fmt.Sprintf("job '%s'", node.ID.Value))
+	}
 	for _, step := range node.Steps {
 		if action, ok := step.Exec.(*ast.ExecAction); ok {
-			usesValue := action.Uses.Value
-			allowed, reason := rule.isActionAllowed(usesValue)
-			if !allowed {
-				rule.Errorf(step.Pos, "%s in step '%s'", reason, step.String())
+			kind := "action"
+			if strings.HasPrefix(action.Uses.Value, "docker://") {
+				kind = "docker image"
 			}
+			rule.checkUses(step.Pos, action.Uses, kind, fmt.Sprintf("step '%s'", step.String()))
 		}
 	}
 	return nil
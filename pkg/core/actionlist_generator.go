@@ -25,6 +25,10 @@ func NewActionListGenerator() *ActionListGenerator {
 // CollectActionsFromWorkflow はワークフローファイルからアクション参照を収集
 func (g *ActionListGenerator) CollectActionsFromWorkflow(workflow *ast.Workflow) {
 	for _, job := range workflow.Jobs {
+		// ジョブから呼び出される再利用可能ワークフローも収集する
+		if job.WorkflowCall != nil && job.WorkflowCall.Uses != nil && job.WorkflowCall.Uses.Value != "" {
+			g.actions[g.normalizeActionPattern(job.WorkflowCall.Uses.Value)] = true
+		}
 		for _, step := range job.Steps {
 			if action, ok := step.Exec.(*ast.ExecAction); ok {
 				usesValue := action.Uses.Value
@@ -158,8 +162,26 @@ func GenerateActionListConfig(root string) error {
 
 	buf.WriteString("# Allowed GitHub Actions (auto-generated from existing workflows)\n")
 	buf.WriteString("action-list:\n")
-	for _, action := range actions {
-		buf.WriteString(fmt.Sprintf("  - %s\n", action))
+	if existingConfig != nil && len(existingConfig.ActionList.Blacklist) > 0 {
+		// 既存のブラックリストは保持する
+		buf.WriteString("  whitelist:\n")
+		for _, action := range actions {
+			buf.WriteString(fmt.Sprintf("    - %s\n", action))
+		}
+		buf.WriteString("  blacklist:\n")
+		for _, entry := range existingConfig.ActionList.Blacklist {
+			buf.WriteString(fmt.Sprintf("    - pattern: %q\n", entry.Pattern))
+			if entry.Reason != "" {
+				buf.WriteString(fmt.Sprintf("      reason: %q\n", entry.Reason))
+			}
+			if entry.Advisory != "" {
+				buf.WriteString(fmt.Sprintf("      advisory: %q\n", entry.Advisory))
+			}
+		}
+	} else {
+		for _, action := range actions {
+			buf.WriteString(fmt.Sprintf("  - %s\n", action))
+		}
 	}
 
 	if err := os.WriteFile(configPath, []byte(buf.String()), 0644); err != nil {
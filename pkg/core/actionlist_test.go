@@ -0,0 +1,203 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseConfig_ActionList(t *testing.T) {
+	tests := []struct {
+		name          string
+		cfg           string
+		wantWhitelist int
+		wantBlacklist int
+		wantErr       string
+	}{
+		{
+			name:          "list is a whitelist",
+			cfg:           "action-list:\n  - actions/checkout@*\n  - actions/setup-go@*\n",
+			wantWhitelist: 2,
+		},
+		{
+			name:          "whitelist and blacklist",
+			cfg:           "action-list:\n  whitelist:\n    - actions/*@*\n  blacklist:\n    - evil/*@*\n    - pattern: tj-actions/changed-files@*\n      reason: compromised\n      advisory: https://example.com/advisory\n",
+			wantWhitelist: 1,
+			wantBlacklist: 2,
+		},
+		{
+			name:    "blacklist entry without pattern",
+			cfg:     "action-list:\n  blacklist:\n    - reason: no pattern\n",
+			wantErr: "blacklist[0] in action list must have a pattern",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := parseConfig([]byte(tt.cfg), "sisakulint.yaml")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("want error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(cfg.ActionList.Whitelist) != tt.wantWhitelist || len(cfg.ActionList.whitelistRegex) != tt.wantWhitelist {
+				t.Errorf("got %d whitelist entries, want %d", len(cfg.ActionList.Whitelist), tt.wantWhitelist)
+			}
+			if len(cfg.ActionList.Blacklist) != tt.wantBlacklist {
+				t.Errorf("got %d blacklist entries, want %d", len(cfg.ActionList.Blacklist), tt.wantBlacklist)
+			}
+		})
+	}
+}
+
+func TestWriteDefaultConfigFile_Parses(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sisakulint.yaml")
+	if err := writeDefaultConfigFile(path); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := ReadConfigFile(path)
+	if err != nil {
+		t.Fatalf("default config file does not parse: %v", err)
+	}
+	if len(cfg.ActionList.Whitelist) == 0 || len(cfg.ActionList.Blacklist) == 0 {
+		t.Errorf("default config should have both whitelist and blacklist: %+v", cfg.ActionList)
+	}
+}
+
+func TestActionList_Rule(t *testing.T) {
+	workflow := `on: push
+jobs:
+  call:
+    uses: other/repo/.github/workflows/ci.yml@v1
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v4
+      - uses: tj-actions/changed-files@v45
+      - uses: docker://alpine:3.19
+      - uses: docker://evil/miner:latest
+      - uses: someone/else@v1
+`
+
+	tests := []struct {
+		name       string
+		config     string
+		wantErrors []string
+	}{
+		{
+			name:   "whitelist only",
+			config: "action-list:\n  - actions/*@*\n  - docker://alpine:*\n",
+			wantErrors: []string{
+				"reusable workflow 'other/repo/.github/workflows/ci.yml@v1' is not in the whitelist in job 'call'",
+				"action 'tj-actions/changed-files@v45' is not in the whitelist",
+				"docker image 'docker://evil/miner:latest' is not in the whitelist",
+				"action 'someone/else@v1' is not in the whitelist",
+			},
+		},
+		{
+			name: "blacklist only",
+			config: `action-list:
+  blacklist:
+    - pattern: tj-actions/changed-files@*
+      reason: compromised in a supply chain attack
+      advisory: https://github.com/advisories/GHSA-mrrh-fwg8-r2c3
+    - docker://evil/*
+    - other/repo/.github/workflows/*@*
+`,
+			wantErrors: []string{
+				"reusable workflow 'other/repo/.github/workflows/ci.yml@v1' is in the blacklist (pattern 'other/repo/.github/workflows/*@*') in job 'call'",
+				"action 'tj-actions/changed-files@v45' is in the blacklist (pattern 'tj-actions/changed-files@*'): compromised in a supply chain attack. see https://github.com/advisories/GHSA-mrrh-fwg8-r2c3",
+				"docker image 'docker://evil/miner:latest' is in the blacklist",
+			},
+		},
+		{
+			name:   "blacklist takes precedence over whitelist",
+			config: "action-list:\n  whitelist:\n    - '*'\n  blacklist:\n    - tj-actions/*@*\n",
+			wantErrors: []string{
+				"action 'tj-actions/changed-files@v45' is in the blacklist",
+			},
+		},
+		{
+			name:       "no action list",
+			config:     "config-variables: null\n",
+			wantErrors: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := parseConfig([]byte(tt.config), "sisakulint.yaml")
+			if err != nil {
+				t.Fatal(err)
+			}
+			parsed, errs := Parse([]byte(workflow))
+			if len(errs) > 0 {
+				t.Fatal(errs)
+			}
+
+			rule := NewActionListRule()
+			rule.UpdateConfig(cfg)
+			v := NewSyntaxTreeVisitor()
+			v.AddVisitor(rule)
+			if err := v.VisitTree(parsed); err != nil {
+				t.Fatal(err)
+			}
+
+			got := rule.Errors()
+			if len(got) != len(tt.wantErrors) {
+				t.Fatalf("got %d errors, want %d: %v", len(got), len(tt.wantErrors), got)
+			}
+			for _, want := range tt.wantErrors {
+				found := false
+				for _, e := range got {
+					if strings.Contains(e.Description, want) {
+						found = true
+						if strings.Contains(want, "blacklist") && e.Severity != SeverityHigh {
+							t.Errorf("blacklisted reference should be reported with high severity: %v", e)
+						}
+					}
+				}
+				if !found {
+					t.Errorf("no error containing %q in %v", want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateActionListConfig_KeepsBlacklist(t *testing.T) {
+	root := t.TempDir()
+	workflows := filepath.Join(root, ".github", "workflows")
+	if err := os.MkdirAll(workflows, 0755); err != nil {
+		t.Fatal(err)
+	}
+	workflow := "on: push\njobs:\n  call:\n    uses: org/repo/.github/workflows/ci.yml@v1\n  build:\n    runs-on: ubuntu-latest\n    steps:\n      - uses: actions/checkout@v4\n"
+	if err := os.WriteFile(filepath.Join(workflows, "ci.yml"), []byte(workflow), 0644); err != nil {
+		t.Fatal(err)
+	}
+	configPath := filepath.Join(root, ".github", "sisakulint.yaml")
+	existing := "action-list:\n  blacklist:\n    - pattern: evil/*@*\n      reason: malware\n"
+	if err := os.WriteFile(configPath, []byte(existing), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := GenerateActionListConfig(root); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := ReadConfigFile(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"actions/checkout@*", "org/repo/.github/workflows/ci.yml@*"}
+	if strings.Join(cfg.ActionList.Whitelist, ",") != strings.Join(want, ",") {
+		t.Errorf("whitelist = %v, want %v", cfg.ActionList.Whitelist, want)
+	}
+	if len(cfg.ActionList.Blacklist) != 1 || cfg.ActionList.Blacklist[0].Pattern != "evil/*@*" || cfg.ActionList.Blacklist[0].Reason != "malware" {
+		t.Errorf("blacklist is not kept: %+v", cfg.ActionList.Blacklist)
+	}
+}
@@ -0,0 +1,230 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/google/go-github/v68/github"
+	"gopkg.in/yaml.v3"
+)
+
+// ActionLockFileNameはプロジェクトの.githubディレクトリに置くアクションのロックファイルの名前
+const ActionLockFileName = "sisakulint-actions.lock"
+
+// defaultGitHubAPIURLは-pin-api-urlとGITHUB_API_URLが指定されていない場合に使うGitHub APIのURL
+const defaultGitHubAPIURL = "https://api.github.com/"
+
+// ActionLockEntryはロックファイルに記録された1つのアクションの参照の解決結果
+type ActionLockEntry struct {
+	// SHAは参照が指しているコミットの完全なSHA
+	SHA string `yaml:"sha"`
+	// Versionはコミットに付けられた正規のバージョンタグ。v4のような短いタグはv4.2.2のような完全なタグになる
+	Version string `yaml:"version"`
+}
+
+// ActionLockは"owner/repo@tag"の形式のアクションの参照を、コミットのSHAとバージョンタグに対応付けるロックファイル
+// commit-shaルールとその自動修正は、ロックファイルがある場合はGitHub APIを使わずにロックファイルだけを参照する
+// imagesセクションはコンテナイメージの参照をダイジェストに対応付け、image-digestルールの自動修正に使う
+type ActionLock struct {
+	// Actionsは"owner/repo@tag"をキーとする解決結果
+	Actions map[string]*ActionLockEntry `yaml:"actions"`
+	// Imagesは"name:tag"の形式のコンテナイメージの参照を"sha256:..."のダイジェストに対応付ける。レジストリにはアクセスしないので手で記録する
+	Images map[string]string `yaml:"images,omitempty"`
+}
+
+// imageDigestPatternはロックファイルに記録するイメージのダイジェストの形式
+var imageDigestPattern = regexp.MustCompile(`^sha256:[0-9a-f]{64}$`)
+
+// actionLockPathはプロジェクトのロックファイルのパスを返す
+func actionLockPath(root string) string {
+	return filepath.Join(root, ".github", ActionLockFileName)
+}
+
+// ReadActionLockFileはロックファイルを読み込む。ファイルが存在しない場合はnilを返す
+func ReadActionLockFile(path string) (*ActionLock, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("could not read action lock file %q: %w", path, err)
+	}
+	var lock ActionLock
+	if err := yaml.Unmarshal(b, &lock); err != nil {
+		return nil, fmt.Errorf("could not parse action lock file %q: %w", path, err)
+	}
+	if lock.Actions == nil {
+		lock.Actions = map[string]*ActionLockEntry{}
+	}
+	for ref, e := range lock.Actions {
+		if e == nil || !fullShaPattern.MatchString(e.SHA) {
+			return nil, fmt.Errorf("invalid entry %q in action lock file %q: \"sha\" must be a full length commit SHA", ref, path)
+		}
+	}
+	for image, digest := range lock.Images {
+		if !imageDigestPattern.MatchString(digest) {
+			return nil, fmt.Errorf("invalid image %q in action lock file %q: digest must be in the form of \"sha256:<64 hex digits>\"", image, path)
+		}
+	}
+	return &lock, nil
+}
+
+// WriteFileはロックファイルを書き込む。エントリはキーの順に並べる
+func (lock *ActionLock) WriteFile(path string) error {
+	var buf bytes.Buffer
+	buf.WriteString("# This file is generated by sisakulint -pin-resolve.\n# It maps action references to commit SHAs so that commit-sha autofix works offline.\n# Digests of container images in \"images\" are used by image-digest autofix.\n")
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(lock); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// Lookupは"owner/repo"とタグに対応するエントリを返す。見つからない場合はnilを返す
+func (lock *ActionLock) Lookup(repo, tag string) *ActionLockEntry {
+	if lock == nil {
+		return nil
+	}
+	return lock.Actions[repo+"@"+tag]
+}
+
+// LookupImageはコンテナイメージの参照に対応するダイジェストを返す。タグのない参照は"latest"タグとして探す。見つからない場合は空文字列を返す
+func (lock *ActionLock) LookupImage(image string) string {
+	if lock == nil {
+		return ""
+	}
+	if d, ok := lock.Images[image]; ok {
+		return d
+	}
+	if _, tag := splitImageRef(image); tag == "" {
+		return lock.Images[image+":latest"]
+	}
+	return ""
+}
+
+// versionsOfは"owner/repo"のSHAに対応するエントリのタグとバージョンを返す
+func (lock *ActionLock) versionsOf(repo, sha string) []string {
+	if lock == nil {
+		return nil
+	}
+	var versions []string
+	for ref, e := range lock.Actions {
+		r, tag, ok := strings.Cut(ref, "@")
+		if !ok || r != repo || e.SHA != sha {
+			continue
+		}
+		versions = append(versions, tag)
+		if e.Version != "" {
+			versions = append(versions, e.Version)
+		}
+	}
+	sort.Strings(versions)
+	return versions
+}
+
+// entryOfは"owner/repo"のSHAに対応するエントリを返す。複数ある場合はキーの順で最初のエントリを返す
+func (lock *ActionLock) entryOf(repo, sha string) *ActionLockEntry {
+	if lock == nil {
+		return nil
+	}
+	keys := make([]string, 0, len(lock.Actions))
+	for ref := range lock.Actions {
+		keys = append(keys, ref)
+	}
+	sort.Strings(keys)
+	for _, ref := range keys {
+		if r, _, _ := strings.Cut(ref, "@"); r == repo && lock.Actions[ref].SHA == sha {
+			return lock.Actions[ref]
+		}
+	}
+	return nil
+}
+
+// splitActionRefは"owner/repo/path@ref"の形式のusesの値を"owner/repo"、"/path"、refに分割する
+func splitActionRef(uses string) (repo, path, ref string, ok bool) {
+	spec, ref, ok := strings.Cut(uses, "@")
+	if !ok || ref == "" || strings.HasPrefix(spec, "./") || strings.HasPrefix(spec, "docker://") {
+		return "", "", "", false
+	}
+	parts := strings.SplitN(spec, "/", 3)
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", "", false
+	}
+	repo = parts[0] + "/" + parts[1]
+	if len(parts) == 3 {
+		path = "/" + parts[2]
+	}
+	return repo, path, ref, true
+}
+
+// ActionRefResolverはGitHub APIを使ってアクションの参照をコミットのSHAとバージョンタグに解決する
+type ActionRefResolver struct {
+	client *github.Client
+}
+
+// NewActionRefResolverはtokenで認証し、apiURLのGitHub APIを使うActionRefResolverを作成する
+// tokenが空の場合はGITHUB_TOKENまたはGH_TOKENを使い、apiURLが空の場合はGITHUB_API_URLまたはhttps://api.github.com/を使う
+func NewActionRefResolver(token, apiURL string) (*ActionRefResolver, error) {
+	if token == "" {
+		token = os.Getenv("GITHUB_TOKEN")
+	}
+	if token == "" {
+		token = os.Getenv("GH_TOKEN")
+	}
+	if apiURL == "" {
+		apiURL = os.Getenv("GITHUB_API_URL")
+	}
+	if apiURL == "" {
+		apiURL = defaultGitHubAPIURL
+	}
+	if !strings.HasSuffix(apiURL, "/") {
+		apiURL += "/"
+	}
+	u, err := url.Parse(apiURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid GitHub API URL %q: %w", apiURL, err)
+	}
+	client := github.NewClient(nil)
+	if token != "" {
+		client = client.WithAuthToken(token)
+	}
+	client.BaseURL = u
+	return &ActionRefResolver{client: client}, nil
+}
+
+// Resolveは"owner/repo"のタグをコミットのSHAとバージョンタグに解決する
+// v4のような短いタグは、同じコミットを指すv4.2.2のような完全なタグをバージョンとする
+func (r *ActionRefResolver) Resolve(ctx context.Context, repo, tag string) (*ActionLockEntry, error) {
+	owner, name, ok := strings.Cut(repo, "/")
+	if !ok {
+		return nil, fmt.Errorf("invalid action repository %q, expected format is 'owner/repo'", repo)
+	}
+	sha, _, err := r.client.Repositories.GetCommitSHA1(ctx, owner, name, tag, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commit SHA1 of %s@%s: %w", repo, tag, err)
+	}
+	version := tag
+	if !semverPattern.MatchString(tag) && shortTagPattern.MatchString(tag) {
+		long, err := getLongVersion(r.client, owner, name, sha, tag)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get long version of %s@%s: %w", repo, tag, err)
+		}
+		if long != "" {
+			version = long
+		}
+	}
+	return &ActionLockEntry{SHA: sha, Version: version}, nil
+}
@@ -0,0 +1,318 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sisaku-security/sisakulint/pkg/ast"
+)
+
+const (
+	testCheckoutSHA = "11bd71901bbe5b1630ceea73d27597364c9af683"
+	testCacheSHA    = "0c45773b623bea8c8e75f6c82b208c3cf94ea4f9"
+)
+
+func testActionLock() *ActionLock {
+	return &ActionLock{Actions: map[string]*ActionLockEntry{
+		"actions/checkout@v4": {SHA: testCheckoutSHA, Version: "v4.2.2"},
+	}}
+}
+
+func TestActionLock_ReadWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ActionLockFileName)
+	if lock, err := ReadActionLockFile(path); lock != nil || err != nil {
+		t.Fatalf("missing lock file should be nil without error: %v, %v", lock, err)
+	}
+
+	want := testActionLock()
+	if err := want.WriteFile(path); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadActionLockFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got.Actions, want.Actions)
+	}
+
+	if err := os.WriteFile(path, []byte("actions:\n  actions/checkout@v4:\n    sha: abc\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadActionLockFile(path); err == nil || !strings.Contains(err.Error(), "full length commit SHA") {
+		t.Errorf("invalid SHA should be rejected: %v", err)
+	}
+}
+
+func TestSplitActionRef(t *testing.T) {
+	tests := []struct {
+		uses            string
+		repo, path, tag string
+		ok              bool
+	}{
+		{"actions/checkout@v4", "actions/checkout", "", "v4", true},
+		{"github/codeql-action/init@v3", "github/codeql-action", "/init", "v3", true},
+		{"./local/action@v1", "", "", "", false},
+		{"docker://alpine:3.8", "", "", "", false},
+		{"actions@v3", "", "", "", false},
+		{"actions/checkout", "", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.uses, func(t *testing.T) {
+			repo, path, tag, ok := splitActionRef(tt.uses)
+			if repo != tt.repo || path != tt.path || tag != tt.tag || ok != tt.ok {
+				t.Errorf("got (%q, %q, %q, %v)", repo, path, tag, ok)
+			}
+		})
+	}
+}
+
+// parseTestStepはworkflowを解析して最初のジョブの最初のステップを返す
+func parseTestStep(t *testing.T, uses string) *ast.Step {
+	t.Helper()
+	w, errs := Parse([]byte("on: push\njobs:\n  build:\n    runs-on: ubuntu-latest\n    steps:\n      - uses: " + uses + "\n"))
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	for _, job := range w.Jobs {
+		return job.Steps[0]
+	}
+	t.Fatal("no job")
+	return nil
+}
+
+func TestCommitSha_FixStep_Lockfile(t *testing.T) {
+	rule := CommitShaRuleWithLock(testActionLock())
+
+	step := parseTestStep(t, "actions/checkout@v4")
+	if err := rule.FixStep(step); err != nil {
+		t.Fatal(err)
+	}
+	uses := step.Exec.(*ast.ExecAction).Uses.BaseNode
+	if uses.Value != "actions/checkout@"+testCheckoutSHA || uses.LineComment != "v4.2.2" {
+		t.Errorf("got %q # %q", uses.Value, uses.LineComment)
+	}
+
+	step = parseTestStep(t, "actions/cache@v4")
+	err := rule.FixStep(step)
+	if err == nil || !strings.Contains(err.Error(), "-pin-resolve") {
+		t.Errorf("action missing in the lock file should be an error: %v", err)
+	}
+	if v := step.Exec.(*ast.ExecAction).Uses.BaseNode.Value; v != "actions/cache@v4" {
+		t.Errorf("action missing in the lock file should not be modified: %q", v)
+	}
+}
+
+func TestLinter_CommitShaFixSuggestion(t *testing.T) {
+	src := `on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v4
+`
+	tests := []struct {
+		name    string
+		lock    *ActionLock
+		wantFix string
+	}{
+		// ロックファイルがあればネットワークにアクセスせずに修正できるので、修正内容を添付する
+		{"with lock file", testActionLock(), "      - uses: actions/checkout@" + testCheckoutSHA + " # v4.2.2\n"},
+		{"without lock file", nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := writeProject(t, map[string]string{"build.yml": src})
+			if tt.lock != nil {
+				if err := tt.lock.WriteFile(actionLockPath(root)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			project, err := NewProject(root)
+			if err != nil {
+				t.Fatal(err)
+			}
+			l, err := NewLinter(io.Discard, &LinterOptions{CurrentWorkingDirectoryPath: root, CustomErrorMessageFormat: "{{sarif .}}"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			results, err := l.LintFiles([]string{filepath.Join(root, ".github", "workflows", "build.yml")}, project)
+			if err != nil {
+				t.Fatal(err)
+			}
+			found := false
+			for _, e := range results[0].Errors {
+				if e.Type != "commit-sha" {
+					continue
+				}
+				found = true
+				got := ""
+				if e.Fix != nil {
+					got = e.Fix.Replacement
+				}
+				if got != tt.wantFix {
+					t.Errorf("got fix suggestion %q, want %q", got, tt.wantFix)
+				}
+			}
+			if !found {
+				t.Fatal("no commit-sha error")
+			}
+		})
+	}
+}
+
+func TestCommitSha_VersionComment(t *testing.T) {
+	tests := []struct {
+		name    string
+		uses    string
+		wantErr bool
+	}{
+		{"comment matches version", "actions/checkout@" + testCheckoutSHA + " # v4.2.2", false},
+		{"comment matches tag", "actions/checkout@" + testCheckoutSHA + " # v4", false},
+		{"comment does not match", "actions/checkout@" + testCheckoutSHA + " # v3.6.0", true},
+		{"no version comment", "actions/checkout@" + testCheckoutSHA + " # pinned", false},
+		{"SHA not in lock file", "actions/cache@" + testCacheSHA + " # v1.0.0", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := CommitShaRuleWithLock(testActionLock())
+			step := parseTestStep(t, tt.uses)
+			if err := rule.VisitStep(step); err != nil {
+				t.Fatal(err)
+			}
+			if got := len(rule.Errors()) > 0; got != tt.wantErr {
+				t.Fatalf("got errors %v, want error: %v", rule.Errors(), tt.wantErr)
+			}
+			if !tt.wantErr {
+				return
+			}
+			if msg := rule.Errors()[0].Description; !strings.Contains(msg, "'v3.6.0'") || !strings.Contains(msg, "'v4.2.2'") {
+				t.Errorf("unexpected message: %s", msg)
+			}
+			if len(rule.AutoFixers()) != 1 {
+				t.Fatalf("got %d autofixers", len(rule.AutoFixers()))
+			}
+			if err := rule.AutoFixers()[0].Fix(); err != nil {
+				t.Fatal(err)
+			}
+			if c := step.Exec.(*ast.ExecAction).Uses.BaseNode.LineComment; c != "v4.2.2" {
+				t.Errorf("comment is not fixed: %q", c)
+			}
+		})
+	}
+}
+
+// newFakeGitHubAPIは、コミットのSHAとタグの一覧を返すGitHub APIのサーバーを起動する
+func newFakeGitHubAPI(t *testing.T, shas map[string]string, tags map[string][]string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/repos/"), "/")
+		if len(parts) < 3 {
+			http.NotFound(w, r)
+			return
+		}
+		repo := parts[0] + "/" + parts[1]
+		switch parts[2] {
+		case "commits":
+			sha, ok := shas[repo+"@"+strings.Join(parts[3:], "/")]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			_, _ = w.Write([]byte(sha))
+		case "tags":
+			type tag struct {
+				Name   string `json:"name"`
+				Commit struct {
+					SHA string `json:"sha"`
+				} `json:"commit"`
+			}
+			var list []tag
+			for _, name := range tags[repo] {
+				var tg tag
+				tg.Name = name
+				tg.Commit.SHA = shas[repo+"@"+name]
+				list = append(list, tg)
+			}
+			_ = json.NewEncoder(w).Encode(list)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestCommand_PinResolve(t *testing.T) {
+	srv := newFakeGitHubAPI(t,
+		map[string]string{
+			"actions/checkout@v4":     testCheckoutSHA,
+			"actions/checkout@v4.2.2": testCheckoutSHA,
+			"actions/cache@v4.1.0":    testCacheSHA,
+		},
+		map[string][]string{"actions/checkout": {"v4.2.2", "v4"}},
+	)
+
+	root := t.TempDir()
+	workflows := filepath.Join(root, ".github", "workflows")
+	if err := os.MkdirAll(workflows, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(root, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	workflow := "on: push\npermissions: {}\njobs:\n  build:\n    runs-on: ubuntu-latest\n    timeout-minutes: 5\n    steps:\n" +
+		"      - uses: actions/checkout@v4\n        with:\n          persist-credentials: false\n" +
+		"      - uses: actions/cache@" + testCacheSHA + " # v4.1.0\n        timeout-minutes: 5\n"
+	path := filepath.Join(workflows, "ci.yml")
+	if err := os.WriteFile(path, []byte(workflow), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// 手で記録したイメージのダイジェストは-pin-resolveで消えない
+	images := map[string]string{"node:20": "sha256:" + strings.Repeat("1", 64)}
+	if err := (&ActionLock{Images: images}).WriteFile(filepath.Join(root, ".github", ActionLockFileName)); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := &Command{Stdout: &stdout, Stderr: &stderr}
+	if code := cmd.Main([]string{"sisakulint", "-pin-resolve", "-pin-api-url", srv.URL, "-pin-token", "dummy", path}); code != ExitStatusSuccessNoProblem {
+		t.Fatalf("exit status %d\nstdout: %s\nstderr: %s", code, stdout.String(), stderr.String())
+	}
+	lock, err := ReadActionLockFile(filepath.Join(root, ".github", ActionLockFileName))
+	if err != nil || lock == nil {
+		t.Fatalf("lock file is not written: %v", err)
+	}
+	want := map[string]*ActionLockEntry{
+		"actions/checkout@v4":  {SHA: testCheckoutSHA, Version: "v4.2.2"},
+		"actions/cache@v4.1.0": {SHA: testCacheSHA, Version: "v4.1.0"},
+	}
+	if !reflect.DeepEqual(lock.Actions, want) {
+		t.Errorf("got lock entries %+v", lock.Actions)
+	}
+	if !reflect.DeepEqual(lock.Images, images) {
+		t.Errorf("images are not kept: %+v", lock.Images)
+	}
+
+	// ロックファイルがあれば、APIサーバーがなくても修正できる
+	srv.Close()
+	stdout.Reset()
+	stderr.Reset()
+	cmd.Main([]string{"sisakulint", "-fix", "on", "-fix-rules", "commit-sha", path})
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "- uses: actions/checkout@"+testCheckoutSHA+" # v4.2.2\n") {
+		t.Errorf("commit-sha fix is not applied from the lock file:\n%s\nstderr: %s", data, stderr.String())
+	}
+}
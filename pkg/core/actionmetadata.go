@@ -0,0 +1,153 @@
+package core
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/sisaku-security/sisakulint/pkg/ast"
+)
+
+// actionRuntimeKindsは、runs.usingに書ける実行環境とアクションの種類の対応
+// * https://docs.github.com/en/actions/sharing-automations/creating-actions/metadata-syntax-for-github-actions#runs
+var actionRuntimeKinds = map[string]string{
+	"node12":    "JavaScript",
+	"node16":    "JavaScript",
+	"node20":    "JavaScript",
+	"node24":    "JavaScript",
+	"docker":    "Docker container",
+	"composite": "composite",
+}
+
+// ActionMetadataRuleは、アクションのメタデータファイル(action.yml)のruns:セクションを検査するルール
+// runs.usingが有効な実行環境か、サポートが終了したNode.jsの実行環境を使っていないか、
+// 実行環境に必要なキーがあるか、composite actionのrun:のステップにshell:があるかを検査する
+type ActionMetadataRule struct {
+	BaseRule
+}
+
+// NewActionMetadataRuleは新しいActionMetadataRuleインスタンスを作成する
+func NewActionMetadataRule() *ActionMetadataRule {
+	return &ActionMetadataRule{
+		BaseRule: BaseRule{
+			RuleName: "action-metadata",
+			RuleDesc: "Checks \"runs\" section of action metadata files such as runtime in \"using\" and required \"shell\" in composite action steps",
+		},
+	}
+}
+
+// VisitWorkflowPreはWorkflowノードを訪れたときのcallback。ワークフローのファイルでは何もしない
+func (rule *ActionMetadataRule) VisitWorkflowPre(n *ast.Workflow) error {
+	if n.Action == nil || n.Action.Runs == nil || n.Action.Runs.Using == nil {
+		return nil
+	}
+	runs := n.Action.Runs
+	using := runs.Using.Value
+	kind, ok := actionRuntimeKinds[using]
+	if !ok {
+		rule.Errorf(runs.Using.Pos, "invalid runtime %q in \"runs.using\". available runtimes are %s", using, sortedQuotes(actionRuntimeKinds))
+		return nil
+	}
+	if _, ok := deprecatedActionRuntimes[using]; ok {
+		rule.Errorf(runs.Using.Pos, "runtime %q in \"runs.using\" is deprecated and no longer supported on GitHub-hosted runners. use \"node24\" or \"node20\" instead", using)
+	}
+
+	required := map[string]string{"JavaScript": "main", "Docker container": "image", "composite": "steps"}[kind]
+	found := false
+	for _, k := range actionRunsKeys(runs) {
+		if k.name == required {
+			found = true
+		}
+		if _, ok := actionRunsAvailableKeys[kind][k.name]; !ok {
+			rule.Errorf(k.pos, "%q is not available in \"runs\" section of %s action", k.name, kind)
+		}
+	}
+	if !found {
+		rule.Errorf(runs.Pos, "%q is required in \"runs\" section of %s action", required, kind)
+	}
+	if kind == "composite" {
+		rule.checkCompositeSteps(runs.Steps)
+	}
+	return nil
+}
+
+// checkCompositeStepsは、composite actionのrun:のステップにshell:があるかを検査する
+// composite actionではdefaults.run.shellが使えないので、ステップごとにshell:が必須になる
+// * https://docs.github.com/en/actions/sharing-automations/creating-actions/metadata-syntax-for-github-actions#runsstepsshell
+func (rule *ActionMetadataRule) checkCompositeSteps(steps []*ast.Step) {
+	for _, s := range steps {
+		e, ok := s.Exec.(*ast.ExecRun)
+		if !ok || e.Shell != nil {
+			continue
+		}
+		pos := s.Pos
+		if e.Run != nil {
+			pos = e.Run.Pos
+		}
+		rule.Errorf(pos, "\"shell\" is required for \"run\" step in composite action. add \"shell: bash\" or other shell explicitly")
+	}
+}
+
+// actionRunsAvailableKeysは、アクションの種類ごとにruns:セクションで使えるキー
+var actionRunsAvailableKeys = map[string]map[string]struct{}{
+	"JavaScript": {
+		"using": {}, "main": {}, "pre": {}, "pre-if": {}, "post": {}, "post-if": {},
+	},
+	"Docker container": {
+		"using": {}, "image": {}, "entrypoint": {}, "pre-entrypoint": {}, "post-entrypoint": {}, "args": {}, "env": {},
+	},
+	"composite": {
+		"using": {}, "steps": {},
+	},
+}
+
+type actionRunsKey struct {
+	name string
+	pos  *ast.Position
+}
+
+// actionRunsKeysは、runs:セクションに書かれているキーをソースに現れる順番で返す
+func actionRunsKeys(runs *ast.ActionRuns) []actionRunsKey {
+	var keys []actionRunsKey
+	for _, k := range []struct {
+		name string
+		val  *ast.String
+	}{
+		{"using", runs.Using},
+		{"main", runs.Main},
+		{"pre", runs.Pre},
+		{"pre-if", runs.PreIf},
+		{"post", runs.Post},
+		{"post-if", runs.PostIf},
+		{"image", runs.Image},
+		{"entrypoint", runs.Entrypoint},
+		{"pre-entrypoint", runs.PreEntrypoint},
+		{"post-entrypoint", runs.PostEntrypoint},
+	} {
+		if k.val != nil {
+			keys = append(keys, actionRunsKey{k.name, k.val.Pos})
+		}
+	}
+	if len(runs.Args) > 0 {
+		keys = append(keys, actionRunsKey{"args", runs.Args[0].Pos})
+	}
+	if runs.Env != nil {
+		keys = append(keys, actionRunsKey{"env", runs.Pos})
+	}
+	if len(runs.Steps) > 0 {
+		keys = append(keys, actionRunsKey{"steps", runs.Steps[0].Pos})
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		return keys[i].pos.Line < keys[j].pos.Line
+	})
+	return keys
+}
+
+// sortedQuotesは、マップのキーをソートして引用符付きでカンマ区切りにした文字列を返す
+func sortedQuotes(m map[string]string) string {
+	ks := make([]string, 0, len(m))
+	for k := range m {
+		ks = append(ks, "\""+k+"\"")
+	}
+	sort.Strings(ks)
+	return strings.Join(ks, ", ")
+}
@@ -0,0 +1,262 @@
+package core
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseAction(t *testing.T) {
+	src := `name: Greet
+description: Greets someone
+inputs:
+  Who-To-Greet:
+    description: who to greet
+    required: true
+    deprecationMessage: use "name" instead
+outputs:
+  greeting:
+    value: ${{ steps.greet.outputs.greeting }}
+runs:
+  using: composite
+  steps:
+    - id: greet
+      run: echo "hello"
+      shell: bash
+branding:
+  icon: sun
+  color: yellow
+`
+	w, errs := ParseAction([]byte(src))
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	a := w.Action
+	if a == nil || a.Name.Value != "Greet" || a.Runs.Using.Value != "composite" {
+		t.Fatalf("unexpected action metadata: %#v", a)
+	}
+	in, ok := a.Inputs["who-to-greet"]
+	if !ok || in.Name.Value != "Who-To-Greet" || !in.Required.Value || in.DeprecationMessage == nil {
+		t.Errorf("unexpected inputs: %#v", a.Inputs)
+	}
+	if o, ok := a.Outputs["greeting"]; !ok || o.Value == nil {
+		t.Errorf("unexpected outputs: %#v", a.Outputs)
+	}
+	j, ok := w.Jobs[ActionMetadataJobID]
+	if !ok || len(j.Steps) != 1 || j.Steps[0].ID.Value != "greet" {
+		t.Fatalf("steps should be in pseudo job: %#v", w.Jobs)
+	}
+
+	for _, tt := range []struct{ src, want string }{
+		{"name: a\n", `missing required key "runs"`},
+		{"name: a\nruns:\n  main: index.js\n", `missing required key "using"`},
+		{"name: a\non: push\nruns:\n  using: node20\n  main: index.js\n", `unexpected key "on" for "action metadata" section`},
+		{"name: a\nruns:\n  using: node20\n  jobs: {}\n", `unexpected key "jobs" for "runs" section`},
+		{"name: a\ninputs:\n  x:\n    type: string\nruns:\n  using: node20\n  main: index.js\n", `unexpected key "type" for "input" section`},
+	} {
+		_, errs := ParseAction([]byte(tt.src))
+		if len(errs) != 1 || !strings.Contains(errs[0].Description, tt.want) {
+			t.Errorf("want error %q for %q but got %v", tt.want, tt.src, errs)
+		}
+	}
+}
+
+func TestActionMetadataRule(t *testing.T) {
+	tests := []struct {
+		name string
+		runs string
+		want []string
+	}{
+		{
+			name: "javascript action",
+			runs: "using: node24\n  main: dist/index.js\n  post: dist/cleanup.js",
+		},
+		{
+			name: "docker action",
+			runs: "using: docker\n  image: Dockerfile\n  args:\n    - foo\n  env:\n    FOO: bar",
+		},
+		{
+			name: "composite action",
+			runs: "using: composite\n  steps:\n    - run: echo\n      shell: bash\n    - uses: actions/checkout@v4",
+		},
+		{
+			name: "invalid runtime",
+			runs: "using: node18\n  main: index.js",
+			want: []string{`invalid runtime "node18" in "runs.using". available runtimes are "composite", "docker", "node12", "node16", "node20", "node24"`},
+		},
+		{
+			name: "deprecated runtime",
+			runs: "using: node16\n  main: index.js",
+			want: []string{`runtime "node16" in "runs.using" is deprecated`},
+		},
+		{
+			name: "missing main",
+			runs: "using: node20\n  pre: setup.js",
+			want: []string{`"main" is required in "runs" section of JavaScript action`},
+		},
+		{
+			name: "missing image",
+			runs: "using: docker\n  entrypoint: /entrypoint.sh",
+			want: []string{`"image" is required in "runs" section of Docker container action`},
+		},
+		{
+			name: "missing steps",
+			runs: "using: composite",
+			want: []string{`"steps" is required in "runs" section of composite action`},
+		},
+		{
+			name: "unavailable keys",
+			runs: "using: composite\n  main: index.js\n  steps:\n    - uses: actions/checkout@v4\n  image: Dockerfile",
+			want: []string{
+				`"main" is not available in "runs" section of composite action`,
+				`"image" is not available in "runs" section of composite action`,
+			},
+		},
+		{
+			name: "shell is missing",
+			runs: "using: composite\n  steps:\n    - run: echo one\n      shell: bash\n    - run: echo two",
+			want: []string{`"shell" is required for "run" step in composite action`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, errs := ParseAction([]byte("name: test\nruns:\n  " + tt.runs + "\n"))
+			if len(errs) > 0 {
+				t.Fatal(errs)
+			}
+			rule := NewActionMetadataRule()
+			v := NewSyntaxTreeVisitor()
+			v.AddVisitor(rule)
+			if err := v.VisitTree(w); err != nil {
+				t.Fatal(err)
+			}
+			got := rule.Errors()
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d errors, want %d: %v", len(got), len(tt.want), got)
+			}
+			for i, want := range tt.want {
+				if !strings.Contains(got[i].Description, want) {
+					t.Errorf("error %q does not contain %q", got[i].Description, want)
+				}
+			}
+		})
+	}
+}
+
+func TestActionMetadata_InputsAreUntrusted(t *testing.T) {
+	src := `name: test
+inputs:
+  title:
+    description: title
+runs:
+  using: composite
+  steps:
+    - run: echo "${{ inputs.title }}"
+      shell: bash
+    - run: echo "${{ inputs.unknown }} ${{ github.event.issue.title }}"
+      shell: bash
+    - run: echo "TITLE=${{ inputs.title }}" >> "$GITHUB_ENV"
+      shell: bash
+`
+	w, errs := ParseAction([]byte(src))
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	code := CodeInjectionMediumRule()
+	env := EnvVarInjectionMediumRule()
+	v := NewSyntaxTreeVisitor()
+	v.AddVisitor(code)
+	v.AddVisitor(env)
+	if err := v.VisitTree(w); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, e := range code.Errors() {
+		got = append(got, e.Description)
+	}
+	if len(got) != 3 || !strings.Contains(got[0], `"inputs.title"`) || !strings.Contains(got[1], `"github.event.issue.title"`) || !strings.Contains(got[2], `"inputs.title"`) {
+		t.Errorf("unexpected code injection errors: %v", got)
+	}
+	if errs := env.Errors(); len(errs) != 1 || !strings.Contains(errs[0].Description, `"inputs.title"`) {
+		t.Errorf("unexpected envvar injection errors: %v", errs)
+	}
+
+	// ワークフローではinputsは信頼できない入力として扱わない
+	wf, errs := Parse([]byte("on:\n  workflow_dispatch:\n    inputs:\n      title:\n        type: string\njobs:\n  test:\n    runs-on: ubuntu-latest\n    steps:\n      - run: echo \"${{ inputs.title }}\"\n"))
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	code = CodeInjectionMediumRule()
+	v = NewSyntaxTreeVisitor()
+	v.AddVisitor(code)
+	if err := v.VisitTree(wf); err != nil {
+		t.Fatal(err)
+	}
+	if errs := code.Errors(); len(errs) > 0 {
+		t.Errorf("inputs in workflow should not be reported: %v", errs)
+	}
+}
+
+func TestLinter_LintRepositoryWithActions(t *testing.T) {
+	root := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		".github/workflows/ci.yml": "on: push\njobs:\n  test:\n    runs-on: ubuntu-latest\n    timeout-minutes: 5\n    steps:\n      - run: echo\n",
+		".github/actions/greet/action.yml": `name: greet
+inputs:
+  who:
+    description: who
+runs:
+  using: composite
+  steps:
+    - run: echo "${{ inputs.who }}"
+`,
+		"action.yaml": "name: root\nruns:\n  using: node16\n  main: index.js\n",
+	}
+	for name, content := range files {
+		p := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	l, err := NewLinter(io.Discard, &LinterOptions{CurrentWorkingDirectoryPath: root})
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := l.LintRepository(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("want 3 results but got %d", len(results))
+	}
+
+	// 結果のパスは作業ディレクトリからの相対パスになる
+	types := map[string][]string{}
+	for _, res := range results {
+		for _, e := range res.Errors {
+			types[filepath.ToSlash(res.FilePath)] = append(types[filepath.ToSlash(res.FilePath)], e.Type)
+		}
+	}
+	want := map[string][]string{
+		".github/actions/greet/action.yml": {"action-metadata", "code-injection-medium"},
+		"action.yaml":                      {"action-metadata"},
+		// permissionsやtimeout-minutesなどのワークフローのルールはアクションのファイルには適用しない
+		".github/workflows/ci.yml": {"permissions", "missing-timeout-minutes"},
+	}
+	for f, ts := range want {
+		if strings.Join(types[f], ",") != strings.Join(ts, ",") {
+			t.Errorf("want errors %v in %s but got %v", ts, f, types[f])
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sisaku-security/sisakulint/pkg/ast"
+	"github.com/sisaku-security/sisakulint/pkg/expressions"
+)
+
+// RuleActionは、ステップのwith:の入力をアクションのメタデータ(action.yml)と照合するルール
+// ローカルのアクションはリポジトリのaction.ymlを読み込み、リモートのアクションは埋め込みのデータセットを使う
+// 非推奨のアクションや、サポートが終了したNode.jsで動くアクションも報告する
+// * https://docs.github.com/en/actions/creating-actions/metadata-syntax-for-github-actions#inputs
+type RuleAction struct {
+	BaseRule
+	cache *LocalActionsMetadataCache
+}
+
+// ActionRuleは新しいRuleActionインスタンスを作成する
+func ActionRule(cache *LocalActionsMetadataCache) *RuleAction {
+	return &RuleAction{
+		BaseRule: BaseRule{
+			RuleName: "action",
+			RuleDesc: "Checks for inputs of actions at \"with:\" against metadata of the actions. Missing required inputs, unknown inputs, deprecated inputs and deprecated actions are checked",
+		},
+		cache: cache,
+	}
+}
+
+// VisitStepはStepノードを訪れたときのcallback
+func (rule *RuleAction) VisitStep(n *ast.Step) error {
+	e, ok := n.Exec.(*ast.ExecAction)
+	if !ok || e.Uses == nil || strings.Contains(e.Uses.Value, "${{") {
+		return nil
+	}
+
+	spec := e.Uses.Value
+	if strings.HasPrefix(spec, "docker://") {
+		return nil
+	}
+	meta, err := rule.cache.FindMetadata(pinnedActionSpec(e.Uses))
+	if err != nil {
+		rule.Error(e.Uses.Pos, err.Error())
+		return nil
+	}
+	if meta == nil {
+		rule.Debug("Skip checking inputs of action %q since no metadata was found", spec)
+		return nil
+	}
+
+	rule.checkDeprecation(e, meta)
+	rule.checkInputs(e, meta)
+	return nil
+}
+
+// checkDeprecationは、非推奨のアクションと、サポートが終了した実行環境で動くアクションを報告する
+func (rule *RuleAction) checkDeprecation(e *ast.ExecAction, meta *ActionMetadata) {
+	if meta.Deprecated != "" {
+		rule.Errorf(e.Uses.Pos, "action %q is deprecated: %s", e.Uses.Value, meta.Deprecated)
+		return
+	}
+	if _, ok := deprecatedActionRuntimes[meta.Runs.Using]; ok {
+		rule.Errorf(
+			e.Uses.Pos,
+			"action %q runs on %q which is no longer supported by GitHub-hosted runners. update the action to a newer version. see https://github.blog/changelog/2023-09-22-github-actions-transitioning-from-node-16-to-node-20/",
+			e.Uses.Value,
+			meta.Runs.Using,
+		)
+	}
+}
+
+// pinnedActionSpecは、コミットSHAでピン留めされたアクションを横の"# vX.Y.Z"のコメントのバージョンの参照に変換する
+func pinnedActionSpec(uses *ast.String) string {
+	spec := uses.Value
+	repo, path, ref, ok := splitActionRef(spec)
+	if !ok || !fullShaPattern.MatchString(ref) || uses.BaseNode == nil {
+		return spec
+	}
+	comment := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(uses.BaseNode.LineComment), "#"))
+	if !majorVersionPattern.MatchString(comment) {
+		return spec
+	}
+	return repo + path + "@" + comment
+}
+
+func (rule *RuleAction) checkInputs(e *ast.ExecAction, meta *ActionMetadata) {
+	spec := e.Uses.Value
+
+	for id, i := range meta.Inputs {
+		if i == nil || !i.Required {
+			continue
+		}
+		if _, ok := e.Inputs[id]; !ok {
+			rule.Errorf(e.Uses.Pos, "missing input %q which is required by action %q. all required inputs are %s", i.Name, spec, requiredInputs(meta))
+		}
+	}
+
+	if meta.SkipInputs {
+		return
+	}
+	for id, i := range e.Inputs {
+		m, ok := meta.Inputs[id]
+		if !ok {
+			note := "no input is defined"
+			if len(meta.Inputs) > 0 {
+				is := make([]string, 0, len(meta.Inputs))
+				for _, i := range meta.Inputs {
+					is = append(is, i.Name)
+				}
+				if len(is) == 1 {
+					note = fmt.Sprintf("defined input is %q", is[0])
+				} else {
+					note = "defined inputs are " + expressions.SortedQuotes(is)
+				}
+			}
+			rule.Errorf(i.Name.Pos, "input %q is not defined in action %q. %s", i.Name.Value, spec, note)
+			continue
+		}
+		if m.Deprecated != "" {
+			rule.Errorf(i.Name.Pos, "input %q of action %q is deprecated: %s", i.Name.Value, spec, strings.TrimSpace(m.Deprecated))
+		}
+	}
+}
+
+func requiredInputs(meta *ActionMetadata) string {
+	is := []string{}
+	for _, i := range meta.Inputs {
+		if i != nil && i.Required {
+			is = append(is, i.Name)
+		}
+	}
+	return expressions.SortedQuotes(is)
+}
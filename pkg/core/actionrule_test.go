@@ -0,0 +1,158 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testLocalActionMetadata = `name: Local
+inputs:
+  target:
+    required: true
+  mode:
+    required: true
+    default: fast
+  legacy-flag:
+    deprecationMessage: use mode instead
+runs:
+  using: node20
+  main: index.js
+`
+
+func TestRuleAction(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, ".github", "actions", "local")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "action.yml"), []byte(testLocalActionMetadata), 0644); err != nil {
+		t.Fatal(err)
+	}
+	broken := filepath.Join(root, ".github", "actions", "broken")
+	if err := os.MkdirAll(broken, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(broken, "action.yml"), []byte("inputs: [oops]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	proj, err := NewProject(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		step       string
+		wantErrors []string
+	}{
+		{
+			name: "local action with valid inputs",
+			step: "uses: ./.github/actions/local\n        with:\n          target: all",
+		},
+		{
+			name:       "missing required input",
+			step:       "uses: ./.github/actions/local\n        with:\n          mode: slow",
+			wantErrors: []string{`missing input "target" which is required by action "./.github/actions/local". all required inputs are "target"`},
+		},
+		{
+			name:       "unknown input",
+			step:       "uses: ./.github/actions/local\n        with:\n          target: all\n          tagret: all",
+			wantErrors: []string{`input "tagret" is not defined in action "./.github/actions/local". defined inputs are "legacy-flag", "mode", "target"`},
+		},
+		{
+			name:       "deprecated input",
+			step:       "uses: ./.github/actions/local\n        with:\n          target: all\n          legacy-flag: true",
+			wantErrors: []string{`input "legacy-flag" of action "./.github/actions/local" is deprecated: use mode instead`},
+		},
+		{
+			name:       "broken metadata",
+			step:       "uses: ./.github/actions/broken",
+			wantErrors: []string{"failed to parse action metadata file"},
+		},
+		{
+			name: "local action without metadata",
+			step: "uses: ./.github/actions/missing\n        with:\n          foo: bar",
+		},
+		{
+			name:       "popular action with unknown input",
+			step:       "uses: actions/checkout@v4\n        with:\n          fetch_depth: 0",
+			wantErrors: []string{`input "fetch_depth" is not defined in action "actions/checkout@v4"`},
+		},
+		{
+			name:       "popular action with full version",
+			step:       "uses: actions/cache@v4.2.0\n        with:\n          path: dist",
+			wantErrors: []string{`missing input "key" which is required by action "actions/cache@v4.2.0"`},
+		},
+		{
+			name:       "popular action pinned with version comment",
+			step:       "uses: actions/cache@" + testCacheSHA + " # v4.1.0\n        with:\n          path: dist\n          key: k\n          save-always: true",
+			wantErrors: []string{`input "save-always" of action "actions/cache@` + testCacheSHA + `" is deprecated`},
+		},
+		{
+			name: "inputs are case-insensitive",
+			step: "uses: actions/cache@v4\n        with:\n          path: dist\n          key: k\n          enableCrossOsArchive: true",
+		},
+		{
+			name:       "action on deprecated runtime",
+			step:       "uses: actions/checkout@v2",
+			wantErrors: []string{`action "actions/checkout@v2" runs on "node12" which is no longer supported`},
+		},
+		{
+			name:       "deprecated action",
+			step:       "uses: actions/upload-artifact@v3\n        with:\n          path: dist",
+			wantErrors: []string{`action "actions/upload-artifact@v3" is deprecated: v3 of actions/upload-artifact is no longer available`},
+		},
+		{
+			name: "action not in catalog",
+			step: "uses: someone/unknown-action@v1\n        with:\n          anything: ok",
+		},
+		{
+			name: "docker action",
+			step: "uses: docker://alpine:3\n        with:\n          args: echo",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := "on: push\njobs:\n  test:\n    runs-on: ubuntu-latest\n    steps:\n      - " + tt.step + "\n"
+			w, errs := Parse([]byte(src))
+			if len(errs) > 0 {
+				t.Fatal(errs)
+			}
+			rule := ActionRule(NewLocalActionsMetadataCache(proj, nil))
+			v := NewSyntaxTreeVisitor()
+			v.AddVisitor(rule)
+			if err := v.VisitTree(w); err != nil {
+				t.Fatal(err)
+			}
+
+			got := rule.Errors()
+			if len(got) != len(tt.wantErrors) {
+				t.Fatalf("got %d errors, want %d: %v", len(got), len(tt.wantErrors), got)
+			}
+			for i, want := range tt.wantErrors {
+				if !strings.Contains(got[i].Description, want) {
+					t.Errorf("error %q does not contain %q", got[i].Description, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRuleAction_NilCache(t *testing.T) {
+	w, errs := Parse([]byte("on: push\njobs:\n  test:\n    runs-on: ubuntu-latest\n    steps:\n      - uses: ./local\n"))
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	rule := ActionRule(nil)
+	v := NewSyntaxTreeVisitor()
+	v.AddVisitor(rule)
+	if err := v.VisitTree(w); err != nil {
+		t.Fatal(err)
+	}
+	if len(rule.Errors()) != 0 {
+		t.Errorf("unexpected errors: %v", rule.Errors())
+	}
+}
@@ -0,0 +1,334 @@
+package core
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// advisoryEcosystemはOSVのアドバイザリでGitHub Actionsのパッケージを表すエコシステムの名前
+// * https://ossf.github.io/osv-schema/#affectedpackage-field
+const advisoryEcosystem = "GitHub Actions"
+
+//go:embed advisories.json
+var advisoriesJSON []byte
+
+// Advisoryはアクションの脆弱性や侵害を表すOSV形式のアドバイザリ。lintに必要なフィールドだけを持つ
+// * https://ossf.github.io/osv-schema/
+type Advisory struct {
+	// IDはアドバイザリのID。"GHSA-xxxx-xxxx-xxxx"など
+	ID string `json:"id"`
+	// AliasesはCVEなどの別名のID
+	Aliases []string `json:"aliases,omitempty"`
+	// Summaryはアドバイザリの1行の説明
+	Summary string `json:"summary,omitempty"`
+	// Affectedは影響を受けるパッケージとバージョンのリスト
+	Affected []*AdvisoryAffected `json:"affected"`
+	// Referencesはアドバイザリの参照先のURLのリスト
+	References []*AdvisoryReference `json:"references,omitempty"`
+	// DatabaseSpecificはデータベース固有の情報。GitHub Advisory Databaseの深刻度を読む
+	DatabaseSpecific struct {
+		Severity string `json:"severity,omitempty"`
+	} `json:"database_specific"`
+}
+
+// AdvisoryAffectedはアドバイザリの影響を受ける1つのパッケージ
+type AdvisoryAffected struct {
+	Package struct {
+		Ecosystem string `json:"ecosystem"`
+		// Nameは"owner/repo"または"owner/repo/path"のアクションの名前
+		Name string `json:"name"`
+	} `json:"package"`
+	// Rangesは影響を受けるバージョンの範囲。SEMVERとECOSYSTEMの範囲だけを扱う
+	Ranges []*AdvisoryRange `json:"ranges,omitempty"`
+	// Versionsは影響を受けるバージョンのリスト。侵害されたコミットのSHAも書ける
+	Versions []string `json:"versions,omitempty"`
+}
+
+// AdvisoryRangeはOSVのバージョンの範囲。eventsを順に評価する
+type AdvisoryRange struct {
+	Type   string `json:"type"`
+	Events []struct {
+		Introduced   string `json:"introduced,omitempty"`
+		Fixed        string `json:"fixed,omitempty"`
+		LastAffected string `json:"last_affected,omitempty"`
+	} `json:"events"`
+}
+
+// AdvisoryReferenceはアドバイザリの参照先
+type AdvisoryReference struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+// URLはアドバイザリの詳細のURLを返す
+func (a *Advisory) URL() string {
+	for _, r := range a.References {
+		if r.Type == "ADVISORY" && r.URL != "" {
+			return r.URL
+		}
+	}
+	if strings.HasPrefix(a.ID, "GHSA-") {
+		return "https://github.com/advisories/" + a.ID
+	}
+	if len(a.References) > 0 {
+		return a.References[0].URL
+	}
+	return ""
+}
+
+// Severityはアドバイザリの深刻度を返す。深刻度が記録されていない場合はSeverityNoneを返す
+func (a *Advisory) Severity() Severity {
+	switch strings.ToUpper(a.DatabaseSpecific.Severity) {
+	case "CRITICAL":
+		return SeverityCritical
+	case "HIGH":
+		return SeverityHigh
+	case "MODERATE", "MEDIUM":
+		return SeverityMedium
+	case "LOW":
+		return SeverityLow
+	default:
+		return SeverityNone
+	}
+}
+
+// AdvisoryDatabaseはアクションのアドバイザリのデータベース
+type AdvisoryDatabase struct {
+	advisories []*Advisory
+}
+
+// defaultAdvisoryDatabaseは埋め込みのadvisories.jsonから作るデータベース
+var defaultAdvisoryDatabase = func() *AdvisoryDatabase {
+	db, err := parseAdvisoryDatabase(advisoriesJSON)
+	if err != nil {
+		panic(fmt.Sprintf("could not parse advisories.json: %v", err))
+	}
+	return db
+}()
+
+// DefaultAdvisoryDatabaseは埋め込みのアドバイザリのデータベースを返す
+func DefaultAdvisoryDatabase() *AdvisoryDatabase {
+	return defaultAdvisoryDatabase
+}
+
+// parseAdvisoryDatabaseはOSVのアドバイザリのJSONの配列、または1つのアドバイザリのJSONを読み込む
+func parseAdvisoryDatabase(b []byte) (*AdvisoryDatabase, error) {
+	var as []*Advisory
+	if t := bytes.TrimSpace(b); len(t) > 0 && t[0] == '{' {
+		var a Advisory
+		if err := json.Unmarshal(t, &a); err != nil {
+			return nil, err
+		}
+		as = []*Advisory{&a}
+	} else if err := json.Unmarshal(b, &as); err != nil {
+		return nil, err
+	}
+	for i, a := range as {
+		if a == nil || a.ID == "" {
+			return nil, fmt.Errorf("advisory at index %d has no \"id\"", i)
+		}
+	}
+	return &AdvisoryDatabase{as}, nil
+}
+
+// ReadAdvisoryDatabaseは-advisory-dbで指定されたファイルまたはディレクトリからアドバイザリを読み込み、埋め込みのデータベースと合わせたデータベースを返す
+// ディレクトリの場合は直下の*.jsonファイルを全て読み込む。埋め込みのアドバイザリと同じIDのアドバイザリは読み込んだもので置き換える
+func ReadAdvisoryDatabase(path string) (*AdvisoryDatabase, error) {
+	files := []string{path}
+	if st, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("could not read advisory database %q: %w", path, err)
+	} else if st.IsDir() {
+		fs, err := filepath.Glob(filepath.Join(path, "*.json"))
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(fs)
+		files = fs
+	}
+
+	db := &AdvisoryDatabase{}
+	for _, f := range files {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			return nil, fmt.Errorf("could not read advisory database %q: %w", f, err)
+		}
+		d, err := parseAdvisoryDatabase(b)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse advisory database %q: %w", f, err)
+		}
+		db.advisories = append(db.advisories, d.advisories...)
+	}
+	return defaultAdvisoryDatabase.merge(db), nil
+}
+
+// mergeはotherのアドバイザリを加えたデータベースを返す。同じIDのアドバイザリはotherのものを使う
+func (db *AdvisoryDatabase) merge(other *AdvisoryDatabase) *AdvisoryDatabase {
+	ids := make(map[string]struct{}, len(other.advisories))
+	for _, a := range other.advisories {
+		ids[a.ID] = struct{}{}
+	}
+	merged := &AdvisoryDatabase{}
+	for _, a := range db.advisories {
+		if _, ok := ids[a.ID]; !ok {
+			merged.advisories = append(merged.advisories, a)
+		}
+	}
+	merged.advisories = append(merged.advisories, other.advisories...)
+	return merged
+}
+
+// AdvisoryMatchは参照が影響を受けるアドバイザリ
+type AdvisoryMatch struct {
+	Advisory *Advisory
+	// Fixedは修正されたバージョン。修正されたバージョンがない場合は空
+	Fixed string
+}
+
+// Matchは"owner/repo[/path]"のアクションのrefが影響を受けるアドバイザリを返す
+// refはタグかコミットのSHAで、versionsはSHAに対応するバージョン(ピン留めの横のコメントやロックファイルのバージョン)
+func (db *AdvisoryDatabase) Match(action, ref string, versions []string) []*AdvisoryMatch {
+	if db == nil {
+		return nil
+	}
+	var ms []*AdvisoryMatch
+	for _, a := range db.advisories {
+		for _, af := range a.Affected {
+			if !strings.EqualFold(af.Package.Ecosystem, advisoryEcosystem) || !advisoryPackageMatches(af.Package.Name, action) {
+				continue
+			}
+			if fixed, ok := af.match(ref, versions); ok {
+				ms = append(ms, &AdvisoryMatch{a, fixed})
+				break
+			}
+		}
+	}
+	return ms
+}
+
+// advisoryPackageMatchesは、アドバイザリのパッケージ名がアクションに一致するかを返す
+// "owner/repo"のパッケージはそのリポジトリの全てのアクションに一致する
+func advisoryPackageMatches(name, action string) bool {
+	name = strings.ToLower(name)
+	action = strings.ToLower(action)
+	return name == action || strings.HasPrefix(action, name+"/")
+}
+
+// matchはrefが影響を受けるかを返す。影響を受ける場合は修正されたバージョンも返す
+func (af *AdvisoryAffected) match(ref string, versions []string) (string, bool) {
+	for _, v := range af.Versions {
+		if v == ref || strings.TrimPrefix(v, "v") == strings.TrimPrefix(ref, "v") {
+			return af.fixedVersion(), true
+		}
+	}
+	candidates := append([]string{ref}, versions...)
+	for _, c := range candidates {
+		v, ok := parseAdvisoryVersion(c, true)
+		if !ok {
+			continue
+		}
+		for _, r := range af.Ranges {
+			if fixed, ok := r.contains(v); ok {
+				return fixed, true
+			}
+		}
+	}
+	return "", false
+}
+
+// fixedVersionは範囲の中の最大の修正されたバージョンを返す
+func (af *AdvisoryAffected) fixedVersion() string {
+	fixed := ""
+	var latest advisoryVersion
+	for _, r := range af.Ranges {
+		for _, e := range r.Events {
+			if v, ok := parseAdvisoryVersion(e.Fixed, false); ok && (fixed == "" || latest.compare(v) < 0) {
+				fixed, latest = e.Fixed, v
+			}
+		}
+	}
+	return fixed
+}
+
+// containsはバージョンが範囲に含まれるかを返す。含まれる場合はその区間を閉じる修正されたバージョンも返す
+func (r *AdvisoryRange) contains(v advisoryVersion) (string, bool) {
+	if r.Type != "SEMVER" && r.Type != "ECOSYSTEM" {
+		return "", false
+	}
+	affected := false
+	for _, e := range r.Events {
+		switch {
+		case e.Introduced != "":
+			iv, ok := parseAdvisoryVersion(e.Introduced, false)
+			affected = e.Introduced == "0" || ok && iv.compare(v) <= 0
+		case e.Fixed != "":
+			fv, ok := parseAdvisoryVersion(e.Fixed, false)
+			if affected && ok {
+				if v.compare(fv) < 0 {
+					return e.Fixed, true
+				}
+				affected = false
+			}
+		case e.LastAffected != "":
+			lv, ok := parseAdvisoryVersion(e.LastAffected, false)
+			if affected && ok {
+				if v.compare(lv) <= 0 {
+					return "", true
+				}
+				affected = false
+			}
+		}
+	}
+	return "", affected
+}
+
+// advisoryVersionは"v1.2.3"のようなセマンティックバージョン
+type advisoryVersion [3]int
+
+// parseAdvisoryVersionは"v1.2.3"や"1.2"のようなバージョンを解析する
+// floatingがtrueの場合、"v4"のような省略されたバージョンは動くタグとして、そのメジャーバージョンの最新のバージョンとみなす
+// 省略された部分はfloatingがfalseの場合は0になる
+func parseAdvisoryVersion(s string, floating bool) (advisoryVersion, bool) {
+	var v advisoryVersion
+	s = strings.TrimPrefix(s, "v")
+	if s == "" {
+		return v, false
+	}
+	ps := strings.Split(s, ".")
+	if len(ps) > 3 {
+		return v, false
+	}
+	for i := range v {
+		if i >= len(ps) {
+			if floating {
+				v[i] = math.MaxInt
+			}
+			continue
+		}
+		n, err := strconv.Atoi(ps[i])
+		if err != nil || n < 0 {
+			return v, false
+		}
+		v[i] = n
+	}
+	return v, true
+}
+
+func (v advisoryVersion) compare(o advisoryVersion) int {
+	for i := range v {
+		if v[i] != o[i] {
+			if v[i] < o[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,298 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testAdvisories = `[
+  {
+    "id": "GHSA-test-0001",
+    "aliases": ["CVE-2099-0001"],
+    "summary": "vulnerable",
+    "affected": [
+      {
+        "package": {"ecosystem": "GitHub Actions", "name": "owner/vuln"},
+        "ranges": [{"type": "SEMVER", "events": [{"introduced": "2.0.0"}, {"fixed": "2.3.1"}, {"introduced": "3.0.0"}, {"last_affected": "3.1.0"}]}]
+      }
+    ],
+    "database_specific": {"severity": "CRITICAL"}
+  },
+  {
+    "id": "GHSA-test-0002",
+    "summary": "compromised",
+    "affected": [
+      {
+        "package": {"ecosystem": "GitHub Actions", "name": "owner/compromised"},
+        "versions": ["v1.0.0", "1111111111111111111111111111111111111111"]
+      }
+    ]
+  },
+  {
+    "id": "GHSA-test-0003",
+    "summary": "other ecosystem",
+    "affected": [
+      {
+        "package": {"ecosystem": "npm", "name": "owner/vuln"},
+        "ranges": [{"type": "SEMVER", "events": [{"introduced": "0"}]}]
+      }
+    ]
+  }
+]`
+
+const testVulnSHA = "2222222222222222222222222222222222222222"
+
+func TestAdvisoryDatabase_Match(t *testing.T) {
+	db, err := parseAdvisoryDatabase([]byte(testAdvisories))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		action   string
+		ref      string
+		versions []string
+		want     string
+		fixed    string
+	}{
+		{"affected version", "owner/vuln", "v2.1.0", nil, "GHSA-test-0001", "2.3.1"},
+		{"without v prefix", "owner/vuln", "2.3.0", nil, "GHSA-test-0001", "2.3.1"},
+		{"fixed version", "owner/vuln", "v2.3.1", nil, "", ""},
+		{"before introduced", "owner/vuln", "v1.9.9", nil, "", ""},
+		{"major tag before fix", "owner/vuln", "v2", nil, "", ""},
+		{"minor tag before fix", "owner/vuln", "v2.3", nil, "", ""},
+		{"minor tag affected", "owner/vuln", "v2.2", nil, "GHSA-test-0001", "2.3.1"},
+		{"last affected", "owner/vuln", "v3.1.0", nil, "GHSA-test-0001", ""},
+		{"after last affected", "owner/vuln", "v3.1.1", nil, "", ""},
+		{"package name is case-insensitive", "Owner/Vuln", "v2.1.0", nil, "GHSA-test-0001", "2.3.1"},
+		{"action in subdirectory", "owner/vuln/sub", "v2.1.0", nil, "GHSA-test-0001", "2.3.1"},
+		{"other repository", "owner/vuln2", "v2.1.0", nil, "", ""},
+		{"branch", "owner/vuln", "main", nil, "", ""},
+		{"SHA with version", "owner/vuln", testVulnSHA, []string{"v2.2.0"}, "GHSA-test-0001", "2.3.1"},
+		{"SHA without version", "owner/vuln", testVulnSHA, nil, "", ""},
+		{"compromised version", "owner/compromised", "1.0.0", nil, "GHSA-test-0002", ""},
+		{"compromised commit", "owner/compromised", "1111111111111111111111111111111111111111", nil, "GHSA-test-0002", ""},
+		{"other commit", "owner/compromised", testVulnSHA, nil, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ms := db.Match(tt.action, tt.ref, tt.versions)
+			if tt.want == "" {
+				if len(ms) > 0 {
+					t.Fatalf("unexpected match: %s", ms[0].Advisory.ID)
+				}
+				return
+			}
+			if len(ms) != 1 {
+				t.Fatalf("want 1 match, got %d", len(ms))
+			}
+			if ms[0].Advisory.ID != tt.want || ms[0].Fixed != tt.fixed {
+				t.Errorf("want %s fixed in %q, got %s fixed in %q", tt.want, tt.fixed, ms[0].Advisory.ID, ms[0].Fixed)
+			}
+		})
+	}
+}
+
+func TestReadAdvisoryDatabase(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "feed.json")
+	if err := os.WriteFile(file, []byte(testAdvisories), 0644); err != nil {
+		t.Fatal(err)
+	}
+	db, err := ReadAdvisoryDatabase(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(db.Match("owner/vuln", "v2.1.0", nil)) != 1 {
+		t.Error("advisory in the file is not loaded")
+	}
+	if len(db.Match("tj-actions/changed-files", "v45", nil)) == 0 {
+		t.Error("embedded advisories should be kept")
+	}
+
+	osv := filepath.Join(dir, "osv")
+	if err := os.Mkdir(osv, 0755); err != nil {
+		t.Fatal(err)
+	}
+	// 埋め込みのアドバイザリと同じIDのアドバイザリで置き換える
+	override := `{"id": "GHSA-mrrh-fwg8-r2c3", "summary": "overridden", "affected": [{"package": {"ecosystem": "GitHub Actions", "name": "tj-actions/changed-files"}, "versions": ["v1.0.0"]}]}`
+	if err := os.WriteFile(filepath.Join(osv, "GHSA-mrrh-fwg8-r2c3.json"), []byte(override), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(osv, "README.md"), []byte("not an advisory"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	db, err = ReadAdvisoryDatabase(osv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range db.Match("tj-actions/changed-files", "v45.0.0", nil) {
+		if m.Advisory.ID == "GHSA-mrrh-fwg8-r2c3" {
+			t.Error("embedded advisory should be overridden")
+		}
+	}
+	if ms := db.Match("tj-actions/changed-files", "v1.0.0", nil); len(ms) == 0 || ms[len(ms)-1].Advisory.Summary != "overridden" {
+		t.Error("overriding advisory is not loaded")
+	}
+
+	for _, c := range []struct{ name, content string }{
+		{"broken.json", "{"},
+		{"noid.json", `[{"summary": "no id"}]`},
+	} {
+		p := filepath.Join(dir, c.name)
+		if err := os.WriteFile(p, []byte(c.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ReadAdvisoryDatabase(p); err == nil {
+			t.Errorf("error should be returned for %s", c.name)
+		}
+	}
+	if _, err := ReadAdvisoryDatabase(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("error should be returned for missing file")
+	}
+}
+
+func TestActionAdvisoryRule(t *testing.T) {
+	db, err := parseAdvisoryDatabase([]byte(testAdvisories))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lock := &ActionLock{Actions: map[string]*ActionLockEntry{
+		"owner/vuln@v2.2.0": {SHA: testVulnSHA, Version: "v2.2.0"},
+		"owner/vuln@v2.3.1": {SHA: "3333333333333333333333333333333333333333", Version: "v2.3.1"},
+	}}
+
+	tests := []struct {
+		name     string
+		uses     string
+		want     []string
+		severity Severity
+		fixed    string
+	}{
+		{
+			name:     "vulnerable version",
+			uses:     "owner/vuln@v2.1.0",
+			want:     []string{`action "owner/vuln@v2.1.0" is affected by advisory GHSA-test-0001 (CVE-2099-0001): vulnerable. upgrade it to 2.3.1 or later`},
+			severity: SeverityCritical,
+			fixed:    "owner/vuln@v2.3.1",
+		},
+		{
+			name:  "minor tag is bumped to full version",
+			uses:  "owner/vuln@2.2",
+			want:  []string{"GHSA-test-0001"},
+			fixed: "owner/vuln@2.3.1",
+		},
+		{
+			name: "SHA resolved with lock file",
+			uses: "owner/vuln@" + testVulnSHA,
+			want: []string{"GHSA-test-0001"},
+			// ロックファイルに記録された修正バージョンのSHAに置き換える
+			fixed: "owner/vuln@3333333333333333333333333333333333333333 # v2.3.1",
+		},
+		{
+			name:     "compromised commit without fixed version",
+			uses:     "owner/compromised@1111111111111111111111111111111111111111",
+			want:     []string{"no fixed version is available"},
+			severity: SeverityHigh,
+		},
+		{
+			name: "safe version",
+			uses: "owner/vuln@v2.3.1",
+		},
+		{
+			name: "local action",
+			uses: "./owner/vuln",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := "on: push\njobs:\n  test:\n    runs-on: ubuntu-latest\n    steps:\n      - uses: " + tt.uses + "\n"
+			w, errs := Parse([]byte(src))
+			if len(errs) > 0 {
+				t.Fatal(errs)
+			}
+			rule := NewActionAdvisoryRule(db, lock)
+			v := NewSyntaxTreeVisitor()
+			v.AddVisitor(rule)
+			if err := v.VisitTree(w); err != nil {
+				t.Fatal(err)
+			}
+
+			got := rule.Errors()
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d errors, want %d: %v", len(got), len(tt.want), got)
+			}
+			for i, want := range tt.want {
+				if !strings.Contains(got[i].Description, want) {
+					t.Errorf("error %q does not contain %q", got[i].Description, want)
+				}
+				if tt.severity != SeverityNone && got[i].Severity != tt.severity {
+					t.Errorf("want severity %s, got %s", tt.severity, got[i].Severity)
+				}
+			}
+
+			fixers := rule.AutoFixers()
+			if tt.fixed == "" {
+				if len(fixers) > 0 {
+					t.Fatalf("unexpected autofix")
+				}
+				return
+			}
+			res := ApplyAutoFixers([]byte(src), w, fixers, nil)
+			if len(res.Failed) > 0 {
+				t.Fatal(res.Failed[0].Err)
+			}
+			want := strings.Replace(src, tt.uses, tt.fixed, 1)
+			if got := string(res.Source); got != want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestActionAdvisoryRule_EmbeddedDatabase(t *testing.T) {
+	src := `on: push
+jobs:
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: tj-actions/changed-files@v40
+      - uses: actions/download-artifact@v4
+      - uses: actions/download-artifact@v4.1.0
+`
+	w, errs := Parse([]byte(src))
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	rule := NewActionAdvisoryRule(nil, nil)
+	v := NewSyntaxTreeVisitor()
+	v.AddVisitor(rule)
+	if err := v.VisitTree(w); err != nil {
+		t.Fatal(err)
+	}
+	got := rule.Errors()
+	want := []string{"GHSA-mrrh-fwg8-r2c3", "GHSA-mcph-m25j-8j63", "GHSA-cxww-7g56-2vh6"}
+	if len(got) != len(want) {
+		t.Fatalf("got %d errors, want %d: %v", len(got), len(want), got)
+	}
+	for i, id := range want {
+		if !strings.Contains(got[i].Description, id) {
+			t.Errorf("error %q does not contain %q", got[i].Description, id)
+		}
+	}
+
+	// 複数のアドバイザリに該当する場合は最も新しい修正バージョンに上げる
+	res := ApplyAutoFixers([]byte(src), w, rule.AutoFixers(), nil)
+	if len(res.Failed) > 0 {
+		t.Fatal(res.Failed[0].Err)
+	}
+	fixed := string(res.Source)
+	for _, s := range []string{"tj-actions/changed-files@v46\n", "actions/download-artifact@v4\n", "actions/download-artifact@v4.1.7\n"} {
+		if !strings.Contains(fixed, s) {
+			t.Errorf("fixed source does not contain %q:\n%s", s, fixed)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sisaku-security/sisakulint/pkg/ast"
+)
+
+// ActionAdvisoryRuleは、ステップで使われているアクションの参照を既知のアドバイザリのデータベースと照合するルール
+// バージョンの範囲とコミットのSHAで照合し、アドバイザリのIDと修正されたバージョンを報告する
+// 修正されたバージョンがある場合は、参照をそのバージョンに上げる自動修正を提供する
+// * https://github.com/advisories?query=ecosystem%3Aactions
+type ActionAdvisoryRule struct {
+	BaseRule
+	db *AdvisoryDatabase
+	// lockはプロジェクトのアクションのロックファイル。SHAでピン留めされた参照のバージョンの解決と自動修正に使う
+	lock *ActionLock
+	// bumpsは自動修正で書き換えるステップのuses:の値と横のコメント
+	bumps map[*ast.Step]advisoryBump
+}
+
+// advisoryBumpは自動修正で書き換えるuses:の値と横のコメント。commentが空の場合はコメントを変更しない
+type advisoryBump struct {
+	uses    string
+	comment string
+}
+
+// NewActionAdvisoryRuleは新しいActionAdvisoryRuleインスタンスを作成する。dbがnilの場合は埋め込みのデータベースを使う
+func NewActionAdvisoryRule(db *AdvisoryDatabase, lock *ActionLock) *ActionAdvisoryRule {
+	if db == nil {
+		db = defaultAdvisoryDatabase
+	}
+	return &ActionAdvisoryRule{
+		BaseRule: BaseRule{
+			RuleName: "action-advisory",
+			RuleDesc: "Checks for actions affected by known security advisories such as vulnerable versions and compromised commits",
+		},
+		db:    db,
+		lock:  lock,
+		bumps: map[*ast.Step]advisoryBump{},
+	}
+}
+
+// VisitStepはStepノードを訪れたときのcallback
+func (rule *ActionAdvisoryRule) VisitStep(n *ast.Step) error {
+	e, ok := n.Exec.(*ast.ExecAction)
+	if !ok || e.Uses == nil || strings.Contains(e.Uses.Value, "${{") {
+		return nil
+	}
+	repo, path, ref, ok := splitActionRef(e.Uses.Value)
+	if !ok {
+		return nil
+	}
+
+	var versions []string
+	if fullShaPattern.MatchString(ref) {
+		if spec := pinnedActionSpec(e.Uses); spec != e.Uses.Value {
+			versions = append(versions, spec[strings.LastIndexByte(spec, '@')+1:])
+		}
+		versions = append(versions, rule.lock.versionsOf(repo, ref)...)
+	}
+
+	matches := rule.db.Match(repo+path, ref, versions)
+	if len(matches) == 0 {
+		return nil
+	}
+
+	fixed := ""
+	var latest advisoryVersion
+	for _, m := range matches {
+		a := m.Advisory
+		id := a.ID
+		if len(a.Aliases) > 0 {
+			id += " (" + strings.Join(a.Aliases, ", ") + ")"
+		}
+		remedy := "no fixed version is available. stop using the action or pin it to a commit which is known to be safe"
+		if m.Fixed != "" {
+			remedy = fmt.Sprintf("upgrade it to %s or later", m.Fixed)
+			if v, ok := parseAdvisoryVersion(m.Fixed, false); ok && (fixed == "" || latest.compare(v) < 0) {
+				fixed, latest = m.Fixed, v
+			}
+		}
+		msg := fmt.Sprintf("action %q is affected by advisory %s: %s. %s", e.Uses.Value, id, a.Summary, remedy)
+		if u := a.URL(); u != "" {
+			msg += ". see " + u
+		}
+		if sev := a.Severity(); sev != SeverityNone {
+			rule.ErrorfWithSeverity(e.Uses.Pos, sev, "%s", msg)
+		} else {
+			rule.Errorf(e.Uses.Pos, "%s", msg)
+		}
+	}
+
+	if fixed == "" {
+		return nil
+	}
+	if b, ok := rule.bump(repo, path, ref, fixed); ok {
+		rule.bumps[n] = b
+		rule.AddAutoFixer(NewStepFixer(n, rule))
+	}
+	return nil
+}
+
+// FixStepは、ステップのアクションの参照を修正されたバージョンに上げる
+func (rule *ActionAdvisoryRule) FixStep(n *ast.Step) error {
+	e, ok := n.Exec.(*ast.ExecAction)
+	b, found := rule.bumps[n]
+	if !ok || !found || e.Uses == nil || e.Uses.BaseNode == nil {
+		return nil
+	}
+	e.Uses.BaseNode.Value = b.uses
+	if b.comment != "" {
+		e.Uses.BaseNode.LineComment = b.comment
+	}
+	return nil
+}
+
+// bumpは、参照を修正されたバージョンに上げたuses:の値を返す
+// "v4"のようなメジャーバージョンのタグは修正されたバージョンのメジャーバージョンのタグに上げる
+// SHAでピン留めされた参照は、ロックファイルに修正されたバージョンが記録されている場合だけそのSHAに置き換える
+func (rule *ActionAdvisoryRule) bump(repo, path, ref, fixed string) (advisoryBump, bool) {
+	fixed = strings.TrimPrefix(fixed, "v")
+
+	if fullShaPattern.MatchString(ref) {
+		for _, tag := range []string{"v" + fixed, fixed} {
+			if entry := rule.lock.Lookup(repo, tag); entry != nil {
+				comment := entry.Version
+				if comment == "" {
+					comment = tag
+				}
+				return advisoryBump{repo + path + "@" + entry.SHA, comment}, true
+			}
+		}
+		return advisoryBump{}, false
+	}
+
+	if _, ok := parseAdvisoryVersion(ref, false); !ok {
+		return advisoryBump{}, false
+	}
+	bumped := fixed
+	if !strings.Contains(ref, ".") {
+		bumped, _, _ = strings.Cut(fixed, ".")
+	}
+	if strings.HasPrefix(ref, "v") {
+		bumped = "v" + bumped
+	}
+	return advisoryBump{uses: repo + path + "@" + bumped}, true
+}
@@ -0,0 +1,258 @@
+package core
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sisaku-security/sisakulint/pkg/ast"
+)
+
+// artifactWorkflow is a linted workflow taking part in the repository-level artifact poisoning analysis.
+type artifactWorkflow struct {
+	// path is the workflow path shown in error messages, relative to the repository root when possible.
+	path string
+	// name is what workflow_run.workflows: refers to. GitHub uses the workflow path when name: is missing.
+	name     string
+	workflow *ast.Workflow
+	cfg      *Config
+}
+
+// artifactUpload is a step uploading an artifact with actions/upload-artifact.
+type artifactUpload struct {
+	producer *artifactWorkflow
+	step     *ast.Step
+	name     string
+}
+
+// artifactPoisoningChains correlates artifacts uploaded by one workflow with privileged workflow_run workflows
+// which download them. The classic attack spans two files: a pull_request workflow has no secrets, but a fork
+// controls everything it uploads, and the workflow_run workflow triggered by it downloads the artifact with write
+// permissions and secrets.
+//
+// The result holds the errors of each workflow at the same index. An error is reported at the download step and
+// names every matching upload with its location. Chains are reported by artifact-poisoning-critical when a producer
+// can be triggered by pull requests from forks, and by artifact-poisoning-medium otherwise.
+func artifactPoisoningChains(workflows []*artifactWorkflow) [][]*LintingError {
+	byName := map[string][]*artifactWorkflow{}
+	for _, w := range workflows {
+		byName[w.name] = append(byName[w.name], w)
+	}
+
+	errs := make([][]*LintingError, len(workflows))
+	for i, consumer := range workflows {
+		var uploads []*artifactUpload
+		for _, producer := range workflowRunProducers(consumer, byName) {
+			uploads = append(uploads, artifactUploads(producer)...)
+		}
+		if len(uploads) == 0 {
+			continue
+		}
+		for _, step := range workflowSteps(consumer.workflow) {
+			name, pattern, workflow, ok := artifactDownload(step)
+			if !ok {
+				continue
+			}
+			var chain []string
+			fork := false
+			for _, u := range uploads {
+				if !matchesProducer(workflow, u.producer) || !matchesArtifact(name, pattern, u.name) {
+					continue
+				}
+				events := forkTriggers(u.producer)
+				if len(events) > 0 {
+					fork = true
+				} else {
+					for _, e := range u.producer.workflow.On {
+						events = append(events, e.EventName())
+					}
+				}
+				chain = append(chain, fmt.Sprintf(
+					"%q uploaded at %s:%d:%d by workflow %q on %s",
+					u.name,
+					u.producer.path,
+					u.step.Pos.Line,
+					u.step.Pos.Col,
+					u.producer.name,
+					strings.Join(events, ", "),
+				))
+			}
+			if len(chain) == 0 {
+				continue
+			}
+			if fork {
+				if !consumer.cfg.IsRuleEnabled("artifact-poisoning-critical") {
+					continue
+				}
+				errs[i] = append(errs[i], FormattedError(
+					step.Pos,
+					"artifact-poisoning-critical",
+					"artifact poisoning chain: step %q in privileged workflow_run workflow downloads artifacts uploaded by workflows which pull requests from forks can trigger: %s. The fork controls the contents of these artifacts. Treat them as untrusted data, extract them to '${{ runner.temp }}/artifacts' and never execute them. See https://codeql.github.com/codeql-query-help/actions/actions-artifact-poisoning-critical/",
+					step.String(),
+					strings.Join(chain, "; "),
+				))
+				continue
+			}
+			if !consumer.cfg.IsRuleEnabled("artifact-poisoning-medium") {
+				continue
+			}
+			errs[i] = append(errs[i], FormattedError(
+				step.Pos,
+				"artifact-poisoning-medium",
+				"artifact poisoning chain: step %q in privileged workflow_run workflow downloads artifacts uploaded by other workflows: %s. Forks cannot trigger these workflows, but validate artifact contents before use and avoid executing them. See https://codeql.github.com/codeql-query-help/actions/actions-artifact-poisoning-medium/",
+				step.String(),
+				strings.Join(chain, "; "),
+			))
+		}
+	}
+	return errs
+}
+
+// workflowRunProducers returns the workflows listed in workflow_run.workflows: of the privileged workflow_run
+// triggers of consumer. Trust is classified without the project, since the producers are what this analysis
+// looks at; only the trigger-trust configuration can make workflow_run unprivileged here.
+func workflowRunProducers(consumer *artifactWorkflow, byName map[string][]*artifactWorkflow) []*artifactWorkflow {
+	var tc *TriggerTrustConfig
+	if consumer.cfg != nil {
+		tc = &consumer.cfg.TriggerTrust
+	}
+	trust := NewTriggerTrustModel(tc, nil)
+
+	var producers []*artifactWorkflow
+	seen := map[*artifactWorkflow]bool{}
+	for _, e := range consumer.workflow.On {
+		w, ok := e.(*ast.WebhookEvent)
+		if !ok || w.Hook == nil || strings.ToLower(w.Hook.Value) != "workflow_run" {
+			continue
+		}
+		if trust.Classify(w) != TriggerPrivileged {
+			continue
+		}
+		for _, name := range w.Workflows {
+			for _, p := range byName[name.Value] {
+				if !seen[p] {
+					seen[p] = true
+					producers = append(producers, p)
+				}
+			}
+		}
+	}
+	return producers
+}
+
+// artifactUploads returns the steps of the producer workflow which upload artifacts.
+func artifactUploads(producer *artifactWorkflow) []*artifactUpload {
+	var uploads []*artifactUpload
+	for _, step := range workflowSteps(producer.workflow) {
+		action, ok := step.Exec.(*ast.ExecAction)
+		if !ok || action.Uses == nil || !strings.HasPrefix(action.Uses.Value, "actions/upload-artifact@") {
+			continue
+		}
+		// "artifact" is the default name of actions/upload-artifact
+		name := actionInputValue(action, "name")
+		if name == "" {
+			name = "artifact"
+		}
+		uploads = append(uploads, &artifactUpload{producer: producer, step: step, name: name})
+	}
+	return uploads
+}
+
+// artifactDownload checks whether the step downloads artifacts of another workflow run and returns the name and
+// the pattern of the artifacts it downloads. Both are empty when the step downloads all artifacts of the run.
+// workflow is the workflow file given to third-party download actions, or empty when the step downloads from the
+// run which triggered the workflow.
+func artifactDownload(step *ast.Step) (name, pattern, workflow string, ok bool) {
+	action, isAction := step.Exec.(*ast.ExecAction)
+	if !isAction || action.Uses == nil {
+		return "", "", "", false
+	}
+	uses := action.Uses.Value
+	switch {
+	case strings.HasPrefix(uses, "actions/download-artifact@"):
+		// Without run-id the action only downloads artifacts uploaded in the same run
+		if actionInputValue(action, "run-id") == "" {
+			return "", "", "", false
+		}
+		return actionInputValue(action, "name"), actionInputValue(action, "pattern"), "", true
+	case isThirdPartyArtifactAction(uses):
+		workflow = actionInputValue(action, "workflow")
+		if strings.EqualFold(actionInputValue(action, "name_is_regexp"), "true") {
+			return "", "", workflow, true
+		}
+		return actionInputValue(action, "name"), "", workflow, true
+	}
+	return "", "", "", false
+}
+
+// matchesProducer checks whether a download from the given workflow may receive artifacts of the producer.
+// The workflow is a file name, a path or a workflow ID. IDs and expressions cannot be resolved statically and are
+// assumed to match.
+func matchesProducer(workflow string, producer *artifactWorkflow) bool {
+	if workflow == "" || strings.Contains(workflow, "${{") {
+		return true
+	}
+	if _, err := strconv.ParseInt(workflow, 10, 64); err == nil {
+		return true
+	}
+	workflow = strings.TrimPrefix(workflow, "./")
+	if strings.Contains(workflow, "/") {
+		return workflow == producer.path
+	}
+	return workflow == path.Base(producer.path)
+}
+
+// matchesArtifact checks whether a download with the given name or pattern may receive the uploaded artifact.
+// Names built with expressions cannot be resolved statically and are assumed to match.
+func matchesArtifact(name, pattern, uploaded string) bool {
+	if strings.Contains(name, "${{") || strings.Contains(pattern, "${{") || strings.Contains(uploaded, "${{") {
+		return true
+	}
+	if name != "" {
+		return name == uploaded
+	}
+	if pattern != "" {
+		matched, err := path.Match(pattern, uploaded)
+		return err == nil && matched
+	}
+	return true
+}
+
+// forkTriggers returns the triggers of the producer workflow which pull requests from forks can fire, taking the
+// trigger-trust configuration of the producer into account.
+func forkTriggers(producer *artifactWorkflow) []string {
+	var tc *TriggerTrustConfig
+	if producer.cfg != nil {
+		tc = &producer.cfg.TriggerTrust
+	}
+	var events []string
+	for _, e := range NewTriggerTrustModel(tc, nil).ForkTriggers(producer.workflow) {
+		events = append(events, e.EventName())
+	}
+	return events
+}
+
+// workflowSteps returns all steps of the workflow, ordered by job ID.
+func workflowSteps(w *ast.Workflow) []*ast.Step {
+	ids := make([]string, 0, len(w.Jobs))
+	for id := range w.Jobs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	var steps []*ast.Step
+	for _, id := range ids {
+		if j := w.Jobs[id]; j != nil {
+			steps = append(steps, j.Steps...)
+		}
+	}
+	return steps
+}
+
+func actionInputValue(action *ast.ExecAction, name string) string {
+	if in, ok := action.Inputs[name]; ok && in != nil && in.Value != nil {
+		return strings.TrimSpace(in.Value.Value)
+	}
+	return ""
+}
@@ -0,0 +1,338 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const artifactProducer = `name: PR build
+on: pull_request
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - run: make
+      - uses: actions/upload-artifact@v4
+        with:
+          name: build
+          path: out
+`
+
+func TestArtifactPoisoningChains(t *testing.T) {
+	tests := []struct {
+		name     string
+		producer string
+		consumer string
+		config   string
+		want     []string
+	}{
+		{
+			name:     "producer triggered by forks",
+			producer: artifactProducer,
+			consumer: `on:
+  workflow_run:
+    workflows: [PR build]
+jobs:
+  deploy:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/download-artifact@v4
+        with:
+          name: build
+          run-id: ${{ github.event.workflow_run.id }}
+`,
+			want: []string{`8:9: artifact-poisoning-critical: "build" uploaded at .github/workflows/producer.yml:8:9 by workflow "PR build" on pull_request`},
+		},
+		{
+			name: "producer not triggered by forks",
+			producer: `on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/upload-artifact@v4
+        with:
+          path: out
+`,
+			consumer: `on:
+  workflow_run:
+    workflows: [.github/workflows/producer.yml]
+jobs:
+  deploy:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/download-artifact@v4
+        with:
+          run-id: ${{ github.event.workflow_run.id }}
+`,
+			want: []string{`8:9: artifact-poisoning-medium: "artifact" uploaded at .github/workflows/producer.yml:6:9 by workflow ".github/workflows/producer.yml" on push`},
+		},
+		{
+			name:     "third-party download action with pattern",
+			producer: artifactProducer,
+			consumer: `on:
+  workflow_run:
+    workflows: [PR build]
+jobs:
+  deploy:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: dawidd6/action-download-artifact@v6
+        with:
+          name: bu.*
+          name_is_regexp: true
+`,
+			want: []string{`8:9: artifact-poisoning-critical: "build" uploaded at .github/workflows/producer.yml:8:9`},
+		},
+		{
+			name:     "third-party download action from the producer file",
+			producer: artifactProducer,
+			consumer: `on:
+  workflow_run:
+    workflows: [PR build]
+jobs:
+  deploy:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: dawidd6/action-download-artifact@v6
+        with:
+          workflow: producer.yml
+          name: build
+`,
+			want: []string{`8:9: artifact-poisoning-critical: "build" uploaded at .github/workflows/producer.yml:8:9`},
+		},
+		{
+			name:     "third-party download action from another workflow",
+			producer: artifactProducer,
+			consumer: `on:
+  workflow_run:
+    workflows: [PR build]
+jobs:
+  deploy:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: dawidd6/action-download-artifact@v6
+        with:
+          workflow: release.yml
+          name: build
+`,
+		},
+		{
+			name:     "glob pattern",
+			producer: artifactProducer,
+			consumer: `on:
+  workflow_run:
+    workflows: [PR build]
+jobs:
+  deploy:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/download-artifact@v4
+        with:
+          pattern: b*
+          run-id: ${{ github.event.workflow_run.id }}
+`,
+			want: []string{`8:9: artifact-poisoning-critical: "build" uploaded at .github/workflows/producer.yml:8:9`},
+		},
+		{
+			name:     "different artifact name",
+			producer: artifactProducer,
+			consumer: `on:
+  workflow_run:
+    workflows: [PR build]
+jobs:
+  deploy:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/download-artifact@v4
+        with:
+          name: coverage
+          run-id: ${{ github.event.workflow_run.id }}
+`,
+		},
+		{
+			name:     "download from the same run",
+			producer: artifactProducer,
+			consumer: `on:
+  workflow_run:
+    workflows: [PR build]
+jobs:
+  deploy:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/download-artifact@v4
+        with:
+          name: build
+`,
+		},
+		{
+			name:     "unrelated workflow_run",
+			producer: artifactProducer,
+			consumer: `on:
+  workflow_run:
+    workflows: [Release]
+jobs:
+  deploy:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/download-artifact@v4
+        with:
+          name: build
+          run-id: ${{ github.event.workflow_run.id }}
+`,
+		},
+		{
+			name:     "workflow_run configured as unprivileged",
+			producer: artifactProducer,
+			consumer: `on:
+  workflow_run:
+    workflows: [PR build]
+jobs:
+  deploy:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/download-artifact@v4
+        with:
+          name: build
+          run-id: ${{ github.event.workflow_run.id }}
+`,
+			config: `
+trigger-trust:
+  unprivileged: [workflow_run]
+`,
+		},
+		{
+			name:     "rule disabled",
+			producer: artifactProducer,
+			consumer: `on:
+  workflow_run:
+    workflows: [PR build]
+jobs:
+  deploy:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/download-artifact@v4
+        with:
+          name: build
+          run-id: ${{ github.event.workflow_run.id }}
+`,
+			config: `
+rules:
+  artifact-poisoning-critical:
+    enabled: false
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg *Config
+			if tt.config != "" {
+				c, err := parseConfig([]byte(tt.config), "sisakulint.yaml")
+				if err != nil {
+					t.Fatal(err)
+				}
+				cfg = c
+			}
+			var workflows []*artifactWorkflow
+			for _, f := range []struct{ path, src string }{
+				{".github/workflows/producer.yml", tt.producer},
+				{".github/workflows/consumer.yml", tt.consumer},
+			} {
+				w, errs := Parse([]byte(f.src))
+				if len(errs) > 0 {
+					t.Fatal(errs)
+				}
+				name := f.path
+				if w.Name != nil {
+					name = w.Name.Value
+				}
+				workflows = append(workflows, &artifactWorkflow{path: f.path, name: name, workflow: w, cfg: cfg})
+			}
+
+			errs := artifactPoisoningChains(workflows)
+			if len(errs[0]) > 0 {
+				t.Errorf("errors in producer: %v", errs[0])
+			}
+			if len(errs[1]) != len(tt.want) {
+				t.Fatalf("got %d errors, want %d: %v", len(errs[1]), len(tt.want), errs[1])
+			}
+			for i, want := range tt.want {
+				e := errs[1][i]
+				pos, rest, _ := strings.Cut(want, ": ")
+				rule, chain, _ := strings.Cut(rest, ": ")
+				if got := fmt.Sprintf("%d:%d", e.LineNumber, e.ColNumber); got != pos {
+					t.Errorf("position: got %s, want %s", got, pos)
+				}
+				if e.Type != rule {
+					t.Errorf("rule: got %s, want %s", e.Type, rule)
+				}
+				if !strings.Contains(e.Description, chain) {
+					t.Errorf("description %q does not contain %q", e.Description, chain)
+				}
+			}
+		})
+	}
+}
+
+func TestLinter_ArtifactPoisoningChain(t *testing.T) {
+	root := writeProject(t, map[string]string{
+		"producer.yml": artifactProducer,
+		"consumer.yml": `on:
+  workflow_run:
+    workflows: [PR build]
+jobs:
+  deploy:
+    runs-on: ubuntu-latest
+    steps:
+      # sisakulint-disable-next-line artifact-poisoning-critical -- artifact is only read as data
+      - uses: actions/download-artifact@v4
+        with:
+          name: build
+          run-id: ${{ github.event.workflow_run.id }}
+          path: ${{ runner.temp }}/artifacts
+      - uses: actions/download-artifact@v4
+        with:
+          name: build
+          run-id: ${{ github.event.workflow_run.id }}
+          path: ${{ runner.temp }}/artifacts
+`,
+	})
+	project, err := NewProject(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := NewLinter(io.Discard, &LinterOptions{CurrentWorkingDirectoryPath: root})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(root, ".github", "workflows")
+	results, err := l.LintFiles([]string{filepath.Join(dir, "consumer.yml"), filepath.Join(dir, "producer.yml")}, project)
+	if err != nil {
+		t.Fatal(err)
+	}
+	consumer := results[0]
+	var found []*LintingError
+	for _, e := range consumer.Errors {
+		if strings.HasPrefix(e.Type, "artifact-poisoning-") || e.Type == SuppressionRuleName {
+			found = append(found, e)
+		}
+	}
+	if len(found) != 1 || found[0].LineNumber != 14 || found[0].Type != "artifact-poisoning-critical" {
+		t.Fatalf("unexpected errors in consumer: %v", found)
+	}
+	if found[0].FilePath != consumer.FilePath || found[0].Fingerprint == "" {
+		t.Errorf("error is not attached to the consumer: %+v", found[0])
+	}
+	if len(consumer.Suppressed) != 1 || consumer.Suppressed[0].LineNumber != 9 {
+		t.Errorf("chain on line 9 should be suppressed: %v", consumer.Suppressed)
+	}
+	for _, e := range results[1].Errors {
+		if strings.HasPrefix(e.Type, "artifact-poisoning-") {
+			t.Errorf("unexpected error in producer: %v", e)
+		}
+	}
+}
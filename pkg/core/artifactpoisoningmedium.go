@@ -78,11 +78,11 @@ func (rule *ArtifactPoisoningMedium) VisitWorkflowPre(node *ast.Workflow) error
 	// Reset state for new workflow
 	rule.unsafeTriggers = nil
 
-	// Detect untrusted triggers
+	// Detect privileged triggers which run with untrusted input
 	for _, event := range node.On {
 		switch e := event.(type) {
 		case *ast.WebhookEvent:
-			if e.Hook != nil && UntrustedTriggers[e.Hook.Value] {
+			if e.Hook != nil && rule.TriggerTrust().Classify(e) == TriggerPrivileged {
 				rule.unsafeTriggers = append(rule.unsafeTriggers, e.Hook.Value)
 			}
 		}
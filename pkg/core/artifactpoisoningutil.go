@@ -2,17 +2,6 @@ package core
 
 import "gopkg.in/yaml.v3"
 
-// UntrustedTriggers defines workflow triggers that may execute with untrusted input.
-// These triggers are commonly associated with security risks in CI/CD pipelines:
-// - workflow_run: Triggered by completion of another workflow (may be from PR)
-// - pull_request_target: Runs in the context of the base branch but with PR info
-// - issue_comment: Triggered by comments which can be from external contributors
-var UntrustedTriggers = map[string]bool{
-	"workflow_run":        true,
-	"pull_request_target": true,
-	"issue_comment":       true,
-}
-
 // AddPathToWithSection adds or updates the path input in the with section of a step node.
 // This is used by artifact poisoning rules to add safe extraction paths.
 // The path will be set to "${{ runner.temp }}/artifacts" if not already present or unsafe.
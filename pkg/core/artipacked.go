@@ -76,12 +76,13 @@ func (rule *ArtipackedRule) VisitJobPre(node *ast.Job) error {
 
 func (rule *ArtipackedRule) VisitJobPost(node *ast.Job) error {
 	for _, info := range rule.checkoutSteps {
-		severity := "Medium"
+		severity, level := "Medium", SeverityMedium
 		if info.version >= 6 {
-			severity = "Low"
+			severity, level = "Low", SeverityLow
 		}
-		rule.Errorf(
+		rule.ErrorfWithSeverity(
 			info.step.Pos,
+			level,
 			"[%s] actions/checkout without 'persist-credentials: false' at step %q. Credentials are stored in %s. While no dangerous upload-artifact was found in this job, consider adding 'persist-credentials: false' to prevent credential exposure. "+
 				"See https://unit42.paloaltonetworks.com/github-repo-artifacts-leak-tokens/",
 			severity,
@@ -173,13 +174,14 @@ func (rule *ArtipackedRule) handleUploadArtifact(step *ast.Step, action *ast.Exe
 	}
 
 	for _, checkoutInfo := range rule.checkoutSteps {
-		severity := "High"
+		severity, level := "High", SeverityHigh
 		if checkoutInfo.version >= 6 {
-			severity = "Medium"
+			severity, level = "Medium", SeverityMedium
 		}
 
-		rule.Errorf(
+		rule.ErrorfWithSeverity(
 			step.Pos,
+			level,
 			"[%s] actions/upload-artifact uploads workspace with path %q, which may include credentials from actions/checkout at line %d. "+
 				"The checkout action stores GITHUB_TOKEN in %s. "+
 				"Add 'persist-credentials: false' to the checkout step or avoid uploading the entire workspace. "+
@@ -1,6 +1,12 @@
 package core
 
-import "github.com/sisaku-security/sisakulint/pkg/ast"
+import (
+	"bytes"
+	"strings"
+
+	"github.com/sisaku-security/sisakulint/pkg/ast"
+	"gopkg.in/yaml.v3"
+)
 
 type AutoFixer interface {
 	RuleName() string
@@ -81,3 +87,175 @@ func NewFuncFixer(ruleName string, fixer func() error) AutoFixer {
 		fixer:         fixer,
 	}
 }
+
+// fixerLineはAutoFixerが修正するステップまたはジョブの行番号を返す。位置がわからない場合は0を返す
+func fixerLine(f AutoFixer) int {
+	switch f := f.(type) {
+	case *stepFixer:
+		if f.step != nil && f.step.Pos != nil {
+			return f.step.Pos.Line
+		}
+	case *jobFixer:
+		if f.job != nil && f.job.Pos != nil {
+			return f.job.Pos.Line
+		}
+	}
+	return 0
+}
+
+// fixerLinesはAutoFixerが修正するステップまたはジョブの最初と最後の行番号を返す。位置がわからない場合は0を返す
+func fixerLines(f AutoFixer) (int, int) {
+	start := fixerLine(f)
+	if start == 0 {
+		return 0, 0
+	}
+	var node *yaml.Node
+	switch f := f.(type) {
+	case *stepFixer:
+		node = f.step.BaseNode
+	case *jobFixer:
+		node = f.job.BaseNode
+	}
+	if node == nil {
+		return start, start
+	}
+	return start, max(start, lastLineOf(node))
+}
+
+// errorFixersは、エラーごとにそのエラーを修正するAutoFixerを返す
+// ステップやジョブを修正するAutoFixerは、そのノードの範囲にある同じルールのエラーを修正する。ジョブとそのステップのように
+// 範囲が入れ子になっている場合は、エラーを含む最も狭い範囲のAutoFixerがそのエラーを修正する
+// どのエラーも修正しないAutoFixerや位置のわからないAutoFixerは、他のAutoFixerが修正しない同じルールのエラーを修正するとみなす
+func errorFixers(errs []*LintingError, fixers []AutoFixer) map[*LintingError][]AutoFixer {
+	type span struct{ start, end int }
+	spans := make(map[AutoFixer]span, len(fixers))
+	for _, f := range fixers {
+		if start, end := fixerLines(f); start > 0 {
+			spans[f] = span{start, end}
+		}
+	}
+
+	result := map[*LintingError][]AutoFixer{}
+	positioned := map[AutoFixer]bool{}
+	for _, err := range errs {
+		var found []AutoFixer
+		width := 0
+		for _, f := range fixers {
+			s, ok := spans[f]
+			if !ok || err.Type != f.RuleName() || err.LineNumber < s.start || s.end < err.LineNumber {
+				continue
+			}
+			switch w := s.end - s.start; {
+			case len(found) == 0 || w < width:
+				found, width = []AutoFixer{f}, w
+			case w == width:
+				found = append(found, f)
+			}
+		}
+		if len(found) > 0 {
+			result[err] = found
+			for _, f := range found {
+				positioned[f] = true
+			}
+		}
+	}
+
+	for _, f := range fixers {
+		if positioned[f] {
+			continue
+		}
+		for _, err := range errs {
+			if err.Type != f.RuleName() {
+				continue
+			}
+			if fs := result[err]; len(fs) == 0 || !positioned[fs[0]] {
+				result[err] = append(fs, f)
+			}
+		}
+	}
+	return result
+}
+
+// NetworkStepFixerは、ステップの修正にネットワークアクセスが必要かを報告するStepFixer
+// 例えばcommit-shaは、ロックファイルがない場合にGitHub APIでタグをコミットSHAに解決する
+type NetworkStepFixer interface {
+	StepFixer
+	NeedsNetwork(step *ast.Step) bool
+}
+
+// needsNetworkはAutoFixerの適用にネットワークアクセスが必要かを返す
+// ネットワークアクセスが必要な修正の内容は、出力のためのプレビューでは計算しない
+func needsNetwork(f AutoFixer) bool {
+	s, ok := f.(*stepFixer)
+	if !ok {
+		return false
+	}
+	n, ok := s.fixer.(NetworkStepFixer)
+	return ok && n.NeedsNetwork(s.step)
+}
+
+// EncodeWorkflowNodeは修正後のworkflowのyaml.NodeをYAMLのバイト列にエンコードする
+func EncodeWorkflowNode(node *yaml.Node) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(node); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// FixSuggestionはAutoFixerによる修正内容をテキストの置換として表す
+// StartLineからEndLineまでの行(両端を含む)をReplacementで置き換える
+// EndLineがStartLine-1の場合は、StartLineの前にReplacementを挿入することを表す
+type FixSuggestion struct {
+	// RuleNameは修正を生成したルールの名前
+	RuleName string `json:"rule"`
+	// StartLineは置換する最初の行番号。1から始まる
+	StartLine int `json:"start_line"`
+	// EndLineは置換する最後の行番号
+	EndLine int `json:"end_line"`
+	// Replacementは置換後のテキスト
+	Replacement string `json:"replacement"`
+}
+
+// NewFixSuggestionは修正前後のソースから変更のあった行の範囲を求めてFixSuggestionを作成する
+// 修正前後で差分がない場合はnilを返す
+func NewFixSuggestion(ruleName string, before, after []byte) *FixSuggestion {
+	if bytes.Equal(before, after) {
+		return nil
+	}
+	a := splitLines(before)
+	b := splitLines(after)
+
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	return &FixSuggestion{
+		RuleName:    ruleName,
+		StartLine:   prefix + 1,
+		EndLine:     len(a) - suffix,
+		Replacement: strings.Join(b[prefix:len(b)-suffix], ""),
+	}
+}
+
+// splitLinesはソースを改行文字を含む行のスライスに分割する
+func splitLines(src []byte) []string {
+	if len(src) == 0 {
+		return nil
+	}
+	lines := strings.SplitAfter(string(src), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
@@ -0,0 +1,201 @@
+package core
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/sisaku-security/sisakulint/pkg/ast"
+)
+
+// baselineVersionはベースラインファイルのフォーマットのバージョン
+const baselineVersion = 1
+
+// BaselineEntryはベースラインに記録された既知のエラー
+// Fingerprint以外のフィールドは人が読むための情報で、照合には使われない
+type BaselineEntry struct {
+	// Fingerprintはエラーの行番号に依存しないフィンガープリント
+	Fingerprint string `json:"fingerprint"`
+	// Ruleはエラーを報告したルール名
+	Rule string `json:"rule"`
+	// Fileはエラーが報告されたファイルのパス
+	File string `json:"file"`
+	// Contextはエラーを含むジョブとステップ
+	Context string `json:"context,omitempty"`
+	// Lineはベースライン作成時のエラーの行番号
+	Line int `json:"line"`
+	// Messageはエラーメッセージ
+	Message string `json:"message"`
+}
+
+// Baselineは-baselineで指定されたベースラインファイルを表す
+// ベースラインに記録されたエラーは報告されず、どのエラーにも一致しなかったエントリは古いエントリとして扱われる
+type Baseline struct {
+	Version int              `json:"version"`
+	Entries []*BaselineEntry `json:"findings"`
+
+	mu      sync.Mutex
+	matched []bool
+	linted  map[string]struct{}
+}
+
+// NewBaselineは検証結果からベースラインを作成する
+func NewBaseline(results []*ValidateResult) *Baseline {
+	b := &Baseline{Version: baselineVersion}
+	for _, res := range results {
+		for _, err := range res.Errors {
+			b.Entries = append(b.Entries, &BaselineEntry{
+				Fingerprint: err.Fingerprint,
+				Rule:        err.Type,
+				File:        filepath.ToSlash(err.FilePath),
+				Context:     err.Context,
+				Line:        err.LineNumber,
+				Message:     err.Description,
+			})
+		}
+	}
+	sort.SliceStable(b.Entries, func(i, j int) bool {
+		if b.Entries[i].File != b.Entries[j].File {
+			return b.Entries[i].File < b.Entries[j].File
+		}
+		return b.Entries[i].Line < b.Entries[j].Line
+	})
+	return b
+}
+
+// ReadBaselineFileはベースラインファイルを読み込む
+func ReadBaselineFile(path string) (*Baseline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read baseline file %q: %w", path, err)
+	}
+	var b Baseline
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("could not parse baseline file %q: %w", path, err)
+	}
+	if b.Version != baselineVersion {
+		return nil, fmt.Errorf("unsupported version %d of baseline file %q. recreate it with -baseline-create", b.Version, path)
+	}
+	b.matched = make([]bool, len(b.Entries))
+	return &b, nil
+}
+
+// WriteFileはベースラインをファイルに書き込む
+func (b *Baseline) WriteFile(path string) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(b); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("could not write baseline file %q: %w", path, err)
+	}
+	return nil
+}
+
+// filterはベースラインに記録されていないエラーと記録されているエラーを返す
+// 同じフィンガープリントのエラーが複数ある場合は、ベースラインのエントリの数だけ既知のエラーとして扱う
+// 複数のファイルを並行して検証するため、このメソッドはgoroutine安全
+func (b *Baseline) filter(filePath string, errs []*LintingError) (kept, baselined []*LintingError) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	file := filepath.ToSlash(filePath)
+	if b.linted == nil {
+		b.linted = map[string]struct{}{}
+	}
+	b.linted[file] = struct{}{}
+
+	kept = make([]*LintingError, 0, len(errs))
+	for _, err := range errs {
+		found := false
+		for i, e := range b.Entries {
+			if !b.matched[i] && e.File == file && e.Fingerprint == err.Fingerprint {
+				b.matched[i] = true
+				found = true
+				break
+			}
+		}
+		if found {
+			baselined = append(baselined, err)
+		} else {
+			kept = append(kept, err)
+		}
+	}
+	return kept, baselined
+}
+
+// StaleEntriesは検証したファイルのエントリのうち、どのエラーにも一致しなかったエントリを返す
+// これらのエントリはエラーが修正されたため、ベースラインから削除できる
+func (b *Baseline) StaleEntries() []*BaselineEntry {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var stale []*BaselineEntry
+	for i, e := range b.Entries {
+		if _, ok := b.linted[e.File]; ok && !b.matched[i] {
+			stale = append(stale, e)
+		}
+	}
+	return stale
+}
+
+// findingContextはエラーの行を含むジョブとステップを"jobs.<job_id>.steps.<step>"の形式で返す
+// ステップはidがあればid、なければnameを使い、どちらもなければ0から始まるインデックスを使う
+func findingContext(workflow *ast.Workflow, line int) string {
+	if workflow == nil {
+		return ""
+	}
+	for id, job := range workflow.Jobs {
+		if job == nil || job.Pos == nil || job.BaseNode == nil {
+			continue
+		}
+		if line < job.Pos.Line || line > lastLineOf(job.BaseNode) {
+			continue
+		}
+		ctx := "jobs." + id
+		for i, step := range job.Steps {
+			if step == nil || step.Pos == nil || step.BaseNode == nil {
+				continue
+			}
+			if line < step.Pos.Line || line > lastLineOf(step.BaseNode) {
+				continue
+			}
+			name := strconv.Itoa(i)
+			if step.ID != nil && step.ID.Value != "" {
+				name = step.ID.Value
+			} else if step.Name != nil && step.Name.Value != "" {
+				name = step.Name.Value
+			}
+			return ctx + ".steps." + name
+		}
+		return ctx
+	}
+	return ""
+}
+
+// errorFingerprintはルール名、ファイルパス、空白を正規化したエラーの行、ジョブとステップからフィンガープリントを計算する
+// 行番号を含まないため、エラーの前に行が追加されてもフィンガープリントは変わらない
+func errorFingerprint(err *LintingError, source []byte) string {
+	snippet := ""
+	if err.LineNumber > 0 {
+		if line, ok := err.extractLineContent(source); ok {
+			snippet = strings.Join(strings.Fields(line), " ")
+		}
+	}
+	h := sha256.New()
+	for _, s := range []string{err.Type, filepath.ToSlash(err.FilePath), snippet, err.Context} {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
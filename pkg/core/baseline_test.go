@@ -0,0 +1,226 @@
+package core
+
+import (
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const baselineTestWorkflow = `on: push
+permissions: {}
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    timeout-minutes: 5
+    steps:
+      - id: checkout
+        uses: actions/checkout@v4
+        timeout-minutes: 5
+      - name: Setup
+        uses: actions/setup-go@v5
+        timeout-minutes: 5
+`
+
+func lintForBaseline(t *testing.T, l *Linter, content string) *ValidateResult {
+	t.Helper()
+	res, err := l.Lint("workflow.yml", []byte(content), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+func TestFindingContext(t *testing.T) {
+	workflow, errs := Parse([]byte(baselineTestWorkflow))
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	tests := []struct {
+		line int
+		want string
+	}{
+		{line: 1, want: ""},
+		{line: 4, want: "jobs.build"},
+		{line: 6, want: "jobs.build"},
+		{line: 9, want: "jobs.build.steps.checkout"},
+		{line: 12, want: "jobs.build.steps.Setup"},
+	}
+	for _, tt := range tests {
+		if got := findingContext(workflow, tt.line); got != tt.want {
+			t.Errorf("findingContext(line %d) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestErrorFingerprint_IgnoresLineNumber(t *testing.T) {
+	source := []byte("a:\n  uses: foo@v1\n\n  uses:   foo@v1\n  uses: bar@v1\n")
+	base := &LintingError{Type: "commit-sha", FilePath: "w.yml", LineNumber: 2, Context: "jobs.a"}
+	moved := &LintingError{Type: "commit-sha", FilePath: "w.yml", LineNumber: 4, Context: "jobs.a"}
+	other := &LintingError{Type: "commit-sha", FilePath: "w.yml", LineNumber: 5, Context: "jobs.a"}
+	otherJob := &LintingError{Type: "commit-sha", FilePath: "w.yml", LineNumber: 2, Context: "jobs.b"}
+
+	if errorFingerprint(base, source) != errorFingerprint(moved, source) {
+		t.Error("fingerprint changed when the error moved to another line with the same content")
+	}
+	if errorFingerprint(base, source) == errorFingerprint(other, source) {
+		t.Error("fingerprint did not change for a different snippet")
+	}
+	if errorFingerprint(base, source) == errorFingerprint(otherJob, source) {
+		t.Error("fingerprint did not change for a different job")
+	}
+}
+
+func TestBaseline_ReportsOnlyNewFindings(t *testing.T) {
+	l, err := NewLinter(io.Discard, &LinterOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := lintForBaseline(t, l, baselineTestWorkflow)
+	if len(before.Errors) == 0 {
+		t.Fatal("test workflow has no errors to record in the baseline")
+	}
+
+	path := filepath.Join(t.TempDir(), "baseline.json")
+	if err := NewBaseline([]*ValidateResult{before}).WriteFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err = NewLinter(io.Discard, &LinterOptions{BaselineFilePath: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 行を追加してもベースラインのエラーは報告されず、新しいステップのエラーだけが報告される
+	changed := strings.Replace(baselineTestWorkflow, "jobs:\n", "# comment\njobs:\n", 1) +
+		"      - uses: actions/cache@v4\n        timeout-minutes: 5\n"
+	after := lintForBaseline(t, l, changed)
+	if len(after.Baselined) != len(before.Errors) {
+		t.Errorf("got %d baselined errors, want %d", len(after.Baselined), len(before.Errors))
+	}
+	if len(after.Errors) == 0 {
+		t.Fatal("new finding in the added step is not reported")
+	}
+	for _, e := range after.Errors {
+		if e.LineNumber < 14 {
+			t.Errorf("finding recorded in the baseline is reported: %v", e)
+		}
+	}
+	if stale := l.StaleBaselineEntries(); len(stale) != 0 {
+		t.Errorf("unexpected stale entries: %v", stale)
+	}
+}
+
+func TestBaseline_StaleEntries(t *testing.T) {
+	l, err := NewLinter(io.Discard, &LinterOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := lintForBaseline(t, l, baselineTestWorkflow)
+
+	path := filepath.Join(t.TempDir(), "baseline.json")
+	baseline := NewBaseline([]*ValidateResult{before})
+	baseline.Entries = append(baseline.Entries, &BaselineEntry{Fingerprint: "other", Rule: "id", File: "other.yml"})
+	if err := baseline.WriteFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err = NewLinter(io.Discard, &LinterOptions{BaselineFilePath: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fixed := strings.ReplaceAll(baselineTestWorkflow, "@v4", "@692973e3d937129bcbf40652eb9f2f61becf3332")
+	after := lintForBaseline(t, l, fixed)
+
+	stale := l.StaleBaselineEntries()
+	if len(stale) == 0 {
+		t.Fatal("fixed findings are not reported as stale baseline entries")
+	}
+	for _, e := range stale {
+		if e.File != "workflow.yml" {
+			t.Errorf("entry for a file which was not linted is reported as stale: %+v", e)
+		}
+	}
+	if len(after.Baselined)+len(stale) != len(before.Errors) {
+		t.Errorf("baselined %d + stale %d != recorded %d", len(after.Baselined), len(stale), len(before.Errors))
+	}
+}
+
+func TestBaseline_FilteredFindingsAreNotStale(t *testing.T) {
+	l, err := NewLinter(io.Discard, &LinterOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := lintForBaseline(t, l, baselineTestWorkflow)
+	path := filepath.Join(t.TempDir(), "baseline.json")
+	if err := NewBaseline([]*ValidateResult{before}).WriteFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	// -min-severityで報告されなくなったエラーも、まだワークフローにあるので古いエントリではない
+	l, err = NewLinter(io.Discard, &LinterOptions{BaselineFilePath: path, MinSeverity: SeverityCritical})
+	if err != nil {
+		t.Fatal(err)
+	}
+	after := lintForBaseline(t, l, baselineTestWorkflow)
+	if len(after.Errors) != 0 {
+		t.Errorf("unexpected errors: %v", after.Errors)
+	}
+	if stale := l.StaleBaselineEntries(); len(stale) != 0 {
+		t.Errorf("findings filtered by the minimum severity are reported as stale: %v", stale)
+	}
+}
+
+func TestReadBaselineFile_Errors(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := ReadBaselineFile(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("no error for a missing baseline file")
+	}
+
+	path := filepath.Join(dir, "baseline.json")
+	b := &Baseline{Version: 99}
+	if err := b.WriteFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadBaselineFile(path); err == nil || !strings.Contains(err.Error(), "unsupported version") {
+		t.Errorf("want unsupported version error, got %v", err)
+	}
+}
+
+func TestBaseline_RemovesOnlyFixersOfBaselinedErrors(t *testing.T) {
+	src := `on: push
+permissions: {}
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo build
+        timeout-minutes: 5
+`
+	l, err := NewLinter(io.Discard, &LinterOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := lintForBaseline(t, l, src)
+	path := filepath.Join(t.TempDir(), "baseline.json")
+	if err := NewBaseline([]*ValidateResult{before}).WriteFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err = NewLinter(io.Discard, &LinterOptions{BaselineFilePath: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// ベースラインにあるbuildジョブの修正は取り除き、新しく追加したtestジョブの修正は残す
+	after := lintForBaseline(t, l, src+"  test:\n    runs-on: ubuntu-latest\n    steps:\n      - run: echo test\n        timeout-minutes: 5\n")
+	var lines []int
+	for _, f := range after.AutoFixers {
+		if f.RuleName() == "missing-timeout-minutes" {
+			lines = append(lines, fixerLine(f))
+		}
+	}
+	if len(lines) != 1 || lines[0] != 9 {
+		t.Errorf("got fixers at lines %v, want [9]", lines)
+	}
+}
@@ -161,7 +161,7 @@ func (rule *CachePoisoningPoisonableStepRule) VisitWorkflowPre(node *ast.Workflo
 	for _, event := range node.On {
 		switch e := event.(type) {
 		case *ast.WebhookEvent:
-			if e.Hook != nil && IsUnsafeTrigger(e.Hook.Value) {
+			if e.Hook != nil && rule.TriggerTrust().Classify(e) == TriggerPrivileged {
 				rule.unsafeTriggers = append(rule.unsafeTriggers, e.Hook.Value)
 			}
 		}
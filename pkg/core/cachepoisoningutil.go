@@ -3,15 +3,10 @@ package core
 import (
 	"strings"
 
+	"github.com/sisaku-security/sisakulint/pkg/ast"
 	"gopkg.in/yaml.v3"
 )
 
-var unsafeTriggerNames = map[string]bool{
-	"issue_comment":       true,
-	"pull_request_target": true,
-	"workflow_run":        true,
-}
-
 var unsafePatternsLower = []string{
 	"github.event.pull_request.head.sha",
 	"github.event.pull_request.head.ref",
@@ -36,8 +31,10 @@ var safePatternsLower = []string{
 }
 
 // IsUnsafeTrigger checks if the trigger event is unsafe for cache poisoning detection.
+// It classifies the event with the default trigger trust model, ignoring repository configuration.
 func IsUnsafeTrigger(eventName string) bool {
-	return unsafeTriggerNames[eventName]
+	e := &ast.WebhookEvent{Hook: &ast.String{Value: eventName}}
+	return defaultTriggerTrust.Classify(e) == TriggerPrivileged
 }
 
 // IsUnsafeCheckoutRef checks if the ref input contains patterns that indicate
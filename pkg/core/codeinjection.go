@@ -16,6 +16,8 @@ type CodeInjectionRule struct {
 	checkPrivileged    bool   // true = check privileged triggers, false = check normal triggers
 	stepsWithUntrusted []*stepWithUntrustedInput
 	workflow           *ast.Workflow
+	untrusted          *taintScope
+	taint              *workflowTaint
 }
 
 // stepWithUntrustedInput tracks steps that need auto-fixing
@@ -34,9 +36,10 @@ type untrustedExprInfo struct {
 
 // parsedExpression represents a parsed expression with its position and AST node
 type parsedExpression struct {
-	raw  string               // Original expression content
-	node expressions.ExprNode // Parsed AST node
-	pos  *ast.Position        // Position in source
+	raw    string               // Original expression content
+	node   expressions.ExprNode // Parsed AST node
+	pos    *ast.Position        // Position in source
+	offset int                  // Byte offset of "${{" in the string
 }
 
 // newCodeInjectionRule creates a new code injection rule with the specified severity level
@@ -63,6 +66,7 @@ func newCodeInjectionRule(severityLevel string, checkPrivileged bool) *CodeInjec
 // VisitWorkflowPre is called before visiting a workflow
 func (rule *CodeInjectionRule) VisitWorkflowPre(node *ast.Workflow) error {
 	rule.workflow = node
+	rule.taint = nil
 	return nil
 }
 
@@ -80,7 +84,16 @@ func (rule *CodeInjectionRule) VisitJobPre(node *ast.Job) error {
 		return nil
 	}
 
-	for _, s := range node.Steps {
+	// Untrusted inputs are the builtin catalog, the sources in the configuration and the values the
+	// workflow derives from them through env:, matrix values, step outputs, job outputs and $GITHUB_ENV.
+	// They are tracked per step since a step only sees the outputs and environment of earlier steps
+	if rule.taint == nil {
+		rule.taint = newWorkflowTaint(rule.workflow, rule.userConfig, rule.TriggerTrust().callerInputs())
+	}
+	taint := rule.taint.job(node)
+
+	for i, s := range node.Steps {
+		rule.untrusted = taint.steps[i]
 		if s.Exec == nil {
 			continue
 		}
@@ -92,29 +105,40 @@ func (rule *CodeInjectionRule) VisitJobPre(node *ast.Job) error {
 			run := s.Exec.(*ast.ExecRun)
 			exprs := rule.extractAndParseExpressions(run.Run)
 
+			// Expressions are substituted before the shell runs the script, so they are reported in any
+			// quoting context, even in single quotes or comments. The shell script model is used only to
+			// know which expressions can be replaced with an environment variable
+			var script *shellScript
+			if len(exprs) > 0 {
+				script = parseShellScript(run.Run.Value)
+			}
+
 			for _, expr := range exprs {
 				untrustedPaths := rule.checkUntrustedInput(expr)
 				if len(untrustedPaths) > 0 && !rule.isDefinedInEnv(expr, s.Env) {
-					if stepUntrusted == nil {
-						stepUntrusted = &stepWithUntrustedInput{step: s}
+					// Variables are never expanded in a heredoc with quoted delimiter such as <<'EOF'
+					if e := script.exprAt(expr.offset); e == nil || e.quote != shellQuoteQuotedHeredoc {
+						if stepUntrusted == nil {
+							stepUntrusted = &stepWithUntrustedInput{step: s}
+						}
+						stepUntrusted.untrustedExprs = append(stepUntrusted.untrustedExprs, untrustedExprInfo{
+							expr:          expr,
+							paths:         untrustedPaths,
+							isInRunScript: true,
+						})
 					}
-					stepUntrusted.untrustedExprs = append(stepUntrusted.untrustedExprs, untrustedExprInfo{
-						expr:          expr,
-						paths:         untrustedPaths,
-						isInRunScript: true,
-					})
 
 					if rule.checkPrivileged {
 						rule.Errorf(
 							expr.pos,
-							"code injection (critical): \"%s\" is potentially untrusted and used in a workflow with privileged triggers. Avoid using it directly in inline scripts. Instead, pass it through an environment variable. See https://docs.github.com/en/actions/security-guides/security-hardening-for-github-actions",
-							strings.Join(untrustedPaths, "\", \""),
+							"code injection (critical): %s is potentially untrusted and used in a workflow with privileged triggers. Avoid using it directly in inline scripts. Instead, pass it through an environment variable. See https://docs.github.com/en/actions/security-guides/security-hardening-for-github-actions",
+							describeUntrustedInputs(rule.untrusted, untrustedPaths, rule.workflow),
 						)
 					} else {
 						rule.Errorf(
 							expr.pos,
-							"code injection (medium): \"%s\" is potentially untrusted. Avoid using it directly in inline scripts. Instead, pass it through an environment variable. See https://docs.github.com/en/actions/security-guides/security-hardening-for-github-actions",
-							strings.Join(untrustedPaths, "\", \""),
+							"code injection (medium): %s is potentially untrusted. Avoid using it directly in inline scripts. Instead, pass it through an environment variable. See https://docs.github.com/en/actions/security-guides/security-hardening-for-github-actions",
+							describeUntrustedInputs(rule.untrusted, untrustedPaths, rule.workflow),
 						)
 					}
 				}
@@ -144,14 +168,14 @@ func (rule *CodeInjectionRule) VisitJobPre(node *ast.Job) error {
 							if rule.checkPrivileged {
 								rule.Errorf(
 									expr.pos,
-									"code injection (critical): \"%s\" is potentially untrusted and used in a workflow with privileged triggers. Avoid using it directly in github-script. Instead, pass it through an environment variable. See https://docs.github.com/en/actions/security-guides/security-hardening-for-github-actions",
-									strings.Join(untrustedPaths, "\", \""),
+									"code injection (critical): %s is potentially untrusted and used in a workflow with privileged triggers. Avoid using it directly in github-script. Instead, pass it through an environment variable. See https://docs.github.com/en/actions/security-guides/security-hardening-for-github-actions",
+									describeUntrustedInputs(rule.untrusted, untrustedPaths, rule.workflow),
 								)
 							} else {
 								rule.Errorf(
 									expr.pos,
-									"code injection (medium): \"%s\" is potentially untrusted. Avoid using it directly in github-script. Instead, pass it through an environment variable. See https://docs.github.com/en/actions/security-guides/security-hardening-for-github-actions",
-									strings.Join(untrustedPaths, "\", \""),
+									"code injection (medium): %s is potentially untrusted. Avoid using it directly in github-script. Instead, pass it through an environment variable. See https://docs.github.com/en/actions/security-guides/security-hardening-for-github-actions",
+									describeUntrustedInputs(rule.untrusted, untrustedPaths, rule.workflow),
 								)
 							}
 						}
@@ -168,30 +192,9 @@ func (rule *CodeInjectionRule) VisitJobPre(node *ast.Job) error {
 	return nil
 }
 
-// hasPrivilegedTriggers checks if the workflow has privileged triggers
+// hasPrivilegedTriggers checks if the workflow has privileged triggers according to the trigger trust model
 func (rule *CodeInjectionRule) hasPrivilegedTriggers() bool {
-	if rule.workflow == nil || rule.workflow.On == nil {
-		return false
-	}
-
-	// Check for privileged triggers
-	// These triggers have write access or run with secrets
-	privilegedTriggers := map[string]bool{
-		"pull_request_target": true,
-		"workflow_run":        true,
-		"issue_comment":       true,
-		"issues":              true,
-		"discussion_comment":  true,
-	}
-
-	for _, event := range rule.workflow.On {
-		eventName := strings.ToLower(event.EventName())
-		if privilegedTriggers[eventName] {
-			return true
-		}
-	}
-
-	return false
+	return rule.TriggerTrust().IsPrivileged(rule.workflow)
 }
 
 // RuleNames implements StepFixer interface
@@ -264,31 +267,14 @@ func (rule *CodeInjectionRule) FixStep(step *ast.Step) error {
 	}
 
 	// Build replacement maps for run: and script:
-	runReplacements := make(map[string]string)
+	runEnvVars := make(map[string]string) // expr.raw -> env var name
 	scriptReplacements := make(map[string]string)
 
 	for _, untrustedInfo := range stepInfo.untrustedExprs {
 		envVarName := envVarMap[untrustedInfo.expr.raw]
 
 		if untrustedInfo.isInRunScript {
-			// For run: scripts, use $ENV_VAR
-			runReplacements[fmt.Sprintf("${{ %s }}", untrustedInfo.expr.raw)] = fmt.Sprintf("$%s", envVarName)
-			runReplacements[fmt.Sprintf("${{%s}}", untrustedInfo.expr.raw)] = fmt.Sprintf("$%s", envVarName)
-
-			// Also update AST
-			run := step.Exec.(*ast.ExecRun)
-			if run.Run != nil {
-				run.Run.Value = strings.ReplaceAll(
-					run.Run.Value,
-					fmt.Sprintf("${{ %s }}", untrustedInfo.expr.raw),
-					fmt.Sprintf("$%s", envVarName),
-				)
-				run.Run.Value = strings.ReplaceAll(
-					run.Run.Value,
-					fmt.Sprintf("${{%s}}", untrustedInfo.expr.raw),
-					fmt.Sprintf("$%s", envVarName),
-				)
-			}
+			runEnvVars[untrustedInfo.expr.raw] = envVarName
 		} else {
 			// For github-script, use process.env.ENV_VAR
 			scriptReplacements[fmt.Sprintf("${{ %s }}", untrustedInfo.expr.raw)] = fmt.Sprintf("process.env.%s", envVarName)
@@ -310,16 +296,37 @@ func (rule *CodeInjectionRule) FixStep(step *ast.Step) error {
 		}
 	}
 
-	// Update BaseNode with replacements
-	if step.BaseNode != nil {
-		if len(runReplacements) > 0 {
-			if err := ReplaceInRunScript(step.BaseNode, runReplacements); err != nil {
-				// Ignore error if run section doesn't exist (might be github-script)
-				if !strings.Contains(err.Error(), "run section not found") {
-					return fmt.Errorf("failed to replace in run script: %w", err)
-				}
+	// For run: scripts, replace each expression with $ENV_VAR depending on its quoting context
+	if run, ok := step.Exec.(*ast.ExecRun); ok && run.Run != nil && len(runEnvVars) > 0 {
+		script := parseShellScript(run.Run.Value)
+		var edits []shellEdit
+		for _, e := range script.exprs {
+			envVarName, ok := runEnvVars[e.name]
+			if !ok {
+				continue
+			}
+			switch e.quote {
+			case shellQuoteSingle:
+				// Variables are not expanded in single quotes: 'a ${{ expr }} b' -> 'a '"$ENV_VAR"' b'
+				edits = append(edits, shellEdit{e.start, e.end, fmt.Sprintf("'\"$%s\"'", envVarName)})
+			case shellQuoteQuotedHeredoc:
+				// Variables are never expanded in the heredoc. Leave it as is
+			default:
+				edits = append(edits, shellEdit{e.start, e.end, fmt.Sprintf("$%s", envVarName)})
+			}
+		}
+		run.Run.Value = applyShellEdits(run.Run.Value, edits)
+
+		// Update BaseNode with the new script
+		if step.BaseNode != nil {
+			if err := setRunScriptValue(step.BaseNode, run.Run.Value); err != nil {
+				return fmt.Errorf("failed to replace in run script: %w", err)
 			}
 		}
+	}
+
+	// Update BaseNode with replacements
+	if step.BaseNode != nil {
 		if len(scriptReplacements) > 0 {
 			if err := ReplaceInGitHubScript(step.BaseNode, scriptReplacements); err != nil {
 				// Ignore error if with/script section doesn't exist (might be run:)
@@ -404,9 +411,10 @@ func (rule *CodeInjectionRule) extractAndParseExpressions(str *ast.String) []par
 			}
 
 			result = append(result, parsedExpression{
-				raw:  exprContent,
-				node: expr,
-				pos:  pos,
+				raw:    exprContent,
+				node:   expr,
+				pos:    pos,
+				offset: start,
 			})
 		}
 
@@ -428,24 +436,7 @@ func (rule *CodeInjectionRule) parseExpression(exprStr string) (expressions.Expr
 
 // checkUntrustedInput checks if the expression contains untrusted input
 func (rule *CodeInjectionRule) checkUntrustedInput(expr parsedExpression) []string {
-	checker := expressions.NewExprSemanticsChecker(true, nil)
-	_, errs := checker.Check(expr.node)
-
-	var paths []string
-	for _, err := range errs {
-		msg := err.Message
-		if strings.Contains(msg, "potentially untrusted") {
-			if idx := strings.Index(msg, "\""); idx != -1 {
-				endIdx := strings.Index(msg[idx+1:], "\"")
-				if endIdx != -1 {
-					path := msg[idx+1 : idx+1+endIdx]
-					paths = append(paths, path)
-				}
-			}
-		}
-	}
-
-	return paths
+	return untrustedPathsInExpr(expr.node, rule.untrusted.roots)
 }
 
 // isDefinedInEnv checks if the expression is defined in the step's env section
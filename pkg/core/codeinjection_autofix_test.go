@@ -300,3 +300,65 @@ func TestCodeInjectionCritical_AutoFix_GitHubScript_YAMLOutput(t *testing.T) {
 		t.Errorf("YAML output should contain process.env.COMMENT_BODY, got:\n%s", yamlOutput)
 	}
 }
+
+func TestCodeInjectionMedium_AutoFix_QuotingContext(t *testing.T) {
+	tests := []struct {
+		name          string
+		runScript     string
+		wantErrors    int
+		wantRunScript string
+	}{
+		{
+			name:          "single quoted",
+			runScript:     `echo 'title: ${{ github.event.pull_request.title }}'`,
+			wantErrors:    1,
+			wantRunScript: `echo 'title: '"$PR_TITLE"''`,
+		},
+		{
+			name:          "unquoted and double quoted",
+			runScript:     `echo ${{ github.event.pull_request.title }} "${{github.event.pull_request.title}}"`,
+			wantErrors:    2,
+			wantRunScript: `echo $PR_TITLE "$PR_TITLE"`,
+		},
+		{
+			name:          "heredoc with quoted delimiter is not fixed",
+			runScript:     "cat <<'EOF'\n${{ github.event.pull_request.title }}\nEOF\necho \"${{ github.event.pull_request.title }}\"",
+			wantErrors:    2,
+			wantRunScript: "cat <<'EOF'\n${{ github.event.pull_request.title }}\nEOF\necho \"$PR_TITLE\"",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := CodeInjectionMediumRule()
+			step := &ast.Step{
+				Exec: &ast.ExecRun{
+					Run: &ast.String{Value: tt.runScript, Pos: &ast.Position{Line: 1, Col: 1}},
+				},
+				BaseNode: &yaml.Node{
+					Kind: yaml.MappingNode,
+					Content: []*yaml.Node{
+						{Kind: yaml.ScalarNode, Value: "run"},
+						{Kind: yaml.ScalarNode, Value: tt.runScript},
+					},
+				},
+			}
+			_ = rule.VisitWorkflowPre(&ast.Workflow{On: []ast.Event{&ast.WebhookEvent{Hook: &ast.String{Value: "pull_request"}}}})
+			_ = rule.VisitJobPre(&ast.Job{Steps: []*ast.Step{step}})
+
+			// Expressions are substituted before the shell runs, so all of them are reported
+			if len(rule.Errors()) != tt.wantErrors {
+				t.Fatalf("got %d errors, want %d: %v", len(rule.Errors()), tt.wantErrors, rule.Errors())
+			}
+			if err := rule.FixStep(step); err != nil {
+				t.Fatalf("FixStep() error = %v", err)
+			}
+			if got := step.Exec.(*ast.ExecRun).Run.Value; got != tt.wantRunScript {
+				t.Errorf("run script = %q, want %q", got, tt.wantRunScript)
+			}
+			if got := step.BaseNode.Content[1].Value; got != tt.wantRunScript {
+				t.Errorf("run script in YAML node = %q, want %q", got, tt.wantRunScript)
+			}
+		})
+	}
+}
@@ -8,12 +8,13 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"runtime"
 	"runtime/debug"
+	"slices"
 	"strings"
 
 	"github.com/sisaku-security/sisakulint/pkg/remote"
-	"gopkg.in/yaml.v3"
 )
 
 // バージョンとインストール情報を保持する変数
@@ -119,52 +120,214 @@ func (cmd *Command) runLint(args []string, linterOpts *LinterOptions, initConfig
 		return nil, l.GenerateBoilerplate(".")
 	}
 
+	var results []*ValidateResult
 	if len(args) == 0 {
-		return l.LintRepository(".")
+		results, err = l.LintRepository(".")
+	} else {
+		results, err = l.LintFiles(args, nil)
 	}
+	if err != nil {
+		return nil, err
+	}
+
+	cmd.printStaleBaselineEntries(l.StaleBaselineEntries(), linterOpts.BaselineFilePath)
+	return results, nil
+}
+
+// runBaselineCreateは、現在の全てのエラーをベースラインファイルに記録する
+func (cmd *Command) runBaselineCreate(args []string, linterOpts *LinterOptions, path string) int {
+	l, err := NewLinter(io.Discard, linterOpts)
+	if err != nil {
+		fmt.Fprintln(cmd.Stderr, err.Error())
+		return ExitStatusFailure
+	}
+
+	var results []*ValidateResult
+	if len(args) == 0 {
+		results, err = l.LintRepository(".")
+	} else {
+		results, err = l.LintFiles(args, nil)
+	}
+	if err != nil {
+		fmt.Fprintln(cmd.Stderr, err.Error())
+		return ExitStatusFailure
+	}
+
+	baseline := NewBaseline(results)
+	if err := baseline.WriteFile(path); err != nil {
+		fmt.Fprintln(cmd.Stderr, err.Error())
+		return ExitStatusFailure
+	}
+	fmt.Fprintf(cmd.Stdout, "Recorded %d errors to baseline file %s\n", len(baseline.Entries), path)
+	return ExitStatusSuccessNoProblem
+}
+
+// printStaleBaselineEntriesは、どのエラーにも一致しなくなったベースラインのエントリを出力する
+// これらのエントリは-baseline-createでベースラインを作り直すと削除される
+func (cmd *Command) printStaleBaselineEntries(stale []*BaselineEntry, path string) {
+	if len(stale) == 0 {
+		return
+	}
+	fmt.Fprintf(cmd.Stderr, "%d entries in baseline file %s no longer match any error. recreate the baseline with -baseline-create to remove them:\n", len(stale), path)
+	for _, e := range stale {
+		fmt.Fprintf(cmd.Stderr, "  %s:%d: %s [%s]\n", e.File, e.Line, e.Message, e.Rule)
+	}
+}
 
-	return l.LintFiles(args, nil)
+// autofixOptionsは-fixで有効になる自動修正の設定
+type autofixOptions struct {
+	// dryRunがtrueの場合は修正内容をルールごとにまとめたunified diffとして出力し、ファイルは変更しない
+	dryRun bool
+	// patchFileが空でない場合は、ファイルを変更する代わりに全ての修正をgit applyで適用できるパッチとしてpatchFileに書き込む
+	patchFile string
+	// rulesが空でない場合は、これらのルールのAutoFixerだけを適用する
+	rules []string
+	// excludeに含まれるルールのAutoFixerは適用しない
+	exclude []string
+	// linterOptsは修正後のワークフローを検証するlinterの設定
+	linterOpts *LinterOptions
 }
 
-func (cmd *Command) runAutofix(results []*ValidateResult, isDryRun bool) {
+// runAutofixはAutoFixerを適用する
+// 各AutoFixerの修正後のワークフローを再度パースしてlintし、パースエラーや新しいエラーが発生した修正は取り消す
+// AutoFixerごとの適用結果はStderrに出力する
+func (cmd *Command) runAutofix(results []*ValidateResult, opts *autofixOptions) error {
+	var patch bytes.Buffer
+	patched := 0
 	for _, res := range results {
-		if len(res.AutoFixers) == 0 {
+		fixers := selectAutoFixers(res.AutoFixers, opts.rules, opts.exclude)
+		if len(fixers) == 0 {
 			continue
 		}
-		for _, fixer := range res.AutoFixers {
-			if err := fixer.Fix(); err != nil {
-				var lintErr *LintingError
-				if errors.As(err, &lintErr) {
-					lintErr.FilePath = res.FilePath
-					lintErr.DisplayError(cmd.Stderr, res.Source)
-				} else {
-					fmt.Fprintf(cmd.Stderr, "Error while fixing %s: %v\n", fixer.RuleName(), err)
-				}
+		verify, err := newFixVerifier(opts.linterOpts, res.FilePath, res.Source)
+		if err != nil {
+			return fmt.Errorf("could not verify fixes of %s: %w", res.FilePath, err)
+		}
+		// 修正したノードに対応する部分だけを書き換え、それ以外のフォーマットはそのまま残す
+		fixed := ApplyAutoFixers(res.Source, res.ParsedWorkflow, fixers, verify)
+		cmd.printAutofixReport(res, fixers, fixed)
+		data := fixed.Source
+		if bytes.Equal(data, res.Source) {
+			continue
+		}
+		if opts.dryRun {
+			if err := WriteUnifiedDiffByRule(cmd.Stdout, diffPath(res.FilePath), res.Source, fixed.Patch.Edits()); err != nil {
+				return err
 			}
+			continue
 		}
-		var buf bytes.Buffer
-		enc := yaml.NewEncoder(&buf)
-		enc.SetIndent(2)
-		err := enc.Encode(res.ParsedWorkflow.BaseNode)
-		if err != nil {
-			fmt.Fprintf(cmd.Stderr, "Error while marshaling the fixed workflow: %v\n", err)
+		if opts.patchFile != "" {
+			if err := WriteUnifiedDiff(&patch, diffPath(res.FilePath), res.Source, fixed.Patch.Edits()); err != nil {
+				return err
+			}
+			patched++
+			continue
 		}
-		data := buf.Bytes()
-		if isDryRun {
-			fmt.Fprintf(cmd.Stdout, "Fixed workflow %s:\n%s\n", res.FilePath, string(data))
-		} else {
-			err := os.WriteFile(res.FilePath, data, 0644)
+		err = os.WriteFile(res.FilePath, data, 0644)
+		if err != nil {
+			fmt.Fprintf(cmd.Stderr, "Error while writing the fixed workflow: %v\n", err)
+			err := os.WriteFile(res.FilePath, res.Source, 0644) // restore the original file
 			if err != nil {
-				fmt.Fprintf(cmd.Stderr, "Error while writing the fixed workflow: %v\n", err)
-				err := os.WriteFile(res.FilePath, res.Source, 0644) // restore the original file
-				if err != nil {
-					fmt.Fprintf(cmd.Stderr, "Error while restoring the original workflow: %v\n", err)
-				}
-			} else {
-				fmt.Fprintf(cmd.Stdout, "Fixed workflow %s\n", res.FilePath)
+				fmt.Fprintf(cmd.Stderr, "Error while restoring the original workflow: %v\n", err)
 			}
+		} else {
+			fmt.Fprintf(cmd.Stdout, "Fixed workflow %s\n", res.FilePath)
 		}
 	}
+	if opts.patchFile == "" || opts.dryRun {
+		return nil
+	}
+	if err := os.WriteFile(opts.patchFile, patch.Bytes(), 0644); err != nil {
+		return fmt.Errorf("could not write patch file %q: %w", opts.patchFile, err)
+	}
+	fmt.Fprintf(cmd.Stdout, "Wrote fixes for %d workflow(s) to patch file %s\n", patched, opts.patchFile)
+	return nil
+}
+
+// selectAutoFixersは-fix-rulesと-fix-excludeに従って適用するAutoFixerを選ぶ
+func selectAutoFixers(fixers []AutoFixer, rules, exclude []string) []AutoFixer {
+	var selected []AutoFixer
+	for _, f := range fixers {
+		if len(rules) > 0 && !slices.Contains(rules, f.RuleName()) {
+			continue
+		}
+		if slices.Contains(exclude, f.RuleName()) {
+			continue
+		}
+		selected = append(selected, f)
+	}
+	return selected
+}
+
+// printAutofixReportはAutoFixerごとの適用結果を出力する
+func (cmd *Command) printAutofixReport(res *ValidateResult, fixers []AutoFixer, fixed *FixResult) {
+	failures := make(map[AutoFixer]error, len(fixed.Failed))
+	for _, f := range fixed.Failed {
+		failures[f.Fixer] = f.Err
+	}
+	fmt.Fprintf(cmd.Stderr, "Autofix report for %s:\n", res.FilePath)
+	for _, f := range fixers {
+		where := ""
+		if line := fixerLine(f); line > 0 {
+			where = fmt.Sprintf(" (line %d)", line)
+		}
+		err, failed := failures[f]
+		if !failed {
+			fmt.Fprintf(cmd.Stderr, "  applied      %s%s\n", f.RuleName(), where)
+			continue
+		}
+		var verifyErr *FixVerificationError
+		var conflict *FixConflictError
+		var lintErr *LintingError
+		switch {
+		case errors.As(err, &verifyErr):
+			fmt.Fprintf(cmd.Stderr, "  rolled back  %s%s: %v\n", f.RuleName(), where, err)
+		case errors.As(err, &conflict):
+			fmt.Fprintf(cmd.Stderr, "  skipped      %s%s: %v\n", f.RuleName(), where, err)
+		case errors.As(err, &lintErr):
+			fmt.Fprintf(cmd.Stderr, "  failed       %s%s: %s\n", f.RuleName(), where, lintErr.Description)
+			lintErr.FilePath = res.FilePath
+			lintErr.DisplayError(cmd.Stderr, res.Source)
+		default:
+			fmt.Fprintf(cmd.Stderr, "  failed       %s%s: %v\n", f.RuleName(), where, err)
+		}
+	}
+	fmt.Fprintf(cmd.Stderr, "Applied %d of %d fixes\n", len(fixed.Applied), len(fixers))
+}
+
+// diffPathはパッチに書き込むファイルのパスを返す
+// git applyはリポジトリのルートからの相対パスで適用するため、ファイルが属するプロジェクトのルートからの相対パスに変換する
+func diffPath(path string) string {
+	abs := getAbsolutePath(path)
+	if project, err := locateProject(filepath.Dir(abs)); err == nil {
+		if rel, err := filepath.Rel(project.RootDirectory(), abs); err == nil && !strings.HasPrefix(rel, "..") {
+			return filepath.ToSlash(rel)
+		}
+	}
+	return filepath.ToSlash(filepath.Clean(path))
+}
+
+// parseRuleListはカンマ区切りのルール名のリストを解析する。存在しないルール名が含まれる場合はエラーを返す
+func parseRuleList(list, flagName string) ([]string, error) {
+	if list == "" {
+		return nil, nil
+	}
+	known := map[string]bool{}
+	for _, r := range makeRules("", nil, nil, nil, nil) {
+		known[r.RuleNames()] = true
+	}
+	var rules []string
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !known[name] {
+			return nil, fmt.Errorf("unknown rule %q in %s", name, flagName)
+		}
+		rules = append(rules, name)
+	}
+	return rules, nil
 }
 
 type ignorePatternFlags []string
@@ -186,11 +349,21 @@ func (cmd *Command) Main(args []string) int {
 	var generateBoilerplate bool
 	var generateActionList bool
 	var autoFixMode string
+	var autoFixOutput string
+	var autoFixPatchFile string
+	var autoFixRules string
+	var autoFixExclude string
 	var remoteInput string
 	var recursive bool
 	var maxDepth int
 	var parallelism int
 	var limit int
+	var minSeverity string
+	var failOn string
+	var baselineCreate string
+	var pinResolve bool
+	var pinToken string
+	var pinAPIURL string
 
 	flags := flag.NewFlagSet(args[0], flag.ContinueOnError)
 	flags.SetOutput(cmd.Stderr)
@@ -205,6 +378,19 @@ func (cmd *Command) Main(args []string) int {
 	flags.BoolVar(&showVersion, "version", false, "Show version and how this binary was installed")
 	flags.StringVar(&linterOpts.StdinInputFileName, "stdin-filename", "", "File name when reading input from stdin")
 	flags.StringVar(&autoFixMode, "fix", "off", "Enable auto-fix mode. Available options: off, on, dry-run")
+	flags.StringVar(&autoFixOutput, "fix-output", FileFixOutputInPlace, "Where -fix on writes fixes. Available options: inplace (overwrite workflow files), patch (write a patch file for git apply)")
+	flags.StringVar(&autoFixPatchFile, "fix-patch-file", "sisakulint-fix.patch", "Patch file written when -fix-output is patch")
+	flags.StringVar(&autoFixRules, "fix-rules", "", "Comma-separated rule names whose fixes are applied by -fix. All fixes are applied when empty")
+	flags.StringVar(&autoFixExclude, "fix-exclude", "", "Comma-separated rule names whose fixes are not applied by -fix")
+	flags.StringVar(&minSeverity, "min-severity", "", "Only report errors whose severity is at least this level. Available options: critical, high, medium, low, info")
+	flags.StringVar(&failOn, "fail-on", "", "Exit with failure status only when an error at or above this severity is found. Available options: critical, high, medium, low, info")
+	flags.StringVar(&linterOpts.BaselineFilePath, "baseline", "", "Baseline file created by -baseline-create. Errors recorded in the baseline are not reported")
+	flags.StringVar(&baselineCreate, "baseline-create", "", "Record all current errors to the baseline file and exit")
+	flags.BoolVar(&pinResolve, "pin-resolve", false, "Resolve actions used in workflows to commit SHAs with GitHub API and record them to .github/"+ActionLockFileName+". commit-sha rule and its autofix use the lock file without network access")
+	flags.StringVar(&pinToken, "pin-token", "", "GitHub token used by -pin-resolve. GITHUB_TOKEN or GH_TOKEN environment variable is used when empty")
+	flags.StringVar(&pinAPIURL, "pin-api-url", "", "GitHub API base URL used by -pin-resolve, e.g. https://github.example.com/api/v3/. GITHUB_API_URL environment variable or https://api.github.com/ is used when empty")
+	flags.StringVar(&linterOpts.ShellcheckExecutable, "shellcheck", "shellcheck", "Command name or file path of shellcheck executable used by shellcheck rule. The rule is disabled when the executable is not found or this value is empty")
+	flags.StringVar(&linterOpts.AdvisoryDatabasePath, "advisory-db", "", "OSV advisory file or directory of OSV files used by action-advisory rule in addition to the embedded advisory database")
 	flags.StringVar(&remoteInput, "remote", "", "Remote repository to scan (owner/repo, URL, or search query like 'org:kubernetes')")
 	flags.BoolVar(&recursive, "r", false, "Enable recursive scanning of reusable workflows (-remote only)")
 	flags.IntVar(&maxDepth, "D", 3, "Max recursion depth for recursive scanning (-remote only)")
@@ -227,6 +413,49 @@ func (cmd *Command) Main(args []string) int {
 		fmt.Fprintf(cmd.Stderr, "Invalid value for -fix: %s\n", autoFixMode)
 		return ExitStatusInvalidCommandOption
 	}
+	if autoFixOutput != FileFixOutputInPlace && autoFixOutput != FileFixOutputPatch {
+		fmt.Fprintf(cmd.Stderr, "Invalid value for -fix-output: %s\n", autoFixOutput)
+		return ExitStatusInvalidCommandOption
+	}
+	if autoFixOutput == FileFixOutputPatch && autoFixMode != "on" {
+		fmt.Fprintln(cmd.Stderr, "-fix-output patch can only be used with -fix on")
+		return ExitStatusInvalidCommandOption
+	}
+
+	fixRules, err := parseRuleList(autoFixRules, "-fix-rules")
+	if err != nil {
+		fmt.Fprintln(cmd.Stderr, err.Error())
+		return ExitStatusInvalidCommandOption
+	}
+	fixExclude, err := parseRuleList(autoFixExclude, "-fix-exclude")
+	if err != nil {
+		fmt.Fprintln(cmd.Stderr, err.Error())
+		return ExitStatusInvalidCommandOption
+	}
+
+	if minSeverity != "" {
+		sev, err := ParseSeverity(minSeverity)
+		if err != nil {
+			fmt.Fprintf(cmd.Stderr, "Invalid value for -min-severity: %v\n", err)
+			return ExitStatusInvalidCommandOption
+		}
+		linterOpts.MinSeverity = sev
+	}
+
+	failOnSeverity := SeverityNone
+	if failOn != "" {
+		sev, err := ParseSeverity(failOn)
+		if err != nil {
+			fmt.Fprintf(cmd.Stderr, "Invalid value for -fail-on: %v\n", err)
+			return ExitStatusInvalidCommandOption
+		}
+		failOnSeverity = sev
+	}
+
+	if baselineCreate != "" && linterOpts.BaselineFilePath != "" {
+		fmt.Fprintln(cmd.Stderr, "-baseline and -baseline-create cannot be used at the same time")
+		return ExitStatusInvalidCommandOption
+	}
 
 	if showVersion {
 		fmt.Fprintf(
@@ -249,7 +478,7 @@ func (cmd *Command) Main(args []string) int {
 	}
 
 	if remoteInput != "" {
-		return cmd.runRemoteScan(remoteInput, &linterOpts, &remote.ScannerOptions{
+		return cmd.runRemoteScan(remoteInput, &linterOpts, failOnSeverity, &remote.ScannerOptions{
 			Parallelism: parallelism,
 			Recursive:   recursive,
 			MaxDepth:    maxDepth,
@@ -259,6 +488,14 @@ func (cmd *Command) Main(args []string) int {
 		})
 	}
 
+	if pinResolve {
+		return cmd.runPinResolve(flags.Args(), &linterOpts, pinToken, pinAPIURL)
+	}
+
+	if baselineCreate != "" {
+		return cmd.runBaselineCreate(flags.Args(), &linterOpts, baselineCreate)
+	}
+
 	errs, err := cmd.runLint(flags.Args(), &linterOpts, initConfig, generateBoilerplate)
 	if err != nil {
 		fmt.Fprintln(cmd.Stderr, err.Error())
@@ -267,16 +504,43 @@ func (cmd *Command) Main(args []string) int {
 	if len(errs) > 0 {
 		enableAutofix := autoFixMode == "on" || autoFixMode == FileFixDryRun
 		if enableAutofix {
-			cmd.runAutofix(errs, autoFixMode == FileFixDryRun)
+			opts := &autofixOptions{
+				dryRun:     autoFixMode == FileFixDryRun,
+				rules:      fixRules,
+				exclude:    fixExclude,
+				linterOpts: &linterOpts,
+			}
+			if autoFixOutput == FileFixOutputPatch {
+				opts.patchFile = autoFixPatchFile
+			}
+			if err := cmd.runAutofix(errs, opts); err != nil {
+				fmt.Fprintln(cmd.Stderr, err.Error())
+				return ExitStatusFailure
+			}
+		}
+		if hasFailingError(errs, failOnSeverity) {
+			return ExitStatusSuccessProblemFound
 		}
-		return ExitStatusSuccessProblemFound
 	}
 
 	return ExitStatusSuccessNoProblem
 }
 
+// hasFailingErrorは、検証結果にfailOn以上の深刻度のエラーが含まれているかを返す
+// failOnがSeverityNoneの場合は、深刻度に関係なくエラーが1つでもあればtrueを返す
+func hasFailingError(results []*ValidateResult, failOn Severity) bool {
+	for _, res := range results {
+		for _, err := range res.Errors {
+			if err.severity() >= failOn {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // runRemoteScan はリモートリポジトリをスキャンする
-func (cmd *Command) runRemoteScan(input string, linterOpts *LinterOptions, scannerOpts *remote.ScannerOptions) int {
+func (cmd *Command) runRemoteScan(input string, linterOpts *LinterOptions, failOn Severity, scannerOpts *remote.ScannerOptions) int {
 	linter, err := NewLinter(cmd.Stdout, linterOpts)
 	if err != nil {
 		fmt.Fprintf(cmd.Stderr, "Error initializing linter: %v\n", err)
@@ -288,7 +552,7 @@ func (cmd *Command) runRemoteScan(input string, linterOpts *LinterOptions, scann
 		if err != nil {
 			return false, err
 		}
-		return len(result.Errors) > 0, nil
+		return hasFailingError([]*ValidateResult{result}, failOn), nil
 	}
 
 	scanner, err := remote.NewScanner(scannerOpts)
@@ -3,8 +3,8 @@ package core
 import (
 	"context"
 	"fmt"
-	"net/http"
 	"regexp"
+	"slices"
 	"strings"
 	"sync"
 
@@ -14,17 +14,28 @@ import (
 
 type CommitSha struct {
 	BaseRule
+	// lockはプロジェクトのアクションのロックファイル。nilの場合、自動修正はGitHub APIで参照を解決する
+	lock *ActionLock
 }
 
 func CommitShaRule() *CommitSha {
+	return CommitShaRuleWithLock(nil)
+}
+
+// CommitShaRuleWithLockはロックファイルを使って参照を解決するCommitShaルールを作成する
+// ロックファイルがある場合、自動修正はネットワークにアクセスせず、ピン留めされたSHAの横のバージョンのコメントがロックファイルと一致するかも検査する
+func CommitShaRuleWithLock(lock *ActionLock) *CommitSha {
 	return &CommitSha{
 		BaseRule: BaseRule{
 			RuleName: "commit-sha",
 			RuleDesc: "Warn if the action ref is not a full length commit SHA and not an official GitHub Action.",
 		},
+		lock: lock,
 	}
 }
 
+var fullShaPattern = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
 // Check if the given ref is a full length commit SHA
 func isFullLengthSha(ref string) bool {
 	re := regexp.MustCompile(`^.+@([0-9a-f]{40})$`)
@@ -40,11 +51,42 @@ func (rule *CommitSha) VisitStep(step *ast.Step) error {
 				"the action ref in 'uses' for step '%s' should be a full length commit SHA for immutability and security. See documents: https://docs.github.com/en/actions/security-guides/security-hardening-for-github-actions#using-third-party-actions",
 				step.String())
 			rule.AddAutoFixer(NewStepFixer(step, rule)) // add autofix for this CommitSha rule
+		} else {
+			rule.checkVersionComment(action)
 		}
 	}
 	return nil
 }
 
+// checkVersionCommentはピン留めされたSHAの横の"# vX.Y.Z"のコメントが、ロックファイルに記録されたそのSHAのバージョンと一致するかを検査する
+// ロックファイルにそのSHAが記録されていない場合は検査しない
+func (rule *CommitSha) checkVersionComment(action *ast.ExecAction) {
+	if rule.lock == nil || action.Uses == nil || action.Uses.BaseNode == nil {
+		return
+	}
+	repo, _, sha, ok := splitActionRef(action.Uses.Value)
+	if !ok {
+		return
+	}
+	comment := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(action.Uses.BaseNode.LineComment), "#"))
+	if !semverPattern.MatchString(comment) && !shortTagPattern.MatchString(comment) {
+		return
+	}
+	versions := rule.lock.versionsOf(repo, sha)
+	if len(versions) == 0 || slices.Contains(versions, comment) {
+		return
+	}
+	entry := rule.lock.entryOf(repo, sha)
+	rule.Errorf(action.Uses.Pos,
+		"the version comment '%s' of action '%s' does not match the lock file %s, which records commit %s as version '%s'",
+		comment, repo, ActionLockFileName, sha, entry.Version)
+	node := action.Uses.BaseNode
+	rule.AddAutoFixer(NewFuncFixer(rule.RuleName, func() error {
+		node.LineComment = entry.Version
+		return nil
+	}))
+}
+
 // from https://github.com/suzuki-shunsuke/pinact/blob/532aa7ba57db6c11937831f993b51640bbda94ac/pkg/controller/run/parse_line.go#L18-L19
 var (
 	semverPattern   = regexp.MustCompile(`^v?\d+\.\d+\.\d+[^ ]*$`)
@@ -77,51 +119,54 @@ func getLongVersion(cl *github.Client, owner, repo, sha string, expectedTag stri
 }
 
 var ghOnce sync.Once
-var ghClient *github.Client
+var ghResolver *ActionRefResolver
+var ghResolverErr error
+
+// NeedsNetworkは、ロックファイルがない場合に修正がGitHub APIで参照を解決するためtrueを返す
+// ロックファイルがある場合は、記録されていない参照もネットワークにアクセスせずにエラーになる
+func (rule *CommitSha) NeedsNetwork(step *ast.Step) bool {
+	return rule.lock == nil
+}
 
 func (rule *CommitSha) FixStep(step *ast.Step) error {
 	// at here, we can assume that the action ref is not a full length commit SHA
 	action := step.Exec.(*ast.ExecAction)
 	usesValue := action.Uses.Value
-	ghOnce.Do(func() {
-		// TODO(on-keyday): make this configurable
-		ghClient = github.NewClient(http.DefaultClient)
-	})
-	gh := ghClient
 	splitTag := strings.Split(usesValue, "@")
 	if len(splitTag) != 2 {
 		// Create a LintingError with position information
 		lintErr := FormattedError(step.Pos, rule.RuleName, "invalid action reference format: '%s', expected format is 'owner/repo@ref'", usesValue)
 		return lintErr
 	}
-	ownerRepo := strings.Split(splitTag[0], "/")
-	if len(ownerRepo) != 2 {
+	repo, path, tag, ok := splitActionRef(usesValue)
+	if !ok {
 		// Create a LintingError with position information
 		lintErr := FormattedError(step.Pos, rule.RuleName, "invalid action owner/repo format: '%s', expected format is 'owner/repo'", splitTag[0])
 		return lintErr
 	}
-	tag := splitTag[1]
-	isSemver := semverPattern.MatchString(splitTag[1])
-	isShortTag := shortTagPattern.MatchString(splitTag[1])
-	//tagComment := action.Uses.BaseNode.LineComment
-	sha, _, err := gh.Repositories.GetCommitSHA1(context.TODO(), ownerRepo[0], ownerRepo[1], tag, "")
-	if err != nil {
-		// Create a LintingError with position information
-		// Using error.Error() to include the full error message
-		lintErr := FormattedError(step.Pos, rule.RuleName, "failed to get commit SHA1: %s at step '%s'", err.Error(), step.String())
-		return lintErr
+
+	// ロックファイルがある場合はネットワークにアクセスせずにロックファイルだけで解決する
+	entry := rule.lock.Lookup(repo, tag)
+	if entry == nil && rule.lock != nil {
+		return FormattedError(step.Pos, rule.RuleName, "action '%s@%s' is not recorded in the lock file %s. run 'sisakulint -pin-resolve' to add it", repo, tag, ActionLockFileName)
 	}
-	if !isSemver && isShortTag {
-		longVersion, err := getLongVersion(gh, ownerRepo[0], ownerRepo[1], sha, splitTag[1])
+	if entry == nil {
+		ghOnce.Do(func() {
+			ghResolver, ghResolverErr = NewActionRefResolver("", "")
+		})
+		if ghResolverErr != nil {
+			return FormattedError(step.Pos, rule.RuleName, "%s", ghResolverErr.Error())
+		}
+		var err error
+		entry, err = ghResolver.Resolve(context.TODO(), repo, tag)
 		if err != nil {
 			// Create a LintingError with position information
 			// Using error.Error() to include the full error message
-			lintErr := FormattedError(step.Pos, rule.RuleName, "failed to get long version: %s at step '%s'", err.Error(), step.String())
+			lintErr := FormattedError(step.Pos, rule.RuleName, "%s at step '%s'", err.Error(), step.String())
 			return lintErr
 		}
-		tag = longVersion
 	}
-	action.Uses.BaseNode.Value = splitTag[0] + "@" + sha
-	action.Uses.BaseNode.LineComment = tag
+	action.Uses.BaseNode.Value = repo + path + "@" + entry.SHA
+	action.Uses.BaseNode.LineComment = entry.Version
 	return nil
 }
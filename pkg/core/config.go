@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 
 	"gopkg.in/yaml.v3"
@@ -20,10 +21,285 @@ type Config struct {
 	// ConfigVariablesはチェックされるworkflowで使用される設定変数の名前を示す
 	//この値がnilの時にvarsのコンテキストのプロパティ名はチェックされない
 	ConfigVariables []string `yaml:"config-variables"`
-	// ActionList は許可アクションのリストを管理する設定
-	ActionList []string `yaml:"action-list"`
+	// ActionList は許可/禁止するアクションのリストを管理する設定
+	ActionList ActionListConfig `yaml:"action-list"`
+	// TriggerTrustはセキュリティのルールが特権トリガーとして扱うイベントの追加と上書き
+	TriggerTrust TriggerTrustConfig `yaml:"trigger-trust"`
+	// UntrustedInputsは組み込みのカタログに加えて信頼できない入力として扱うソース
+	UntrustedInputs []*UntrustedInputConfig `yaml:"untrusted-inputs"`
+	// Rulesはルール名ごとの有効/無効とルール固有のオプション
+	Rules map[string]*RuleConfig `yaml:"rules"`
+	// Overridesはworkflowのパスのglobごとにrulesの設定を上書きする
+	//後に書かれたものほど優先される
+	Overrides []*ConfigOverride `yaml:"overrides"`
+}
+
+// ActionListConfigはaction-listセクションの設定
+// 文字列のリストを書いた場合はwhitelistの省略形として扱う
+type ActionListConfig struct {
+	// Whitelistは許可するアクションのパターン。空でない場合、どのパターンにもマッチしないアクションはエラーになる
+	Whitelist []string `yaml:"whitelist"`
+	// Blacklistは禁止するアクションのパターン。whitelistより優先される
+	Blacklist []*ActionListBlacklistEntry `yaml:"blacklist"`
+
+	whitelistRegex []*regexp.Regexp
+}
+
+// ActionListBlacklistEntryはblacklistの1つのエントリ
+// 文字列だけを書いた場合はpatternの省略形として扱う
+type ActionListBlacklistEntry struct {
+	// Patternは禁止するアクションのパターン
+	Pattern string `yaml:"pattern"`
+	// Reasonは禁止する理由。エラーメッセージに表示される
+	Reason string `yaml:"reason"`
+	// Advisoryは禁止の根拠となるアドバイザリのURL。エラーメッセージに表示される
+	Advisory string `yaml:"advisory"`
+
+	regex *regexp.Regexp
+}
+
+// UnmarshalYAMLはwhitelistの省略形である文字列のリストを受け付ける
+func (c *ActionListConfig) UnmarshalYAML(n *yaml.Node) error {
+	if n.Kind == yaml.SequenceNode {
+		return n.Decode(&c.Whitelist)
+	}
+	type plain ActionListConfig
+	return n.Decode((*plain)(c))
+}
+
+// UnmarshalYAMLはpatternの省略形である文字列を受け付ける
+func (e *ActionListBlacklistEntry) UnmarshalYAML(n *yaml.Node) error {
+	if n.Kind == yaml.ScalarNode {
+		return n.Decode(&e.Pattern)
+	}
+	type plain ActionListBlacklistEntry
+	return n.Decode((*plain)(e))
+}
+
+// compileはwhitelistとblacklistのパターンをコンパイルする
+func (c *ActionListConfig) compile() error {
+	for _, pattern := range c.Whitelist {
+		re, err := compileActionPattern(pattern)
+		if err != nil {
+			return fmt.Errorf("failed to compile regex for action list %q: %w", pattern, err)
+		}
+		c.whitelistRegex = append(c.whitelistRegex, re)
+	}
+	for i, e := range c.Blacklist {
+		if e == nil || e.Pattern == "" {
+			return fmt.Errorf("blacklist[%d] in action list must have a pattern", i)
+		}
+		re, err := compileActionPattern(e.Pattern)
+		if err != nil {
+			return fmt.Errorf("failed to compile regex for action list %q: %w", e.Pattern, err)
+		}
+		e.regex = re
+	}
+	return nil
+}
+
+// isEmptyはwhitelistとblacklistのどちらも設定されていないかどうかを返す
+func (c *ActionListConfig) isEmpty() bool {
+	return len(c.Whitelist) == 0 && len(c.Blacklist) == 0
+}
+
+// RuleConfigはrulesセクションの1つのルールの設定
+// "rule-name: false"のように真偽値だけを書いた場合はenabledの省略形として扱う
+type RuleConfig struct {
+	// Enabledはルールを有効にするかどうか。nilの場合は既定(有効)のまま
+	Enabled *bool `yaml:"enabled"`
+	// Optionsはルール固有のオプション。ルールのConfigureOptionsに渡される
+	Options yaml.Node `yaml:"options"`
+}
+
+// UnmarshalYAMLは真偽値だけの省略形を受け付ける
+func (c *RuleConfig) UnmarshalYAML(n *yaml.Node) error {
+	if n.Kind == yaml.ScalarNode {
+		var enabled bool
+		if err := n.Decode(&enabled); err != nil {
+			return fmt.Errorf("line %d: rule config must be a boolean or a mapping with \"enabled\" and \"options\" keys", n.Line)
+		}
+		c.Enabled = &enabled
+		return nil
+	}
+	type plain RuleConfig
+	return n.Decode((*plain)(c))
+}
+
+// hasOptionsはoptionsが設定されているかどうかを返す
+func (c *RuleConfig) hasOptions() bool {
+	return c.Options.Kind != 0
+}
+
+// ConfigOverrideはoverridesセクションの1つの要素
+type ConfigOverride struct {
+	// Pathsはこの設定を適用するworkflowのパスのglob。リポジトリのルートからの相対パスと照合される
+	// "*"は"/"以外の任意の文字列、"**"は任意の階層にマッチする。"/"を含まないglobはファイル名と照合される
+	Paths []string `yaml:"paths"`
+	// Rulesは上書きするルールの設定
+	Rules map[string]*RuleConfig `yaml:"rules"`
+	// UntrustedInputsはこのworkflowだけで信頼できない入力として扱うソース。例えば再利用可能なworkflowのinputs.*
+	UntrustedInputs []*UntrustedInputConfig `yaml:"untrusted-inputs"`
+
+	pathRegex []*regexp.Regexp
+}
+
+// compilePathGlobはworkflowのパスのglobを正規表現にコンパイルする
+func compilePathGlob(glob string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	if !strings.Contains(glob, "/") {
+		// ファイル名だけのglobは任意のディレクトリのファイルにマッチする
+		b.WriteString("(?:.*/)?")
+	}
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				i++
+				if i+1 < len(glob) && glob[i+1] == '/' {
+					// "**/"は0個以上のディレクトリにマッチする
+					i++
+					b.WriteString("(?:.*/)?")
+				} else {
+					b.WriteString(".*")
+				}
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
+
+// matchesは与えられたパスがoverrideのglobのどれかにマッチするかを返す
+func (o *ConfigOverride) matches(path string) bool {
+	path = strings.TrimPrefix(filepath.ToSlash(path), "./")
+	for _, re := range o.pathRegex {
+		if re.MatchString(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeRuleConfigsはbaseのルールの設定をoverrideで上書きした新しいmapを返す
+// enabledとoptionsはそれぞれoverrideに書かれている場合のみ上書きされる
+func mergeRuleConfigs(base, override map[string]*RuleConfig) map[string]*RuleConfig {
+	merged := make(map[string]*RuleConfig, len(base)+len(override))
+	for name, rc := range base {
+		merged[name] = rc
+	}
+	for name, rc := range override {
+		prev, ok := merged[name]
+		if !ok {
+			merged[name] = rc
+			continue
+		}
+		next := *prev
+		if rc.Enabled != nil {
+			next.Enabled = rc.Enabled
+		}
+		if rc.hasOptions() {
+			next.Options = rc.Options
+		}
+		merged[name] = &next
+	}
+	return merged
+}
+
+// ForPathはpathにマッチするoverridesを適用した設定を返す
+// pathはリポジトリのルートからの相対パス。マッチするoverrideがない場合はレシーバをそのまま返す
+// overridesのuntrusted-inputsは上書きではなく追加される
+func (c *Config) ForPath(path string) *Config {
+	var rules map[string]*RuleConfig
+	var inputs []*UntrustedInputConfig
+	matched := false
+	for _, o := range c.Overrides {
+		if !o.matches(path) {
+			continue
+		}
+		if !matched {
+			rules = c.Rules
+			inputs = c.UntrustedInputs
+			matched = true
+		}
+		rules = mergeRuleConfigs(rules, o.Rules)
+		if len(o.UntrustedInputs) > 0 {
+			inputs = append(inputs[:len(inputs):len(inputs)], o.UntrustedInputs...)
+		}
+	}
+	if !matched {
+		return c
+	}
+	resolved := *c
+	resolved.Rules = rules
+	resolved.UntrustedInputs = inputs
+	resolved.Overrides = nil
+	return &resolved
+}
+
+// IsRuleEnabledはルールが有効かどうかを返す。設定のないルールは有効
+func (c *Config) IsRuleEnabled(name string) bool {
+	if c == nil {
+		return true
+	}
+	if rc, ok := c.Rules[name]; ok && rc != nil && rc.Enabled != nil {
+		return *rc.Enabled
+	}
+	return true
+}
 
-	actionListRegex []*regexp.Regexp
+// RuleOptionsはルール固有のオプションを返す。オプションがない場合はnil
+func (c *Config) RuleOptions(name string) *yaml.Node {
+	if c == nil {
+		return nil
+	}
+	if rc, ok := c.Rules[name]; ok && rc != nil && rc.hasOptions() {
+		return &rc.Options
+	}
+	return nil
+}
+
+// validateRuleConfigsはrulesセクションのルール名とオプションを検証する
+func validateRuleConfigs(rules map[string]*RuleConfig, section string) error {
+	if len(rules) == 0 {
+		return nil
+	}
+	known := map[string]Rule{}
+	for _, r := range makeRules("", nil, nil, nil, nil) {
+		known[r.RuleNames()] = r
+	}
+	// shellcheckは実行ファイルが見つかった場合だけbuildRulesで追加されるが、設定では常に指定できる
+	known["shellcheck"] = newShellcheckRule(nil)
+	names := make([]string, 0, len(rules))
+	for name := range rules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		rule, ok := known[name]
+		if !ok {
+			return fmt.Errorf("unknown rule %q in %s section", name, section)
+		}
+		rc := rules[name]
+		if rc == nil || !rc.hasOptions() {
+			continue
+		}
+		configurable, ok := rule.(ConfigurableRule)
+		if !ok {
+			return fmt.Errorf("rule %q in %s section does not take any options", name, section)
+		}
+		if err := configurable.ConfigureOptions(&rc.Options); err != nil {
+			return fmt.Errorf("invalid options for rule %q in %s section: %w", name, section, err)
+		}
+	}
+	return nil
 }
 
 // parseConfigは与えられたbyte sliceをConfigにparseする
@@ -34,12 +310,35 @@ func parseConfig(b []byte, path string) (*Config, error) {
 		return nil, fmt.Errorf("failed to parse config file %q: %s", path, msg)
 	}
 	// ActionListのパターンをコンパイル
-	for _, pattern := range c.ActionList {
-		re, err := compileActionPattern(pattern)
-		if err != nil {
-			return nil, fmt.Errorf("failed to compile regex for action list %q: %w", pattern, err)
+	if err := c.ActionList.compile(); err != nil {
+		return nil, err
+	}
+	if err := c.TriggerTrust.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config file %q: %w", path, err)
+	}
+	if err := compileUntrustedInputs(c.UntrustedInputs, "untrusted-inputs"); err != nil {
+		return nil, fmt.Errorf("invalid config file %q: %w", path, err)
+	}
+	if err := validateRuleConfigs(c.Rules, "rules"); err != nil {
+		return nil, fmt.Errorf("invalid config file %q: %w", path, err)
+	}
+	for i, o := range c.Overrides {
+		if o == nil || len(o.Paths) == 0 {
+			return nil, fmt.Errorf("invalid config file %q: overrides[%d] must have at least one glob in \"paths\"", path, i)
+		}
+		for _, glob := range o.Paths {
+			re, err := compilePathGlob(glob)
+			if err != nil {
+				return nil, fmt.Errorf("invalid config file %q: invalid glob %q in overrides[%d]: %w", path, glob, i, err)
+			}
+			o.pathRegex = append(o.pathRegex, re)
+		}
+		if err := validateRuleConfigs(o.Rules, fmt.Sprintf("overrides[%d].rules", i)); err != nil {
+			return nil, fmt.Errorf("invalid config file %q: %w", path, err)
+		}
+		if err := compileUntrustedInputs(o.UntrustedInputs, fmt.Sprintf("overrides[%d].untrusted-inputs", i)); err != nil {
+			return nil, fmt.Errorf("invalid config file %q: %w", path, err)
 		}
-		c.actionListRegex = append(c.actionListRegex, re)
 	}
 	return &c, nil
 }
@@ -92,7 +391,10 @@ config-variables: null
 
 # action-list section is for specifying which GitHub Actions are allowed or blocked in your workflows.
 # You can define a whitelist (only these actions are allowed) or a blacklist (these actions are blocked).
+# The blacklist takes precedence over the whitelist. An empty whitelist allows every action not in the blacklist.
+# Both lists also apply to reusable workflows called by jobs (owner/repo/.github/workflows/x.yml@ref) and docker:// images.
 # Using wildcards is supported: actions/checkout@* matches any version of actions/checkout.
+# A blacklist entry can have a reason and an advisory URL which are shown in the error message.
 action-list:
   whitelist:
     - actions/checkout@*
@@ -100,12 +402,62 @@ action-list:
     - actions/cache@*
   blacklist:
     - untrusted/*@*
-    - suspicious/*@*
+    - pattern: tj-actions/changed-files@*
+      reason: compromised in March 2025 supply chain attack
+      advisory: https://github.com/advisories/GHSA-mrrh-fwg8-r2c3
+
+# trigger-trust section changes which workflow triggers security rules treat as privileged.
+# Privileged triggers can be started by outside users and run with secrets or a write token, so findings in
+# such workflows get higher severities. By default pull_request_target, workflow_run, issue_comment, issues and
+# discussion_comment are privileged. Unprivileged entries take precedence over privileged ones.
+# An entry can be an event name or a mapping with "event" and "types" to match only some activity types.
+# 🧠 Example:
+# trigger-trust:
+#   privileged:
+#     - workflow_dispatch
+#   unprivileged:
+#     - event: issues
+#       types: [labeled]
+
+# untrusted-inputs section adds sources of untrusted input to the builtin catalog used by injection rules.
+# A source is a property path, or outputs of steps which use an action (without version, wildcards allowed).
+# "events" are shown in error messages. Sources in overrides apply only to workflows matching the paths,
+# for example inputs of a reusable workflow which receives issue titles from its callers.
+# 🧠 Example:
+# untrusted-inputs:
+#   - github.event.client_payload.title
+#   - action: my-org/fetch-issue
+#     outputs: [title, body]
+#     events: [issues]
+# overrides:
+#   - paths: [".github/workflows/comment.yml"]
+#     untrusted-inputs:
+#       - inputs.comment
+
+# rules section enables or disables each rule by name and passes rule-specific options.
+# 🧠 Example:
+# rules:
+#   commit-sha: false
+#   missing-timeout-minutes:
+#     options:
+#       min: 1
+#       max: 60
+#       default: 10
+
+# overrides section changes the rules settings for workflows whose path matches the globs.
+# Later overrides take precedence over earlier ones.
+# 🧠 Example:
+# overrides:
+#   - paths: [".github/workflows/deploy-*.yml"]
+#     rules:
+#       missing-timeout-minutes:
+#         options:
+#           max: 30
 
 # Add other optional settings below.
 # 🧠 Example: some-option: value
 # Note: Refer to the sisakulint documentation for more information on available settings.
-	`)
+`)
 	if err := os.WriteFile(path, b, 0644); err != nil {
 		return fmt.Errorf("failed to write config file %q: %w", path, err)
 	}
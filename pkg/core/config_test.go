@@ -0,0 +1,260 @@
+package core
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseConfig_Rules(t *testing.T) {
+	cfg, err := parseConfig([]byte(`
+rules:
+  commit-sha: false
+  permissions:
+    enabled: true
+  missing-timeout-minutes:
+    options:
+      min: 1
+      max: 60
+overrides:
+  - paths: [".github/workflows/deploy-*.yml"]
+    rules:
+      missing-timeout-minutes:
+        options:
+          max: 30
+      commit-sha: true
+`), "sisakulint.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.IsRuleEnabled("commit-sha") {
+		t.Error("commit-sha should be disabled")
+	}
+	if !cfg.IsRuleEnabled("permissions") || !cfg.IsRuleEnabled("credentials") {
+		t.Error("permissions and credentials should be enabled")
+	}
+	if cfg.RuleOptions("missing-timeout-minutes") == nil {
+		t.Error("options of missing-timeout-minutes are not parsed")
+	}
+
+	deploy := cfg.ForPath(".github/workflows/deploy-prod.yml")
+	if !deploy.IsRuleEnabled("commit-sha") {
+		t.Error("commit-sha should be enabled by the override")
+	}
+	var opts map[string]int
+	if err := deploy.RuleOptions("missing-timeout-minutes").Decode(&opts); err != nil {
+		t.Fatal(err)
+	}
+	if opts["max"] != 30 {
+		t.Errorf("options of missing-timeout-minutes are not replaced by the override: %v", opts)
+	}
+	if cfg.IsRuleEnabled("commit-sha") {
+		t.Error("override must not modify the base config")
+	}
+	if test := cfg.ForPath(".github/workflows/test.yml"); test != cfg {
+		t.Error("config without matching override should be returned as is")
+	}
+}
+
+func TestParseConfig_Shellcheck(t *testing.T) {
+	cfg, err := parseConfig([]byte("rules:\n  shellcheck: false\n"), "sisakulint.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.IsRuleEnabled("shellcheck") {
+		t.Error("shellcheck should be disabled")
+	}
+}
+
+func TestParseConfig_InvalidRules(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  string
+		want string
+	}{
+		{
+			name: "unknown rule",
+			cfg:  "rules:\n  no-such-rule: false\n",
+			want: `unknown rule "no-such-rule" in rules section`,
+		},
+		{
+			name: "options for rule without options",
+			cfg:  "rules:\n  commit-sha:\n    options:\n      foo: bar\n",
+			want: `rule "commit-sha" in rules section does not take any options`,
+		},
+		{
+			name: "options for shellcheck",
+			cfg:  "rules:\n  shellcheck:\n    options:\n      severity: style\n",
+			want: `rule "shellcheck" in rules section does not take any options`,
+		},
+		{
+			name: "invalid timeout range",
+			cfg:  "rules:\n  missing-timeout-minutes:\n    options:\n      min: 30\n      max: 10\n",
+			want: `invalid options for rule "missing-timeout-minutes"`,
+		},
+		{
+			name: "override without paths",
+			cfg:  "overrides:\n  - rules:\n      commit-sha: false\n",
+			want: `overrides[0] must have at least one glob`,
+		},
+		{
+			name: "unknown rule in override",
+			cfg:  "overrides:\n  - paths: ['*.yml']\n    rules:\n      no-such-rule: false\n",
+			want: `unknown rule "no-such-rule" in overrides[0].rules section`,
+		},
+		{
+			name: "invalid shorthand",
+			cfg:  "rules:\n  commit-sha: maybe\n",
+			want: "must be a boolean or a mapping",
+		},
+		{
+			name: "invalid untrusted input path",
+			cfg:  "untrusted-inputs:\n  - github.event.\n",
+			want: `untrusted-inputs[0]: invalid path of untrusted input "github.event."`,
+		},
+		{
+			name: "untrusted action without outputs",
+			cfg:  "overrides:\n  - paths: ['*.yml']\n    untrusted-inputs:\n      - action: my-org/fetch\n",
+			want: `overrides[0].untrusted-inputs[0]: untrusted input of action "my-org/fetch" must have at least one name in "outputs"`,
+		},
+		{
+			name: "trigger trust entry without event",
+			cfg:  "trigger-trust:\n  privileged:\n    - types: [opened]\n",
+			want: "must have an event name",
+		},
+		{
+			name: "event both privileged and unprivileged",
+			cfg:  "trigger-trust:\n  privileged: [workflow_dispatch]\n  unprivileged:\n    - event: Workflow_Dispatch\n      types: [x]\n",
+			want: `event "workflow_dispatch" is both privileged and unprivileged`,
+		},
+		{
+			name: "event both privileged and unprivileged on same branch",
+			cfg:  "trigger-trust:\n  privileged:\n    - event: workflow_run\n      branches: [main, dev]\n  unprivileged:\n    - event: workflow_run\n      branches: [main]\n",
+			want: `event "workflow_run" is both privileged and unprivileged`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseConfig([]byte(tt.cfg), "sisakulint.yaml")
+			if err == nil {
+				t.Fatal("parseConfig() returned no error")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error %q does not contain %q", err.Error(), tt.want)
+			}
+		})
+	}
+}
+
+func TestCompilePathGlob(t *testing.T) {
+	tests := []struct {
+		glob  string
+		path  string
+		match bool
+	}{
+		{glob: ".github/workflows/deploy-*.yml", path: ".github/workflows/deploy-prod.yml", match: true},
+		{glob: ".github/workflows/deploy-*.yml", path: ".github/workflows/test.yml", match: false},
+		{glob: ".github/*.yml", path: ".github/workflows/deploy.yml", match: false},
+		{glob: ".github/**/*.yml", path: ".github/workflows/deploy.yml", match: true},
+		{glob: "**/release.yml", path: ".github/workflows/release.yml", match: true},
+		{glob: "release.y?l", path: ".github/workflows/release.yml", match: true},
+		{glob: "release.yml", path: ".github/workflows/prerelease.yml", match: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.glob+" "+tt.path, func(t *testing.T) {
+			re, err := compilePathGlob(tt.glob)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := re.MatchString(tt.path); got != tt.match {
+				t.Errorf("compilePathGlob(%q) match %q = %v, want %v", tt.glob, tt.path, got, tt.match)
+			}
+		})
+	}
+}
+
+func TestLinter_RulesConfig(t *testing.T) {
+	root := t.TempDir()
+	workflows := filepath.Join(root, ".github", "workflows")
+	if err := os.MkdirAll(workflows, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(root, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	config := `
+rules:
+  commit-sha: false
+  missing-timeout-minutes:
+    options:
+      max: 60
+overrides:
+  - paths: [".github/workflows/deploy.yml"]
+    rules:
+      missing-timeout-minutes:
+        options:
+          max: 30
+`
+	if err := os.WriteFile(filepath.Join(root, ".github", "sisakulint.yaml"), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	workflow := `on: push
+permissions: {}
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    timeout-minutes: 45
+    steps:
+      - uses: actions/checkout@v4
+        with:
+          persist-credentials: false
+        timeout-minutes: 5
+`
+	files := []string{filepath.Join(workflows, "deploy.yml"), filepath.Join(workflows, "test.yml")}
+	for _, f := range files {
+		if err := os.WriteFile(f, []byte(workflow), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	project, err := NewProject(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := NewLinter(io.Discard, &LinterOptions{CurrentWorkingDirectoryPath: root})
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := l.LintFiles(files, project)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, res := range results {
+		var timeoutErrs int
+		for _, e := range res.Errors {
+			switch e.Type {
+			case "commit-sha":
+				t.Errorf("disabled rule reported an error in %s: %v", res.FilePath, e)
+			case "missing-timeout-minutes":
+				timeoutErrs++
+				if !strings.Contains(e.Description, "out of the range [0, 30]") {
+					t.Errorf("unexpected error in %s: %v", res.FilePath, e)
+				}
+			}
+		}
+		want := 0
+		if filepath.Base(res.FilePath) == "deploy.yml" {
+			want = 1
+		}
+		if timeoutErrs != want {
+			t.Errorf("got %d missing-timeout-minutes errors in %s, want %d", timeoutErrs, res.FilePath, want)
+		}
+	}
+}
@@ -41,6 +41,10 @@ const (
 
 	// File action constants
 	FileFixDryRun = "dry-run"
+	// FileFixOutputInPlace は修正したワークフローのファイルを上書きする出力モード
+	FileFixOutputInPlace = "inplace"
+	// FileFixOutputPatch は修正をgit applyで適用できるパッチファイルに書き込む出力モード
+	FileFixOutputPatch = "patch"
 
 	// Parse SBOM tag constants
 	SBOMNullTag  = "!!null"
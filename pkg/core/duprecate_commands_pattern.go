@@ -4,6 +4,7 @@ package core
 import (
 	"fmt"
 	"regexp"
+	"strings"
 
 	"github.com/sisaku-security/sisakulint/pkg/ast"
 )
@@ -17,6 +18,10 @@ var deprecatedCommandsPattern = regexp.MustCompile(`(?:::(save-state|set-output|
 // * https://github.blog/changelog/2022-10-11-github-actions-deprecating-save-state-and-set-output-commands/
 type RuleDeprecatedCommands struct {
 	BaseRule
+	// workflowShellはworkflowのdefaults.run.shell
+	workflowShell string
+	// jobShellはジョブでrun:を実行するデフォルトのシェル
+	jobShell string
 }
 
 // DeprecatedCommandsRule()は新しいRuleDeprecatedCommandsインスタンスを作成します。
@@ -29,6 +34,35 @@ func DeprecatedCommandsRule() *RuleDeprecatedCommands {
 	}
 }
 
+// VisitWorkflowPreはWorkflowノードを訪れたときのcallback
+func (rule *RuleDeprecatedCommands) VisitWorkflowPre(n *ast.Workflow) error {
+	rule.workflowShell = ""
+	if n.Defaults != nil && n.Defaults.Run != nil && n.Defaults.Run.Shell != nil {
+		rule.workflowShell = n.Defaults.Run.Shell.Value
+	}
+	return nil
+}
+
+// VisitJobPreはJobノードを訪れたときのcallback
+// ジョブのdefaults.run.shell、workflowのdefaults.run.shell、ランナーのOSの順にデフォルトのシェルを決める
+func (rule *RuleDeprecatedCommands) VisitJobPre(n *ast.Job) error {
+	rule.jobShell = rule.workflowShell
+	if n.Defaults != nil && n.Defaults.Run != nil && n.Defaults.Run.Shell != nil {
+		rule.jobShell = n.Defaults.Run.Shell.Value
+	}
+	if rule.jobShell == "" {
+		rule.jobShell = "bash"
+		if n.RunsOn != nil {
+			for _, l := range n.RunsOn.Labels {
+				if l != nil && strings.HasPrefix(strings.ToLower(l.Value), "windows") {
+					rule.jobShell = "pwsh"
+				}
+			}
+		}
+	}
+	return nil
+}
+
 // VisitStepはStepノードを訪れたときのcallback
 func (rule *RuleDeprecatedCommands) VisitStep(step *ast.Step) error {
 	// ExecRunタイプの実行をチェックします。
@@ -61,6 +95,172 @@ func (rule *RuleDeprecatedCommands) VisitStep(step *ast.Step) error {
 				replacement,
 			)
 		}
+
+		shell := rule.jobShell
+		if shell == "" {
+			shell = "bash"
+		}
+		if execRun.Shell != nil {
+			shell = execRun.Shell.Value
+		}
+		if _, ok := rewriteDeprecatedCommands(execRun.Run.Value, shell); ok {
+			rule.AddAutoFixer(NewFuncFixer(rule.RuleNames(), func() error {
+				return fixDeprecatedCommands(execRun, shell)
+			}))
+		}
 	}
 	return nil
 }
+
+// fixDeprecatedCommandsはrun:のスクリプトの非推奨のコマンドを環境ファイルへの追記に書き換える
+func fixDeprecatedCommands(execRun *ast.ExecRun, shell string) error {
+	if execRun.Run.BaseNode == nil {
+		return nil
+	}
+	fixed, ok := rewriteDeprecatedCommands(execRun.Run.Value, shell)
+	if !ok {
+		return nil
+	}
+	execRun.Run.Value = fixed
+	execRun.Run.BaseNode.Value = fixed
+	return nil
+}
+
+var (
+	// deprecatedCommandLineは、1行全体がecho等で非推奨のコマンドを出力している行にマッチする
+	deprecatedCommandLine = regexp.MustCompile(`^(\s*)(echo|Write-Output|Write-Host)\s+(.+?)\s*$`)
+	// deprecatedCommandArgは、非推奨のコマンドの名前と値を取り出す
+	deprecatedCommandArg = regexp.MustCompile(`^::(set-output|save-state|set-env)\s+name=([a-zA-Z][a-zA-Z0-9_-]*)::(.*)$|^::(add-path)::(.*)$`)
+	// multiLineValueは、コマンドの出力やexpressionのように複数行になる可能性がある値にマッチする
+	multiLineValue = regexp.MustCompile("\\$\\(|`|\\$\\{\\{")
+)
+
+// deprecatedCommandFilesは非推奨のコマンドを置き換える環境ファイルの変数名
+var deprecatedCommandFiles = map[string]string{
+	"set-output": "GITHUB_OUTPUT",
+	"save-state": "GITHUB_STATE",
+	"set-env":    "GITHUB_ENV",
+	"add-path":   "GITHUB_PATH",
+}
+
+// shellKindはshell:の値からスクリプトの書き方を決める。bash、pwsh、powershell以外のシェルは空文字列を返す
+func shellKind(shell string) string {
+	name := shell
+	if i := strings.IndexAny(name, " \t"); i >= 0 {
+		name = name[:i]
+	}
+	switch strings.ToLower(name) {
+	case "bash", "sh":
+		return "bash"
+	case "pwsh":
+		return "pwsh"
+	case "powershell":
+		return "powershell"
+	default:
+		return ""
+	}
+}
+
+// rewriteDeprecatedCommandsはスクリプトの中の非推奨のコマンドを書き換える
+// 1行全体がecho等でコマンドを出力している行だけを書き換え、書き換えた行があればtrueを返す
+func rewriteDeprecatedCommands(script, shell string) (string, bool) {
+	kind := shellKind(shell)
+	if kind == "" {
+		return script, false
+	}
+	lines := strings.Split(script, "\n")
+	changed := false
+	for i, line := range lines {
+		m := deprecatedCommandLine.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		indent, arg := m[1], m[3]
+		quote := ""
+		if c := arg[0]; c == '"' || c == '\'' {
+			if len(arg) < 2 || arg[len(arg)-1] != c {
+				continue
+			}
+			quote, arg = string(c), arg[1:len(arg)-1]
+		}
+		a := deprecatedCommandArg.FindStringSubmatch(arg)
+		if a == nil {
+			continue
+		}
+		if quote == "" && strings.ContainsAny(arg, "\"'") {
+			continue
+		}
+		command, name, value := a[1], a[2], a[3]
+		if command == "" {
+			command, value = a[4], a[5]
+		}
+		file := deprecatedCommandFiles[command]
+		var rewritten []string
+		switch {
+		case command == "add-path":
+			rewritten = []string{appendToEnvFile(kind, quoteValue(kind, quote, value), file)}
+		case multiLineValue.MatchString(value):
+			rewritten = heredocToEnvFile(kind, name, quoteValue(kind, quote, value), file)
+		default:
+			rewritten = []string{appendToEnvFile(kind, quoteValue(kind, quote, name+"="+value), file)}
+		}
+		for j := range rewritten {
+			rewritten[j] = indent + rewritten[j]
+		}
+		lines[i] = strings.Join(rewritten, "\n")
+		changed = true
+	}
+	return strings.Join(lines, "\n"), changed
+}
+
+// quoteValueは元のechoの引用符で値を囲む。PowerShellでは引用符のない値をダブルクォートで囲む
+func quoteValue(kind, quote, value string) string {
+	if quote == "" && kind != "bash" {
+		quote = `"`
+	}
+	return quote + value + quote
+}
+
+// appendToEnvFileは1行の値を環境ファイルに追記するコマンドを返す
+func appendToEnvFile(kind, value, file string) string {
+	switch kind {
+	case "pwsh":
+		return value + " >> $env:" + file
+	case "powershell":
+		return value + " | " + outFileUTF8(file)
+	}
+	return "echo " + value + ` >> "$` + file + `"`
+}
+
+// heredocToEnvFileは複数行になる可能性がある値をヒアドキュメントの区切り文字の構文で環境ファイルに追記するコマンドを返す
+// 値に区切り文字が含まれないように、区切り文字は実行時にランダムに生成する
+// * https://docs.github.com/en/actions/using-workflows/workflow-commands-for-github-actions#multiline-strings
+func heredocToEnvFile(kind, name, value, file string) []string {
+	switch kind {
+	case "pwsh":
+		return []string{
+			"$delimiter = [guid]::NewGuid().ToString()",
+			`@("` + name + `<<$delimiter", ` + value + `, $delimiter) >> $env:` + file,
+		}
+	case "powershell":
+		return []string{
+			"$delimiter = [guid]::NewGuid().ToString()",
+			`@("` + name + `<<$delimiter", ` + value + `, $delimiter) | ` + outFileUTF8(file),
+		}
+	}
+	return []string{
+		`delimiter="$(dd if=/dev/urandom bs=15 count=1 status=none | base64)"`,
+		"{",
+		`  echo "` + name + `<<$delimiter"`,
+		"  echo " + value,
+		`  echo "$delimiter"`,
+		`} >> "$` + file + `"`,
+	}
+}
+
+// outFileUTF8は環境ファイルにUTF-8で追記するOut-Fileのコマンドを返す
+// Windows PowerShell 5.1の>>はUTF-16LEで書き込み、ランナーが環境ファイルを読めなくなるため、エンコーディングを明示する
+// * https://docs.github.com/en/actions/using-workflows/workflow-commands-for-github-actions#setting-an-output-parameter
+func outFileUTF8(file string) string {
+	return "Out-File -FilePath $env:" + file + " -Encoding utf8 -Append"
+}
@@ -89,3 +89,193 @@ func TestRuleDeprecatedCommands_VisitStep(t *testing.T) {
 		})
 	}
 }
+
+func TestRuleDeprecatedCommands_AutoFix(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name: "bash",
+			input: `on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - run: |
+          echo "::set-output name=version::1.2.3"
+          echo '::save-state name=pid::$PID'
+          echo ::set-env name=MODE::release
+          echo "::add-path::$HOME/bin"
+          echo "::set-output name=files::$(git ls-files)"
+`,
+			want: `on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - run: |
+          echo "version=1.2.3" >> "$GITHUB_OUTPUT"
+          echo 'pid=$PID' >> "$GITHUB_STATE"
+          echo MODE=release >> "$GITHUB_ENV"
+          echo "$HOME/bin" >> "$GITHUB_PATH"
+          delimiter="$(dd if=/dev/urandom bs=15 count=1 status=none | base64)"
+          {
+            echo "files<<$delimiter"
+            echo "$(git ls-files)"
+            echo "$delimiter"
+          } >> "$GITHUB_OUTPUT"
+`,
+		},
+		{
+			name: "pwsh from step shell",
+			input: `on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - shell: pwsh
+        run: |
+          Write-Output "::set-output name=version::$version"
+          echo "::set-output name=log::$(Get-Content log.txt)"
+`,
+			want: `on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - shell: pwsh
+        run: |
+          "version=$version" >> $env:GITHUB_OUTPUT
+          $delimiter = [guid]::NewGuid().ToString()
+          @("log<<$delimiter", "$(Get-Content log.txt)", $delimiter) >> $env:GITHUB_OUTPUT
+`,
+		},
+		{
+			name: "windows powershell writes utf-8 with Out-File",
+			input: `on: push
+jobs:
+  build:
+    runs-on: windows-latest
+    steps:
+      - shell: powershell
+        run: |
+          echo "::set-output name=version::$version"
+          echo ::add-path::C:\\tools
+          Write-Output "::set-output name=log::$(Get-Content log.txt)"
+`,
+			want: `on: push
+jobs:
+  build:
+    runs-on: windows-latest
+    steps:
+      - shell: powershell
+        run: |
+          "version=$version" | Out-File -FilePath $env:GITHUB_OUTPUT -Encoding utf8 -Append
+          "C:\\tools" | Out-File -FilePath $env:GITHUB_PATH -Encoding utf8 -Append
+          $delimiter = [guid]::NewGuid().ToString()
+          @("log<<$delimiter", "$(Get-Content log.txt)", $delimiter) | Out-File -FilePath $env:GITHUB_OUTPUT -Encoding utf8 -Append
+`,
+		},
+		{
+			name: "pwsh from defaults",
+			input: `on: push
+defaults:
+  run:
+    shell: pwsh
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo "::set-env name=MODE::${{ inputs.mode }}"
+`,
+			want: `on: push
+defaults:
+  run:
+    shell: pwsh
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - run: |-
+          $delimiter = [guid]::NewGuid().ToString()
+          @("MODE<<$delimiter", "${{ inputs.mode }}", $delimiter) >> $env:GITHUB_ENV
+`,
+		},
+		{
+			name: "job defaults override workflow defaults",
+			input: `on: push
+defaults:
+  run:
+    shell: pwsh
+jobs:
+  build:
+    runs-on: windows-latest
+    defaults:
+      run:
+        shell: bash
+    steps:
+      - run: echo "::add-path::/opt/bin"
+`,
+			want: `on: push
+defaults:
+  run:
+    shell: pwsh
+jobs:
+  build:
+    runs-on: windows-latest
+    defaults:
+      run:
+        shell: bash
+    steps:
+      - run: echo "/opt/bin" >> "$GITHUB_PATH"
+`,
+		},
+		{
+			name: "unsupported shell and command in the middle of line are not fixed",
+			input: `on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - shell: cmd
+        run: echo ::set-output name=a::b
+      - run: test -n "$X" && echo "::set-output name=a::b"
+`,
+			want: `on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - shell: cmd
+        run: echo ::set-output name=a::b
+      - run: test -n "$X" && echo "::set-output name=a::b"
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, errs := Parse([]byte(tt.input))
+			if len(errs) > 0 {
+				t.Fatal(errs)
+			}
+			rule := DeprecatedCommandsRule()
+			v := NewSyntaxTreeVisitor()
+			v.AddVisitor(rule)
+			if err := v.VisitTree(w); err != nil {
+				t.Fatal(err)
+			}
+			if len(rule.Errors()) == 0 {
+				t.Fatal("deprecated commands are not detected")
+			}
+			res := ApplyAutoFixers([]byte(tt.input), w, rule.AutoFixers(), nil)
+			if len(res.Failed) > 0 {
+				t.Fatal(res.Failed[0].Err)
+			}
+			if got := string(res.Source); got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,7 +2,6 @@ package core
 
 import (
 	"fmt"
-	"regexp"
 	"strings"
 
 	"github.com/sisaku-security/sisakulint/pkg/ast"
@@ -19,6 +18,8 @@ type EnvPathInjectionRule struct {
 	checkPrivileged    bool   // true = check privileged triggers, false = check normal triggers
 	stepsWithUntrusted []*stepWithEnvPathInjection
 	workflow           *ast.Workflow
+	untrusted          *taintScope
+	taint              *workflowTaint
 }
 
 // stepWithEnvPathInjection tracks steps that need auto-fixing for PATH injection
@@ -31,22 +32,8 @@ type stepWithEnvPathInjection struct {
 type envPathUntrustedExprInfo struct {
 	expr  parsedExpression
 	paths []string
-	line  string // The line containing the GITHUB_PATH redirect
 }
 
-// Pattern to detect writes to $GITHUB_PATH
-// Matches various formats of GITHUB_PATH redirects:
-//
-//	>> $GITHUB_PATH          (standard format)
-//	>> "$GITHUB_PATH"        (double quoted)
-//	>> '$GITHUB_PATH'        (single quoted)
-//	>> ${GITHUB_PATH}        (with braces)
-//	>>$GITHUB_PATH           (no space after >>)
-//	>> "${GITHUB_PATH}"      (braces with quotes)
-//
-// This helps catch all common patterns of PATH writes
-var githubPathPattern = regexp.MustCompile(`>>\s*["']?\$\{?GITHUB_PATH\}?["']?`)
-
 // newEnvPathInjectionRule creates a new PATH injection rule with the specified severity level
 func newEnvPathInjectionRule(severityLevel string, checkPrivileged bool) *EnvPathInjectionRule {
 	var desc string
@@ -71,6 +58,7 @@ func newEnvPathInjectionRule(severityLevel string, checkPrivileged bool) *EnvPat
 // VisitWorkflowPre is called before visiting a workflow
 func (rule *EnvPathInjectionRule) VisitWorkflowPre(node *ast.Workflow) error {
 	rule.workflow = node
+	rule.taint = nil
 	return nil
 }
 
@@ -83,7 +71,16 @@ func (rule *EnvPathInjectionRule) VisitJobPre(node *ast.Job) error {
 		return nil
 	}
 
-	for _, s := range node.Steps {
+	// Untrusted inputs are the builtin catalog, the sources in the configuration and the values the
+	// workflow derives from them through env:, matrix values, step outputs, job outputs and $GITHUB_ENV.
+	// They are tracked per step since a step only sees the outputs and environment of earlier steps
+	if rule.taint == nil {
+		rule.taint = newWorkflowTaint(rule.workflow, rule.userConfig, rule.TriggerTrust().callerInputs())
+	}
+	taint := rule.taint.job(node)
+
+	for i, s := range node.Steps {
+		rule.untrusted = taint.steps[i]
 		if s.Exec == nil || s.Exec.Kind() != ast.ExecKindRun {
 			continue
 		}
@@ -93,9 +90,8 @@ func (rule *EnvPathInjectionRule) VisitJobPre(node *ast.Job) error {
 			continue
 		}
 
-		// Check if the run script writes to $GITHUB_PATH
-		script := run.Run.Value
-		if !githubPathPattern.MatchString(script) {
+		// Skip scripts which never mention $GITHUB_PATH before parsing them
+		if !strings.Contains(run.Run.Value, "GITHUB_PATH") {
 			continue
 		}
 
@@ -105,59 +101,48 @@ func (rule *EnvPathInjectionRule) VisitJobPre(node *ast.Job) error {
 			continue
 		}
 
+		// Parse the script as a shell script to know which expressions are actually written to $GITHUB_PATH.
+		// Expressions in comments, in commands writing to other files, and in strings which merely
+		// contain ">> $GITHUB_PATH" are not written to it
+		script := parseShellScript(run.Run.Value)
+
 		var stepUntrusted *stepWithEnvPathInjection
 
-		// Split script into lines to find which lines write to GITHUB_PATH
-		lines := strings.Split(script, "\n")
-		for lineIdx, line := range lines {
-			// Check if this line writes to GITHUB_PATH
-			if !githubPathPattern.MatchString(line) {
+		for _, expr := range exprs {
+			e := script.exprAt(expr.offset)
+			if !script.writesToVarFile(e, "GITHUB_PATH") {
 				continue
 			}
 
-			// Check if this line contains any untrusted expressions
-			for _, expr := range exprs {
-				// Check if the expression is in this line
-				if !strings.Contains(line, fmt.Sprintf("${{ %s }}", expr.raw)) &&
-					!strings.Contains(line, fmt.Sprintf("${{%s}}", expr.raw)) {
-					continue
-				}
-
-				untrustedPaths := rule.checkUntrustedInput(expr)
-				if len(untrustedPaths) > 0 && !rule.isDefinedInEnv(expr, s.Env) {
-					if stepUntrusted == nil {
-						stepUntrusted = &stepWithEnvPathInjection{step: s}
-					}
-
-					stepUntrusted.untrustedExprs = append(stepUntrusted.untrustedExprs, envPathUntrustedExprInfo{
-						expr:  expr,
-						paths: untrustedPaths,
-						line:  line,
-					})
-
-					// Calculate the actual line position
-					linePos := &ast.Position{
-						Line: run.Run.Pos.Line + lineIdx,
-						Col:  run.Run.Pos.Col,
-					}
-					if run.Run.Literal {
-						linePos.Line++
-					}
+			untrustedPaths := rule.checkUntrustedInput(expr)
+			if len(untrustedPaths) == 0 || rule.isDefinedInEnv(expr, s.Env) {
+				continue
+			}
 
-					if rule.checkPrivileged {
-						rule.Errorf(
-							linePos,
-							"PATH injection (critical): \"%s\" is potentially untrusted and written to $GITHUB_PATH in a workflow with privileged triggers. This can allow attackers to hijack command execution by prepending a malicious directory to PATH. Validate the path or use absolute paths instead. See https://codeql.github.com/codeql-query-help/actions/actions-envpath-injection-critical/",
-							strings.Join(untrustedPaths, "\", \""),
-						)
-					} else {
-						rule.Errorf(
-							linePos,
-							"PATH injection (medium): \"%s\" is potentially untrusted and written to $GITHUB_PATH. This can allow attackers to hijack command execution by prepending a malicious directory to PATH. Validate the path or use absolute paths instead. See https://codeql.github.com/codeql-query-help/actions/actions-envpath-injection-medium/",
-							strings.Join(untrustedPaths, "\", \""),
-						)
-					}
+			// The validated form is not expanded in single quotes or in a heredoc with quoted delimiter,
+			// so expressions there are reported but not fixed automatically
+			if e.quote.expandsVariables() {
+				if stepUntrusted == nil {
+					stepUntrusted = &stepWithEnvPathInjection{step: s}
 				}
+				stepUntrusted.untrustedExprs = append(stepUntrusted.untrustedExprs, envPathUntrustedExprInfo{
+					expr:  expr,
+					paths: untrustedPaths,
+				})
+			}
+
+			if rule.checkPrivileged {
+				rule.Errorf(
+					expr.pos,
+					"PATH injection (critical): %s is potentially untrusted and written to $GITHUB_PATH in a workflow with privileged triggers. This can allow attackers to hijack command execution by prepending a malicious directory to PATH. Validate the path or use absolute paths instead. See https://codeql.github.com/codeql-query-help/actions/actions-envpath-injection-critical/",
+					describeUntrustedInputs(rule.untrusted, untrustedPaths, rule.workflow),
+				)
+			} else {
+				rule.Errorf(
+					expr.pos,
+					"PATH injection (medium): %s is potentially untrusted and written to $GITHUB_PATH. This can allow attackers to hijack command execution by prepending a malicious directory to PATH. Validate the path or use absolute paths instead. See https://codeql.github.com/codeql-query-help/actions/actions-envpath-injection-medium/",
+					describeUntrustedInputs(rule.untrusted, untrustedPaths, rule.workflow),
+				)
 			}
 		}
 
@@ -169,30 +154,9 @@ func (rule *EnvPathInjectionRule) VisitJobPre(node *ast.Job) error {
 	return nil
 }
 
-// hasPrivilegedTriggers checks if the workflow has privileged triggers
+// hasPrivilegedTriggers checks if the workflow has privileged triggers according to the trigger trust model
 func (rule *EnvPathInjectionRule) hasPrivilegedTriggers() bool {
-	if rule.workflow == nil || rule.workflow.On == nil {
-		return false
-	}
-
-	// Check for privileged triggers
-	// These triggers have write access or run with secrets
-	privilegedTriggers := map[string]bool{
-		"pull_request_target": true,
-		"workflow_run":        true,
-		"issue_comment":       true,
-		"issues":              true,
-		"discussion_comment":  true,
-	}
-
-	for _, event := range rule.workflow.On {
-		eventName := strings.ToLower(event.EventName())
-		if privilegedTriggers[eventName] {
-			return true
-		}
-	}
-
-	return false
+	return rule.TriggerTrust().IsPrivileged(rule.workflow)
 }
 
 // RuleNames implements StepFixer interface
@@ -269,56 +233,32 @@ func (rule *EnvPathInjectionRule) FixStep(step *ast.Step) error {
 		}
 	}
 
-	// Build replacement map for the run script
-	// For each untrusted expression in GITHUB_PATH writes, replace with validated version
-	replacements := make(map[string]string)
-
-	for _, untrustedInfo := range stepInfo.untrustedExprs {
-		envVarName := envVarMap[untrustedInfo.expr.raw]
-
-		// Replace ${{ expr }} with validated path using realpath
-		// realpath resolves the path and ensures it's absolute and canonical
-		oldPattern := fmt.Sprintf("${{ %s }}", untrustedInfo.expr.raw)
-		newPattern := fmt.Sprintf("$(realpath \"$%s\")", envVarName)
-		replacements[oldPattern] = newPattern
-
-		// Also handle no-space variant
-		oldPatternNoSpace := fmt.Sprintf("${{%s}}", untrustedInfo.expr.raw)
-		replacements[oldPatternNoSpace] = newPattern
+	// Replace untrusted values written to $GITHUB_PATH with validated version
+	// Two scenarios:
+	// 1. The expression is still in the run script: ${{ expr }}
+	// 2. The expression was already replaced by code-injection fixer: $ENV_VAR
+	// Both are replaced with: $(realpath "$ENV_VAR")
+	// realpath resolves the path and ensures it's absolute and canonical
+	// Only the occurrences which are written to $GITHUB_PATH and where the shell expands variables are replaced
+	envVarNames := make(map[string]bool, len(envVarMap))
+	for _, name := range envVarMap {
+		envVarNames[name] = true
 	}
 
-	// Apply replacements to the run script
-	newScript := run.Run.Value
-	for old, new := range replacements {
-		newScript = strings.ReplaceAll(newScript, old, new)
+	script := parseShellScript(run.Run.Value)
+	var edits []shellEdit
+	for _, e := range script.exprs {
+		if envVarName, ok := envVarMap[e.name]; ok && e.quote.expandsVariables() && script.writesToVarFile(e, "GITHUB_PATH") {
+			edits = append(edits, shellEdit{e.start, e.end, fmt.Sprintf("$(realpath \"$%s\")", envVarName)})
+		}
 	}
-
-	// Additional pass: validate env var references in GITHUB_PATH lines
-	// Split into lines and process each line that writes to GITHUB_PATH
-	lines := strings.Split(newScript, "\n")
-	for i, line := range lines {
-		if githubPathPattern.MatchString(line) {
-			// This line writes to GITHUB_PATH
-			// Replace any $ENV_VAR references (that aren't already wrapped) with validated version
-			for _, untrustedInfo := range stepInfo.untrustedExprs {
-				envVarName := envVarMap[untrustedInfo.expr.raw]
-
-				// Pattern to match $ENV_VAR but not $(realpath "$ENV_VAR")
-				// and not "$GITHUB_PATH"
-				plainVarPattern := fmt.Sprintf("$%s", envVarName)
-
-				// Only replace if it's not already wrapped in validation
-				if strings.Contains(line, plainVarPattern) &&
-					!strings.Contains(line, fmt.Sprintf("$(realpath \"$%s\")", envVarName)) {
-					// Replace $ENV_VAR with $(realpath "$ENV_VAR")
-					validatedVar := fmt.Sprintf("$(realpath \"$%s\")", envVarName)
-					line = strings.ReplaceAll(line, plainVarPattern, validatedVar)
-				}
-			}
-			lines[i] = line
+	for _, v := range script.vars {
+		// $ENV_VAR in the command substitution of $(realpath "$ENV_VAR") is already validated
+		if envVarNames[v.name] && !v.subst && v.quote.expandsVariables() && script.writesToVarFile(v, "GITHUB_PATH") {
+			edits = append(edits, shellEdit{v.start, v.end, fmt.Sprintf("$(realpath \"$%s\")", v.name)})
 		}
 	}
-	newScript = strings.Join(lines, "\n")
+	newScript := applyShellEdits(run.Run.Value, edits)
 
 	// Update AST
 	run.Run.Value = newScript
@@ -425,9 +365,10 @@ func (rule *EnvPathInjectionRule) extractAndParseExpressions(str *ast.String) []
 			}
 
 			result = append(result, parsedExpression{
-				raw:  exprContent,
-				node: expr,
-				pos:  pos,
+				raw:    exprContent,
+				node:   expr,
+				pos:    pos,
+				offset: start,
 			})
 		}
 
@@ -449,24 +390,7 @@ func (rule *EnvPathInjectionRule) parseExpression(exprStr string) (expressions.E
 
 // checkUntrustedInput checks if the expression contains untrusted input
 func (rule *EnvPathInjectionRule) checkUntrustedInput(expr parsedExpression) []string {
-	checker := expressions.NewExprSemanticsChecker(true, nil)
-	_, errs := checker.Check(expr.node)
-
-	var paths []string
-	for _, err := range errs {
-		msg := err.Message
-		if strings.Contains(msg, "potentially untrusted") {
-			if idx := strings.Index(msg, "\""); idx != -1 {
-				endIdx := strings.Index(msg[idx+1:], "\"")
-				if endIdx != -1 {
-					path := msg[idx+1 : idx+1+endIdx]
-					paths = append(paths, path)
-				}
-			}
-		}
-	}
-
-	return paths
+	return untrustedPathsInExpr(expr.node, rule.untrusted.roots)
 }
 
 // isDefinedInEnv checks if the expression is defined in the step's env section
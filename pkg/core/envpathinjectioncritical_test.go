@@ -60,6 +60,22 @@ echo "${{ github.event.pull_request.body }}" >> "$GITHUB_PATH"`,
 			wantErrors:  2,
 			description: "Should detect both PATH injections",
 		},
+		{
+			name:        "while loop redirected to GITHUB_PATH",
+			trigger:     "pull_request_target",
+			runScript:   `while read -r dir; do echo "${{ github.event.pull_request.body }}/$dir"; done < dirs.txt >> "$GITHUB_PATH"`,
+			wantErrors:  1,
+			description: "Should detect PATH injection in a loop redirected after done",
+		},
+		{
+			name:    "if block redirected to GITHUB_PATH",
+			trigger: "pull_request_target",
+			runScript: `if true; then
+  echo "${{ github.head_ref }}"
+fi >> "$GITHUB_PATH"`,
+			wantErrors:  1,
+			description: "Should detect PATH injection in an if block redirected after fi",
+		},
 		{
 			name:        "safe with trusted input",
 			trigger:     "pull_request_target",
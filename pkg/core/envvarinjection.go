@@ -2,7 +2,6 @@ package core
 
 import (
 	"fmt"
-	"regexp"
 	"strings"
 
 	"github.com/sisaku-security/sisakulint/pkg/ast"
@@ -19,6 +18,8 @@ type EnvVarInjectionRule struct {
 	checkPrivileged    bool   // true = check privileged triggers, false = check normal triggers
 	stepsWithUntrusted []*stepWithEnvVarInjection
 	workflow           *ast.Workflow
+	untrusted          *taintScope
+	taint              *workflowTaint
 }
 
 // stepWithEnvVarInjection tracks steps that need auto-fixing for environment variable injection
@@ -31,22 +32,8 @@ type stepWithEnvVarInjection struct {
 type envVarUntrustedExprInfo struct {
 	expr  parsedExpression
 	paths []string
-	line  string // The line containing the GITHUB_ENV redirect
 }
 
-// Pattern to detect writes to $GITHUB_ENV
-// Matches various formats of GITHUB_ENV redirects:
-//
-//	>> $GITHUB_ENV          (standard format)
-//	>> "$GITHUB_ENV"        (double quoted)
-//	>> '$GITHUB_ENV'        (single quoted)
-//	>> ${GITHUB_ENV}        (with braces)
-//	>>$GITHUB_ENV           (no space after >>)
-//	>> "${GITHUB_ENV}"      (braces with quotes)
-//
-// This helps catch all common patterns of environment variable writes
-var githubEnvPattern = regexp.MustCompile(`>>\s*["']?\$\{?GITHUB_ENV\}?["']?`)
-
 // newEnvVarInjectionRule creates a new environment variable injection rule with the specified severity level
 func newEnvVarInjectionRule(severityLevel string, checkPrivileged bool) *EnvVarInjectionRule {
 	var desc string
@@ -71,6 +58,7 @@ func newEnvVarInjectionRule(severityLevel string, checkPrivileged bool) *EnvVarI
 // VisitWorkflowPre is called before visiting a workflow
 func (rule *EnvVarInjectionRule) VisitWorkflowPre(node *ast.Workflow) error {
 	rule.workflow = node
+	rule.taint = nil
 	return nil
 }
 
@@ -83,7 +71,16 @@ func (rule *EnvVarInjectionRule) VisitJobPre(node *ast.Job) error {
 		return nil
 	}
 
-	for _, s := range node.Steps {
+	// Untrusted inputs are the builtin catalog, the sources in the configuration and the values the
+	// workflow derives from them through env:, matrix values, step outputs, job outputs and $GITHUB_ENV.
+	// They are tracked per step since a step only sees the outputs and environment of earlier steps
+	if rule.taint == nil {
+		rule.taint = newWorkflowTaint(rule.workflow, rule.userConfig, rule.TriggerTrust().callerInputs())
+	}
+	taint := rule.taint.job(node)
+
+	for i, s := range node.Steps {
+		rule.untrusted = taint.steps[i]
 		if s.Exec == nil || s.Exec.Kind() != ast.ExecKindRun {
 			continue
 		}
@@ -93,9 +90,8 @@ func (rule *EnvVarInjectionRule) VisitJobPre(node *ast.Job) error {
 			continue
 		}
 
-		// Check if the run script writes to $GITHUB_ENV
-		script := run.Run.Value
-		if !githubEnvPattern.MatchString(script) {
+		// Skip scripts which never mention $GITHUB_ENV before parsing them
+		if !strings.Contains(run.Run.Value, "GITHUB_ENV") {
 			continue
 		}
 
@@ -105,59 +101,48 @@ func (rule *EnvVarInjectionRule) VisitJobPre(node *ast.Job) error {
 			continue
 		}
 
+		// Parse the script as a shell script to know which expressions are actually written to $GITHUB_ENV.
+		// Expressions in comments, in commands writing to other files, and in strings which merely
+		// contain ">> $GITHUB_ENV" are not written to it
+		script := parseShellScript(run.Run.Value)
+
 		var stepUntrusted *stepWithEnvVarInjection
 
-		// Split script into lines to find which lines write to GITHUB_ENV
-		lines := strings.Split(script, "\n")
-		for lineIdx, line := range lines {
-			// Check if this line writes to GITHUB_ENV
-			if !githubEnvPattern.MatchString(line) {
+		for _, expr := range exprs {
+			e := script.exprAt(expr.offset)
+			if !script.writesToVarFile(e, "GITHUB_ENV") {
 				continue
 			}
 
-			// Check if this line contains any untrusted expressions
-			for _, expr := range exprs {
-				// Check if the expression is in this line
-				if !strings.Contains(line, fmt.Sprintf("${{ %s }}", expr.raw)) &&
-					!strings.Contains(line, fmt.Sprintf("${{%s}}", expr.raw)) {
-					continue
-				}
-
-				untrustedPaths := rule.checkUntrustedInput(expr)
-				if len(untrustedPaths) > 0 && !rule.isDefinedInEnv(expr, s.Env) {
-					if stepUntrusted == nil {
-						stepUntrusted = &stepWithEnvVarInjection{step: s}
-					}
-
-					stepUntrusted.untrustedExprs = append(stepUntrusted.untrustedExprs, envVarUntrustedExprInfo{
-						expr:  expr,
-						paths: untrustedPaths,
-						line:  line,
-					})
-
-					// Calculate the actual line position
-					linePos := &ast.Position{
-						Line: run.Run.Pos.Line + lineIdx,
-						Col:  run.Run.Pos.Col,
-					}
-					if run.Run.Literal {
-						linePos.Line += 1
-					}
+			untrustedPaths := rule.checkUntrustedInput(expr)
+			if len(untrustedPaths) == 0 || rule.isDefinedInEnv(expr, s.Env) {
+				continue
+			}
 
-					if rule.checkPrivileged {
-						rule.Errorf(
-							linePos,
-							"environment variable injection (critical): \"%s\" is potentially untrusted and written to $GITHUB_ENV in a workflow with privileged triggers. This can allow attackers to inject additional environment variables. Use heredoc syntax with unique delimiters or sanitize the input with 'tr -d '\\n''. See https://codeql.github.com/codeql-query-help/actions/actions-envvar-injection-critical/",
-							strings.Join(untrustedPaths, "\", \""),
-						)
-					} else {
-						rule.Errorf(
-							linePos,
-							"environment variable injection (medium): \"%s\" is potentially untrusted and written to $GITHUB_ENV. This can allow attackers to inject additional environment variables. Use heredoc syntax with unique delimiters or sanitize the input with 'tr -d '\\n''. See https://codeql.github.com/codeql-query-help/actions/actions-envvar-injection-medium/",
-							strings.Join(untrustedPaths, "\", \""),
-						)
-					}
+			// The sanitized form is not expanded in single quotes or in a heredoc with quoted delimiter,
+			// so expressions there are reported but not fixed automatically
+			if e.quote.expandsVariables() {
+				if stepUntrusted == nil {
+					stepUntrusted = &stepWithEnvVarInjection{step: s}
 				}
+				stepUntrusted.untrustedExprs = append(stepUntrusted.untrustedExprs, envVarUntrustedExprInfo{
+					expr:  expr,
+					paths: untrustedPaths,
+				})
+			}
+
+			if rule.checkPrivileged {
+				rule.Errorf(
+					expr.pos,
+					"environment variable injection (critical): %s is potentially untrusted and written to $GITHUB_ENV in a workflow with privileged triggers. This can allow attackers to inject additional environment variables. Use heredoc syntax with unique delimiters or sanitize the input with 'tr -d '\\n''. See https://codeql.github.com/codeql-query-help/actions/actions-envvar-injection-critical/",
+					describeUntrustedInputs(rule.untrusted, untrustedPaths, rule.workflow),
+				)
+			} else {
+				rule.Errorf(
+					expr.pos,
+					"environment variable injection (medium): %s is potentially untrusted and written to $GITHUB_ENV. This can allow attackers to inject additional environment variables. Use heredoc syntax with unique delimiters or sanitize the input with 'tr -d '\\n''. See https://codeql.github.com/codeql-query-help/actions/actions-envvar-injection-medium/",
+					describeUntrustedInputs(rule.untrusted, untrustedPaths, rule.workflow),
+				)
 			}
 		}
 
@@ -169,30 +154,9 @@ func (rule *EnvVarInjectionRule) VisitJobPre(node *ast.Job) error {
 	return nil
 }
 
-// hasPrivilegedTriggers checks if the workflow has privileged triggers
+// hasPrivilegedTriggers checks if the workflow has privileged triggers according to the trigger trust model
 func (rule *EnvVarInjectionRule) hasPrivilegedTriggers() bool {
-	if rule.workflow == nil || rule.workflow.On == nil {
-		return false
-	}
-
-	// Check for privileged triggers
-	// These triggers have write access or run with secrets
-	privilegedTriggers := map[string]bool{
-		"pull_request_target": true,
-		"workflow_run":        true,
-		"issue_comment":       true,
-		"issues":              true,
-		"discussion_comment":  true,
-	}
-
-	for _, event := range rule.workflow.On {
-		eventName := strings.ToLower(event.EventName())
-		if privilegedTriggers[eventName] {
-			return true
-		}
-	}
-
-	return false
+	return rule.TriggerTrust().IsPrivileged(rule.workflow)
 }
 
 // RuleNames implements StepFixer interface
@@ -269,66 +233,31 @@ func (rule *EnvVarInjectionRule) FixStep(step *ast.Step) error {
 		}
 	}
 
-	// Build replacement map for the run script
-	// For each untrusted expression in GITHUB_ENV writes, replace with sanitized version
-	replacements := make(map[string]string)
-
-	for _, untrustedInfo := range stepInfo.untrustedExprs {
-		envVarName := envVarMap[untrustedInfo.expr.raw]
-
-		// Two scenarios:
-		// 1. The expression is still in the run script: ${{ expr }}
-		//    Replace with: $(echo "$ENV_VAR" | tr -d '\n')
-		// 2. The expression was already replaced by code-injection fixer: $ENV_VAR
-		//    Replace with: $(echo "$ENV_VAR" | tr -d '\n')
-
-		// Handle case 1: Replace ${{ expr }} with sanitized env var reference
-		oldPattern := fmt.Sprintf("${{ %s }}", untrustedInfo.expr.raw)
-		newPattern := fmt.Sprintf("$(echo \"$%s\" | tr -d '\\n')", envVarName)
-		replacements[oldPattern] = newPattern
-
-		// Also handle no-space variant
-		oldPatternNoSpace := fmt.Sprintf("${{%s}}", untrustedInfo.expr.raw)
-		replacements[oldPatternNoSpace] = newPattern
-
-		// Handle case 2: If code-injection fixer already replaced it with $ENV_VAR
-		// We need to wrap it with sanitization
-		// Look for patterns like: echo "VAR=$ENV_VAR" >> $GITHUB_ENV
-		// This is tricky because we need to only replace in GITHUB_ENV write lines
+	// Replace untrusted values written to $GITHUB_ENV with sanitized version
+	// Two scenarios:
+	// 1. The expression is still in the run script: ${{ expr }}
+	// 2. The expression was already replaced by code-injection fixer: $ENV_VAR
+	// Both are replaced with: $(echo "$ENV_VAR" | tr -d '\n')
+	// Only the occurrences which are written to $GITHUB_ENV and where the shell expands variables are replaced
+	envVarNames := make(map[string]bool, len(envVarMap))
+	for _, name := range envVarMap {
+		envVarNames[name] = true
 	}
 
-	// Apply replacements to the run script
-	newScript := run.Run.Value
-	for old, new := range replacements {
-		newScript = strings.ReplaceAll(newScript, old, new)
+	script := parseShellScript(run.Run.Value)
+	var edits []shellEdit
+	for _, e := range script.exprs {
+		if envVarName, ok := envVarMap[e.name]; ok && e.quote.expandsVariables() && script.writesToVarFile(e, "GITHUB_ENV") {
+			edits = append(edits, shellEdit{e.start, e.end, fmt.Sprintf("$(echo \"$%s\" | tr -d '\\n')", envVarName)})
+		}
 	}
-
-	// Additional pass: sanitize env var references in GITHUB_ENV lines
-	// Split into lines and process each line that writes to GITHUB_ENV
-	lines := strings.Split(newScript, "\n")
-	for i, line := range lines {
-		if githubEnvPattern.MatchString(line) {
-			// This line writes to GITHUB_ENV
-			// Replace any $ENV_VAR references (that aren't already wrapped) with sanitized version
-			for _, untrustedInfo := range stepInfo.untrustedExprs {
-				envVarName := envVarMap[untrustedInfo.expr.raw]
-
-				// Pattern to match $ENV_VAR but not $(echo "$ENV_VAR" | tr -d '\n')
-				// and not "$GITHUB_ENV"
-				plainVarPattern := fmt.Sprintf("$%s", envVarName)
-
-				// Only replace if it's not already wrapped in sanitization
-				if strings.Contains(line, plainVarPattern) &&
-					!strings.Contains(line, fmt.Sprintf("$(echo \"$%s\"", envVarName)) {
-					// Replace $ENV_VAR with $(echo "$ENV_VAR" | tr -d '\n')
-					sanitizedVar := fmt.Sprintf("$(echo \"$%s\" | tr -d '\\n')", envVarName)
-					line = strings.ReplaceAll(line, plainVarPattern, sanitizedVar)
-				}
-			}
-			lines[i] = line
+	for _, v := range script.vars {
+		// $ENV_VAR in the command substitution of $(echo "$ENV_VAR" | tr -d '\n') is already sanitized
+		if envVarNames[v.name] && !v.subst && v.quote.expandsVariables() && script.writesToVarFile(v, "GITHUB_ENV") {
+			edits = append(edits, shellEdit{v.start, v.end, fmt.Sprintf("$(echo \"$%s\" | tr -d '\\n')", v.name)})
 		}
 	}
-	newScript = strings.Join(lines, "\n")
+	newScript := applyShellEdits(run.Run.Value, edits)
 
 	// Update AST
 	run.Run.Value = newScript
@@ -435,9 +364,10 @@ func (rule *EnvVarInjectionRule) extractAndParseExpressions(str *ast.String) []p
 			}
 
 			result = append(result, parsedExpression{
-				raw:  exprContent,
-				node: expr,
-				pos:  pos,
+				raw:    exprContent,
+				node:   expr,
+				pos:    pos,
+				offset: start,
 			})
 		}
 
@@ -459,24 +389,7 @@ func (rule *EnvVarInjectionRule) parseExpression(exprStr string) (expressions.Ex
 
 // checkUntrustedInput checks if the expression contains untrusted input
 func (rule *EnvVarInjectionRule) checkUntrustedInput(expr parsedExpression) []string {
-	checker := expressions.NewExprSemanticsChecker(true, nil)
-	_, errs := checker.Check(expr.node)
-
-	var paths []string
-	for _, err := range errs {
-		msg := err.Message
-		if strings.Contains(msg, "potentially untrusted") {
-			if idx := strings.Index(msg, "\""); idx != -1 {
-				endIdx := strings.Index(msg[idx+1:], "\"")
-				if endIdx != -1 {
-					path := msg[idx+1 : idx+1+endIdx]
-					paths = append(paths, path)
-				}
-			}
-		}
-	}
-
-	return paths
+	return untrustedPathsInExpr(expr.node, rule.untrusted.roots)
 }
 
 // isDefinedInEnv checks if the expression is defined in the step's env section
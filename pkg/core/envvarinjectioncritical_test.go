@@ -58,6 +58,31 @@ echo "BODY=${{ github.event.pull_request.body }}" >> "$GITHUB_ENV"`,
 			wantErrors:  0,
 			description: "Should not detect for trusted input",
 		},
+		{
+			name:        "if block redirected to GITHUB_ENV",
+			trigger:     "pull_request_target",
+			runScript:   `if [ -n "$X" ]; then echo "X=${{ github.head_ref }}"; fi >> "$GITHUB_ENV"`,
+			wantErrors:  1,
+			description: "Should detect writes of a compound command redirected after fi",
+		},
+		{
+			name:    "for loop redirected to GITHUB_ENV",
+			trigger: "pull_request_target",
+			runScript: `for l in a b; do
+  echo "L=${{ github.event.pull_request.title }}"
+done >> "$GITHUB_ENV"`,
+			wantErrors:  1,
+			description: "Should detect writes of a loop redirected after done",
+		},
+		{
+			name:    "case redirected to GITHUB_ENV",
+			trigger: "pull_request_target",
+			runScript: `case "$X" in
+  a) echo "X=${{ github.event.pull_request.body }}" ;;
+esac >> "$GITHUB_ENV"`,
+			wantErrors:  1,
+			description: "Should detect writes of a case command redirected after esac",
+		},
 		{
 			name:    "safe with heredoc syntax",
 			trigger: "pull_request_target",
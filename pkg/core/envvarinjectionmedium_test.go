@@ -72,6 +72,41 @@ echo "BODY=${{ github.event.pull_request.body }}" >> "$GITHUB_ENV"`,
 			wantErrors:  1,
 			description: "Should detect GITHUB_ENV write without space after >>",
 		},
+		{
+			name:    "other command on the same line",
+			trigger: "pull_request",
+			runScript: `echo "${{ github.event.pull_request.title }}"; echo "A=b" >> "$GITHUB_ENV"
+echo 'log ${{ github.event.pull_request.body }} >> $GITHUB_ENV'`,
+			wantErrors:  0,
+			description: "Should not detect expressions in commands which do not write to GITHUB_ENV",
+		},
+		{
+			name:        "expression in comment",
+			trigger:     "pull_request",
+			runScript:   `echo "A=b" >> "$GITHUB_ENV" # ${{ github.event.pull_request.title }}`,
+			wantErrors:  0,
+			description: "Should not detect expressions in comments",
+		},
+		{
+			name:    "heredoc written to GITHUB_ENV",
+			trigger: "pull_request",
+			runScript: `cat <<EOF >> "$GITHUB_ENV"
+TITLE=${{ github.event.pull_request.title }}
+EOF`,
+			wantErrors:  1,
+			description: "Should detect expressions in heredoc body written to GITHUB_ENV",
+		},
+		{
+			name:    "group and line continuation",
+			trigger: "pull_request",
+			runScript: `{
+  echo "TITLE=${{ github.event.pull_request.title }}"
+} >> "$GITHUB_ENV"
+echo "BODY=${{ github.event.pull_request.body }}" \
+  | tee -a "$GITHUB_ENV"`,
+			wantErrors:  2,
+			description: "Should detect writes via group redirect and tee over multiple lines",
+		},
 	}
 
 	for _, tt := range tests {
@@ -185,3 +220,45 @@ func TestEnvVarInjectionMedium_AutoFix(t *testing.T) {
 		t.Error("expected env vars to be added")
 	}
 }
+
+func TestEnvVarInjectionMedium_ShellScriptContext(t *testing.T) {
+	script := `echo "${{ github.event.pull_request.title }}"
+cat <<EOF >> "$GITHUB_ENV"
+TITLE=${{ github.event.pull_request.title }}
+EOF
+echo 'BODY=${{ github.event.pull_request.body }}' >> "$GITHUB_ENV"
+echo "REF=$PR_TITLE" >> "$GITHUB_ENV"`
+	rule := EnvVarInjectionMediumRule()
+	step := &ast.Step{
+		Exec: &ast.ExecRun{
+			Run: &ast.String{Value: script, Pos: &ast.Position{Line: 10, Col: 9}, Literal: true},
+		},
+	}
+	_ = rule.VisitWorkflowPre(&ast.Workflow{On: []ast.Event{&ast.WebhookEvent{Hook: &ast.String{Value: "pull_request"}}}})
+	_ = rule.VisitJobPre(&ast.Job{Steps: []*ast.Step{step}})
+
+	// Errors point at the expressions instead of the lines
+	errs := rule.Errors()
+	if len(errs) != 2 {
+		t.Fatalf("want 2 errors but got %v", errs)
+	}
+	for i, want := range []struct{ line, col int }{{13, 15}, {15, 20}} {
+		if errs[i].LineNumber != want.line || errs[i].ColNumber != want.col {
+			t.Errorf("error #%d is at %d:%d, want %d:%d", i, errs[i].LineNumber, errs[i].ColNumber, want.line, want.col)
+		}
+	}
+
+	// The single quoted expression cannot be replaced with a command substitution, so it is not fixed
+	if err := rule.FixStep(step); err != nil {
+		t.Fatal(err)
+	}
+	want := `echo "${{ github.event.pull_request.title }}"
+cat <<EOF >> "$GITHUB_ENV"
+TITLE=$(echo "$PR_TITLE" | tr -d '\n')
+EOF
+echo 'BODY=${{ github.event.pull_request.body }}' >> "$GITHUB_ENV"
+echo "REF=$(echo "$PR_TITLE" | tr -d '\n')" >> "$GITHUB_ENV"`
+	if got := step.Exec.(*ast.ExecRun).Run.Value; got != want {
+		t.Errorf("got script\n%s\nwant\n%s", got, want)
+	}
+}
@@ -37,6 +37,16 @@ type LintingError struct {
 	ColNumber int
 	//LintingErrorが発生した行の内容
 	Type string
+	//LintingErrorの深刻度
+	Severity Severity
+	//FixはAutoFixerによる修正内容。修正内容が計算されていない場合はnil
+	Fix *FixSuggestion
+	//Suppressionはこのエラーを抑制したインライン抑制コメント。抑制されていない場合はnil
+	Suppression *ast.Suppression
+	//Contextはエラーを含むジョブとステップ。"jobs.<job_id>.steps.<step>"の形式
+	Context string
+	//Fingerprintは行番号に依存しないエラーのフィンガープリント。ベースラインとSARIFのpartialFingerprintsに使われる
+	Fingerprint string
 }
 
 func (e *LintingError) Error() string {
@@ -53,6 +63,7 @@ func NewError(position *ast.Position, errorType string, message string) *Linting
 		LineNumber:  position.Line,
 		ColNumber:   position.Col,
 		Type:        errorType,
+		Severity:    severityOfRule(errorType),
 	}
 }
 
@@ -62,6 +73,7 @@ func FormattedError(position *ast.Position, errorType string, format string, arg
 		LineNumber:  position.Line,
 		ColNumber:   position.Col,
 		Type:        errorType,
+		Severity:    severityOfRule(errorType),
 	}
 }
 
@@ -74,19 +86,28 @@ func (e *LintingError) ExtractTemplateFields(sourceContent []byte) *TemplateFiel
 			codeSnippet = lineContent
 		}
 	}
+	suppression := ""
+	if e.Suppression != nil {
+		suppression = e.Suppression.Reason
+	}
 	return &TemplateFields{
-		Message:  e.Description,
-		Filepath: e.FilePath,
-		Line:     e.LineNumber,
-		Column:   e.ColNumber,
-		Type:     e.Type,
-		Snippet:  codeSnippet,
+		Message:     e.Description,
+		Filepath:    e.FilePath,
+		Line:        e.LineNumber,
+		Column:      e.ColNumber,
+		Type:        e.Type,
+		Severity:    e.severity().String(),
+		Snippet:     codeSnippet,
+		Fix:         e.Fix,
+		Suppression: suppression,
+		Fingerprint: e.Fingerprint,
 	}
 }
 
 type RuleTemplateField struct {
 	Name        string
 	Description string
+	Severity    Severity
 }
 
 type ByRuleTemplateField []*RuleTemplateField
@@ -113,7 +134,8 @@ func (e *LintingError) DisplayError(output io.Writer, sourceContent []byte) {
 	fmt.Fprint(output, e.ColNumber)
 	printColored(output, GrayStyle, ": ")
 	printColored(output, OrangeStyle, e.Description)
-	printColored(output, RedStyle, fmt.Sprintf(" [%s]\n", e.Type))
+	printColored(output, RedStyle, fmt.Sprintf(" [%s]", e.Type))
+	printColored(output, severityStyle(e.severity()), fmt.Sprintf(" (%s)\n", e.severity()))
 
 	if len(sourceContent) == 0 || e.LineNumber == 0 {
 		return
@@ -135,6 +157,26 @@ func (e *LintingError) DisplayError(output io.Writer, sourceContent []byte) {
 	printColored(output, GrayStyle, fmt.Sprintf("%s %s\n", padding, strings.Repeat(" ", colNumber)))
 }
 
+// severityはエラーの深刻度を返す。未設定の場合はルール名から推定する
+func (e *LintingError) severity() Severity {
+	if e.Severity != SeverityNone {
+		return e.Severity
+	}
+	return severityOfRule(e.Type)
+}
+
+// severityStyleは深刻度に応じた出力の色を返す
+func severityStyle(sev Severity) *color.Color {
+	switch sev {
+	case SeverityCritical, SeverityHigh:
+		return RedStyle
+	case SeverityMedium:
+		return YellowStyle
+	default:
+		return GrayStyle
+	}
+}
+
 // helper function to print with color
 func printColored(output io.Writer, colorizer *color.Color, content string) {
 	colorizer.Fprint(output, content)
@@ -186,9 +228,17 @@ type TemplateFields struct {
 	Column int `json:"column"`
 	// Type はエラーが属しているルールの名前
 	Type string `json:"type"`
+	// Severity はエラーの深刻度 (critical, high, medium, low, info)
+	Severity string `json:"severity"`
 	// Snippet はエラーが発生した位置を示すコードスニペットおよびインジケーター
 	// JSONにエンコードする際、スニペットが空の場合、(このフィールドは省略される可能性あり)
 	Snippet string `json:"snippet,omitempty"`
+	// Fix はAutoFixerによる修正内容。修正内容がない場合は省略される
+	Fix *FixSuggestion `json:"fix,omitempty"`
+	// Suppression はインライン抑制コメントでエラーが抑制された場合の理由。抑制されていない場合は省略される
+	Suppression string `json:"suppression,omitempty"`
+	// Fingerprint は行番号に依存しないエラーのフィンガープリント
+	Fingerprint string `json:"fingerprint,omitempty"`
 }
 
 // backslashのunescape
@@ -220,6 +270,7 @@ type ErrorFormatter struct {
 	templateInstance *template.Template
 	ruleTemplates    map[string]*RuleTemplateField
 	m                sync.Mutex
+	usesSARIF        bool
 }
 
 // NewErrorformatterは新しいErrorFormatterインスタンスを作成する。
@@ -230,8 +281,11 @@ func NewErrorFormatter(format string) (*ErrorFormatter, error) {
 		return nil, fmt.Errorf("the specified format should contain at least one {{ }} placeholder : %s", format)
 	}
 
-	ruleTemplates := map[string]*RuleTemplateField{
-		"syntax-check": {"syntax-check", "Check the Github Actions workflow syntax"},
+	formatter := &ErrorFormatter{
+		ruleTemplates: map[string]*RuleTemplateField{
+			"syntax-check": {"syntax-check", "Check the Github Actions workflow syntax", SeverityMedium},
+		},
+		usesSARIF: strings.Contains(format, "sarif"),
 	}
 
 	funcMap := template.FuncMap(map[string]interface{}{
@@ -250,22 +304,42 @@ func NewErrorFormatter(format string) (*ErrorFormatter, error) {
 
 		"toPascalCase": toPascalCase,
 		//"getVersion": getCommandVersion,
-		"allKinds": func() []*RuleTemplateField {
-			ret := make([]*RuleTemplateField, 0, len(ruleTemplates))
-			for _, rule := range ruleTemplates {
-				ret = append(ret, rule)
-			}
-			sort.Sort(ByRuleTemplateField(ret))
-			return ret
-		},
+		"allKinds": formatter.allRules,
 
-		"sarif": toSARIF,
+		"sarif": func(fields []*TemplateFields) (string, error) {
+			return toSARIF(fields, formatter.allRules())
+		},
 	})
 	t, err := template.New("error formatter").Funcs(funcMap).Parse(unescapeBackslash(format))
 	if err != nil {
 		return nil, fmt.Errorf("failed to ast %q the specified format: %w", format, err)
 	}
-	return &ErrorFormatter{t, ruleTemplates, sync.Mutex{}}, nil
+	formatter.templateInstance = t
+	return formatter, nil
+}
+
+// allRulesは登録済みのルールを名前順に返す
+func (formatter *ErrorFormatter) allRules() []*RuleTemplateField {
+	formatter.m.Lock()
+	defer formatter.m.Unlock()
+	ret := make([]*RuleTemplateField, 0, len(formatter.ruleTemplates))
+	for _, rule := range formatter.ruleTemplates {
+		ret = append(ret, rule)
+	}
+	sort.Sort(ByRuleTemplateField(ret))
+	return ret
+}
+
+// IncludesSuppressedはフォーマットの出力に抑制されたエラーを含めるかどうかを返す
+// 抑制されたエラーはSARIFのsuppressionsとして出力され、監査できるようにする
+func (formatter *ErrorFormatter) IncludesSuppressed() bool {
+	return formatter.usesSARIF
+}
+
+// NeedsFixSuggestionsはフォーマットの出力にAutoFixerの修正内容が必要かどうかを返す
+// 修正内容はSARIFのfixesとして出力される
+func (formatter *ErrorFormatter) NeedsFixSuggestions() bool {
+	return formatter.usesSARIF
 }
 
 // PrintErrorsはテンプレートでフォーマットした後でエラーを出力する
@@ -295,6 +369,7 @@ func (formatter *ErrorFormatter) RegisterRule(rule Rule) {
 		formatter.ruleTemplates[ruleName] = &RuleTemplateField{
 			Name:        ruleName,
 			Description: rule.RuleDescription(),
+			Severity:    rule.Severity(),
 		}
 	}
 }
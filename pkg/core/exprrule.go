@@ -327,6 +327,11 @@ func (rule *ExprRule) getActionOutputsType(spec *ast.String) *expressions.Object
 		return typeOfActionOutputs(meta)
 	}
 
+	// よく使われるアクションは、埋め込みのデータセットの出力で型を決める
+	if meta, _ := rule.LocalActionsCache.FindMetadata(pinnedActionSpec(spec)); meta != nil {
+		return typeOfActionOutputs(meta)
+	}
+
 	// github-script アクションは、`core.setOutput` を直接呼び出すことで任意の出力を設定することができます。
 	// そのため、どんな `outputs.*` プロパティも受け入れられるべきです (#104)
 	if strings.HasPrefix(spec.Value, "actions/github-script@") {
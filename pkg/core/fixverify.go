@@ -0,0 +1,94 @@
+package core
+
+import (
+	"fmt"
+	"io"
+)
+
+// FixVerificationErrorはAutoFixerの修正後のワークフローの検証に失敗したため、修正を取り消したことを表す
+type FixVerificationError struct {
+	// RuleNameは取り消されたAutoFixerのルール名
+	RuleName string
+	// ParseErrorsは修正後のワークフローで新たに発生したパースエラー
+	ParseErrors []*LintingError
+	// NewFindingsは修正前には報告されていなかったエラー
+	NewFindings []*LintingError
+}
+
+func (e *FixVerificationError) Error() string {
+	if len(e.ParseErrors) > 0 {
+		return fmt.Sprintf("fix of %q was rolled back because the fixed workflow has a parse error: %s%s", e.RuleName, e.ParseErrors[0].Description, andMore(len(e.ParseErrors)))
+	}
+	f := e.NewFindings[0]
+	return fmt.Sprintf("fix of %q was rolled back because it introduced a new finding: %s [%s]%s", e.RuleName, f.Description, f.Type, andMore(len(e.NewFindings)))
+}
+
+func andMore(n int) string {
+	if n <= 1 {
+		return ""
+	}
+	return fmt.Sprintf(" (and %d more)", n-1)
+}
+
+// newFixVerifierは修正後のソースを再度パースしてlintし、修正前になかったパースエラーやエラーが発生した修正を拒否するFixVerifierを作成する
+// 比較の対象は直前に受け入れた修正までを適用したソースの結果なので、先に適用した修正で消えたエラーが後の修正で再び現れた場合も拒否する
+func newFixVerifier(opts *LinterOptions, filePath string, source []byte) (FixVerifier, error) {
+	o := *opts
+	// ベースラインのエントリは一度一致すると消費されるため、検証では使わない
+	o.BaselineFilePath = ""
+	o.LogOutputDestination = io.Discard
+	l, err := NewLinter(io.Discard, &o)
+	if err != nil {
+		return nil, err
+	}
+
+	_, prevParseErrs := parseFile(filePath, source)
+	prev, err := l.Lint(filePath, source, nil)
+	if err != nil {
+		return nil, err
+	}
+	prevFindings := prev.Errors
+
+	return func(fixer AutoFixer, fixed []byte) error {
+		_, parseErrs := parseFile(filePath, fixed)
+		if added := newFindings(prevParseErrs, parseErrs); len(added) > 0 {
+			return &FixVerificationError{RuleName: fixer.RuleName(), ParseErrors: added}
+		}
+		res, err := l.Lint(filePath, fixed, nil)
+		if err != nil {
+			return fmt.Errorf("could not lint the workflow fixed by %q: %w", fixer.RuleName(), err)
+		}
+		if added := newFindings(prevFindings, res.Errors); len(added) > 0 {
+			return &FixVerificationError{RuleName: fixer.RuleName(), NewFindings: added}
+		}
+		prevParseErrs, prevFindings = parseErrs, res.Errors
+		return nil
+	}, nil
+}
+
+// newFindingsはbeforeに対応するエラーがないafterのエラーを返す
+// 修正で行番号が変わるため、まずルールとメッセージが同じエラーを対応付け、残ったエラーはルールとジョブやステップが同じものを
+// 対応付ける。後者はメッセージに修正した値が含まれるエラーが、修正によって別のメッセージになった場合のため
+func newFindings(before, after []*LintingError) []*LintingError {
+	used := make([]bool, len(before))
+	match := func(errs []*LintingError, same func(a, b *LintingError) bool) []*LintingError {
+		var rest []*LintingError
+	Errors:
+		for _, e := range errs {
+			for i, b := range before {
+				if !used[i] && same(b, e) {
+					used[i] = true
+					continue Errors
+				}
+			}
+			rest = append(rest, e)
+		}
+		return rest
+	}
+	rest := match(after, func(a, b *LintingError) bool {
+		return a.Type == b.Type && a.Description == b.Description
+	})
+	return match(rest, func(a, b *LintingError) bool {
+		return a.Type == b.Type && a.Context == b.Context
+	})
+}
@@ -0,0 +1,186 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sisaku-security/sisakulint/pkg/ast"
+	"gopkg.in/yaml.v3"
+)
+
+func TestNewFindings(t *testing.T) {
+	e := func(typ, desc, ctx string) *LintingError {
+		return &LintingError{Type: typ, Description: desc, Context: ctx}
+	}
+	tests := []struct {
+		name   string
+		before []*LintingError
+		after  []*LintingError
+		want   []string
+	}{
+		{
+			name:   "same findings",
+			before: []*LintingError{e("r1", "a", "jobs.x"), e("r2", "b", "jobs.y")},
+			after:  []*LintingError{e("r2", "b", "jobs.y"), e("r1", "a", "jobs.x")},
+		},
+		{
+			name:   "removed finding",
+			before: []*LintingError{e("r1", "a", "jobs.x"), e("r2", "b", "jobs.y")},
+			after:  []*LintingError{e("r1", "a", "jobs.x")},
+		},
+		{
+			name:   "message changed in same context",
+			before: []*LintingError{e("r1", "uses v1", "jobs.x.steps.0")},
+			after:  []*LintingError{e("r1", "uses v2", "jobs.x.steps.0")},
+		},
+		{
+			name:   "new finding",
+			before: []*LintingError{e("r1", "a", "jobs.x")},
+			after:  []*LintingError{e("r1", "a", "jobs.x"), e("r3", "c", "jobs.x")},
+			want:   []string{"c"},
+		},
+		{
+			name:   "same message appears once more",
+			before: []*LintingError{e("r1", "a", "jobs.x")},
+			after:  []*LintingError{e("r1", "a", "jobs.x"), e("r1", "a", "jobs.y")},
+			want:   []string{"a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, f := range newFindings(tt.before, tt.after) {
+				got = append(got, f.Description)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyAutoFixers_VerifierRollsBack(t *testing.T) {
+	src := "on: push\npermissions: {}\njobs:\n  build:\n    runs-on: ubuntu-latest\n    timeout-minutes: 5\n    steps:\n      - run: echo hi\n"
+	var root yaml.Node
+	if err := yaml.Unmarshal([]byte(src), &root); err != nil {
+		t.Fatal(err)
+	}
+	job := yamlNodeAt(t, &root, "jobs", "build")
+	removeKey := func(key string) func() error {
+		return func() error {
+			for i := 0; i < len(job.Content); i += 2 {
+				if job.Content[i].Value == key {
+					job.Content = append(job.Content[:i], job.Content[i+2:]...)
+					break
+				}
+			}
+			return nil
+		}
+	}
+	breaking := NewFuncFixer("breaking", removeKey("runs-on"))
+	regressing := NewFuncFixer("regressing", removeKey("timeout-minutes"))
+	good := NewFuncFixer("good", func() error {
+		yamlNodeAt(t, &root, "jobs", "build", "steps", "0", "run").Value = "echo bye"
+		return nil
+	})
+
+	verify, err := newFixVerifier(&LinterOptions{}, "ci.yml", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := ApplyAutoFixers([]byte(src), &ast.Workflow{BaseNode: &root}, []AutoFixer{breaking, regressing, good}, verify)
+
+	if want := strings.Replace(src, "echo hi", "echo bye", 1); string(res.Source) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", res.Source, want)
+	}
+	if len(res.Applied) != 1 || res.Applied[0] != good {
+		t.Errorf("only the good fixer should be applied: %v", res.Applied)
+	}
+	if len(res.Failed) != 2 {
+		t.Fatalf("got %d failures, want 2", len(res.Failed))
+	}
+	var verr *FixVerificationError
+	if !errors.As(res.Failed[0].Err, &verr) || len(verr.ParseErrors) == 0 {
+		t.Errorf("breaking fix should be rolled back by parse error, got %v", res.Failed[0].Err)
+	}
+	if !errors.As(res.Failed[1].Err, &verr) || len(verr.NewFindings) == 0 || verr.NewFindings[0].Type != "missing-timeout-minutes" {
+		t.Errorf("regressing fix should be rolled back by new finding, got %v", res.Failed[1].Err)
+	}
+	if len(job.Content) != 6 {
+		t.Errorf("changes of rolled back fixers are not restored: %d nodes", len(job.Content))
+	}
+}
+
+func TestCommand_FixRules(t *testing.T) {
+	root := t.TempDir()
+	workflows := filepath.Join(root, ".github", "workflows")
+	if err := os.MkdirAll(workflows, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(root, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	workflow := "on: push\npermissions: {}\njobs:\n  build:\n    runs-on: ubuntu-latest\n    steps:\n      - uses: actions/checkout@11bd71901bbe5b1630ceea73d27597364c9af683\n"
+	path := filepath.Join(workflows, "ci.yml")
+
+	tests := []struct {
+		name    string
+		args    []string
+		applied []string
+		skipped []string
+		report  string
+	}{
+		{
+			name:    "fix-rules",
+			args:    []string{"-fix-rules", "missing-timeout-minutes"},
+			applied: []string{"timeout-minutes: 5"},
+			skipped: []string{"persist-credentials"},
+			report:  "Applied 2 of 2 fixes",
+		},
+		{
+			name:    "fix-exclude",
+			args:    []string{"-fix-exclude", "missing-timeout-minutes"},
+			applied: []string{"persist-credentials: false"},
+			skipped: []string{"timeout-minutes"},
+			report:  "Applied 1 of 1 fixes",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := os.WriteFile(path, []byte(workflow), 0644); err != nil {
+				t.Fatal(err)
+			}
+			var stdout, stderr bytes.Buffer
+			cmd := &Command{Stdout: &stdout, Stderr: &stderr}
+			cmd.Main(append(append([]string{"sisakulint", "-fix", "on"}, tt.args...), path))
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, s := range tt.applied {
+				if !strings.Contains(string(data), s) {
+					t.Errorf("fix %q is not applied:\n%s", s, data)
+				}
+			}
+			for _, s := range tt.skipped {
+				if strings.Contains(string(data), s) {
+					t.Errorf("fix %q should not be applied:\n%s", s, data)
+				}
+			}
+			if !strings.Contains(stderr.String(), "Autofix report for ") || !strings.Contains(stderr.String(), tt.report) {
+				t.Errorf("autofix report is not printed:\n%s", stderr.String())
+			}
+		})
+	}
+
+	var stderr bytes.Buffer
+	cmd := &Command{Stdout: &bytes.Buffer{}, Stderr: &stderr}
+	if code := cmd.Main([]string{"sisakulint", "-fix", "on", "-fix-rules", "no-such-rule", path}); code != ExitStatusInvalidCommandOption {
+		t.Errorf("unknown rule in -fix-rules should be rejected, got exit status %d", code)
+	}
+}
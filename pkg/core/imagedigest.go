@@ -0,0 +1,162 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/sisaku-security/sisakulint/pkg/ast"
+)
+
+// ImageDigestRuleは、ジョブのコンテナ、サービスコンテナ、"docker://"のステップのイメージがダイジェストでピン留めされているかを検査するルール
+// ":latest"のようなタグは差し替えられる可能性があるので、"@sha256:"のダイジェストを要求する
+// 自動修正は、ロックファイルのimagesセクションに記録されたダイジェストを使い、レジストリにはアクセスしない
+// * https://docs.github.com/en/actions/security-for-github-actions/security-guides/security-hardening-for-github-actions#using-third-party-actions
+type ImageDigestRule struct {
+	BaseRule
+	lock       *ActionLock
+	privileged string
+}
+
+// NewImageDigestRuleは新しいImageDigestRuleインスタンスを作成する。lockがnilの場合は自動修正を提供しない
+func NewImageDigestRule(lock *ActionLock) *ImageDigestRule {
+	return &ImageDigestRule{
+		BaseRule: BaseRule{
+			RuleName: "image-digest",
+			RuleDesc: "Checks for container images which are not pinned to a digest in \"container:\", \"services:\" and \"docker://\" steps",
+		},
+		lock: lock,
+	}
+}
+
+// VisitWorkflowPreはWorkflowノードを訪れたときのcallback
+func (rule *ImageDigestRule) VisitWorkflowPre(n *ast.Workflow) error {
+	// 特権トリガーで起動されるワークフローでは、タグが差し替えられたイメージの影響が大きいので深刻度を上げる
+	rule.privileged = ""
+	if e := rule.TriggerTrust().FirstPrivileged(n); e != nil {
+		rule.privileged = e.EventName()
+	}
+	return nil
+}
+
+// VisitJobPreはJobノードを訪れたときのcallback
+func (rule *ImageDigestRule) VisitJobPre(n *ast.Job) error {
+	fixable := false
+	if n.Container != nil && rule.checkImage(n.Container.Image, fmt.Sprintf("container image of job %q", n.ID.Value)) {
+		fixable = true
+	}
+	for _, name := range sortedServiceNames(n.Services) {
+		s := n.Services[name]
+		if s.Container != nil && rule.checkImage(s.Container.Image, fmt.Sprintf("image of service %q in job %q", s.Name.Value, n.ID.Value)) {
+			fixable = true
+		}
+	}
+	if fixable {
+		rule.AddAutoFixer(NewJobFixer(n, rule))
+	}
+	return nil
+}
+
+// VisitStepはStepノードを訪れたときのcallback
+func (rule *ImageDigestRule) VisitStep(n *ast.Step) error {
+	e, ok := n.Exec.(*ast.ExecAction)
+	if !ok || e.Uses == nil || !strings.HasPrefix(e.Uses.Value, "docker://") {
+		return nil
+	}
+	if rule.checkImage(e.Uses, "image of \"docker://\" action") {
+		rule.AddAutoFixer(NewStepFixer(n, rule))
+	}
+	return nil
+}
+
+// checkImageはイメージがダイジェストでピン留めされていない場合にエラーを報告する
+// ロックファイルにダイジェストが記録されていて自動修正できる場合はtrueを返す
+func (rule *ImageDigestRule) checkImage(s *ast.String, what string) bool {
+	if s == nil || s.Value == "" || strings.Contains(s.Value, "${{") {
+		return false
+	}
+	image := strings.TrimPrefix(s.Value, "docker://")
+	if strings.Contains(image, "@sha256:") {
+		return false
+	}
+
+	digest := rule.lock.LookupImage(image)
+	hint := fmt.Sprintf("record the digest of %q in \"images\" section of .github/%s to fix it automatically", image, ActionLockFileName)
+	if digest != "" {
+		hint = "the digest is recorded in the lock file and can be fixed automatically"
+	}
+	_, tag := splitImageRef(image)
+	if tag == "" {
+		tag = "latest"
+	}
+	severity := SeverityMedium
+	trigger := ""
+	if rule.privileged != "" {
+		severity = SeverityHigh
+		trigger = fmt.Sprintf(" in a workflow triggered by %q", rule.privileged)
+	}
+	rule.ErrorfWithSeverity(
+		s.Pos,
+		severity,
+		"%s %q is not pinned to a digest%s. tag %q is mutable and can be replaced with a malicious image. pin it like \"%s@sha256:<digest>\". %s",
+		what,
+		image,
+		trigger,
+		tag,
+		image,
+		hint,
+	)
+	return digest != ""
+}
+
+// FixJobは、ジョブのコンテナとサービスコンテナのイメージをロックファイルのダイジェストでピン留めする
+func (rule *ImageDigestRule) FixJob(n *ast.Job) error {
+	if n.Container != nil {
+		rule.pin(n.Container.Image)
+	}
+	for _, s := range n.Services {
+		if s.Container != nil {
+			rule.pin(s.Container.Image)
+		}
+	}
+	return nil
+}
+
+// FixStepは、"docker://"のステップのイメージをロックファイルのダイジェストでピン留めする
+func (rule *ImageDigestRule) FixStep(n *ast.Step) error {
+	if e, ok := n.Exec.(*ast.ExecAction); ok {
+		rule.pin(e.Uses)
+	}
+	return nil
+}
+
+// pinはイメージの参照を"name:tag@sha256:..."に書き換える。タグは読みやすさのために残す
+func (rule *ImageDigestRule) pin(s *ast.String) {
+	if s == nil || s.BaseNode == nil || strings.Contains(s.Value, "@sha256:") {
+		return
+	}
+	image := strings.TrimPrefix(s.Value, "docker://")
+	digest := rule.lock.LookupImage(image)
+	if digest == "" {
+		return
+	}
+	s.BaseNode.Value = strings.TrimSuffix(s.Value, image) + image + "@" + digest
+}
+
+// splitImageRefは"registry:port/name:tag"の形式のイメージの参照を名前とタグに分割する。タグがない場合は空文字列を返す
+func splitImageRef(image string) (name, tag string) {
+	i := strings.LastIndexByte(image, ':')
+	if i < 0 || strings.Contains(image[i+1:], "/") {
+		return image, ""
+	}
+	return image[:i], image[i+1:]
+}
+
+func sortedServiceNames(services map[string]*ast.Service) []string {
+	names := make([]string, 0, len(services))
+	for n := range services {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,187 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	testNodeDigest  = "sha256:1111111111111111111111111111111111111111111111111111111111111111"
+	testRedisDigest = "sha256:2222222222222222222222222222222222222222222222222222222222222222"
+)
+
+func TestSplitImageRef(t *testing.T) {
+	tests := []struct {
+		image, name, tag string
+	}{
+		{"node:20", "node", "20"},
+		{"node", "node", ""},
+		{"ghcr.io/owner/image:v1.2", "ghcr.io/owner/image", "v1.2"},
+		{"localhost:5000/image", "localhost:5000/image", ""},
+		{"localhost:5000/image:dev", "localhost:5000/image", "dev"},
+	}
+	for _, tt := range tests {
+		name, tag := splitImageRef(tt.image)
+		if name != tt.name || tag != tt.tag {
+			t.Errorf("splitImageRef(%q) = %q, %q, want %q, %q", tt.image, name, tag, tt.name, tt.tag)
+		}
+	}
+}
+
+func TestActionLock_Images(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ActionLockFileName)
+	if err := os.WriteFile(path, []byte("images:\n  node:20: "+testNodeDigest+"\n  redis:latest: "+testRedisDigest+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	lock, err := ReadActionLockFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for image, want := range map[string]string{
+		"node:20":      testNodeDigest,
+		"redis":        testRedisDigest,
+		"redis:latest": testRedisDigest,
+		"node":         "",
+		"node:18":      "",
+	} {
+		if got := lock.LookupImage(image); got != want {
+			t.Errorf("LookupImage(%q) = %q, want %q", image, got, want)
+		}
+	}
+
+	if err := os.WriteFile(path, []byte("images:\n  node:20: sha256:abc\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadActionLockFile(path); err == nil || !strings.Contains(err.Error(), "sha256:<64 hex digits>") {
+		t.Errorf("invalid digest should be rejected: %v", err)
+	}
+}
+
+func TestImageDigestRule(t *testing.T) {
+	lock := &ActionLock{Images: map[string]string{
+		"node:20":      testNodeDigest,
+		"redis:latest": testRedisDigest,
+	}}
+
+	tests := []struct {
+		name     string
+		on       string
+		job      string
+		step     string
+		want     []string
+		severity Severity
+		fixed    string
+	}{
+		{
+			name:     "container image with tag",
+			on:       "push",
+			job:      "container: node:20",
+			want:     []string{`container image of job "test" "node:20" is not pinned to a digest. tag "20" is mutable`},
+			severity: SeverityMedium,
+			fixed:    "container: node:20@" + testNodeDigest,
+		},
+		{
+			name: "container mapping in privileged workflow",
+			on:   "pull_request_target",
+			job:  "container:\n      image: node:20\n      options: --cpus 1",
+			want: []string{
+				`container image of job "test" "node:20" is not pinned to a digest in a workflow triggered by "pull_request_target"`,
+			},
+			severity: SeverityHigh,
+			fixed:    "container:\n      image: node:20@" + testNodeDigest + "\n      options: --cpus 1",
+		},
+		{
+			name: "services",
+			on:   "push",
+			job:  "services:\n      redis:\n        image: redis\n      db:\n        image: postgres:16",
+			want: []string{
+				`image of service "db" in job "test" "postgres:16" is not pinned to a digest. tag "16" is mutable`,
+				`image of service "redis" in job "test" "redis" is not pinned to a digest. tag "latest" is mutable`,
+			},
+			fixed: "services:\n      redis:\n        image: redis@" + testRedisDigest + "\n      db:\n        image: postgres:16",
+		},
+		{
+			name:  "docker action",
+			on:    "push",
+			step:  "uses: docker://node:20",
+			want:  []string{`image of "docker://" action "node:20" is not pinned to a digest`},
+			fixed: "uses: docker://node:20@" + testNodeDigest,
+		},
+		{
+			name: "image without digest in lock file",
+			on:   "push",
+			step: "uses: docker://alpine:3",
+			want: []string{`record the digest of "alpine:3" in "images" section`},
+		},
+		{
+			name: "pinned images",
+			on:   "push",
+			job:  "container: node:20@" + testNodeDigest + "\n    services:\n      redis:\n        image: redis@" + testRedisDigest,
+			step: "uses: docker://alpine@" + testRedisDigest,
+		},
+		{
+			name: "expression",
+			on:   "push",
+			job:  "container: ${{ matrix.image }}",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step := tt.step
+			if step == "" {
+				step = "run: echo"
+			}
+			job := ""
+			if tt.job != "" {
+				job = "    " + tt.job + "\n"
+			}
+			src := "on: " + tt.on + "\njobs:\n  test:\n    runs-on: ubuntu-latest\n" + job + "    steps:\n      - " + step + "\n"
+			w, errs := Parse([]byte(src))
+			if len(errs) > 0 {
+				t.Fatal(errs)
+			}
+			rule := NewImageDigestRule(lock)
+			v := NewSyntaxTreeVisitor()
+			v.AddVisitor(rule)
+			if err := v.VisitTree(w); err != nil {
+				t.Fatal(err)
+			}
+
+			got := rule.Errors()
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d errors, want %d: %v", len(got), len(tt.want), got)
+			}
+			for i, want := range tt.want {
+				if !strings.Contains(got[i].Description, want) {
+					t.Errorf("error %q does not contain %q", got[i].Description, want)
+				}
+				if tt.severity != SeverityNone && got[i].Severity != tt.severity {
+					t.Errorf("want severity %s, got %s", tt.severity, got[i].Severity)
+				}
+			}
+
+			fixers := rule.AutoFixers()
+			if tt.fixed == "" {
+				if len(fixers) > 0 {
+					t.Fatal("unexpected autofix")
+				}
+				return
+			}
+			res := ApplyAutoFixers([]byte(src), w, fixers, nil)
+			if len(res.Failed) > 0 {
+				t.Fatal(res.Failed[0].Err)
+			}
+			orig := tt.job
+			if tt.step != "" {
+				orig = tt.step
+			}
+			want := strings.Replace(src, orig, tt.fixed, 1)
+			if got := string(res.Source); got != want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, want)
+			}
+		})
+	}
+}
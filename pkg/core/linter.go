@@ -72,6 +72,12 @@ type LinterOptions struct {
 	StdinInputFileName string
 	// CurrentWorkingDirectoryPathは、現在の作業ディレクトリのパス
 	CurrentWorkingDirectoryPath string
+	// MinSeverityは、報告するエラーの最小の深刻度。これより深刻度の低いエラーは報告されない
+	MinSeverity Severity
+	// BaselineFilePathは、ベースラインファイルのパス。ベースラインに記録されたエラーは報告されない
+	BaselineFilePath string
+	// AdvisoryDatabasePathは、埋め込みのデータベースに加えて使うOSV形式のアドバイザリのファイルまたはディレクトリのパス
+	AdvisoryDatabasePath string
 	//todo: OnCheckRulesModifiedは、チェックルールの追加や削除を行うフック
 	OnCheckRulesModified func([]Rule) []Rule
 }
@@ -100,6 +106,12 @@ type Linter struct {
 	currentWorkingDirectory string
 	//todo: modifyCheckRulesは、チェックルールを追加または削除するためのフック関数
 	modifyCheckRules func([]Rule) []Rule
+	// minSeverityは、報告するエラーの最小の深刻度
+	minSeverity Severity
+	// baselineは、既知のエラーを記録したベースライン。nilの場合は全てのエラーを報告する
+	baseline *Baseline
+	// advisoriesは、action-advisoryルールが使うアドバイザリのデータベース
+	advisories *AdvisoryDatabase
 }
 
 // NewLinterは新しいLinterインスタンスを作成する
@@ -158,6 +170,26 @@ func NewLinter(errorOutput io.Writer, options *LinterOptions) (*Linter, error) {
 		ignorePatterns[i] = re
 	}
 
+	//ベースラインファイルの読み込み
+	var baseline *Baseline
+	if options.BaselineFilePath != "" {
+		b, err := ReadBaselineFile(options.BaselineFilePath)
+		if err != nil {
+			return nil, err
+		}
+		baseline = b
+	}
+
+	//アドバイザリのデータベースの読み込み
+	advisories := DefaultAdvisoryDatabase()
+	if options.AdvisoryDatabasePath != "" {
+		db, err := ReadAdvisoryDatabase(options.AdvisoryDatabasePath)
+		if err != nil {
+			return nil, err
+		}
+		advisories = db
+	}
+
 	//エラーメッセージのフォーマットの作成
 	var errorFormatter *ErrorFormatter
 	if options.CustomErrorMessageFormat != "" {
@@ -188,6 +220,9 @@ func NewLinter(errorOutput io.Writer, options *LinterOptions) (*Linter, error) {
 		errorFormatter,
 		workDir,
 		options.OnCheckRulesModified,
+		options.MinSeverity,
+		baseline,
+		advisories,
 	}, nil
 }
 
@@ -280,12 +315,36 @@ func (l *Linter) LintRepository(dir string) ([]*ValidateResult, error) {
 		return nil, errors.New("project not found")
 	}
 	l.log("Detected project:", project.RootDirectory())
-	workflowsDir := project.WorkflowDirectory()
-	return l.LintDir(workflowsDir, project)
+	files, err := yamlFilesInDir(project.WorkflowDirectory())
+	if err != nil {
+		return nil, err
+	}
+	// composite actionなどのアクションのメタデータもワークフローと一緒にlintする
+	files = append(files, project.ActionMetadataFiles()...)
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no yaml files found in %q", project.WorkflowDirectory())
+	}
+	l.log("the number of corrected yaml file", len(files), "yaml files")
+	return l.LintFiles(files, project)
 }
 
 // LintDirは、指定されたディレクトリをLint
 func (l *Linter) LintDir(dir string, project *Project) ([]*ValidateResult, error) {
+	files, err := yamlFilesInDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no yaml files found in %q", dir)
+	}
+	l.log("the number of corrected yaml file", len(files), "yaml files")
+
+	return l.LintFiles(files, project)
+}
+
+// yamlFilesInDirは、ディレクトリ以下のyamlファイルのパスをソートして返す
+func yamlFilesInDir(dir string) ([]string, error) {
 	// Preallocate files slice with a reasonable capacity for workflow files
 	files := make([]string, 0, 10)
 	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
@@ -301,15 +360,9 @@ func (l *Linter) LintDir(dir string, project *Project) ([]*ValidateResult, error
 		return nil, fmt.Errorf("it could not read %q , failed to walk directory: %w", dir, err)
 	}
 
-	if len(files) == 0 {
-		return nil, fmt.Errorf("no yaml files found in %q", dir)
-	}
-	l.log("the number of corrected yaml file", len(files), "yaml files")
-
 	//sort order of filepaths
 	sort.Strings(files)
-
-	return l.LintFiles(files, project)
+	return files, nil
 }
 
 // lintFilesは、指定されたyaml workflowをlintしてエラーを返す
@@ -336,9 +389,13 @@ func (l *Linter) LintFiles(filepaths []string, project *Project) ([]*ValidateRes
 	reusableWorkflowCacheFactory := NewLocalReusableWorkflowCacheFactory(currentDir, debugLog)
 
 	type workspace struct {
-		path   string
-		result *ValidateResult
-		source []byte
+		path     string
+		result   *ValidateResult
+		source   []byte
+		parsed   *parsedFile
+		project  *Project
+		actions  *LocalActionsMetadataCache
+		reusable *LocalReusableWorkflowCache
 	}
 
 	workspaces := make([]workspace, len(filepaths))
@@ -359,8 +416,9 @@ func (l *Linter) LintFiles(filepaths []string, project *Project) ([]*ValidateRes
 			}
 			localProject = projectForPath
 		}
-		actionCache := actionCacheFactory.GetCache(localProject) //[173]
-		reusableWorkflowCache := reusableWorkflowCacheFactory.GetCache(localProject)
+		ws.project = localProject
+		ws.actions = actionCacheFactory.GetCache(localProject) //[173]
+		ws.reusable = reusableWorkflowCacheFactory.GetCache(localProject)
 
 		errorGroups.Go(func() error {
 			source, err := os.ReadFile(ws.path)
@@ -372,18 +430,46 @@ func (l *Linter) LintFiles(filepaths []string, project *Project) ([]*ValidateRes
 					ws.path = relPath //相対パスの活用
 				}
 			}
-			result, err := l.validate(ws.path, source, localProject, proc, actionCache, reusableWorkflowCache)
+			ws.source = source
+			ws.parsed = l.parse(ws.path, source)
+			return nil
+		})
+	}
+	if err := errorGroups.Wait(); err != nil {
+		return nil, err
+	}
+
+	// 複数のworkflowにまたがる問題は、全てのworkflowを解析してから検出する
+	workflows := make([]*artifactWorkflow, 0, len(workspaces))
+	indices := make([]int, 0, len(workspaces))
+	for i := range workspaces {
+		ws := &workspaces[i]
+		if ws.parsed.workflow == nil || IsActionMetadataFile(ws.path) {
+			continue
+		}
+		workflows = append(workflows, l.artifactWorkflow(ws.path, ws.parsed.workflow, ws.project))
+		indices = append(indices, i)
+	}
+	for i, errs := range artifactPoisoningChains(workflows) {
+		ws := &workspaces[indices[i]]
+		ws.parsed.related = append(ws.parsed.related, errs...)
+	}
+
+	validateGroups := errgroup.Group{}
+	for i := range workspaces {
+		ws := &workspaces[i]
+		validateGroups.Go(func() error {
+			result, err := l.validate(ws.path, ws.source, ws.parsed, ws.project, proc, ws.actions, ws.reusable)
 			if err != nil {
 				return fmt.Errorf("occur error when check %s: %w", ws.path, err)
 			}
-			ws.source = source
 			ws.result = result
 			return nil
 		})
 	}
 
 	proc.Wait()
-	if err := errorGroups.Wait(); err != nil {
+	if err := validateGroups.Wait(); err != nil {
 		return nil, err
 	}
 
@@ -399,7 +485,7 @@ func (l *Linter) LintFiles(filepaths []string, project *Project) ([]*ValidateRes
 		templateFields := make([]*TemplateFields, 0, totalErrors)
 		for i := range workspaces {
 			ws := &workspaces[i]
-			for _, err := range ws.result.Errors {
+			for _, err := range l.formattedErrors(ws.result) {
 				templateFields = append(templateFields, err.ExtractTemplateFields(ws.source))
 			}
 			//allErrors = append(allErrors, ws.result.Errors...)
@@ -446,14 +532,14 @@ func (l *Linter) LintFile(file string, project *Project) (*ValidateResult, error
 	localActions := NewLocalActionsMetadataCache(project, l.debugWriter())
 	//todo: reusing-workflows.go
 	localReusableWorkflow := NewLocalReusableWorkflowCache(project, l.currentWorkingDirectory, l.debugWriter())
-	result, err := l.validate(file, source, project, proc, localActions, localReusableWorkflow)
+	result, err := l.validate(file, source, nil, project, proc, localActions, localReusableWorkflow)
 	proc.Wait()
 
 	if err != nil {
 		return nil, err
 	}
 	if l.errorFormatter != nil {
-		if err := l.errorFormatter.PrintErrors(l.errorOutput, result.Errors, source); err != nil {
+		if err := l.errorFormatter.PrintErrors(l.errorOutput, l.formattedErrors(result), source); err != nil {
 			return nil, fmt.Errorf("error formatting output: %w", err)
 		}
 	} else {
@@ -480,14 +566,14 @@ func (l *Linter) Lint(filepath string, content []byte, project *Project) (*Valid
 	proc := NewConcurrentExecutor(runtime.NumCPU())
 	localActions := NewLocalActionsMetadataCache(project, l.debugWriter())
 	localReusableWorkflow := NewLocalReusableWorkflowCache(project, l.currentWorkingDirectory, l.debugWriter())
-	result, err := l.validate(filepath, content, project, proc, localActions, localReusableWorkflow)
+	result, err := l.validate(filepath, content, nil, project, proc, localActions, localReusableWorkflow)
 	proc.Wait()
 	if err != nil {
 		return nil, err
 	}
 
 	if l.errorFormatter != nil {
-		if err := l.errorFormatter.PrintErrors(l.errorOutput, result.Errors, content); err != nil {
+		if err := l.errorFormatter.PrintErrors(l.errorOutput, l.formattedErrors(result), content); err != nil {
 			return nil, fmt.Errorf("error formatting output: %w", err)
 		}
 	} else {
@@ -496,13 +582,13 @@ func (l *Linter) Lint(filepath string, content []byte, project *Project) (*Valid
 	return result, nil
 }
 
-func makeRules(filePath string, localActions *LocalActionsMetadataCache, localReusableWorkflow *LocalReusableWorkflowCache) []Rule {
+func makeRules(filePath string, localActions *LocalActionsMetadataCache, localReusableWorkflow *LocalReusableWorkflowCache, actionLock *ActionLock, advisories *AdvisoryDatabase) []Rule {
 	return []Rule{
 		// MatrixRule(),
 		CredentialsRule(),
 		// EventsRule(),
 		JobNeedsRule(),
-		// ActionRule(localActions),
+		ActionRule(localActions),
 		EnvironmentVariableRule(),
 		IDRule(),
 		PermissionsRule(),
@@ -517,45 +603,221 @@ func makeRules(filePath string, localActions *LocalActionsMetadataCache, localRe
 		EnvVarInjectionMediumRule(),    // Detects envvar injection in normal workflow triggers
 		EnvPathInjectionCriticalRule(), // Detects PATH injection in privileged workflow triggers
 		EnvPathInjectionMediumRule(),   // Detects PATH injection in normal workflow triggers
-		CommitShaRule(),
+		CommitShaRuleWithLock(actionLock),
 		ArtifactPoisoningRule(),
 		NewArtifactPoisoningMediumRule(),
 		NewActionListRule(),
 		NewUntrustedCheckoutRule(),
 		NewCachePoisoningRule(),
 		NewCachePoisoningPoisonableStepRule(),
-		NewSecretExposureRule(),                  // Detects toJSON(secrets) and secrets[dynamic-access]
-		NewUnmaskedSecretExposureRule(),          // Detects fromJson(secrets.XXX).yyy unmasked exposure
-		NewImproperAccessControlRule(),           // Detects improper access control with label-based approval and synchronize events
-		NewUntrustedCheckoutTOCTOUCriticalRule(), // Detects TOCTOU with labeled event type and mutable refs
-		NewUntrustedCheckoutTOCTOUHighRule(),     // Detects TOCTOU with deployment environment and mutable refs
-		NewBotConditionsRule(),                   // Detects spoofable bot detection conditions
-		NewArtipackedRule(),                      // Detects credential leakage via artifact upload
-		NewUnsoundContainsRule(),                 // Detects bypassable contains() function usage in conditions
+		NewSecretExposureRule(),                       // Detects toJSON(secrets) and secrets[dynamic-access]
+		NewUnmaskedSecretExposureRule(),               // Detects fromJson(secrets.XXX).yyy unmasked exposure
+		NewImproperAccessControlRule(),                // Detects improper access control with label-based approval and synchronize events
+		NewUntrustedCheckoutTOCTOUCriticalRule(),      // Detects TOCTOU with labeled event type and mutable refs
+		NewUntrustedCheckoutTOCTOUHighRule(),          // Detects TOCTOU with deployment environment and mutable refs
+		NewBotConditionsRule(),                        // Detects spoofable bot detection conditions
+		NewArtipackedRule(),                           // Detects credential leakage via artifact upload
+		NewUnsoundContainsRule(),                      // Detects bypassable contains() function usage in conditions
+		NewRunnerLabelRule(),                          // Detects unknown or retired runner labels and self-hosted runners exposed to forks
+		NewActionAdvisoryRule(advisories, actionLock), // Detects actions affected by known security advisories
+		NewImageDigestRule(actionLock),                // Detects container images not pinned to a digest
+		NewActionMetadataRule(),                       // Detects invalid runs configuration in action metadata files
 	}
 }
 
+// actionMetadataRulesは、アクションのメタデータファイルに適用するルール
+// トリガーやジョブの設定を前提とするルールはアクションには意味がないので、ステップを検査するルールだけを適用する
+var actionMetadataRules = map[string]struct{}{
+	"action-metadata":            {},
+	"action":                     {},
+	"action-list":                {},
+	"action-advisory":            {},
+	"commit-sha":                 {},
+	"image-digest":               {},
+	"id":                         {},
+	"deprecated-commands":        {},
+	"secret-exposure":            {},
+	"unmasked-secret-exposure":   {},
+	"code-injection-critical":    {},
+	"code-injection-medium":      {},
+	"envvar-injection-critical":  {},
+	"envvar-injection-medium":    {},
+	"envpath-injection-critical": {},
+	"envpath-injection-medium":   {},
+}
+
+// buildRulesは、設定に従ってworkflowを検証するルールのリストを作成する
+// 設定で無効にされたルールは取り除かれ、オプションが設定されたルールにはオプションが適用される
+func (l *Linter) buildRules(
+	filePath string,
+	cfg *Config,
+	localActions *LocalActionsMetadataCache,
+	localReusableWorkflow *LocalReusableWorkflowCache,
+	actionLock *ActionLock,
+	trust *TriggerTrustModel,
+	proc *ConcurrentExecutor,
+) ([]Rule, error) {
+	all := makeRules(filePath, localActions, localReusableWorkflow, actionLock, l.advisories)
+	// shellcheckは外部コマンドなので、実行ファイルが見つかった場合だけ有効にする
+	// 自動修正のプレビューのようにprocがnilの場合は実行しない
+	if l.shellcheckExecutablePath != "" && proc != nil {
+		if r, err := NewShellcheckRule(l.shellcheckExecutablePath, proc); err == nil {
+			all = append(all, r)
+		} else {
+			l.log("rule for shellcheck was disabled:", err)
+		}
+	}
+	isAction := IsActionMetadataFile(filePath)
+	rules := make([]Rule, 0, len(all))
+	for _, rule := range all {
+		name := rule.RuleNames()
+		if _, ok := actionMetadataRules[name]; isAction && !ok {
+			continue
+		}
+		if !cfg.IsRuleEnabled(name) {
+			l.debug("rule %s is disabled by configuration", name)
+			continue
+		}
+		rule.UpdateTriggerTrust(trust)
+		if cfg != nil {
+			rule.UpdateConfig(cfg)
+			if opts := cfg.RuleOptions(name); opts != nil {
+				if r, ok := rule.(ConfigurableRule); ok {
+					if err := r.ConfigureOptions(opts); err != nil {
+						return nil, fmt.Errorf("invalid options for rule %q: %w", name, err)
+					}
+				}
+			}
+		}
+		rules = append(rules, rule)
+	}
+	if l.modifyCheckRules != nil {
+		rules = l.modifyCheckRules(rules)
+	}
+	return rules, nil
+}
+
+// configForPathは、filePathのworkflowに適用する設定を返す
+// overridesのglobはプロジェクトのルートからの相対パスと照合する
+func (l *Linter) configForPath(cfg *Config, filePath string, project *Project) *Config {
+	if cfg == nil || len(cfg.Overrides) == 0 {
+		return cfg
+	}
+	rel, _ := l.projectRelativePath(filePath, project)
+	return cfg.ForPath(rel)
+}
+
+// projectRelativePathは、filePathをプロジェクトのルートからの相対パスに変換する
+// プロジェクトの外のファイルの場合はfilePathをそのまま返し、falseを返す
+func (l *Linter) projectRelativePath(filePath string, project *Project) (string, bool) {
+	if project == nil {
+		return filePath, false
+	}
+	abs := filePath
+	if !filepath.IsAbs(abs) && l.currentWorkingDirectory != "" {
+		abs = filepath.Join(l.currentWorkingDirectory, abs)
+	}
+	if r, err := filepath.Rel(project.RootDirectory(), abs); err == nil && !strings.HasPrefix(r, "..") {
+		return r, true
+	}
+	return filePath, false
+}
+
+// triggerTrustは、ルールが問い合わせるトリガーの信頼度のモデルを作成する
+// trigger-trustセクションはoverridesで上書きされないので、どのworkflowでも同じ分類になる
+// 再利用可能なワークフローの呼び出し元をたどるために、モデルには検査するworkflowのパスと、overridesを解決する前の設定baseを渡す
+// ジョブの条件でトリガーを分類するために、パース済みのworkflowも渡す
+func (l *Linter) triggerTrust(base, cfg *Config, filePath string, project *Project, workflow *ast.Workflow) *TriggerTrustModel {
+	var tc *TriggerTrustConfig
+	if cfg != nil {
+		tc = &cfg.TriggerTrust
+	}
+	path := ""
+	if rel, ok := l.projectRelativePath(filePath, project); ok {
+		path = filepath.ToSlash(rel)
+	}
+	return NewTriggerTrustModel(tc, project).forWorkflow(path, base, workflow)
+}
+
 // ValidateResultは、workflowの検証結果を表す
 // この構造体は、Linter.validateメソッドの戻り値として使用される
 // FilePathは、検証されたファイルのパス
 // Sourceは、検証されたworkflowのソースコード
 // ParsedWorkflowは、検証されたworkflowの構文木
 // Errorsは、検証中に発生したエラーのリスト
+// Suppressedは、インライン抑制コメントによって抑制されたエラーのリスト
+// Baselinedは、ベースラインに記録されているため報告されなかったエラーのリスト
 // AutoFixersは、検証中に生成されたAutoFixerのリスト
 type ValidateResult struct {
 	FilePath       string
 	Source         []byte
 	ParsedWorkflow *ast.Workflow
 	Errors         []*LintingError
+	Suppressed     []*LintingError
+	Baselined      []*LintingError
 	AutoFixers     []AutoFixer
 	Repository     string
 }
 
+// parsedFileは、解析済みのworkflowのソース
+// relatedは、複数のworkflowにまたがる解析で見つかったこのworkflowのエラー
+type parsedFile struct {
+	workflow *ast.Workflow
+	errs     []*LintingError
+	related  []*LintingError
+}
+
+// parseは、workflowのソースを解析する
+func (l *Linter) parse(filePath string, content []byte) *parsedFile {
+	var start time.Time
+	if l.loggingLevel >= LogLevelDetailedOutput {
+		start = time.Now()
+	}
+	workflow, errs := parseFile(filePath, content)
+	if l.loggingLevel >= LogLevelDetailedOutput {
+		l.log("parsed workflow in", len(errs), time.Since(start).Milliseconds(), "ms", filePath)
+	}
+	return &parsedFile{workflow: workflow, errs: errs}
+}
+
+// projectConfigは、overridesを解決する前の設定を返す
+func (l *Linter) projectConfig(project *Project) *Config {
+	if l.defaultConfiguration != nil {
+		return l.defaultConfiguration
+	}
+	if project != nil {
+		return project.ProjectConfig()
+	}
+	return nil
+}
+
+// artifactWorkflowは、リポジトリ全体のアーティファクトポイズニングの解析に渡すworkflowを作成する
+// エラーメッセージとworkflow_runのworkflows:の照合には、プロジェクトのルートからの相対パスを使う
+func (l *Linter) artifactWorkflow(filePath string, workflow *ast.Workflow, project *Project) *artifactWorkflow {
+	p := filepath.ToSlash(filePath)
+	if rel, ok := l.projectRelativePath(filePath, project); ok {
+		p = filepath.ToSlash(rel)
+	}
+	name := p
+	if workflow.Name != nil && workflow.Name.Value != "" {
+		name = workflow.Name.Value
+	}
+	return &artifactWorkflow{
+		path:     p,
+		name:     name,
+		workflow: workflow,
+		cfg:      l.configForPath(l.projectConfig(project), filePath, project),
+	}
+}
+
+// validateは、workflowを検証する
+// parsedはLintFilesで解析済みのworkflowで、nilの場合はcontentを解析する
 func (l *Linter) validate(
 	filePath string,
 	content []byte,
+	parsed *parsedFile,
 	project *Project,
-	_ *ConcurrentExecutor, // proc parameter is unused
+	proc *ConcurrentExecutor,
 	localActions *LocalActionsMetadataCache,
 	localReusableWorkflow *LocalReusableWorkflowCache,
 ) (*ValidateResult, error) {
@@ -569,50 +831,27 @@ func (l *Linter) validate(
 		l.log("Detected project:", project.RootDirectory())
 	}
 
-	var cfg *Config
-	if l.defaultConfiguration != nil {
-		cfg = l.defaultConfiguration
-	} else if project != nil {
-		cfg = project.ProjectConfig()
-	}
+	cfg := l.projectConfig(project)
+	base := cfg
+	cfg = l.configForPath(cfg, filePath, project)
 	if cfg != nil {
 		l.debug("setting configuration: %#v", cfg)
 	} else {
 		l.debug("no configuration file")
 	}
 
-	parsedWorkflow, allErrors := Parse(content)
-
-	if l.loggingLevel >= LogLevelDetailedOutput {
-		elapsed := time.Since(validationStart)
-		l.log("parsed workflow in", len(allErrors), elapsed.Milliseconds(), "ms", filePath)
+	if parsed == nil {
+		parsed = l.parse(filePath, content)
 	}
+	parsedWorkflow := parsed.workflow
+	trust := l.triggerTrust(base, cfg, filePath, project, parsedWorkflow)
+	allErrors := parsed.errs
 
 	var allAutoFixers []AutoFixer
 
 	if parsedWorkflow != nil {
-		dbg := l.debugWriter()
-
-		rules := makeRules(filePath, localActions, localReusableWorkflow)
-
-		v := NewSyntaxTreeVisitor()
-		for _, rule := range rules {
-			v.AddVisitor(rule)
-		}
-
-		if dbg != nil {
-			v.EnableDebugOutput(dbg)
-			for _, rule := range rules {
-				rule.EnableDebugOutput(dbg)
-			}
-		}
-		if cfg != nil {
-			for _, rule := range rules {
-				rule.UpdateConfig(cfg)
-			}
-		}
-		if err := v.VisitTree(parsedWorkflow); err != nil {
-			l.debug("error occurred while visiting syntax tree: %v", err)
+		rules, err := l.runRules(filePath, parsedWorkflow, cfg, localActions, localReusableWorkflow, project.ActionLock(), trust, proc)
+		if err != nil {
 			return nil, err
 		}
 
@@ -628,7 +867,36 @@ func (l *Linter) validate(
 			for _, rule := range rules {
 				l.errorFormatter.RegisterRule(rule)
 			}
+			if l.errorFormatter.NeedsFixSuggestions() {
+				l.attachFixSuggestions(content, parsedWorkflow, allErrors, allAutoFixers)
+			}
+		}
+	}
+	allErrors = append(allErrors, parsed.related...)
+
+	for _, err := range allErrors {
+		err.FilePath = filePath
+		err.Context = findingContext(parsedWorkflow, err.LineNumber)
+		err.Fingerprint = errorFingerprint(err, content)
+	}
+
+	var suppressed []*LintingError
+	if parsedWorkflow != nil {
+		var unused []*LintingError
+		allErrors, suppressed, unused = applySuppressions(parsedWorkflow.Suppressions, allErrors)
+		for _, err := range unused {
+			err.FilePath = filePath
+			err.Fingerprint = errorFingerprint(err, content)
 		}
+		allErrors = append(allErrors, unused...)
+		allAutoFixers = filterRemovedAutoFixers(allAutoFixers, allErrors, suppressed)
+	}
+
+	// -ignoreや-min-severityで除外するエラーもベースラインのエントリに一致させ、古いエントリとして報告しないようにする
+	var baselined []*LintingError
+	if l.baseline != nil {
+		allErrors, baselined = l.baseline.filter(filePath, allErrors)
+		allAutoFixers = filterRemovedAutoFixers(allAutoFixers, allErrors, baselined)
 	}
 
 	l.filterAndLogErrors(filePath, &allErrors, &allAutoFixers, validationStart)
@@ -638,10 +906,82 @@ func (l *Linter) validate(
 		Source:         content,
 		ParsedWorkflow: parsedWorkflow,
 		Errors:         allErrors,
+		Suppressed:     suppressed,
+		Baselined:      baselined,
 		AutoFixers:     allAutoFixers,
 	}, nil
 }
 
+// runRulesは、構文木に対して全てのルールを実行し、実行したルールを返す
+func (l *Linter) runRules(
+	filePath string,
+	parsedWorkflow *ast.Workflow,
+	cfg *Config,
+	localActions *LocalActionsMetadataCache,
+	localReusableWorkflow *LocalReusableWorkflowCache,
+	actionLock *ActionLock,
+	trust *TriggerTrustModel,
+	proc *ConcurrentExecutor,
+) ([]Rule, error) {
+	dbg := l.debugWriter()
+
+	rules, err := l.buildRules(filePath, cfg, localActions, localReusableWorkflow, actionLock, trust, proc)
+	if err != nil {
+		return nil, err
+	}
+
+	v := NewSyntaxTreeVisitor()
+	for _, rule := range rules {
+		v.AddVisitor(rule)
+	}
+
+	if dbg != nil {
+		v.EnableDebugOutput(dbg)
+		for _, rule := range rules {
+			rule.EnableDebugOutput(dbg)
+		}
+	}
+	if err := v.VisitTree(parsedWorkflow); err != nil {
+		l.debug("error occurred while visiting syntax tree: %v", err)
+		return nil, err
+	}
+	return rules, nil
+}
+
+// attachFixSuggestionsは、AutoFixerを持つルールのエラーに、そのエラーを修正するAutoFixerの修正内容を添付する
+// AutoFixerを1つずつ構文木に適用して元のソースに対する編集を求め、すぐに取り消すため、構文木は変更されない
+func (l *Linter) attachFixSuggestions(content []byte, workflow *ast.Workflow, errs []*LintingError, fixers []AutoFixer) {
+	src := newSourceText(content)
+	edits := make(map[AutoFixer][]*TextEdit, len(fixers))
+	for _, fixer := range fixers {
+		if needsNetwork(fixer) {
+			continue
+		}
+		snapshot := snapshotNode(src, workflow)
+		if err := fixer.Fix(); err != nil {
+			l.debug("failed to preview fix of %s: %v", fixer.RuleName(), err)
+		} else if e, err := snapshot.edits(fixer.RuleName()); err != nil {
+			l.debug("failed to preview fix of %s: %v", fixer.RuleName(), err)
+		} else {
+			edits[fixer] = e
+		}
+		snapshot.restore()
+	}
+
+	for err, fs := range errorFixers(errs, fixers) {
+		patch := NewSourcePatch(content)
+		for _, f := range fs {
+			if e := patch.Add(edits[f]); e != nil {
+				l.debug("failed to preview fix of %s: %v", f.RuleName(), e)
+			}
+		}
+		if len(patch.Edits()) == 0 {
+			continue
+		}
+		err.Fix = NewFixSuggestion(err.Type, content, patch.Apply())
+	}
+}
+
 func (l *Linter) filterAndLogErrors(filePath string, allErrors *[]*LintingError, allAutoFixers *[]AutoFixer, validationStart time.Time) {
 	if len(l.errorIgnorePatterns) > 0 {
 		filtered := make([]*LintingError, 0, len(*allErrors))
@@ -673,6 +1013,15 @@ func (l *Linter) filterAndLogErrors(filePath string, allErrors *[]*LintingError,
 		}
 		*allAutoFixers = filteredAutoFixers
 	}
+	if l.minSeverity > SeverityNone {
+		filtered := make([]*LintingError, 0, len(*allErrors))
+		for _, err := range *allErrors {
+			if err.severity() >= l.minSeverity {
+				filtered = append(filtered, err)
+			}
+		}
+		*allErrors = filtered
+	}
 	for _, err := range *allErrors {
 		err.FilePath = filePath
 	}
@@ -685,6 +1034,28 @@ func (l *Linter) filterAndLogErrors(filePath string, allErrors *[]*LintingError,
 	}
 }
 
+// StaleBaselineEntriesは、ベースラインのエントリのうち、検証したファイルのどのエラーにも一致しなかったエントリを返す
+// ベースラインが指定されていない場合はnilを返す
+func (l *Linter) StaleBaselineEntries() []*BaselineEntry {
+	if l.baseline == nil {
+		return nil
+	}
+	return l.baseline.StaleEntries()
+}
+
+// formattedErrorsは、エラーフォーマッターで出力するエラーを返す
+// フォーマットが抑制されたエラーを扱う場合は、抑制されたエラーも位置順に含める
+func (l *Linter) formattedErrors(result *ValidateResult) []*LintingError {
+	if len(result.Suppressed) == 0 || !l.errorFormatter.IncludesSuppressed() {
+		return result.Errors
+	}
+	errs := make([]*LintingError, 0, len(result.Errors)+len(result.Suppressed))
+	errs = append(errs, result.Errors...)
+	errs = append(errs, result.Suppressed...)
+	sort.Stable(ByRuleErrorPosition(errs))
+	return errs
+}
+
 // displayErrorsは、指定されたエラーを出力する
 func (l *Linter) displayErrors(errors []*LintingError, source []byte) {
 	for _, err := range errors {
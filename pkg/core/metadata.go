@@ -15,7 +15,9 @@ import (
 // *https://docs.github.com/en/actions/creating-actions/metadata-syntax-for-github-actions#inputs
 type ActionInputMetadata struct {
 	Name     string `json:"name"`
-	Required bool   `json:"required"`
+	Required bool   `json:"required,omitempty"`
+	// Deprecatedは入力のdeprecationMessage。空文字列の場合は非推奨ではない
+	Deprecated string `json:"deprecated,omitempty"`
 }
 
 // actionの入力メタデータのマップ
@@ -28,8 +30,9 @@ func (inputs *ActionInputsMetadata) UnmarshalYAML(n *yaml.Node) error {
 	}
 
 	type TempInputMetadata struct {
-		Required bool    `yaml:"required"`
-		Default  *string `yaml:"default"`
+		Required           bool    `yaml:"required"`
+		Default            *string `yaml:"default"`
+		DeprecationMessage string  `yaml:"deprecationMessage"`
 	}
 
 	md := make(ActionInputsMetadata, len(n.Content)/2)
@@ -45,7 +48,8 @@ func (inputs *ActionInputsMetadata) UnmarshalYAML(n *yaml.Node) error {
 		if _, ok := md[id]; ok {
 			return fmt.Errorf("duplicate input %q", name)
 		}
-		md[id] = &ActionInputMetadata{name, m.Required || m.Default != nil}
+		// デフォルト値がある入力は、required: trueでも省略できる
+		md[id] = &ActionInputMetadata{name, m.Required && m.Default == nil, m.DeprecationMessage}
 	}
 	*inputs = md
 	return nil
@@ -83,10 +87,27 @@ func (outputs *ActionOutputsMetadata) UnmarshalYAML(n *yaml.Node) error {
 // *https://docs.github.com/en/actions/creating-actions/metadata-syntax-for-github-actions
 type ActionMetadata struct {
 	Name        string                `yaml:"name" json:"name"`
-	Inputs      ActionInputsMetadata  `yaml:"inputs" json:"inputs"`
-	Outputs     ActionOutputsMetadata `yaml:"outputs" json:"outputs"`
-	SkipInputs  bool                  `json:"skip_inputs"`
-	SkipOutputs bool                  `json:"skip_outputs"`
+	Inputs      ActionInputsMetadata  `yaml:"inputs" json:"inputs,omitempty"`
+	Outputs     ActionOutputsMetadata `yaml:"outputs" json:"outputs,omitempty"`
+	SkipInputs  bool                  `yaml:"-" json:"skip_inputs,omitempty"`
+	SkipOutputs bool                  `yaml:"-" json:"skip_outputs,omitempty"`
+	// Runsはアクションの実行環境。using:はnode20、docker、compositeなど
+	Runs ActionRunsMetadata `yaml:"runs" json:"runs"`
+	// Deprecatedはアクション自体が非推奨になっている理由。空文字列の場合は非推奨ではない
+	Deprecated string `yaml:"-" json:"deprecated,omitempty"`
+}
+
+// GitHub Actionsの実行環境のメタデータ構造体 : runs
+// *https://docs.github.com/en/actions/creating-actions/metadata-syntax-for-github-actions#runs
+type ActionRunsMetadata struct {
+	Using string `yaml:"using" json:"using"`
+}
+
+// deprecatedActionRuntimesは、GitHub-hostedランナーでサポートが終了したアクションの実行環境
+// * https://github.blog/changelog/2023-09-22-github-actions-transitioning-from-node-16-to-node-20/
+var deprecatedActionRuntimes = map[string]struct{}{
+	"node12": {},
+	"node16": {},
 }
 
 // ローカルアクションのメタデータキャッシュ構造体
@@ -136,8 +157,13 @@ func (c *LocalActionsMetadataCache) writeCache(key string, val *ActionMetadata)
 // LocalActionCacheは、アクションが見つからなかったことをキャッシュします。最初の検索時には、
 // アクションが見つからなかったというエラーを返します。しかし、2回目の検索では、結果がnilであってもエラーは返されません。
 // この振る舞いは、同じエラーを複数の場所から繰り返し報告するのを防ぐためです。
+//
+// "./"で始まらないリモートのアクションは、埋め込みのよく使われるアクションのメタデータから探す
 func (c *LocalActionsMetadataCache) FindMetadata(spec string) (*ActionMetadata, error) {
-	if c.proj == nil || !strings.HasPrefix(spec, "./") {
+	if !strings.HasPrefix(spec, "./") {
+		return findPopularAction(spec), nil
+	}
+	if c == nil || c.proj == nil {
 		return nil, nil
 	}
 
@@ -0,0 +1,885 @@
+package core
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/sisaku-security/sisakulint/pkg/ast"
+	"gopkg.in/yaml.v3"
+)
+
+// sourceTextはyaml.Nodeの位置をバイトオフセットに変換するためのソース
+type sourceText struct {
+	src []byte
+	// linesは各行の先頭のバイトオフセット
+	lines []int
+}
+
+func newSourceText(src []byte) *sourceText {
+	lines := []int{0}
+	for i, b := range src {
+		if b == '\n' {
+			lines = append(lines, i+1)
+		}
+	}
+	return &sourceText{src: src, lines: lines}
+}
+
+// offsetはyaml.Nodeの行と列(どちらも1から始まり、列は文字単位)をバイトオフセットに変換する
+func (s *sourceText) offset(line, col int) int {
+	if line < 1 || line > len(s.lines) {
+		return -1
+	}
+	off := s.lines[line-1]
+	for c := 1; c < col && off < len(s.src) && s.src[off] != '\n'; c++ {
+		_, size := utf8.DecodeRune(s.src[off:])
+		off += size
+	}
+	return off
+}
+
+// lineStartはoffを含む行の先頭のオフセットを返す
+func (s *sourceText) lineStart(off int) int {
+	return bytes.LastIndexByte(s.src[:off], '\n') + 1
+}
+
+// lineEndはoffを含む行の改行文字のオフセットを返す。最後の行に改行がない場合はソースの長さを返す
+func (s *sourceText) lineEnd(off int) int {
+	if i := bytes.IndexByte(s.src[off:], '\n'); i >= 0 {
+		return off + i
+	}
+	return len(s.src)
+}
+
+// nextLineはoffを含む行の次の行の先頭のオフセットを返す
+func (s *sourceText) nextLine(off int) int {
+	end := s.lineEnd(off)
+	if end < len(s.src) {
+		return end + 1
+	}
+	return end
+}
+
+// atLineStartはoffより前に同じ行に空白しかないかを返す
+func (s *sourceText) atLineStart(off int) bool {
+	return len(bytes.TrimLeft(s.src[s.lineStart(off):off], " \t")) == 0
+}
+
+// columnはoffの行の先頭からの文字数を返す
+func (s *sourceText) column(off int) int {
+	return utf8.RuneCount(s.src[s.lineStart(off):off])
+}
+
+// plainScalarEndはプレーンスカラーの終わりのオフセットを返す
+// 複数行にわたるスカラーは、値に含まれる単語の数だけ行を読み進める
+func (s *sourceText) plainScalarEnd(start int, value string, inFlow bool) int {
+	words := len(strings.Fields(value))
+	end := start
+	for off := start; ; {
+		le := s.lineEnd(off)
+		stop := le
+	scan:
+		for i := off; i < le; i++ {
+			switch c := s.src[i]; c {
+			case '#':
+				if i > off && (s.src[i-1] == ' ' || s.src[i-1] == '\t') {
+					stop = i
+					break scan
+				}
+			case ':':
+				if i+1 >= le || s.src[i+1] == ' ' || s.src[i+1] == '\t' || (inFlow && strings.IndexByte(",[]{}", s.src[i+1]) >= 0) {
+					stop = i
+					break scan
+				}
+			case ',', '[', ']', '{', '}':
+				if inFlow {
+					stop = i
+					break scan
+				}
+			}
+		}
+		text := bytes.TrimRight(s.src[off:stop], " \t")
+		if len(bytes.TrimSpace(text)) > 0 {
+			end = off + len(text)
+		}
+		words -= len(bytes.Fields(text))
+		if words <= 0 || stop < le || le >= len(s.src) {
+			return end
+		}
+		off = le + 1
+	}
+}
+
+// quotedScalarEndはクォートされたスカラーの閉じクォートの次のオフセットを返す
+func (s *sourceText) quotedScalarEnd(start int, quote byte) int {
+	for i := start + 1; i < len(s.src); i++ {
+		switch s.src[i] {
+		case '\\':
+			if quote == '"' {
+				i++
+			}
+		case quote:
+			if quote == '\'' && i+1 < len(s.src) && s.src[i+1] == '\'' {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(s.src)
+}
+
+// blockHeaderEndはブロックスカラーのインジケーター("|-"など)の終わりのオフセットを返す
+func (s *sourceText) blockHeaderEnd(start int) int {
+	end := start
+	for end < len(s.src) && strings.IndexByte(" \t\n#", s.src[end]) < 0 {
+		end++
+	}
+	return end
+}
+
+// blockScalarEndはブロックスカラーの最後の空でない行の終わりのオフセットを返す
+// 内容の行は親のインデントindentより深くインデントされている
+func (s *sourceText) blockScalarEnd(start, indent int) int {
+	end := s.blockHeaderEnd(start)
+	content := -1
+	for off := s.nextLine(start); off < len(s.src); off = s.nextLine(off) {
+		le := s.lineEnd(off)
+		line := s.src[off:le]
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		ind := len(line) - len(bytes.TrimLeft(line, " "))
+		if content < 0 {
+			if ind <= indent {
+				break
+			}
+			content = ind
+		} else if ind < content {
+			break
+		}
+		end = le
+	}
+	return end
+}
+
+// blockContentIndentはブロックスカラーの内容のインデントを返す。内容が空の場合は-1を返す
+func (s *sourceText) blockContentIndent(start int) int {
+	for off := s.nextLine(start); off < len(s.src); off = s.nextLine(off) {
+		line := s.src[off:s.lineEnd(off)]
+		if len(bytes.TrimSpace(line)) > 0 {
+			return len(line) - len(bytes.TrimLeft(line, " "))
+		}
+	}
+	return -1
+}
+
+// flowCollectionEndはフローコレクションの閉じ括弧の次のオフセットを返す
+func (s *sourceText) flowCollectionEnd(start int) int {
+	depth := 0
+	for i := start; i < len(s.src); i++ {
+		switch c := s.src[i]; c {
+		case '"', '\'':
+			i = s.quotedScalarEnd(i, c) - 1
+		case '#':
+			if i > 0 && (s.src[i-1] == ' ' || s.src[i-1] == '\t') {
+				i = s.lineEnd(i)
+			}
+		case '[', '{':
+			depth++
+		case ']', '}':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return len(s.src)
+}
+
+// nodeStateはAutoFixerを適用する前のノードの状態
+type nodeState struct {
+	kind        yaml.Kind
+	style       yaml.Style
+	tag         string
+	value       string
+	anchor      string
+	alias       *yaml.Node
+	headComment string
+	lineComment string
+	footComment string
+	content     []*yaml.Node
+
+	// startとendは元のソースでのノードの範囲。AutoFixerが追加したノードのように元のソースにない場合は-1
+	start, end int
+	// indentはノードを含むブロックコレクションのエントリのインデント
+	indent int
+	inFlow bool
+}
+
+// nodeSnapshotはAutoFixerを適用する前のyaml.Nodeの木の状態
+// AutoFixerはノードのポインタを保持して直接変更するため、木をコピーする代わりに各ノードの状態を記録する
+// AutoFixerはyaml.Nodeと一緒に構文木も書き換え、後のAutoFixerは構文木の値を読むため、修正を取り消す時に戻せるよう
+// 構文木の各構造体、マップ、スライスの中身も記録する
+type nodeSnapshot struct {
+	src    *sourceText
+	root   *yaml.Node
+	states map[*yaml.Node]*nodeState
+	tree   []savedValue
+}
+
+// savedValueは構文木のポインタの指す値、マップ、スライスの中身の記録
+type savedValue struct {
+	target reflect.Value
+	saved  reflect.Value
+	// keysはマップのキー
+	keys []reflect.Value
+}
+
+func snapshotNode(src *sourceText, w *ast.Workflow) *nodeSnapshot {
+	s := &nodeSnapshot{src: src, states: map[*yaml.Node]*nodeState{}}
+	if w == nil {
+		return s
+	}
+	s.root = w.BaseNode
+	if s.root != nil {
+		s.record(s.root, -1, false)
+	}
+	s.recordTree(reflect.ValueOf(w), map[uintptr]bool{})
+	return s
+}
+
+var yamlNodeType = reflect.TypeOf(yaml.Node{})
+
+// recordTreeは構文木をたどり、ポインタの指す値とマップ、スライスの中身を記録する
+// yaml.Nodeはrecordで記録するのでたどらない
+func (s *nodeSnapshot) recordTree(v reflect.Value, visited map[uintptr]bool) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() || v.Type().Elem() == yamlNodeType || visited[v.Pointer()] {
+			return
+		}
+		visited[v.Pointer()] = true
+		saved := reflect.New(v.Type().Elem()).Elem()
+		saved.Set(v.Elem())
+		s.tree = append(s.tree, savedValue{target: v.Elem(), saved: saved})
+		s.recordTree(v.Elem(), visited)
+	case reflect.Interface:
+		if !v.IsNil() {
+			s.recordTree(v.Elem(), visited)
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				s.recordTree(v.Field(i), visited)
+			}
+		}
+	case reflect.Slice:
+		if v.IsNil() {
+			return
+		}
+		saved := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		reflect.Copy(saved, v)
+		s.tree = append(s.tree, savedValue{target: v, saved: saved})
+		for i := 0; i < v.Len(); i++ {
+			s.recordTree(v.Index(i), visited)
+		}
+	case reflect.Map:
+		if v.IsNil() || visited[v.Pointer()] {
+			return
+		}
+		visited[v.Pointer()] = true
+		keys := v.MapKeys()
+		saved := reflect.MakeMapWithSize(v.Type(), len(keys))
+		for _, k := range keys {
+			saved.SetMapIndex(k, v.MapIndex(k))
+		}
+		s.tree = append(s.tree, savedValue{target: v, saved: saved, keys: keys})
+		for _, k := range keys {
+			s.recordTree(v.MapIndex(k), visited)
+		}
+	}
+}
+
+func (s *nodeSnapshot) record(n *yaml.Node, indent int, inFlow bool) *nodeState {
+	st := &nodeState{
+		kind:        n.Kind,
+		style:       n.Style,
+		tag:         n.Tag,
+		value:       n.Value,
+		anchor:      n.Anchor,
+		alias:       n.Alias,
+		headComment: n.HeadComment,
+		lineComment: n.LineComment,
+		footComment: n.FootComment,
+		content:     append([]*yaml.Node(nil), n.Content...),
+		start:       -1,
+		end:         -1,
+		indent:      indent,
+		inFlow:      inFlow,
+	}
+	s.states[n] = st
+
+	flow := inFlow || (isCollection(n.Kind) && n.Style&yaml.FlowStyle != 0)
+	childIndent := indent
+	if isCollection(n.Kind) && !flow && n.Line > 0 {
+		childIndent = n.Column - 1
+	}
+	children := make([]*nodeState, 0, len(n.Content))
+	for _, c := range n.Content {
+		children = append(children, s.record(c, childIndent, flow))
+	}
+
+	if n.Kind == yaml.DocumentNode {
+		st.start, st.end = 0, len(s.src.src)
+		return st
+	}
+	if n.Line <= 0 {
+		return st
+	}
+	start := s.src.offset(n.Line, n.Column)
+	if start < 0 {
+		return st
+	}
+	st.start = start
+	switch n.Kind {
+	case yaml.ScalarNode:
+		switch {
+		case n.Style&yaml.DoubleQuotedStyle != 0:
+			st.end = s.src.quotedScalarEnd(start, '"')
+		case n.Style&yaml.SingleQuotedStyle != 0:
+			st.end = s.src.quotedScalarEnd(start, '\'')
+		case n.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0:
+			st.end = s.src.blockScalarEnd(start, indent)
+		case n.Value == "":
+			// "with:"のような空の値は、コロンの直後の長さ0の範囲になる
+			st.end = start
+		default:
+			st.end = s.src.plainScalarEnd(start, n.Value, inFlow)
+		}
+	case yaml.AliasNode:
+		st.end = start + 1 + len(n.Value)
+	default:
+		if flow {
+			st.end = s.src.flowCollectionEnd(start)
+			break
+		}
+		for _, c := range children {
+			if c.end > st.end {
+				st.end = c.end
+			}
+		}
+	}
+	return st
+}
+
+// restoreはスナップショットを取った時の状態にノードを戻す
+func (s *nodeSnapshot) restore() {
+	for n, st := range s.states {
+		n.Kind = st.kind
+		n.Style = st.style
+		n.Tag = st.tag
+		n.Value = st.value
+		n.Anchor = st.anchor
+		n.Alias = st.alias
+		n.HeadComment = st.headComment
+		n.LineComment = st.lineComment
+		n.FootComment = st.footComment
+		n.Content = append([]*yaml.Node(nil), st.content...)
+	}
+	for _, v := range s.tree {
+		switch v.target.Kind() {
+		case reflect.Slice:
+			reflect.Copy(v.target, v.saved)
+		case reflect.Map:
+			v.target.Clear()
+			for _, k := range v.keys {
+				v.target.SetMapIndex(k, v.saved.MapIndex(k))
+			}
+		default:
+			v.target.Set(v.saved)
+		}
+	}
+}
+
+// editsはスナップショットを取ってからのノードの変更を、元のソースに対する編集に変換する
+func (s *nodeSnapshot) edits(ruleName string) ([]*TextEdit, error) {
+	if s.root == nil {
+		return nil, nil
+	}
+	d := &nodeDiff{snap: s, src: s.src, ruleName: ruleName}
+	replace, err := d.node(s.root)
+	if err != nil {
+		return nil, err
+	}
+	if replace {
+		// ドキュメント全体が置き換えられた場合だけは、ファイル全体をエンコードし直す
+		data, err := EncodeWorkflowNode(s.root)
+		if err != nil {
+			return nil, err
+		}
+		d.edits = []*TextEdit{{Start: 0, End: len(s.src.src), NewText: string(data), RuleName: ruleName}}
+	}
+	return d.edits, nil
+}
+
+// nodeDiffは1つのAutoFixerによるノードの変更をテキストの編集に変換する
+type nodeDiff struct {
+	snap     *nodeSnapshot
+	src      *sourceText
+	ruleName string
+	edits    []*TextEdit
+}
+
+func (d *nodeDiff) add(start, end int, text string) {
+	d.edits = append(d.edits, &TextEdit{Start: start, End: end, NewText: text, RuleName: d.ruleName})
+}
+
+// outsideは元のソースに位置を持たないノード(別のAutoFixerが追加したノードなど)を変更しようとしたことを表すエラーを返す
+func (d *nodeDiff) outside(line int) error {
+	return &FixConflictError{RuleName: d.ruleName, Line: line}
+}
+
+func isCollection(kind yaml.Kind) bool {
+	return kind == yaml.MappingNode || kind == yaml.SequenceNode
+}
+
+func isBlockCollection(n *yaml.Node) bool {
+	return isCollection(n.Kind) && n.Style&yaml.FlowStyle == 0 && len(n.Content) > 0
+}
+
+func sameNodes(a, b []*yaml.Node) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// nodeは既存のノードnの変更を編集に変換する
+// ノードの種類が変わった場合など、親がnをまるごと置き換える必要がある場合はtrueを返す
+func (d *nodeDiff) node(n *yaml.Node) (bool, error) {
+	st := d.snap.states[n]
+	if st == nil || n.Kind != st.kind {
+		return true, nil
+	}
+	switch n.Kind {
+	case yaml.DocumentNode:
+		if !sameNodes(n.Content, st.content) {
+			return true, nil
+		}
+		for _, c := range n.Content {
+			if replace, err := d.node(c); err != nil || replace {
+				return replace, err
+			}
+		}
+	case yaml.ScalarNode:
+		return false, d.scalar(n, st)
+	case yaml.AliasNode:
+		return n.Value != st.value || n.Alias != st.alias, nil
+	case yaml.MappingNode, yaml.SequenceNode:
+		if (n.Style&yaml.FlowStyle == 0) != (st.style&yaml.FlowStyle == 0) {
+			return true, nil
+		}
+		if st.inFlow || st.style&yaml.FlowStyle != 0 {
+			// フローコレクションは要素の増減があれば全体を置き換える
+			if !sameNodes(n.Content, st.content) {
+				return true, nil
+			}
+			for _, c := range n.Content {
+				if replace, err := d.node(c); err != nil || replace {
+					return replace, err
+				}
+			}
+			return false, nil
+		}
+		return d.collection(n, st)
+	}
+	return false, nil
+}
+
+// scalarはスカラーの値とその行のコメントの変更を編集に変換する
+func (d *nodeDiff) scalar(n *yaml.Node, st *nodeState) error {
+	if n.Value != st.value || n.Style != st.style {
+		if st.end < 0 {
+			return d.outside(n.Line)
+		}
+		text, err := d.renderScalar(n, st)
+		if err != nil {
+			return err
+		}
+		if st.start == st.end && st.start > 0 && d.src.src[st.start-1] == ':' {
+			text = " " + text
+		}
+		d.add(st.start, st.end, text)
+	}
+
+	if n.LineComment != st.lineComment {
+		if st.end < 0 {
+			return d.outside(n.Line)
+		}
+		// ブロックスカラーのコメントはインジケーターの後ろにある
+		from := st.end
+		if st.style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 {
+			from = d.src.blockHeaderEnd(st.start)
+		}
+		end := from
+		i := from
+		for i < len(d.src.src) && (d.src.src[i] == ' ' || d.src.src[i] == '\t') {
+			i++
+		}
+		if i < len(d.src.src) && d.src.src[i] == '#' {
+			end = d.src.lineEnd(i)
+		}
+		text := ""
+		if n.LineComment != "" {
+			text = " " + formatLineComment(n.LineComment)
+		}
+		d.add(from, end, text)
+	}
+	return nil
+}
+
+func formatLineComment(comment string) string {
+	if strings.HasPrefix(comment, "#") {
+		return comment
+	}
+	return "# " + comment
+}
+
+func (d *nodeDiff) renderScalar(n *yaml.Node, st *nodeState) (string, error) {
+	c := *n
+	if st.inFlow && c.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 {
+		c.Style = yaml.DoubleQuotedStyle
+	}
+	const block = yaml.LiteralStyle | yaml.FoldedStyle
+	indent := st.indent
+	if st.style&block != 0 && c.Style&block != 0 {
+		// 元のブロックスカラーの内容のインデントを保つ
+		if ci := d.src.blockContentIndent(st.start); ci > st.indent && ci >= 2 {
+			indent = ci - 2
+		}
+	}
+	text, err := renderNode(&c, indent, true)
+	if err != nil {
+		return "", err
+	}
+	if first, _, _ := strings.Cut(text, "\n"); strings.ContainsAny(first, "123456789") && c.Style&block != 0 && indent != st.indent {
+		// インデントのインジケーターは親のインデントからの相対値なので、インデントを変えられない
+		if text, err = renderNode(&c, st.indent, true); err != nil {
+			return "", err
+		}
+	}
+	if st.inFlow && c.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle) == 0 && strings.ContainsAny(text, ",[]{}") {
+		c.Style = yaml.DoubleQuotedStyle
+		return renderNode(&c, 0, true)
+	}
+	return text, nil
+}
+
+// renderNodeはノードをYAMLとしてエンコードし、2行目以降の空でない行の先頭にindent個の空白を加える
+// stripOuterがtrueの場合、置き換える範囲の外にあるノード自身のコメントと最初の子のヘッドコメントは出力しない
+func renderNode(n *yaml.Node, indent int, stripOuter bool) (string, error) {
+	if stripOuter {
+		c := *n
+		c.HeadComment, c.FootComment = "", ""
+		if c.Kind == yaml.ScalarNode {
+			c.LineComment = ""
+		}
+		if len(c.Content) > 0 {
+			first := *c.Content[0]
+			first.HeadComment = ""
+			c.Content = append([]*yaml.Node{&first}, c.Content[1:]...)
+		}
+		n = &c
+	}
+	data, err := EncodeWorkflowNode(n)
+	if err != nil {
+		return "", err
+	}
+	text := strings.TrimSuffix(string(data), "\n")
+	if indent <= 0 {
+		return text, nil
+	}
+	pad := strings.Repeat(" ", indent)
+	lines := strings.Split(text, "\n")
+	for i := 1; i < len(lines); i++ {
+		if lines[i] != "" {
+			lines[i] = pad + lines[i]
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// entrySpanはブロックコレクションのエントリ(マッピングのキーと値のペア、またはシーケンスの要素)の元のソースでの範囲
+// シーケンスの要素の範囲は"-"から始まる
+type entrySpan struct {
+	start, end int
+}
+
+// entrySpansはスナップショットを取った時のコレクションの各エントリの範囲を、エントリの最初のノードをキーにして返す
+func (d *nodeDiff) entrySpans(st *nodeState) map[*yaml.Node]entrySpan {
+	spans := map[*yaml.Node]entrySpan{}
+	if st.kind == yaml.MappingNode {
+		for i := 0; i+1 < len(st.content); i += 2 {
+			k, v := d.snap.states[st.content[i]], d.snap.states[st.content[i+1]]
+			if k.start < 0 || k.end < 0 || v.end < 0 {
+				continue
+			}
+			spans[st.content[i]] = entrySpan{k.start, max(k.end, v.end)}
+		}
+		return spans
+	}
+	for _, item := range st.content {
+		s := d.snap.states[item]
+		if s.start < 0 || s.end < 0 {
+			continue
+		}
+		dash := s.start - 1
+		for dash >= 0 && (d.src.src[dash] == ' ' || d.src.src[dash] == '\t') {
+			dash--
+		}
+		if dash < 0 || d.src.src[dash] != '-' {
+			continue
+		}
+		spans[item] = entrySpan{dash, s.end}
+	}
+	return spans
+}
+
+// collectionはブロックコレクションのエントリの追加、削除、変更を編集に変換する
+func (d *nodeDiff) collection(n *yaml.Node, st *nodeState) (bool, error) {
+	stride := 1
+	if n.Kind == yaml.MappingNode {
+		stride = 2
+	}
+	old := map[*yaml.Node]*yaml.Node{}
+	for i := 0; i+stride-1 < len(st.content); i += stride {
+		old[st.content[i]] = st.content[i+stride-1]
+	}
+	current := map[*yaml.Node]bool{}
+	kept := 0
+	for i := 0; i+stride-1 < len(n.Content); i += stride {
+		current[n.Content[i]] = true
+		if _, ok := old[n.Content[i]]; ok {
+			kept++
+		}
+	}
+	if kept == 0 {
+		// 元のエントリが1つも残っていない場合はコレクション全体を置き換える
+		return len(st.content) > 0 || len(n.Content) > 0, nil
+	}
+
+	spans := d.entrySpans(st)
+	if replace, err := d.removeEntries(st, stride, spans, current); err != nil || replace {
+		return replace, err
+	}
+
+	for i := 0; i+stride-1 < len(n.Content); i += stride {
+		entry := n.Content[i]
+		prev, ok := old[entry]
+		if !ok {
+			continue
+		}
+		replace, err := d.node(entry)
+		if err != nil {
+			return false, err
+		}
+		if n.Kind == yaml.SequenceNode {
+			if replace {
+				if err := d.replaceItem(entry); err != nil {
+					return false, err
+				}
+			}
+			continue
+		}
+		if replace {
+			// キーが変わった場合はマッピング全体を置き換える
+			return true, nil
+		}
+		value := n.Content[i+1]
+		if value != prev {
+			if err := d.replaceValue(n, entry, prev, value); err != nil {
+				return false, err
+			}
+			continue
+		}
+		if replace, err = d.node(value); err != nil {
+			return false, err
+		}
+		if replace {
+			if err := d.replaceValue(n, entry, value, value); err != nil {
+				return false, err
+			}
+		}
+	}
+
+	return false, d.insertEntries(n, st, stride, spans, old)
+}
+
+// removeEntriesは削除されたエントリの行を削除する編集を追加する
+// 連続して削除されたエントリは1つの編集にまとめる
+func (d *nodeDiff) removeEntries(st *nodeState, stride int, spans map[*yaml.Node]entrySpan, current map[*yaml.Node]bool) (bool, error) {
+	for i := 0; i < len(st.content); i += stride {
+		if current[st.content[i]] {
+			continue
+		}
+		j := i
+		end := -1
+		for ; j < len(st.content) && !current[st.content[j]]; j += stride {
+			span, ok := spans[st.content[j]]
+			if !ok {
+				return false, d.outside(st.content[j].Line)
+			}
+			end = max(end, span.end)
+		}
+		start := spans[st.content[i]].start
+		if d.src.atLineStart(start) {
+			// 行全体と、最後のエントリの行末のコメントを削除する
+			d.add(d.src.lineStart(start), d.src.nextLine(end), "")
+		} else {
+			// "- "の後ろから始まるエントリは、次のエントリをその位置に詰める
+			next := -1
+			for k := j; k < len(st.content); k += stride {
+				if span, ok := spans[st.content[k]]; ok {
+					next = span.start
+					break
+				}
+			}
+			if next < 0 {
+				return true, nil
+			}
+			d.add(start, next, "")
+		}
+		i = j - stride
+	}
+	return false, nil
+}
+
+// insertEntriesは追加されたエントリを、直前の既存のエントリの次の行に挿入する
+// 直前に既存のエントリがない場合は、直後の既存のエントリの前に挿入する
+func (d *nodeDiff) insertEntries(n *yaml.Node, st *nodeState, stride int, spans map[*yaml.Node]entrySpan, old map[*yaml.Node]*yaml.Node) error {
+	indent := n.Column - 1
+	for i := 0; i < len(n.Content); i += stride {
+		if _, ok := old[n.Content[i]]; ok {
+			continue
+		}
+		j := i
+		for j < len(n.Content) {
+			if _, ok := old[n.Content[j]]; ok {
+				break
+			}
+			j += stride
+		}
+		group := &yaml.Node{Kind: n.Kind, Content: n.Content[i:min(j, len(n.Content))]}
+		text, err := renderNode(group, indent, false)
+		if err != nil {
+			return err
+		}
+		text = strings.Repeat(" ", indent) + text
+
+		prevEnd := -1
+		for k := i - stride; k >= 0; k -= stride {
+			if span, ok := spans[n.Content[k]]; ok {
+				prevEnd = span.end
+				break
+			}
+		}
+		if prevEnd >= 0 {
+			off := d.src.nextLine(prevEnd)
+			if off == len(d.src.src) && (off == 0 || d.src.src[off-1] != '\n') {
+				d.add(off, off, "\n"+text)
+			} else {
+				d.add(off, off, text+"\n")
+			}
+			i = j - stride
+			continue
+		}
+
+		next := -1
+		for k := j; k < len(n.Content); k += stride {
+			if span, ok := spans[n.Content[k]]; ok {
+				next = span.start
+				break
+			}
+		}
+		if next < 0 {
+			return d.outside(n.Line)
+		}
+		if d.src.atLineStart(next) {
+			off := d.src.lineStart(next)
+			d.add(off, off, text+"\n")
+		} else {
+			// "- "の直後に挿入し、既存の最初のエントリを次の行に送る
+			d.add(next, next, strings.TrimLeft(text, " ")+"\n"+strings.Repeat(" ", d.src.column(next)))
+		}
+		i = j - stride
+	}
+	return nil
+}
+
+// replaceValueはマッピングのキーkeyの値を、コロンの後ろからまるごと置き換える
+func (d *nodeDiff) replaceValue(mapping, key, prev, value *yaml.Node) error {
+	ks, vs := d.snap.states[key], d.snap.states[prev]
+	if ks == nil || vs == nil || ks.end < 0 || vs.end < 0 {
+		return d.outside(key.Line)
+	}
+	colon := ks.end
+	for colon < len(d.src.src) && (d.src.src[colon] == ' ' || d.src.src[colon] == '\t') {
+		colon++
+	}
+	if colon >= len(d.src.src) || d.src.src[colon] != ':' {
+		return d.outside(key.Line)
+	}
+	end := vs.end
+	oldBlock := isCollection(vs.kind) && vs.style&yaml.FlowStyle == 0
+	if oldBlock || isBlockCollection(value) {
+		// ブロックコレクションの最後の行のコメントも置き換える範囲に含める
+		end = d.src.lineEnd(end)
+	}
+
+	keyIndent := mapping.Column - 1
+	if isBlockCollection(value) {
+		childIndent := keyIndent + 2
+		text, err := renderNode(value, childIndent, false)
+		if err != nil {
+			return err
+		}
+		d.add(colon+1, end, "\n"+strings.Repeat(" ", childIndent)+text)
+		return nil
+	}
+	text, err := renderNode(value, keyIndent, true)
+	if err != nil {
+		return err
+	}
+	d.add(colon+1, end, " "+text)
+	return nil
+}
+
+// replaceItemはシーケンスの要素を"- "の後ろからまるごと置き換える
+func (d *nodeDiff) replaceItem(item *yaml.Node) error {
+	st := d.snap.states[item]
+	if st == nil || st.start < 0 || st.end < 0 {
+		return d.outside(item.Line)
+	}
+	end := st.end
+	indent := st.indent
+	if isCollection(st.kind) && st.style&yaml.FlowStyle == 0 {
+		end = d.src.lineEnd(end)
+	}
+	if item.Kind != yaml.ScalarNode {
+		indent = d.src.column(st.start)
+	}
+	text, err := renderNode(item, indent, true)
+	if err != nil {
+		return err
+	}
+	d.add(st.start, end, text)
+	return nil
+}
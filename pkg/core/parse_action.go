@@ -0,0 +1,188 @@
+package core
+
+import (
+	"path/filepath"
+
+	"github.com/sisaku-security/sisakulint/pkg/ast"
+	"gopkg.in/yaml.v3"
+)
+
+// ActionMetadataJobIDは、アクションのメタデータファイルのruns.stepsを持つ擬似的なジョブのID
+const ActionMetadataJobID = "runs"
+
+// IsActionMetadataFileは、パスがアクションのメタデータファイル(action.ymlまたはaction.yaml)かを返す
+func IsActionMetadataFile(path string) bool {
+	b := filepath.Base(path)
+	return b == "action.yml" || b == "action.yaml"
+}
+
+// parseActionはアクションのメタデータを解析する
+// 既存のルールがそのまま使えるように、runs.stepsを1つの擬似的なジョブのステップとして持つワークフローを返す
+// *https://docs.github.com/en/actions/sharing-automations/creating-actions/metadata-syntax-for-github-actions
+func (project *parser) parseAction(node *yaml.Node) *ast.Workflow {
+	workflow := &ast.Workflow{BaseNode: node, Jobs: map[string]*ast.Job{}}
+
+	if node.Line == 0 {
+		node.Line = 1
+	}
+	if node.Column == 0 {
+		node.Column = 1
+	}
+
+	if len(node.Content) == 0 {
+		project.error(node, "empty action metadata")
+		return workflow
+	}
+
+	root := node.Content[0]
+	action := &ast.ActionMetadata{Pos: positionAt(root)}
+	workflow.Action = action
+
+	for _, kv := range project.parseMapping("action metadata", root, false, true) {
+		switch kv.id {
+		case "name":
+			action.Name = project.parseString(kv.val, false)
+			workflow.Name = action.Name
+		case "author":
+			action.Author = project.parseString(kv.val, true)
+		case "description":
+			action.Description = project.parseString(kv.val, true)
+			workflow.Description = action.Description
+		case "inputs":
+			action.Inputs = project.parseActionInputs(kv.val)
+		case "outputs":
+			action.Outputs = project.parseActionOutputs(kv.val)
+		case "runs":
+			action.Runs = project.parseActionRuns(kv.key.Pos, kv.val)
+			if action.Runs.Steps != nil {
+				id := &ast.String{Value: ActionMetadataJobID, Pos: kv.key.Pos}
+				workflow.Jobs[ActionMetadataJobID] = &ast.Job{ID: id, Steps: action.Runs.Steps, Pos: kv.key.Pos, BaseNode: kv.val}
+			}
+		case "branding":
+			// brandingはMarketplaceでの表示にしか使われないので検査しない
+		default:
+			project.unexpectedKey(kv.key, "action metadata", []string{"name", "author", "description", "inputs", "outputs", "runs", "branding"})
+		}
+	}
+	if action.Name == nil {
+		project.error(root, "section is missing required key \"name\"")
+	}
+	if action.Runs == nil {
+		project.error(root, "section is missing required key \"runs\"")
+	}
+	return workflow
+}
+
+// *https://docs.github.com/en/actions/sharing-automations/creating-actions/metadata-syntax-for-github-actions#inputs
+func (project *parser) parseActionInputs(node *yaml.Node) map[string]*ast.ActionInput {
+	inputs := project.parseSectionMapping("inputs", node, true, false)
+	ret := make(map[string]*ast.ActionInput, len(inputs))
+	for _, kv := range inputs {
+		input := &ast.ActionInput{Name: kv.key}
+		for _, attr := range project.parseMapping("input", kv.val, true, true) {
+			switch attr.id {
+			case "description":
+				input.Description = project.parseString(attr.val, true)
+			case "required":
+				input.Required = project.parseBool(attr.val)
+			case "default":
+				input.Default = project.parseString(attr.val, true)
+			case "deprecationMessage":
+				input.DeprecationMessage = project.parseString(attr.val, false)
+			default:
+				project.unexpectedKey(attr.key, "input", []string{"description", "required", "default", "deprecationMessage"})
+			}
+		}
+		ret[kv.id] = input
+	}
+	return ret
+}
+
+// *https://docs.github.com/en/actions/sharing-automations/creating-actions/metadata-syntax-for-github-actions#outputs-for-composite-actions
+func (project *parser) parseActionOutputs(node *yaml.Node) map[string]*ast.ActionOutput {
+	outputs := project.parseSectionMapping("outputs", node, true, false)
+	ret := make(map[string]*ast.ActionOutput, len(outputs))
+	for _, kv := range outputs {
+		output := &ast.ActionOutput{Name: kv.key}
+		for _, attr := range project.parseMapping("output", kv.val, true, true) {
+			switch attr.id {
+			case "description":
+				output.Description = project.parseString(attr.val, true)
+			case "value":
+				output.Value = project.parseString(attr.val, false)
+			default:
+				project.unexpectedKey(attr.key, "output", []string{"description", "value"})
+			}
+		}
+		ret[kv.id] = output
+	}
+	return ret
+}
+
+// runsのキーの組み合わせ(usingとmainなど)はパーサーでは検査せず、action-metadataルールで検査する
+// *https://docs.github.com/en/actions/sharing-automations/creating-actions/metadata-syntax-for-github-actions#runs
+func (project *parser) parseActionRuns(pos *ast.Position, node *yaml.Node) *ast.ActionRuns {
+	ret := &ast.ActionRuns{Pos: pos}
+	for _, kv := range project.parseSectionMapping("runs", node, false, true) {
+		switch kv.id {
+		case "using":
+			ret.Using = project.parseString(kv.val, false)
+		case "main":
+			ret.Main = project.parseString(kv.val, false)
+		case "pre":
+			ret.Pre = project.parseString(kv.val, false)
+		case "pre-if":
+			ret.PreIf = project.parseString(kv.val, false)
+		case "post":
+			ret.Post = project.parseString(kv.val, false)
+		case "post-if":
+			ret.PostIf = project.parseString(kv.val, false)
+		case "image":
+			ret.Image = project.parseString(kv.val, false)
+		case "entrypoint":
+			ret.Entrypoint = project.parseString(kv.val, false)
+		case "pre-entrypoint":
+			ret.PreEntrypoint = project.parseString(kv.val, false)
+		case "post-entrypoint":
+			ret.PostEntrypoint = project.parseString(kv.val, false)
+		case "args":
+			ret.Args = project.parseStringSequence("args", kv.val, true, true)
+		case "env":
+			ret.Env = project.parseEnv(kv.val)
+		case "steps":
+			ret.Steps = project.parseSteps(kv.val)
+		default:
+			project.unexpectedKey(kv.key, "runs", []string{
+				"using", "main", "pre", "pre-if", "post", "post-if",
+				"image", "entrypoint", "pre-entrypoint", "post-entrypoint", "args", "env", "steps",
+			})
+		}
+	}
+	if ret.Using == nil {
+		project.errorAt(pos, "section is missing required key \"using\"")
+	}
+	return ret
+}
+
+// ParseActionは、byteで与えられたアクションのメタデータ(action.yml)を解析する
+// Parseと同じく、エラーがあっても最後まで解析して検出したエラーを全部返す
+func ParseAction(sourceContent []byte) (*ast.Workflow, []*LintingError) {
+	var node yaml.Node
+	if err := yaml.Unmarshal(sourceContent, &node); err != nil {
+		return nil, handleYamlError(err)
+	}
+
+	parserInstance := &parser{src: newSourceText(sourceContent)}
+	workflow := parserInstance.parseAction(&node)
+	workflow.Suppressions = parserInstance.parseSuppressions(&node)
+
+	return workflow, parserInstance.errors
+}
+
+// parseFileは、パスに応じてワークフローまたはアクションのメタデータとしてソースを解析する
+func parseFile(path string, sourceContent []byte) (*ast.Workflow, []*LintingError) {
+	if IsActionMetadataFile(path) {
+		return ParseAction(sourceContent)
+	}
+	return Parse(sourceContent)
+}
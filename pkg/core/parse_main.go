@@ -12,6 +12,8 @@ import (
 
 type parser struct {
 	errors []*LintingError
+	// srcは解析するソース。ブロックスカラーの内容のインデントを調べるために使う
+	src *sourceText
 }
 
 func (project *parser) parse(node *yaml.Node) *ast.Workflow {
@@ -95,7 +97,7 @@ func handleYamlError(err error) []*LintingError {
 			lineNumber, _ = strconv.Atoi(matches[1])
 		}
 		msg = fmt.Sprintf("it could not parse as YAML: %s", msg)
-		return &LintingError{msg, "", lineNumber, 0, "syntax"}
+		return &LintingError{Description: msg, LineNumber: lineNumber, ColNumber: 0, Type: "syntax", Severity: SeverityMedium}
 	}
 
 	var typeError *yaml.TypeError
@@ -118,8 +120,9 @@ func Parse(sourceContent []byte) (*ast.Workflow, []*LintingError) {
 		return nil, handleYamlError(err)
 	}
 
-	parserInstance := &parser{}
+	parserInstance := &parser{src: newSourceText(sourceContent)}
 	workflow := parserInstance.parse(&node)
+	workflow.Suppressions = parserInstance.parseSuppressions(&node)
 
 	return workflow, parserInstance.errors
 }
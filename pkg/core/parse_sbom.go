@@ -27,11 +27,11 @@ func nodeKindName(k yaml.Kind) string {
 }
 
 func (project *parser) error(node *yaml.Node, msg string) {
-	project.errors = append(project.errors, &LintingError{msg, "", node.Line, node.Column, "syntax"})
+	project.errors = append(project.errors, &LintingError{Description: msg, LineNumber: node.Line, ColNumber: node.Column, Type: "syntax", Severity: SeverityMedium})
 }
 
 func (project *parser) errorAt(position *ast.Position, msg string) {
-	project.errors = append(project.errors, &LintingError{msg, "", position.Line, position.Col, "syntax"})
+	project.errors = append(project.errors, &LintingError{Description: msg, LineNumber: position.Line, ColNumber: position.Col, Type: "syntax", Severity: SeverityMedium})
 }
 
 func (project *parser) errorf(node *yaml.Node, format string, args ...interface{}) {
@@ -52,10 +52,19 @@ func isNull(node *yaml.Node) bool {
 	return node.Kind == yaml.ScalarNode && node.Tag == SBOMNullTag
 }
 
-func newString(node *yaml.Node) *ast.String {
+func (project *parser) newString(node *yaml.Node) *ast.String {
 	quoted := node.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle) != 0
 	literal := node.Style&yaml.LiteralStyle != 0
-	return &ast.String{Value: node.Value, Quoted: quoted, Literal: literal, Pos: positionAt(node), BaseNode: node}
+	s := &ast.String{Value: node.Value, Quoted: quoted, Literal: literal, Pos: positionAt(node), BaseNode: node}
+	// ブロックスカラーの内容の列は"|"の位置からは分からないので、ソースの内容の行のインデントを記録する
+	if literal && project.src != nil {
+		if off := project.src.offset(node.Line, node.Column); off >= 0 {
+			if indent := project.src.blockContentIndent(off); indent > 0 {
+				s.Indent = indent
+			}
+		}
+	}
+	return s
 }
 
 type workflowKeyValue struct {
@@ -133,7 +142,7 @@ func (project *parser) parseExpression(node *yaml.Node, _ /* expecting */ string
 		project.missingExpression(node, expecting)
 		return nil
 	} */
-	return newString(node)
+	return project.newString(node)
 }
 
 func (project *parser) parseBool(node *yaml.Node) *ast.Bool {
@@ -635,7 +644,7 @@ func (project *parser) maybeParseExpression(node *yaml.Node) *ast.String {
 	if node.Tag != "!!str" {
 		return nil
 	}
-	return newString(node)
+	return project.newString(node)
 }
 
 // for parseJob
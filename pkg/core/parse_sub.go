@@ -58,7 +58,7 @@ func (project *parser) parseString(node *yaml.Node, allowEmpty bool) *ast.String
 	if !project.checkString(node, allowEmpty) {
 		return &ast.String{Value: "", Quoted: false, Pos: positionAt(node), BaseNode: node}
 	}
-	return newString(node)
+	return project.newString(node)
 }
 
 // *https://docs.github.com/en/actions/using-workflows/workflow-syntax-for-github-actions#on
@@ -0,0 +1,174 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sisaku-security/sisakulint/pkg/ast"
+	"gopkg.in/yaml.v3"
+)
+
+// suppressionDirectivePrefixはインライン抑制コメントの接頭辞
+const suppressionDirectivePrefix = "sisakulint-disable"
+
+// SuppressionRuleNameはインライン抑制コメントに関するエラーのルール名
+const SuppressionRuleName = "suppression"
+
+// suppressionErrorAtは抑制コメントに関するエラーを追加する
+func (project *parser) suppressionErrorAt(position *ast.Position, format string, args ...interface{}) {
+	err := FormattedError(position, SuppressionRuleName, format, args...)
+	project.errors = append(project.errors, err)
+}
+
+// lastLineOfはノードとその子孫が占める最後の行を返す
+func lastLineOf(node *yaml.Node) int {
+	last := node.Line
+	if node.Kind == yaml.ScalarNode {
+		n := strings.Count(strings.TrimSuffix(node.Value, "\n"), "\n")
+		if node.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 {
+			// ブロックスカラーの内容はインジケーターの次の行から始まる
+			n++
+		}
+		last += n
+	}
+	for _, c := range node.Content {
+		if l := lastLineOf(c); l > last {
+			last = l
+		}
+	}
+	return last
+}
+
+// parseSuppressionsはyaml.v3のコメントから"# sisakulint-disable"形式の抑制コメントを収集する
+// run:などのブロックスカラー内の"#"はコメントとして扱われないため、シェルスクリプトのコメントは対象外になる
+func (project *parser) parseSuppressions(root *yaml.Node) []*ast.Suppression {
+	var result []*ast.Suppression
+
+	var visit func(node *yaml.Node, scope *yaml.Node)
+	visit = func(node *yaml.Node, scope *yaml.Node) {
+		// ブロックの範囲はマッピングのキーの場合は値の終わりまで、それ以外の場合はノード自身の終わりまで
+		start, end := node.Line, lastLineOf(scope)
+		if s := project.parseSuppressionComment(node.HeadComment, node.Line, false, start, end); s != nil {
+			result = append(result, s...)
+		}
+		if s := project.parseSuppressionComment(node.LineComment, node.Line, true, start, end); s != nil {
+			result = append(result, s...)
+		}
+		if s := project.parseSuppressionComment(node.FootComment, end+1, true, start, end); s != nil {
+			result = append(result, s...)
+		}
+
+		if node.Kind == yaml.MappingNode {
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				visit(node.Content[i], node.Content[i+1])
+				visit(node.Content[i+1], node.Content[i+1])
+			}
+			return
+		}
+		for _, c := range node.Content {
+			visit(c, c)
+		}
+	}
+	visit(root, root)
+
+	return result
+}
+
+// parseSuppressionCommentは1つのコメントから抑制コメントを解析する
+// lineはコメントが付与されたノードの行で、trailingはコメントがノードの後(行末やノードの後)にあることを示す
+func (project *parser) parseSuppressionComment(comment string, line int, trailing bool, start, end int) []*ast.Suppression {
+	if !strings.Contains(comment, suppressionDirectivePrefix) {
+		return nil
+	}
+
+	lines := strings.Split(comment, "\n")
+	var result []*ast.Suppression
+	for i, text := range lines {
+		text = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(text), "#"))
+		if !strings.HasPrefix(text, suppressionDirectivePrefix) {
+			continue
+		}
+
+		// ヘッドコメントはノードの直前の行に並んでいる
+		commentLine := line
+		if !trailing {
+			commentLine = line - (len(lines) - i)
+			if commentLine < 1 {
+				commentLine = 1
+			}
+		}
+		pos := &ast.Position{Line: commentLine, Col: 1}
+
+		s := project.parseSuppressionDirective(text, pos)
+		if s == nil {
+			continue
+		}
+		switch s.Kind {
+		case ast.SuppressionKindNextLine:
+			next := line
+			if trailing {
+				next = line + 1
+			}
+			s.StartLine, s.EndLine = next, next
+		case ast.SuppressionKindBlock:
+			s.StartLine, s.EndLine = start, end
+		}
+		result = append(result, s)
+	}
+	return result
+}
+
+// parseSuppressionDirectiveは"sisakulint-disable[-next-line|-file] rule-a,rule-b -- reason"を解析する
+func (project *parser) parseSuppressionDirective(text string, pos *ast.Position) *ast.Suppression {
+	body, reason, hasReason := strings.Cut(text, "--")
+	fields := strings.Fields(body)
+	directive := fields[0]
+
+	var kind ast.SuppressionKind
+	switch directive {
+	case suppressionDirectivePrefix:
+		kind = ast.SuppressionKindBlock
+	case suppressionDirectivePrefix + "-next-line":
+		kind = ast.SuppressionKindNextLine
+	case suppressionDirectivePrefix + "-file":
+		kind = ast.SuppressionKindFile
+	default:
+		project.suppressionErrorAt(pos, "unknown suppression directive %q. available directives are %q, %q and %q", directive, suppressionDirectivePrefix+"-next-line", suppressionDirectivePrefix, suppressionDirectivePrefix+"-file")
+		return nil
+	}
+
+	var rules []string
+	for _, f := range fields[1:] {
+		for _, r := range strings.Split(f, ",") {
+			if r = strings.TrimSpace(r); r != "" {
+				rules = append(rules, r)
+			}
+		}
+	}
+	if len(rules) == 0 {
+		project.suppressionErrorAt(pos, "suppression comment %q must list at least one rule name to disable", directive)
+		return nil
+	}
+
+	reason = strings.TrimSpace(reason)
+	if !hasReason || reason == "" {
+		project.suppressionErrorAt(pos, "suppression comment for %s must have a reason like \"# %s %s -- <reason>\". the suppression is ignored", quotedRules(rules), directive, strings.Join(rules, ","))
+		return nil
+	}
+
+	return &ast.Suppression{
+		Kind:   kind,
+		Rules:  rules,
+		Reason: reason,
+		Pos:    pos,
+	}
+}
+
+// quotedRulesはルール名のリストをエラーメッセージ用の文字列にする
+func quotedRules(rules []string) string {
+	qs := make([]string, 0, len(rules))
+	for _, r := range rules {
+		qs = append(qs, fmt.Sprintf("%q", r))
+	}
+	return strings.Join(qs, ", ")
+}
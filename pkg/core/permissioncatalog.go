@@ -0,0 +1,379 @@
+package core
+
+import (
+	_ "embed"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/sisaku-security/sisakulint/pkg/ast"
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed permissioncatalog.yaml
+var permissionCatalogYAML []byte
+
+// permissionLevel is access level of a permission scope. Larger value grants more access.
+type permissionLevel int
+
+const (
+	permissionNone permissionLevel = iota
+	permissionRead
+	permissionWrite
+)
+
+func (l permissionLevel) String() string {
+	switch l {
+	case permissionRead:
+		return "read"
+	case permissionWrite:
+		return "write"
+	default:
+		return "none"
+	}
+}
+
+func parsePermissionLevel(s string) (permissionLevel, bool) {
+	switch s {
+	case "none":
+		return permissionNone, true
+	case "read":
+		return permissionRead, true
+	case "write":
+		return permissionWrite, true
+	default:
+		return permissionNone, false
+	}
+}
+
+// permissionSet is a mapping from permission scope name to its access level.
+type permissionSet map[string]permissionLevel
+
+// merge raises each scope of the set to the level in other.
+func (s permissionSet) merge(other permissionSet) {
+	for scope, level := range other {
+		if level > s[scope] {
+			s[scope] = level
+		}
+	}
+}
+
+// scopes returns scope names of the set in alphabetical order, omitting scopes with none level.
+func (s permissionSet) scopes() []string {
+	scopes := make([]string, 0, len(s))
+	for scope, level := range s {
+		if level > permissionNone {
+			scopes = append(scopes, scope)
+		}
+	}
+	sort.Strings(scopes)
+	return scopes
+}
+
+// String formats the set like "{contents: read, issues: write}".
+func (s permissionSet) String() string {
+	scopes := s.scopes()
+	parts := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		parts = append(parts, scope+": "+s[scope].String())
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+// yamlNode builds "permissions:" mapping node for the set. Empty set is rendered as "{}".
+func (s permissionSet) yamlNode() *yaml.Node {
+	n := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	scopes := s.scopes()
+	if len(scopes) == 0 {
+		n.Style = yaml.FlowStyle
+	}
+	for _, scope := range scopes {
+		n.Content = append(n.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: scope},
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: s[scope].String()},
+		)
+	}
+	return n
+}
+
+// permissionCatalogAPI is an entry of REST API paths in the permission catalog.
+type permissionCatalogAPI struct {
+	Path  string `yaml:"path"`
+	Scope string `yaml:"scope"`
+
+	segments []string
+}
+
+// matches returns true when the path segments start with the entry's path.
+func (a *permissionCatalogAPI) matches(segments []string) bool {
+	if len(segments) < len(a.segments) {
+		return false
+	}
+	for i, s := range a.segments {
+		if strings.HasPrefix(s, "{") && strings.HasSuffix(s, "}") {
+			continue
+		}
+		if s != segments[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// permissionCatalogCommand is an entry of commands in run: scripts in the permission catalog.
+type permissionCatalogCommand struct {
+	Pattern     string            `yaml:"pattern"`
+	Permissions map[string]string `yaml:"permissions"`
+
+	regex       *regexp.Regexp
+	permissions permissionSet
+}
+
+// permissionCatalog is a catalog of GITHUB_TOKEN permissions required by actions, GitHub CLI subcommands,
+// commands and REST API calls in steps. It is embedded from permissioncatalog.yaml.
+type permissionCatalog struct {
+	Actions  map[string]map[string]string `yaml:"actions"`
+	GH       map[string]map[string]string `yaml:"gh"`
+	Commands []*permissionCatalogCommand  `yaml:"commands"`
+	API      []*permissionCatalogAPI      `yaml:"api"`
+
+	actions map[string]permissionSet
+	gh      map[string]permissionSet
+}
+
+func toPermissionSet(m map[string]string, where string) (permissionSet, error) {
+	s := make(permissionSet, len(m))
+	for scope, v := range m {
+		if _, ok := allPermissionScopes[scope]; !ok {
+			return nil, fmt.Errorf("unknown permission scope %q in %s", scope, where)
+		}
+		level, ok := parsePermissionLevel(v)
+		if !ok {
+			return nil, fmt.Errorf("invalid permission %q for scope %q in %s", v, scope, where)
+		}
+		s[scope] = level
+	}
+	return s, nil
+}
+
+func parsePermissionCatalog(b []byte) (*permissionCatalog, error) {
+	var c permissionCatalog
+	if err := yaml.Unmarshal(b, &c); err != nil {
+		return nil, fmt.Errorf("could not parse permission catalog: %w", err)
+	}
+	c.actions = make(map[string]permissionSet, len(c.Actions))
+	for name, m := range c.Actions {
+		s, err := toPermissionSet(m, "action "+name)
+		if err != nil {
+			return nil, err
+		}
+		c.actions[strings.ToLower(name)] = s
+	}
+	c.gh = make(map[string]permissionSet, len(c.GH))
+	for cmd, m := range c.GH {
+		s, err := toPermissionSet(m, "gh "+cmd)
+		if err != nil {
+			return nil, err
+		}
+		c.gh[cmd] = s
+	}
+	for _, cmd := range c.Commands {
+		re, err := regexp.Compile(cmd.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid command pattern %q: %w", cmd.Pattern, err)
+		}
+		s, err := toPermissionSet(cmd.Permissions, "command "+cmd.Pattern)
+		if err != nil {
+			return nil, err
+		}
+		cmd.regex, cmd.permissions = re, s
+	}
+	for _, a := range c.API {
+		if _, ok := allPermissionScopes[a.Scope]; !ok {
+			return nil, fmt.Errorf("unknown permission scope %q for API path %q", a.Scope, a.Path)
+		}
+		a.segments = strings.Split(strings.Trim(a.Path, "/"), "/")
+	}
+	return &c, nil
+}
+
+// defaultPermissionCatalog is the catalog embedded in the binary.
+var defaultPermissionCatalog = func() *permissionCatalog {
+	c, err := parsePermissionCatalog(permissionCatalogYAML)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}()
+
+// jobPermissionNeeds is the result of inferring the permissions a job requires.
+type jobPermissionNeeds struct {
+	// permissions is the minimal permissions required by the steps of the job.
+	permissions permissionSet
+	// unknown describes the first step which could not be analyzed. When it is not empty, permissions is incomplete.
+	unknown string
+}
+
+var (
+	ghSubcommandPattern = regexp.MustCompile(`(?:^|[\s;&|(` + "`" + `])gh\s+([a-z][a-z-]*)(?:\s+([a-z][a-z-]*))?`)
+	githubTokenPattern  = regexp.MustCompile(`secrets\.GITHUB_TOKEN|github\.token|\bGITHUB_TOKEN\b|\bGH_TOKEN\b`)
+	githubAPICallLine   = regexp.MustCompile(`api\.github\.com|GITHUB_API_URL|\bgh\s+api\b`)
+	githubAPIPath       = regexp.MustCompile(`(?:^|[/\s"'])(repos/[^\s"'?#]+|graphql\b)`)
+	githubAPIMethod     = regexp.MustCompile(`(?:-X\s*|--request[=\s]\s*|--method[=\s]\s*)["']?([A-Za-z]+)`)
+	curlDataFlag        = regexp.MustCompile(`(?:^|\s)(?:-d|-F|--data\S*|--form|--json)(?:\s|=|$)`)
+	ghAPIDataFlag       = regexp.MustCompile(`(?:^|\s)(?:-f|-F|--field|--raw-field|--input)(?:\s|=|$)`)
+	repositoryExpr      = regexp.MustCompile(`\$\{\{\s*github\.repository\s*\}\}|\$\{?GITHUB_REPOSITORY\}?`)
+	otherExpr           = regexp.MustCompile(`\$\{\{[^}]*\}\}`)
+)
+
+// inferJob infers the minimal permissions required by the steps of the job.
+func (c *permissionCatalog) inferJob(job *ast.Job) *jobPermissionNeeds {
+	needs := &jobPermissionNeeds{permissions: permissionSet{}}
+	if job.WorkflowCall != nil {
+		needs.unknown = "the job calls a reusable workflow"
+		return needs
+	}
+	for _, step := range job.Steps {
+		if step == nil {
+			continue
+		}
+		var (
+			s      permissionSet
+			reason string
+		)
+		switch e := step.Exec.(type) {
+		case *ast.ExecAction:
+			s, reason = c.inferAction(e)
+		case *ast.ExecRun:
+			s, reason = c.inferRun(e, step.Env)
+		}
+		if reason != "" {
+			needs.unknown = reason
+			return needs
+		}
+		needs.permissions.merge(s)
+	}
+	return needs
+}
+
+func (c *permissionCatalog) inferAction(e *ast.ExecAction) (permissionSet, string) {
+	if e.Uses == nil {
+		return nil, ""
+	}
+	uses := e.Uses.Value
+	if strings.HasPrefix(uses, "docker://") {
+		return nil, ""
+	}
+	repo, path, _, ok := splitActionRef(uses)
+	if !ok {
+		return nil, fmt.Sprintf("action %q is not in the permission catalog", uses)
+	}
+	if s, ok := c.actions[strings.ToLower(repo+path)]; ok {
+		return s, ""
+	}
+	if s, ok := c.actions[strings.ToLower(repo)]; ok && path == "" {
+		return s, ""
+	}
+	return nil, fmt.Sprintf("action %q is not in the permission catalog", repo+path)
+}
+
+func (c *permissionCatalog) inferRun(e *ast.ExecRun, env *ast.Env) (permissionSet, string) {
+	if e.Run == nil {
+		return nil, ""
+	}
+	script := strings.ReplaceAll(e.Run.Value, "\\\n", " ")
+	s := permissionSet{}
+	found := false
+
+	for _, m := range ghSubcommandPattern.FindAllStringSubmatch(script, -1) {
+		if m[1] == "api" {
+			continue
+		}
+		cmd := m[1] + " " + m[2]
+		p, ok := c.gh[cmd]
+		if !ok {
+			return nil, fmt.Sprintf("\"gh %s\" is not in the permission catalog", strings.TrimSpace(cmd))
+		}
+		s.merge(p)
+		found = true
+	}
+
+	for _, cmd := range c.Commands {
+		if cmd.regex.MatchString(script) {
+			s.merge(cmd.permissions)
+			found = true
+		}
+	}
+
+	script = repositoryExpr.ReplaceAllString(script, "o/r")
+	script = otherExpr.ReplaceAllString(script, "x")
+	for _, line := range strings.Split(script, "\n") {
+		if !githubAPICallLine.MatchString(line) {
+			continue
+		}
+		m := githubAPIPath.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		if m[1] == "graphql" {
+			return nil, "GraphQL API call is not analyzed"
+		}
+		scope := c.apiScope(strings.Split(m[1], "/"))
+		if scope == "" {
+			return nil, fmt.Sprintf("API path %q is not in the permission catalog", "/"+m[1])
+		}
+		s.merge(permissionSet{scope: apiPermissionLevel(line)})
+		found = true
+	}
+
+	if !found && (githubTokenPattern.MatchString(e.Run.Value) || envReferencesToken(env)) {
+		return nil, "the script uses GITHUB_TOKEN in a way which is not analyzed"
+	}
+	return s, ""
+}
+
+// apiScope returns the permission scope of the REST API path. It returns an empty string when the path is unknown.
+func (c *permissionCatalog) apiScope(segments []string) string {
+	for _, a := range c.API {
+		if a.matches(segments) {
+			return a.Scope
+		}
+	}
+	return ""
+}
+
+// apiPermissionLevel returns read for GET and HEAD requests and write for other requests in the line.
+func apiPermissionLevel(line string) permissionLevel {
+	if m := githubAPIMethod.FindStringSubmatch(line); m != nil {
+		switch strings.ToUpper(m[1]) {
+		case "GET", "HEAD":
+			return permissionRead
+		default:
+			return permissionWrite
+		}
+	}
+	data := curlDataFlag
+	if strings.Contains(line, "gh api") {
+		data = ghAPIDataFlag
+	}
+	if data.MatchString(line) {
+		return permissionWrite
+	}
+	return permissionRead
+}
+
+func envReferencesToken(env *ast.Env) bool {
+	if env == nil {
+		return false
+	}
+	if env.Expression != nil && githubTokenPattern.MatchString(env.Expression.Value) {
+		return true
+	}
+	for _, v := range env.Vars {
+		if v != nil && v.Value != nil && githubTokenPattern.MatchString(v.Value.Value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,312 @@
+package core
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sisaku-security/sisakulint/pkg/ast"
+)
+
+func TestParsePermissionCatalog(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{"valid", "actions:\n  a/b: {contents: read}\napi:\n  - {path: \"/repos/{o}/{r}/issues\", scope: issues}\n", ""},
+		{"unknown scope", "actions:\n  a/b: {content: read}\n", "unknown permission scope \"content\""},
+		{"invalid level", "gh:\n  pr view: {pull-requests: admin}\n", "invalid permission \"admin\""},
+		{"invalid pattern", "commands:\n  - {pattern: '(', permissions: {}}\n", "invalid command pattern"},
+		{"unknown API scope", "api:\n  - {path: /repos, scope: repo}\n", "unknown permission scope \"repo\""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parsePermissionCatalog([]byte(tt.yaml))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("want error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+// parseTestJobはstepsのYAMLからジョブを作って返す
+func parseTestJob(t *testing.T, steps string) *ast.Job {
+	t.Helper()
+	w, errs := Parse([]byte("on: push\njobs:\n  build:\n    runs-on: ubuntu-latest\n    steps:\n" + steps))
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	return w.Jobs["build"]
+}
+
+func TestPermissionCatalog_InferJob(t *testing.T) {
+	tests := []struct {
+		name    string
+		steps   string
+		want    string
+		unknown bool
+	}{
+		{
+			name:  "actions in catalog",
+			steps: "      - uses: actions/checkout@v4\n      - uses: actions/setup-go@v5\n      - uses: github/codeql-action/analyze@v3\n",
+			want:  "{actions: read, contents: read, security-events: write}",
+		},
+		{
+			name:  "docker action",
+			steps: "      - uses: docker://alpine:3\n",
+			want:  "{}",
+		},
+		{
+			name:    "action not in catalog",
+			steps:   "      - uses: someone/unknown-action@v1\n",
+			unknown: true,
+		},
+		{
+			name:    "local action",
+			steps:   "      - uses: ./.github/actions/build\n",
+			unknown: true,
+		},
+		{
+			name:  "gh subcommands",
+			steps: "      - run: |\n          gh pr comment 1 --body hi\n          gh release create v1\n        env:\n          GH_TOKEN: ${{ github.token }}\n",
+			want:  "{contents: write, pull-requests: write}",
+		},
+		{
+			name:    "gh subcommand not in catalog",
+			steps:   "      - run: gh secret set FOO\n",
+			unknown: true,
+		},
+		{
+			name:  "gh api with fields",
+			steps: "      - run: gh api repos/{owner}/{repo}/issues/1/labels -f labels[]=bug\n",
+			want:  "{issues: write}",
+		},
+		{
+			name:  "gh api GET",
+			steps: "      - run: gh api repos/${{ github.repository }}/pulls/1\n",
+			want:  "{pull-requests: read}",
+		},
+		{
+			name: "curl with method",
+			steps: "      - run: |\n          curl -X POST \\\n            -H \"Authorization: token $GITHUB_TOKEN\" \\\n" +
+				"            \"$GITHUB_API_URL/repos/$GITHUB_REPOSITORY/statuses/$GITHUB_SHA\"\n",
+			want: "{statuses: write}",
+		},
+		{
+			name:  "curl with data",
+			steps: "      - run: curl -d '{}' https://api.github.com/repos/o/r/dispatches\n",
+			want:  "{contents: write}",
+		},
+		{
+			name:    "graphql",
+			steps:   "      - run: gh api graphql -f query=x\n",
+			unknown: true,
+		},
+		{
+			name:    "API path not in catalog",
+			steps:   "      - run: gh api repos/o/r/environments\n",
+			unknown: true,
+		},
+		{
+			name:  "git push",
+			steps: "      - uses: actions/checkout@v4\n      - run: git push origin HEAD\n",
+			want:  "{contents: write}",
+		},
+		{
+			name:    "token used in unknown way",
+			steps:   "      - run: ./deploy.sh\n        env:\n          TOKEN: ${{ secrets.GITHUB_TOKEN }}\n",
+			unknown: true,
+		},
+		{
+			name:  "script without token",
+			steps: "      - run: make test\n",
+			want:  "{}",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			needs := defaultPermissionCatalog.inferJob(parseTestJob(t, tt.steps))
+			if (needs.unknown != "") != tt.unknown {
+				t.Fatalf("want unknown %v, got %q with %s", tt.unknown, needs.unknown, needs.permissions)
+			}
+			if !tt.unknown && needs.permissions.String() != tt.want {
+				t.Errorf("got %s, want %s", needs.permissions, tt.want)
+			}
+		})
+	}
+}
+
+func TestPermissionRule_MinimalPermissions(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		errors   []string
+		want     string
+		wantNoFx bool
+	}{
+		{
+			name: "unused contents write of job",
+			input: `on: push
+permissions: {}
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    permissions:
+      contents: write
+      pull-requests: write
+    steps:
+      - uses: actions/checkout@v4
+      - run: gh pr comment 1 --body ok
+`,
+			errors: []string{`permission "contents: write" of job "build" is broader than needed since the steps only read "contents". minimal permissions inferred from the steps are {contents: read, pull-requests: write}`},
+			want: `on: push
+permissions: {}
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    permissions:
+      contents: read
+      pull-requests: write
+    steps:
+      - uses: actions/checkout@v4
+      - run: gh pr comment 1 --body ok
+`,
+		},
+		{
+			name: "write-all of workflow",
+			input: `on: push
+permissions: write-all
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v4
+  release:
+    runs-on: ubuntu-latest
+    steps:
+      - run: gh release create v1
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      - run: make test
+`,
+			want: `on: push
+permissions: {}
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    permissions:
+      contents: read
+    steps:
+      - uses: actions/checkout@v4
+  release:
+    runs-on: ubuntu-latest
+    permissions:
+      contents: write
+    steps:
+      - run: gh release create v1
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      - run: make test
+`,
+		},
+		{
+			name: "workflow grant used by a job",
+			input: `on: push
+permissions:
+  contents: write
+  issues: write
+jobs:
+  release:
+    runs-on: ubuntu-latest
+    steps:
+      - run: gh release create v1
+`,
+			errors: []string{`permission "issues: write" of the workflow is broader than needed since no step uses "issues". minimal permissions inferred from the steps are {contents: write}`},
+			want: `on: push
+permissions: {}
+jobs:
+  release:
+    runs-on: ubuntu-latest
+    permissions:
+      contents: write
+    steps:
+      - run: gh release create v1
+`,
+		},
+		{
+			name: "job not in catalog is not checked",
+			input: `on: push
+permissions:
+  contents: write
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: someone/unknown-action@v1
+`,
+			wantNoFx: true,
+		},
+		{
+			name: "required write permission",
+			input: `on: push
+permissions: {}
+jobs:
+  deploy:
+    runs-on: ubuntu-latest
+    permissions:
+      pages: write
+      id-token: write
+    steps:
+      - uses: actions/deploy-pages@v4
+`,
+			wantNoFx: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, errs := Parse([]byte(tt.input))
+			if len(errs) > 0 {
+				t.Fatal(errs)
+			}
+			rule := PermissionsRule()
+			if err := rule.VisitWorkflowPre(w); err != nil {
+				t.Fatal(err)
+			}
+			var msgs []string
+			for _, e := range rule.Errors() {
+				if strings.Contains(e.Description, "broader than needed") {
+					msgs = append(msgs, e.Description)
+				}
+			}
+			if len(msgs) != len(tt.errors) {
+				t.Fatalf("got errors %q, want %q", msgs, tt.errors)
+			}
+			for i, want := range tt.errors {
+				if !strings.Contains(msgs[i], want) {
+					t.Errorf("error %q does not contain %q", msgs[i], want)
+				}
+			}
+			if tt.wantNoFx {
+				if len(rule.AutoFixers()) != 0 {
+					t.Errorf("got %d autofixers, want none", len(rule.AutoFixers()))
+				}
+				return
+			}
+			res := ApplyAutoFixers([]byte(tt.input), w, rule.AutoFixers(), nil)
+			if len(res.Failed) > 0 {
+				t.Fatal(res.Failed[0].Err)
+			}
+			if got := string(res.Source); got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,6 +1,9 @@
 package core
 
 import (
+	"fmt"
+	"sort"
+
 	"github.com/sisaku-security/sisakulint/pkg/ast"
 	"github.com/sisaku-security/sisakulint/pkg/expressions"
 	"gopkg.in/yaml.v3"
@@ -83,6 +86,7 @@ func (rule *PermissionRule) VisitWorkflowPre(n *ast.Workflow) error {
 	}
 
 	rule.checkPermissions(n.Permissions)
+	rule.checkMinimalPermissions(n)
 	return nil
 }
 
@@ -182,5 +186,179 @@ func (rule *PermissionRule) fixMissingPermissions(n *ast.Workflow) error {
 	newContent = append(newContent, mappingNode.Content[insertIndex:]...)
 	mappingNode.Content = newContent
 
+	// Jobs whose required permissions are fully known get their own minimal permissions
+	// so that they keep working with the empty workflow-level permissions.
+	for _, job := range sortedJobs(n) {
+		if job.Permissions != nil {
+			continue
+		}
+		needs := defaultPermissionCatalog.inferJob(job)
+		if needs.unknown == "" && len(needs.permissions.scopes()) > 0 {
+			setJobPermissions(job, needs.permissions)
+		}
+	}
+
 	return nil
 }
+
+// checkMinimalPermissions compares granted permissions with the minimal permissions inferred from
+// the steps of each job using the embedded permission catalog, and reports write permissions which
+// no step requires. Jobs which use anything not in the catalog are not checked since their required
+// permissions are unknown.
+func (rule *PermissionRule) checkMinimalPermissions(n *ast.Workflow) {
+	var inheriting []*ast.Job
+	inherited := permissionSet{}
+	allKnown := true
+
+	for _, job := range sortedJobs(n) {
+		needs := defaultPermissionCatalog.inferJob(job)
+		if job.Permissions == nil {
+			inheriting = append(inheriting, job)
+			if needs.unknown != "" {
+				allKnown = false
+			}
+			inherited.merge(needs.permissions)
+			continue
+		}
+		if needs.unknown != "" {
+			continue
+		}
+		owner := fmt.Sprintf("job %q", job.ID.Value)
+		if rule.checkOverBroad(job.Permissions, needs.permissions, owner) {
+			job, needs := job, needs
+			rule.AddAutoFixer(NewFuncFixer(rule.RuleNames(), func() error {
+				setJobPermissions(job, needs.permissions)
+				return nil
+			}))
+		}
+	}
+
+	if n.Permissions == nil || !allKnown {
+		return
+	}
+	if rule.checkOverBroad(n.Permissions, inherited, "the workflow") {
+		rule.AddAutoFixer(NewFuncFixer(rule.RuleNames(), func() error {
+			return rule.fixWorkflowPermissions(n, inheriting)
+		}))
+	}
+}
+
+// checkOverBroad reports write permissions in p which are broader than the required permissions.
+// It returns true when p grants more than required and can be replaced with the required permissions.
+func (rule *PermissionRule) checkOverBroad(p *ast.Permissions, required permissionSet, owner string) bool {
+	if p.All != nil {
+		// write-all is already reported by checkPermissions
+		return p.All.Value == "write-all"
+	}
+
+	names := make([]string, 0, len(p.Scopes))
+	for name := range p.Scopes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	overBroad := false
+	for _, name := range names {
+		scope := p.Scopes[name]
+		if scope.Value == nil || scope.Value.Value != "write" {
+			continue
+		}
+		if _, ok := allPermissionScopes[scope.Name.Value]; !ok {
+			continue
+		}
+		if required[scope.Name.Value] == permissionWrite {
+			continue
+		}
+		reason := fmt.Sprintf("no step uses %q", scope.Name.Value)
+		if required[scope.Name.Value] == permissionRead {
+			reason = fmt.Sprintf("the steps only read %q", scope.Name.Value)
+		}
+		rule.Errorf(
+			scope.Value.Pos,
+			"permission \"%s: write\" of %s is broader than needed since %s. minimal permissions inferred from the steps are %s",
+			scope.Name.Value,
+			owner,
+			reason,
+			required,
+		)
+		overBroad = true
+	}
+	return overBroad
+}
+
+// fixWorkflowPermissions replaces the workflow-level permissions with an empty mapping and gives
+// the minimal permissions to each job which inherited the workflow-level permissions.
+func (rule *PermissionRule) fixWorkflowPermissions(n *ast.Workflow, inheriting []*ast.Job) error {
+	m := workflowMappingNode(n)
+	if m == nil {
+		return nil
+	}
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == "permissions" {
+			m.Content[i+1] = permissionSet{}.yamlNode()
+			break
+		}
+	}
+	for _, job := range inheriting {
+		needs := defaultPermissionCatalog.inferJob(job)
+		if len(needs.permissions.scopes()) > 0 {
+			setJobPermissions(job, needs.permissions)
+		}
+	}
+	return nil
+}
+
+func workflowMappingNode(n *ast.Workflow) *yaml.Node {
+	if n.BaseNode == nil {
+		return nil
+	}
+	if n.BaseNode.Kind == yaml.DocumentNode && len(n.BaseNode.Content) > 0 {
+		return n.BaseNode.Content[0]
+	}
+	if n.BaseNode.Kind == yaml.MappingNode {
+		return n.BaseNode
+	}
+	return nil
+}
+
+// setJobPermissions sets "permissions:" of the job to the given permissions. When the job does not
+// have "permissions:" yet, it is inserted before "steps:".
+func setJobPermissions(job *ast.Job, p permissionSet) {
+	m := job.BaseNode
+	if m == nil || m.Kind != yaml.MappingNode {
+		return
+	}
+	insert := len(m.Content)
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		switch m.Content[i].Value {
+		case "permissions":
+			m.Content[i+1] = p.yamlNode()
+			return
+		case "steps":
+			insert = i
+		}
+	}
+	key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "permissions"}
+	content := make([]*yaml.Node, 0, len(m.Content)+2)
+	content = append(content, m.Content[:insert]...)
+	content = append(content, key, p.yamlNode())
+	content = append(content, m.Content[insert:]...)
+	m.Content = content
+}
+
+// sortedJobs returns the jobs of the workflow in the order of their positions in source.
+func sortedJobs(n *ast.Workflow) []*ast.Job {
+	jobs := make([]*ast.Job, 0, len(n.Jobs))
+	for _, job := range n.Jobs {
+		if job != nil {
+			jobs = append(jobs, job)
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		if jobs[i].Pos == nil || jobs[j].Pos == nil {
+			return jobs[i].ID.Value < jobs[j].ID.Value
+		}
+		return jobs[i].Pos.Line < jobs[j].Pos.Line
+	})
+	return jobs
+}
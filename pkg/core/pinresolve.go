@@ -0,0 +1,136 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sisaku-security/sisakulint/pkg/ast"
+)
+
+// actionRefはロックファイルに記録するアクションの参照
+type actionRef struct {
+	repo string
+	tag  string
+}
+
+func (r actionRef) String() string {
+	return r.repo + "@" + r.tag
+}
+
+// collectActionRefsはworkflowのステップで使われているアクションの参照を集める
+// SHAでピン留めされたアクションは、横の"# vX.Y.Z"のコメントのバージョンを参照として集める
+func collectActionRefs(workflow *ast.Workflow, refs map[actionRef]bool) {
+	if workflow == nil {
+		return
+	}
+	for _, job := range workflow.Jobs {
+		if job == nil {
+			continue
+		}
+		for _, step := range job.Steps {
+			if step == nil {
+				continue
+			}
+			action, ok := step.Exec.(*ast.ExecAction)
+			if !ok || action.Uses == nil {
+				continue
+			}
+			repo, _, tag, ok := splitActionRef(action.Uses.Value)
+			if !ok {
+				continue
+			}
+			if fullShaPattern.MatchString(tag) {
+				if action.Uses.BaseNode == nil {
+					continue
+				}
+				tag = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(action.Uses.BaseNode.LineComment), "#"))
+				if !semverPattern.MatchString(tag) && !shortTagPattern.MatchString(tag) {
+					continue
+				}
+			}
+			refs[actionRef{repo, tag}] = true
+		}
+	}
+}
+
+// runPinResolveは、workflowで使われているアクションの参照をGitHub APIで解決してロックファイルに書き込む
+// すでにロックファイルに記録されている参照は解決し直さず、どのworkflowでも使われなくなった参照はロックファイルから削除する
+func (cmd *Command) runPinResolve(args []string, linterOpts *LinterOptions, token, apiURL string) int {
+	l, err := NewLinter(io.Discard, linterOpts)
+	if err != nil {
+		fmt.Fprintln(cmd.Stderr, err.Error())
+		return ExitStatusFailure
+	}
+
+	dir := "."
+	var results []*ValidateResult
+	if len(args) == 0 {
+		results, err = l.LintRepository(dir)
+	} else {
+		dir = filepath.Dir(args[0])
+		results, err = l.LintFiles(args, nil)
+	}
+	if err != nil {
+		fmt.Fprintln(cmd.Stderr, err.Error())
+		return ExitStatusFailure
+	}
+	project, err := locateProject(dir)
+	if err != nil {
+		fmt.Fprintf(cmd.Stderr, "project not found for %q. the lock file is written to the .github directory of the repository\n", dir)
+		return ExitStatusFailure
+	}
+	path := actionLockPath(project.RootDirectory())
+
+	refs := map[actionRef]bool{}
+	for _, res := range results {
+		collectActionRefs(res.ParsedWorkflow, refs)
+	}
+	sorted := make([]actionRef, 0, len(refs))
+	for r := range refs {
+		sorted = append(sorted, r)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].String() < sorted[j].String() })
+
+	prev := project.ActionLock()
+	lock := &ActionLock{Actions: make(map[string]*ActionLockEntry, len(sorted))}
+	if prev != nil {
+		lock.Images = prev.Images
+	}
+	var resolver *ActionRefResolver
+	resolved, failed := 0, 0
+	for _, r := range sorted {
+		if e := prev.Lookup(r.repo, r.tag); e != nil {
+			lock.Actions[r.String()] = e
+			continue
+		}
+		if resolver == nil {
+			resolver, err = NewActionRefResolver(token, apiURL)
+			if err != nil {
+				fmt.Fprintln(cmd.Stderr, err.Error())
+				return ExitStatusFailure
+			}
+		}
+		e, err := resolver.Resolve(context.Background(), r.repo, r.tag)
+		if err != nil {
+			fmt.Fprintf(cmd.Stderr, "could not resolve %s: %v\n", r, err)
+			failed++
+			continue
+		}
+		lock.Actions[r.String()] = e
+		resolved++
+	}
+
+	if err := lock.WriteFile(path); err != nil {
+		fmt.Fprintf(cmd.Stderr, "could not write action lock file %q: %v\n", path, err)
+		return ExitStatusFailure
+	}
+	fmt.Fprintf(cmd.Stdout, "Resolved %d new action reference(s) and recorded %d to lock file %s\n", resolved, len(lock.Actions), path)
+	if failed > 0 {
+		return ExitStatusFailure
+	}
+	return ExitStatusSuccessNoProblem
+}
@@ -0,0 +1,44 @@
+package core
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+//go:generate go run ../../script/generate-popular-actions -mirror $SISAKULINT_ACTIONS_MIRROR -list ../../script/generate-popular-actions/actions.yaml -out popular_actions.json
+
+//go:embed popular_actions.json
+var popularActionsJSON []byte
+
+// popularActionsは、よく使われるマーケットプレイスのアクションのメタデータのデータセット
+// キーは"owner/repo[/path]@メジャーバージョン"で、ネットワークにアクセスせずにアクションの入力や出力をチェックするために使う
+// popular_actions.jsonはscript/generate-popular-actionsで生成する
+var popularActions = func() map[string]*ActionMetadata {
+	var m map[string]*ActionMetadata
+	if err := json.Unmarshal(popularActionsJSON, &m); err != nil {
+		panic(fmt.Sprintf("could not parse popular_actions.json: %v", err))
+	}
+	return m
+}()
+
+var majorVersionPattern = regexp.MustCompile(`^v\d+`)
+
+// findPopularActionは、"owner/repo[/path]@ref"のアクションのメタデータをカタログから探す
+// "v4.2.2"のようなバージョンはメジャーバージョンの"v4"として探す。見つからない場合はnilを返す
+func findPopularAction(spec string) *ActionMetadata {
+	if m, ok := popularActions[spec]; ok {
+		return m
+	}
+	idx := strings.LastIndexByte(spec, '@')
+	if idx <= 0 {
+		return nil
+	}
+	major := majorVersionPattern.FindString(spec[idx+1:])
+	if major == "" {
+		return nil
+	}
+	return popularActions[spec[:idx+1]+major]
+}
@@ -0,0 +1,60 @@
+package core
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestPopularActions_Dataset(t *testing.T) {
+	if len(popularActions) == 0 {
+		t.Fatal("dataset is empty")
+	}
+	key := regexp.MustCompile(`^[^/@]+/[^/@]+(/[^@]+)?@v\d+$`)
+	for spec, meta := range popularActions {
+		if !key.MatchString(spec) {
+			t.Errorf("invalid key %q", spec)
+		}
+		if meta.Runs.Using == "" {
+			t.Errorf("runtime of %q is empty", spec)
+		}
+		for id, i := range meta.Inputs {
+			if i == nil || i.Name == "" {
+				t.Errorf("input %q of %q has no name", id, spec)
+			}
+		}
+	}
+}
+
+func TestLocalActionsMetadataCache_FindMetadataFallback(t *testing.T) {
+	tests := []struct {
+		spec    string
+		runtime string
+	}{
+		{"actions/checkout@v4", "node20"},
+		{"actions/checkout@v4.1.7", "node20"},
+		{"actions/checkout@v2", "node12"},
+		{"actions/cache/restore@v4", "node20"},
+		{"actions/checkout@main", ""},
+		{"actions/checkout@v99", ""},
+		{"someone/unknown@v1", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			for _, c := range []*LocalActionsMetadataCache{nil, NewLocalActionsMetadataCache(nil, nil)} {
+				meta, err := c.FindMetadata(tt.spec)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if tt.runtime == "" {
+					if meta != nil {
+						t.Errorf("want no metadata, got %+v", meta)
+					}
+					continue
+				}
+				if meta == nil || meta.Runs.Using != tt.runtime {
+					t.Errorf("want runtime %q, got %+v", tt.runtime, meta)
+				}
+			}
+		})
+	}
+}
@@ -4,16 +4,25 @@ package core
 
 import (
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+
+	"github.com/sisaku-security/sisakulint/pkg/ast"
 )
 
 // ProjectはGithubプロジェクト- 1つのリポジトリに対応
 type Project struct {
-	root   string
-	config *Config
-	boiler *Boiler
+	root       string
+	config     *Config
+	boiler     *Boiler
+	actionLock *ActionLock
+
+	workflowsOnce sync.Once
+	workflows     map[string][]ast.Event
+	parsed        []*projectWorkflow
 }
 
 func getAbsolutePath(path string) string {
@@ -50,7 +59,11 @@ func NewProject(root string) (*Project, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &Project{root: root, config: c, boiler: d}, nil
+	lock, err := ReadActionLockFile(actionLockPath(root))
+	if err != nil {
+		return nil, err
+	}
+	return &Project{root: root, config: c, boiler: d, actionLock: lock}, nil
 }
 
 // githubプロジェクトのルートディレクトリを返す
@@ -63,6 +76,25 @@ func (project *Project) WorkflowDirectory() string {
 	return filepath.Join(project.root, ".github", "workflows")
 }
 
+// ActionMetadataFilesは、リポジトリのルートと"/.github/actions"以下にあるアクションのメタデータファイル(action.yml)のパスを返す
+func (project *Project) ActionMetadataFiles() []string {
+	var files []string
+	for _, name := range []string{"action.yml", "action.yaml"} {
+		p := filepath.Join(project.root, name)
+		if s, err := os.Stat(p); err == nil && !s.IsDir() {
+			files = append(files, p)
+		}
+	}
+	dir := filepath.Join(project.root, ".github", "actions")
+	_ = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() && IsActionMetadataFile(path) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files
+}
+
 // プロジェクトが指定されたファイルを知っている場合はtrueを返す
 func (project *Project) IsKnown(path string) bool {
 	return strings.HasPrefix(getAbsolutePath(path), project.root)
@@ -73,6 +105,74 @@ func (project *Project) ProjectConfig() *Config {
 	return project.config
 }
 
+// ActionLockはプロジェクトの.github/sisakulint-actions.lockを返す。プロジェクトがnilの場合やロックファイルがない場合はnilを返す
+func (project *Project) ActionLock() *ActionLock {
+	if project == nil {
+		return nil
+	}
+	return project.actionLock
+}
+
+// projectWorkflowはプロジェクトの.github/workflowsにあるワークフロー
+type projectWorkflow struct {
+	// pathはリポジトリのルートからの相対パス。区切り文字は/
+	path     string
+	workflow *ast.Workflow
+}
+
+// parsedWorkflowsは、プロジェクトの全てのワークフローを解析した結果を返す
+// ワークフローは最初の呼び出しで全て読み込んでキャッシュする。構文木はルールで変更しないこと
+func (project *Project) parsedWorkflows() []*projectWorkflow {
+	if project == nil {
+		return nil
+	}
+	project.workflowsOnce.Do(func() {
+		project.workflows = map[string][]ast.Event{}
+		files, err := yamlFilesInDir(project.WorkflowDirectory())
+		if err != nil {
+			return
+		}
+		for _, f := range files {
+			b, err := os.ReadFile(f)
+			if err != nil {
+				continue
+			}
+			w, _ := Parse(b)
+			if w == nil {
+				continue
+			}
+			rel, err := filepath.Rel(project.root, f)
+			if err != nil {
+				continue
+			}
+			pw := &projectWorkflow{path: filepath.ToSlash(rel), workflow: w}
+			project.parsed = append(project.parsed, pw)
+			key := ""
+			if w.Name != nil {
+				key = w.Name.Value
+			}
+			if key == "" {
+				key = pw.path
+			}
+			// 同じ名前のワークフローが複数ある場合はどれの完了でもworkflow_runが実行されるので、トリガーをまとめる
+			project.workflows[key] = append(project.workflows[key], w.On...)
+		}
+	})
+	return project.parsed
+}
+
+// workflowEventsは、名前がnameのワークフローのトリガーを返す。ワークフローが見つからない場合はfalseを返す
+// GitHubと同じく、name:のないワークフローはリポジトリのルートからの相対パスを名前とする
+// 同じ名前のワークフローが複数ある場合は、それら全てのトリガーを返す
+func (project *Project) workflowEvents(name string) ([]ast.Event, bool) {
+	if project == nil {
+		return nil, false
+	}
+	project.parsedWorkflows()
+	events, ok := project.workflows[name]
+	return events, ok
+}
+
 // Projectsはプロジェクトのset , 前に作られたprojectインスタンスをキャッシュして再利用
 type Projects struct {
 	known []*Project
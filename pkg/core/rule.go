@@ -6,16 +6,20 @@ import (
 	"io"
 
 	"github.com/sisaku-security/sisakulint/pkg/ast"
+	"gopkg.in/yaml.v3"
 )
 
 // BaseRuleはruleの基本構造体
 type BaseRule struct {
-	RuleName   string
-	RuleDesc   string
-	ruleErrors []*LintingError
-	autoFixers []AutoFixer
-	debugOut   io.Writer
-	userConfig *Config
+	RuleName string
+	RuleDesc string
+	// RuleSeverityはルールが報告するエラーの深刻度。SeverityNoneの場合はルール名から推定される
+	RuleSeverity Severity
+	ruleErrors   []*LintingError
+	autoFixers   []AutoFixer
+	debugOut     io.Writer
+	userConfig   *Config
+	triggerTrust *TriggerTrustModel
 }
 
 // CreateBaseRuleは新しいBaseRuleのインスタンスを作成する
@@ -26,12 +30,22 @@ func CreateBaseRule(name string, desc string) BaseRule {
 // Errorはソースの位置とエラーメッセージから新しいエラーを作成してrule instanceに追加する
 func (rule *BaseRule) Error(position *ast.Position, msg string) {
 	err := NewError(position, rule.RuleName, msg)
+	err.Severity = rule.Severity()
 	rule.ruleErrors = append(rule.ruleErrors, err)
 }
 
 // Errorf
 func (rule *BaseRule) Errorf(position *ast.Position, format string, args ...interface{}) {
 	err := FormattedError(position, rule.RuleName, format, args...)
+	err.Severity = rule.Severity()
+	rule.ruleErrors = append(rule.ruleErrors, err)
+}
+
+// ErrorfWithSeverityはルールの既定とは異なる深刻度を持つエラーを追加する
+// 同じルール内で検出条件によって深刻度が変わる場合に使用する
+func (rule *BaseRule) ErrorfWithSeverity(position *ast.Position, severity Severity, format string, args ...interface{}) {
+	err := FormattedError(position, rule.RuleName, format, args...)
+	err.Severity = severity
 	rule.ruleErrors = append(rule.ruleErrors, err)
 }
 
@@ -58,6 +72,14 @@ func (rule *BaseRule) RuleDescription() string {
 	return rule.RuleDesc
 }
 
+// Severityはルールが報告するエラーの深刻度を返す
+func (rule *BaseRule) Severity() Severity {
+	if rule.RuleSeverity != SeverityNone {
+		return rule.RuleSeverity
+	}
+	return severityOfRule(rule.RuleName)
+}
+
 func (rule *BaseRule) EnableDebugOutput(out io.Writer) {
 	rule.debugOut = out
 }
@@ -66,6 +88,19 @@ func (rule *BaseRule) UpdateConfig(config *Config) {
 	rule.userConfig = config
 }
 
+// UpdateTriggerTrustはルールが問い合わせるトリガーの信頼度のモデルを設定する
+func (rule *BaseRule) UpdateTriggerTrust(model *TriggerTrustModel) {
+	rule.triggerTrust = model
+}
+
+// TriggerTrustはトリガーの信頼度のモデルを返す。設定されていない場合は既定のモデルを返す
+func (rule *BaseRule) TriggerTrust() *TriggerTrustModel {
+	if rule.triggerTrust == nil {
+		return defaultTriggerTrust
+	}
+	return rule.triggerTrust
+}
+
 // AddAutoFixerはruleにAutoFixerを追加する
 // AutoFixersによって回収される
 func (rule *BaseRule) AddAutoFixer(fixer AutoFixer) {
@@ -83,8 +118,17 @@ type Rule interface {
 	Errors() []*LintingError
 	RuleNames() string
 	RuleDescription() string
+	Severity() Severity
 	EnableDebugOutput(out io.Writer)
 	UpdateConfig(config *Config)
+	UpdateTriggerTrust(model *TriggerTrustModel)
 	AddAutoFixer(fixer AutoFixer)
 	AutoFixers() []AutoFixer
 }
+
+// ConfigurableRuleは設定ファイルのrulesセクションでルール固有のオプションを受け取るルールが実装するインターフェース
+type ConfigurableRule interface {
+	Rule
+	// ConfigureOptionsはoptionsの値をデコードしてルールに適用する。不正なオプションの場合はエラーを返す
+	ConfigureOptions(options *yaml.Node) error
+}
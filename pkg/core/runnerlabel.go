@@ -0,0 +1,253 @@
+package core
+
+import (
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/sisaku-security/sisakulint/pkg/ast"
+	"github.com/sisaku-security/sisakulint/pkg/expressions"
+)
+
+// githubHostedRunnerLabels is the set of labels of GitHub-hosted runner images currently available.
+// * https://docs.github.com/en/actions/using-github-hosted-runners/about-github-hosted-runners#supported-runners-and-hardware-resources
+// * https://docs.github.com/en/actions/using-github-hosted-runners/about-larger-runners
+var githubHostedRunnerLabels = map[string]struct{}{
+	"ubuntu-latest":       {},
+	"ubuntu-24.04":        {},
+	"ubuntu-22.04":        {},
+	"ubuntu-24.04-arm":    {},
+	"ubuntu-22.04-arm":    {},
+	"ubuntu-slim":         {},
+	"windows-latest":      {},
+	"windows-2025":        {},
+	"windows-2022":        {},
+	"windows-11-arm":      {},
+	"macos-latest":        {},
+	"macos-latest-large":  {},
+	"macos-latest-xlarge": {},
+	"macos-26":            {},
+	"macos-26-xlarge":     {},
+	"macos-15":            {},
+	"macos-15-large":      {},
+	"macos-15-intel":      {},
+	"macos-15-xlarge":     {},
+	"macos-14":            {},
+	"macos-14-large":      {},
+	"macos-14-xlarge":     {},
+}
+
+// retiredRunnerLabels maps labels of GitHub-hosted runner images which were deprecated or removed
+// to the images which should be used instead.
+// * https://github.com/actions/runner-images#available-images
+var retiredRunnerLabels = map[string]string{
+	"ubuntu-16.04":    "ubuntu-24.04",
+	"ubuntu-18.04":    "ubuntu-24.04",
+	"ubuntu-20.04":    "ubuntu-24.04",
+	"windows-2016":    "windows-2025",
+	"windows-2019":    "windows-2025",
+	"macos-10.15":     "macos-15",
+	"macos-11":        "macos-15",
+	"macos-12":        "macos-15",
+	"macos-12-xl":     "macos-15-xlarge",
+	"macos-13":        "macos-15-intel",
+	"macos-13-large":  "macos-15-large",
+	"macos-13-xlarge": "macos-15-xlarge",
+}
+
+// selfHostedRunnerPresetLabels is the set of labels which are given to every self-hosted runner by default.
+// * https://docs.github.com/en/actions/hosting-your-own-runners/using-self-hosted-runners-in-a-workflow#using-default-labels-to-route-jobs
+var selfHostedRunnerPresetLabels = map[string]struct{}{
+	"self-hosted": {},
+	"linux":       {},
+	"macos":       {},
+	"windows":     {},
+	"x64":         {},
+	"arm":         {},
+	"arm64":       {},
+}
+
+var matrixPropertyExpr = regexp.MustCompile(`^\$\{\{\s*matrix\.([a-zA-Z_][a-zA-Z0-9_-]*)\s*\}\}$`)
+
+// RunnerLabelRule is a rule checker to check labels in "runs-on:". Labels are checked against labels of
+// GitHub-hosted runners and labels of self-hosted runners configured in "self-hosted-runner" of the config file.
+// It also reports self-hosted runners in workflows which pull requests from forks can trigger, since untrusted
+// code from forks can then run on the runner and persist there.
+// * https://docs.github.com/en/actions/writing-workflows/workflow-syntax-for-github-actions#jobsjob_idruns-on
+// * https://docs.github.com/en/actions/security-for-github-actions/security-guides/security-hardening-for-github-actions#hardening-for-self-hosted-runners
+type RunnerLabelRule struct {
+	BaseRule
+	// forkTrigger is the name of the event which pull requests from forks can trigger, as classified by the trigger trust
+	// model. Empty when no such event exists.
+	forkTrigger string
+}
+
+// NewRunnerLabelRule creates a new RunnerLabelRule instance.
+func NewRunnerLabelRule() *RunnerLabelRule {
+	return &RunnerLabelRule{
+		BaseRule: BaseRule{
+			RuleName: "runner-label",
+			RuleDesc: "Checks for labels of runners in \"runs-on:\" against GitHub-hosted runners and configured self-hosted runners",
+		},
+	}
+}
+
+// VisitWorkflowPre is callback when visiting Workflow node before visiting its children.
+func (rule *RunnerLabelRule) VisitWorkflowPre(n *ast.Workflow) error {
+	rule.forkTrigger = ""
+	if events := rule.TriggerTrust().ForkTriggers(n); len(events) > 0 {
+		rule.forkTrigger = events[0].EventName()
+	}
+	return nil
+}
+
+// runnerLabel is a label in "runs-on:" after resolving matrix values.
+type runnerLabel struct {
+	value string
+	pos   *ast.Position
+}
+
+// VisitJobPre is callback when visiting Job node before visiting its children.
+func (rule *RunnerLabelRule) VisitJobPre(n *ast.Job) error {
+	if n.RunsOn == nil {
+		return nil
+	}
+
+	var labels []runnerLabel
+	if n.RunsOn.LabelsExpr != nil {
+		labels = rule.resolveLabel(n.RunsOn.LabelsExpr, n.Strategy)
+	}
+	for _, l := range n.RunsOn.Labels {
+		if l != nil {
+			labels = append(labels, rule.resolveLabel(l, n.Strategy)...)
+		}
+	}
+
+	selfHosted := n.RunsOn.Group != nil
+	for _, l := range labels {
+		if rule.checkLabel(l) {
+			selfHosted = true
+		}
+	}
+
+	if selfHosted && rule.forkTrigger != "" {
+		pos := n.Pos
+		if n.RunsOn.Group != nil {
+			pos = n.RunsOn.Group.Pos
+		} else if len(labels) > 0 {
+			pos = labels[0].pos
+		}
+		rule.Errorf(
+			pos,
+			"job %q runs on a self-hosted runner but the workflow can be triggered by %q from forked repositories. untrusted code from forks can run on the runner and persist on it. use GitHub-hosted runners for jobs triggered by pull requests. see https://docs.github.com/en/actions/security-for-github-actions/security-guides/security-hardening-for-github-actions#hardening-for-self-hosted-runners",
+			n.ID.Value,
+			rule.forkTrigger,
+		)
+	}
+	return nil
+}
+
+// resolveLabel resolves "${{ matrix.xxx }}" to values of the matrix row. Other expressions are not checked.
+func (rule *RunnerLabelRule) resolveLabel(s *ast.String, strategy *ast.Strategy) []runnerLabel {
+	if !strings.Contains(s.Value, "${{") {
+		return []runnerLabel{{s.Value, s.Pos}}
+	}
+	m := matrixPropertyExpr.FindStringSubmatch(strings.TrimSpace(s.Value))
+	if m == nil || strategy == nil || strategy.Matrix == nil {
+		return nil
+	}
+	name := strings.ToLower(m[1])
+
+	var values []ast.RawYAMLValue
+	if row, ok := strategy.Matrix.Rows[name]; ok && row.Expression == nil {
+		values = append(values, row.Values...)
+	}
+	if inc := strategy.Matrix.Include; inc != nil {
+		for _, c := range inc.Combinations {
+			if a, ok := c.Assigns[name]; ok {
+				values = append(values, a.Value)
+			}
+		}
+	}
+
+	var labels []runnerLabel
+	for _, v := range values {
+		switch v := v.(type) {
+		case *ast.RawYAMLString:
+			labels = append(labels, runnerLabel{v.Value, v.Posi})
+		case *ast.RawYAMLArray:
+			for _, e := range v.Elems {
+				if s, ok := e.(*ast.RawYAMLString); ok {
+					labels = append(labels, runnerLabel{s.Value, s.Posi})
+				}
+			}
+		}
+	}
+	return labels
+}
+
+// checkLabel reports unknown or retired label. It returns true when the label is a label of self-hosted runners.
+func (rule *RunnerLabelRule) checkLabel(l runnerLabel) bool {
+	if strings.Contains(l.value, "${{") {
+		return false
+	}
+	label := strings.ToLower(l.value)
+	if _, ok := githubHostedRunnerLabels[label]; ok {
+		return false
+	}
+	if alt, ok := retiredRunnerLabels[label]; ok {
+		rule.Errorf(
+			l.pos,
+			"runner image %q is deprecated or no longer available on GitHub-hosted runners. use %q or another supported image instead. see https://github.com/actions/runner-images#available-images",
+			l.value,
+			alt,
+		)
+		return false
+	}
+	if _, ok := selfHostedRunnerPresetLabels[label]; ok {
+		return true
+	}
+	if rule.isConfiguredLabel(label) {
+		return true
+	}
+
+	rule.Errorf(
+		l.pos,
+		"label %q is unknown. available labels are %s. if it is a custom label for self-hosted runner, set list of labels in \"self-hosted-runner\" section of sisakulint.yaml config file",
+		l.value,
+		rule.knownLabels(),
+	)
+	return false
+}
+
+// isConfiguredLabel returns true when the label matches one of labels in "self-hosted-runner" config.
+// Labels in the config can be glob patterns like "linux-*".
+func (rule *RunnerLabelRule) isConfiguredLabel(label string) bool {
+	if rule.userConfig == nil {
+		return false
+	}
+	for _, p := range rule.userConfig.SelfHostedRunner.Labels {
+		p = strings.ToLower(p)
+		if p == label {
+			return true
+		}
+		if ok, err := path.Match(p, label); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (rule *RunnerLabelRule) knownLabels() string {
+	labels := make([]string, 0, len(githubHostedRunnerLabels)+len(selfHostedRunnerPresetLabels))
+	for l := range githubHostedRunnerLabels {
+		labels = append(labels, l)
+	}
+	for l := range selfHostedRunnerPresetLabels {
+		labels = append(labels, l)
+	}
+	if rule.userConfig != nil {
+		labels = append(labels, rule.userConfig.SelfHostedRunner.Labels...)
+	}
+	return expressions.SortedQuotes(labels)
+}
@@ -0,0 +1,229 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRunnerLabelRule(t *testing.T) {
+	tests := []struct {
+		name         string
+		on           string
+		job          string
+		labels       []string
+		unprivileged []*TriggerTrustEntry
+		wantErrors   []string
+	}{
+		{
+			name: "GitHub-hosted runner",
+			on:   "push",
+			job:  "runs-on: ubuntu-latest",
+		},
+		{
+			name: "labels are case-insensitive",
+			on:   "push",
+			job:  "runs-on: [Self-Hosted, Linux, X64]",
+		},
+		{
+			name:       "typo of label",
+			on:         "push",
+			job:        "runs-on: ubuntu-lastest",
+			wantErrors: []string{`label "ubuntu-lastest" is unknown`},
+		},
+		{
+			name:       "retired image",
+			on:         "push",
+			job:        "runs-on: ubuntu-20.04",
+			wantErrors: []string{`runner image "ubuntu-20.04" is deprecated or no longer available`},
+		},
+		{
+			name:   "configured self-hosted label",
+			on:     "push",
+			job:    "runs-on: [self-hosted, gpu]",
+			labels: []string{"gpu"},
+		},
+		{
+			name:   "configured label pattern",
+			on:     "push",
+			job:    "runs-on: [self-hosted, linux-large-1]",
+			labels: []string{"linux-large-*"},
+		},
+		{
+			name:       "unknown label of self-hosted runner",
+			on:         "push",
+			job:        "runs-on: [self-hosted, gpu]",
+			labels:     []string{"cpu"},
+			wantErrors: []string{`label "gpu" is unknown. available labels are "arm", "arm64", "cpu"`},
+		},
+		{
+			name: "matrix values",
+			on:   "push",
+			job: `strategy:
+      matrix:
+        os: [ubuntu-latest, macos-12, windoes-latest]
+        include:
+          - os: [self-hosted, linux]
+    runs-on: ${{ matrix.os }}`,
+			wantErrors: []string{
+				`runner image "macos-12" is deprecated`,
+				`label "windoes-latest" is unknown`,
+			},
+		},
+		{
+			name: "other expressions are not checked",
+			on:   "push",
+			job:  "runs-on: ${{ inputs.runner }}",
+		},
+		{
+			name: "runner group",
+			on:   "push",
+			job: `runs-on:
+      group: large-runners
+      labels: [ubuntu-latest]`,
+		},
+		{
+			name:       "self-hosted runner with pull_request",
+			on:         "pull_request",
+			job:        "runs-on: [self-hosted, linux]",
+			wantErrors: []string{`job "test" runs on a self-hosted runner but the workflow can be triggered by "pull_request" from forked repositories`},
+		},
+		{
+			name:       "configured self-hosted label with pull_request_target",
+			on:         "pull_request_target",
+			job:        "runs-on: gpu",
+			labels:     []string{"gpu"},
+			wantErrors: []string{`triggered by "pull_request_target"`},
+		},
+		{
+			name: "runner group with pull_request",
+			on:   "pull_request",
+			job: `runs-on:
+      group: large-runners`,
+			wantErrors: []string{`job "test" runs on a self-hosted runner`},
+		},
+		{
+			name: "self-hosted runner from matrix with pull_request",
+			on:   "pull_request",
+			job: `strategy:
+      matrix:
+        runner: [ubuntu-latest, self-hosted]
+    runs-on: ${{ matrix.runner }}`,
+			wantErrors: []string{`job "test" runs on a self-hosted runner`},
+		},
+		{
+			name:         "pull_request declared unprivileged in trigger-trust",
+			on:           "pull_request",
+			job:          "runs-on: [self-hosted, linux]",
+			unprivileged: []*TriggerTrustEntry{{Event: "pull_request"}},
+		},
+		{
+			name:         "activity types declared unprivileged in trigger-trust",
+			on:           "{pull_request_target: {types: [labeled]}}",
+			job:          "runs-on: [self-hosted, linux]",
+			unprivileged: []*TriggerTrustEntry{{Event: "pull_request_target", Types: []string{"labeled"}}},
+		},
+		{
+			name:         "other activity types than declared unprivileged",
+			on:           "{pull_request_target: {types: [labeled, opened]}}",
+			job:          "runs-on: [self-hosted, linux]",
+			unprivileged: []*TriggerTrustEntry{{Event: "pull_request_target", Types: []string{"labeled"}}},
+			wantErrors:   []string{`triggered by "pull_request_target"`},
+		},
+		{
+			name: "GitHub-hosted runner with pull_request",
+			on:   "pull_request",
+			job:  "runs-on: ubuntu-24.04",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := "on: " + tt.on + "\njobs:\n  test:\n    " + tt.job + "\n    steps:\n      - run: echo\n"
+			w, errs := Parse([]byte(src))
+			if len(errs) > 0 {
+				t.Fatal(errs)
+			}
+			rule := NewRunnerLabelRule()
+			cfg := &Config{}
+			cfg.SelfHostedRunner.Labels = tt.labels
+			cfg.TriggerTrust.Unprivileged = tt.unprivileged
+			rule.UpdateConfig(cfg)
+			rule.UpdateTriggerTrust(NewTriggerTrustModel(&cfg.TriggerTrust, nil))
+			v := NewSyntaxTreeVisitor()
+			v.AddVisitor(rule)
+			if err := v.VisitTree(w); err != nil {
+				t.Fatal(err)
+			}
+
+			got := rule.Errors()
+			if len(got) != len(tt.wantErrors) {
+				t.Fatalf("got %d errors, want %d: %v", len(got), len(tt.wantErrors), got)
+			}
+			for i, want := range tt.wantErrors {
+				if !strings.Contains(got[i].Description, want) {
+					t.Errorf("error %q does not contain %q", got[i].Description, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRunnerLabelRule_WorkflowCall(t *testing.T) {
+	callee := `on: workflow_call
+jobs:
+  test:
+    runs-on: [self-hosted, linux]
+    steps:
+      - run: make test
+`
+	tests := []struct {
+		name      string
+		caller    string
+		wantError bool
+	}{
+		{
+			name:      "called from pull_request",
+			caller:    "on: pull_request\njobs:\n  call:\n    uses: ./.github/workflows/callee.yml\n",
+			wantError: true,
+		},
+		{
+			name:   "called from push",
+			caller: "on: push\njobs:\n  call:\n    uses: ./.github/workflows/callee.yml\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			project, err := NewProject(writeProject(t, map[string]string{
+				"callee.yml": callee,
+				"caller.yml": tt.caller,
+			}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			w, errs := Parse([]byte(callee))
+			if len(errs) > 0 {
+				t.Fatal(errs)
+			}
+			rule := NewRunnerLabelRule()
+			rule.UpdateConfig(&Config{})
+			rule.UpdateTriggerTrust(NewTriggerTrustModel(nil, project).forWorkflow(".github/workflows/callee.yml", nil, w))
+			v := NewSyntaxTreeVisitor()
+			v.AddVisitor(rule)
+			if err := v.VisitTree(w); err != nil {
+				t.Fatal(err)
+			}
+
+			got := rule.Errors()
+			if !tt.wantError {
+				if len(got) > 0 {
+					t.Fatalf("got unexpected errors: %v", got)
+				}
+				return
+			}
+			if len(got) != 1 || !strings.Contains(got[0].Description, `triggered by "workflow_call" from forked repositories`) {
+				t.Fatalf("got %v, want an error about the self-hosted runner", got)
+			}
+		})
+	}
+}
@@ -1,13 +1,204 @@
 package core
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+
 	"github.com/haya14busa/go-sarif/sarif"
 )
 
-func toResult(fields *TemplateFields) sarif.Result {
-	return sarif.Result{
-		RuleID: sarif.String(fields.Type),
-		Level:  sarif.Warning.Ptr(),
+// ruleDocsBaseURLはsisakulintのドキュメントのURL
+const ruleDocsBaseURL = "https://sisaku-security.github.io/lint/docs/"
+
+// fingerprintKeyはpartialFingerprintsに設定するsisakulint独自のフィンガープリントのキー
+const fingerprintKey = "sisakulint/v1"
+
+// ruleDocPagesはルール名とdocs/*.mdのページの対応
+var ruleDocPages = map[string]string{
+	"action-advisory":                    "actionadvisory",
+	"action-list":                        "actionlist",
+	"action-metadata":                    "actionmetadata",
+	"action":                             "actionrule",
+	"artifact-poisoning-critical":        "artifactpoisoningcritical",
+	"artifact-poisoning-medium":          "artifactpoisoningmedium",
+	"artipacked":                         "artipacked",
+	"bot-conditions":                     "botconditionsrule",
+	"cache-poisoning-poisonable-step":    "cachepoisoningpoisonablesteprule",
+	"cache-poisoning":                    "cachepoisoningrule",
+	"code-injection-critical":            "codeinjectioncritical",
+	"code-injection-medium":              "codeinjectionmedium",
+	"commit-sha":                         "commitsharule",
+	"cond":                               "conditionalrule",
+	"credentials":                        "credentialrules",
+	"deprecated-commands":                "deprecatedcommandsrule",
+	"env-var":                            "environmentvariablerule",
+	"envpath-injection-critical":         "envpathinjectioncritical",
+	"envpath-injection-medium":           "envpathinjectionmedium",
+	"envvar-injection-critical":          "envvarinjectioncritical",
+	"envvar-injection-medium":            "envvarinjectionmedium",
+	"expression":                         "expressionrule",
+	"id":                                 "idrule",
+	"image-digest":                       "imagedigest",
+	"improper-access-control":            "improperaccesscontrol",
+	"needs":                              "jobneeds",
+	"permissions":                        "permissions",
+	"runner-label":                       "runnerlabel",
+	"secret-exposure":                    "secretexposure",
+	"shellcheck":                         "shellcheck",
+	"missing-timeout-minutes":            "timeoutminutesrule",
+	"unmasked-secret-exposure":           "unmaskedsecretexposure",
+	"unsound-contains":                   "unsoundcontains",
+	"untrusted-checkout":                 "untrustedcheckout",
+	"untrusted-checkout-toctou/critical": "untrustedcheckouttoctoucritical",
+	"untrusted-checkout-toctou/high":     "untrustedcheckouttoctouhigh",
+	"workflow-call":                      "workflowcall",
+}
+
+// heuristicRulesはヒューリスティックな検出を行うため誤検知の可能性があるルール
+var heuristicRules = map[string]bool{
+	"artifact-poisoning-medium":          true,
+	"artipacked":                         true,
+	"bot-conditions":                     true,
+	"cache-poisoning-poisonable-step":    true,
+	"credentials":                        true,
+	"unsound-contains":                   true,
+	"untrusted-checkout-toctou/critical": true,
+	"untrusted-checkout-toctou/high":     true,
+}
+
+// ruleHelpURIはルールのドキュメントのURLを返す
+// ドキュメントのページがないルールはドキュメントのトップページを返す
+func ruleHelpURI(name string) string {
+	if page, ok := ruleDocPages[name]; ok {
+		return ruleDocsBaseURL + page + "/"
+	}
+	return ruleDocsBaseURL
+}
+
+// rulePrecisionはGitHub code scanningのprecisionプロパティの値を返す
+func rulePrecision(name string) string {
+	if heuristicRules[name] {
+		return "medium"
+	}
+	return "high"
+}
+
+// ruleTagsはルールのタグを返す
+func ruleTags(rule *RuleTemplateField) []string {
+	sev := rule.Severity
+	if sev == SeverityNone {
+		sev = severityOfRule(rule.Name)
+	}
+	category := "security"
+	if sev <= SeverityLow {
+		category = "maintainability"
+	}
+	return []string{category, sev.String()}
+}
+
+// shortDescriptionはルールの説明の最初の文を返す
+func shortDescription(desc string) string {
+	if i := strings.Index(desc, ". "); i >= 0 {
+		return desc[:i+1]
+	}
+	return desc
+}
+
+// toReportingDescriptorは登録済みのルールをSARIFのreportingDescriptorに変換する
+func toReportingDescriptor(rule *RuleTemplateField) sarif.ReportingDescriptor {
+	desc := rule.Description
+	if desc == "" {
+		desc = rule.Name
+	}
+	helpURI := ruleHelpURI(rule.Name)
+	return sarif.ReportingDescriptor{
+		ID:               rule.Name,
+		Name:             sarif.String(toPascalCase(rule.Name)),
+		ShortDescription: &sarif.MultiformatMessageString{Text: shortDescription(desc)},
+		FullDescription:  &sarif.MultiformatMessageString{Text: desc},
+		Help: &sarif.MultiformatMessageString{
+			Text:     desc + " See " + helpURI,
+			Markdown: sarif.String(desc + "\n\nSee [documentation](" + helpURI + ")."),
+		},
+		HelpURI: sarif.String(helpURI),
+		DefaultConfiguration: &sarif.ReportingConfiguration{
+			Level: sarif.Level(rule.Severity.SARIFLevel()).Ptr(),
+		},
+		Properties: &sarif.PropertyBag{
+			Tags: ruleTags(rule),
+		},
+	}
+}
+
+// descriptorPropertiesはreportingDescriptor.propertiesに追加するプロパティを返す
+func descriptorProperties(rule *RuleTemplateField) map[string]interface{} {
+	sev := rule.Severity
+	if sev == SeverityNone {
+		sev = severityOfRule(rule.Name)
+	}
+	return map[string]interface{}{
+		"precision":         rulePrecision(rule.Name),
+		"security-severity": sev.SecurityScore(),
+	}
+}
+
+// templateFieldsSeverityはTemplateFieldsの深刻度を返す
+func templateFieldsSeverity(fields *TemplateFields) Severity {
+	sev, err := ParseSeverity(fields.Severity)
+	if err != nil {
+		sev = severityOfRule(fields.Type)
+	}
+	return sev
+}
+
+// sarifFingerprintはエラーの位置に依存しないフィンガープリントを返す
+// Linterが計算したフィンガープリントがあればそれを使い、ベースラインと同じ値にする
+func sarifFingerprint(fields *TemplateFields) string {
+	if fields.Fingerprint != "" {
+		return fields.Fingerprint
+	}
+	h := sha256.New()
+	h.Write([]byte(fields.Type))
+	h.Write([]byte{0})
+	h.Write([]byte(fields.Filepath))
+	h.Write([]byte{0})
+	h.Write([]byte(strings.Join(strings.Fields(fields.Snippet), " ")))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// toFixはFixSuggestionをSARIFのfixに変換する
+func toFix(fields *TemplateFields) sarif.Fix {
+	fix := fields.Fix
+	// 削除する範囲はStartLineの行頭からEndLineの次の行の行頭まで
+	region := sarif.Region{
+		StartLine:   sarif.Int64(int64(fix.StartLine)),
+		StartColumn: sarif.Int64(1),
+		EndLine:     sarif.Int64(int64(fix.EndLine + 1)),
+		EndColumn:   sarif.Int64(1),
+	}
+	return sarif.Fix{
+		Description: &sarif.Message{Text: sarif.String("Apply the autofix of " + fix.RuleName)},
+		ArtifactChanges: []sarif.ArtifactChange{
+			{
+				ArtifactLocation: sarif.ArtifactLocation{URI: &fields.Filepath},
+				Replacements: []sarif.Replacement{
+					{
+						DeletedRegion:   region,
+						InsertedContent: &sarif.ArtifactContent{Text: sarif.String(fix.Replacement)},
+					},
+				},
+			},
+		},
+	}
+}
+
+func toResult(fields *TemplateFields, ruleIndex int) sarif.Result {
+	result := sarif.Result{
+		RuleID:    sarif.String(fields.Type),
+		RuleIndex: sarif.Int64(int64(ruleIndex)),
+		Level:     sarif.Level(templateFieldsSeverity(fields).SARIFLevel()).Ptr(),
 		Message: sarif.Message{
 			Text: &fields.Message,
 		},
@@ -29,10 +220,80 @@ func toResult(fields *TemplateFields) sarif.Result {
 				},
 			},
 		},
+		PartialFingerprints: map[string]string{
+			fingerprintKey: sarifFingerprint(fields),
+		},
+	}
+	if fields.Fix != nil {
+		result.Fixes = []sarif.Fix{toFix(fields)}
+	}
+	if fields.Suppression != "" {
+		result.Suppressions = []sarif.Suppression{
+			{
+				Kind:          sarif.InSource,
+				Justification: sarif.String(fields.Suppression),
+			},
+		}
+	}
+	return result
+}
+
+// resultPropertiesはresult.propertiesに追加するプロパティを返す
+// go-sarifのPropertyBagはtags以外のプロパティを表現できないため、エンコード後のJSONに追加する
+func resultProperties(fields *TemplateFields) map[string]interface{} {
+	sev := templateFieldsSeverity(fields)
+	return map[string]interface{}{
+		"severity":          sev.String(),
+		"security-severity": sev.SecurityScore(),
+	}
+}
+
+// mergeJSONPropertiesはJSONの配列の各要素のpropertiesにプロパティを追加する
+func mergeJSONProperties(items interface{}, props []map[string]interface{}) {
+	list, ok := items.([]interface{})
+	if !ok {
+		return
+	}
+	for i, item := range list {
+		obj, ok := item.(map[string]interface{})
+		if !ok || i >= len(props) {
+			continue
+		}
+		bag, ok := obj["properties"].(map[string]interface{})
+		if !ok {
+			bag = map[string]interface{}{}
+		}
+		for k, v := range props[i] {
+			bag[k] = v
+		}
+		obj["properties"] = bag
+	}
+}
+
+// mergeSARIFPropertiesはエンコード済みのSARIFのrulesとresultsにプロパティを追加する
+func mergeSARIFProperties(encoded []byte, ruleProps, resultProps []map[string]interface{}) ([]byte, error) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(encoded, &doc); err != nil {
+		return nil, err
+	}
+	runs, ok := doc["runs"].([]interface{})
+	if !ok || len(runs) == 0 {
+		return encoded, nil
 	}
+	run, ok := runs[0].(map[string]interface{})
+	if !ok {
+		return encoded, nil
+	}
+	if tool, ok := run["tool"].(map[string]interface{}); ok {
+		if driver, ok := tool["driver"].(map[string]interface{}); ok {
+			mergeJSONProperties(driver["rules"], ruleProps)
+		}
+	}
+	mergeJSONProperties(run["results"], resultProps)
+	return json.Marshal(doc)
 }
 
-func toSARIF(fields []*TemplateFields) (string, error) {
+func toSARIF(fields []*TemplateFields, rules []*RuleTemplateField) (string, error) {
 	s := &sarif.Sarif{
 		Version: sarif.The210,
 		Schema:  sarif.String("https://schemastore.azurewebsites.net/schemas/json/sarif-2.1.0.json"),
@@ -40,18 +301,44 @@ func toSARIF(fields []*TemplateFields) (string, error) {
 			{
 				Tool: sarif.Tool{
 					Driver: sarif.ToolComponent{
-						Name: "sisakulint",
+						Name:           "sisakulint",
+						InformationURI: sarif.String(ruleDocsBaseURL),
 					},
 				},
 			},
 		},
 	}
+
+	ruleIndices := make(map[string]int, len(rules))
+	ruleProps := make([]map[string]interface{}, 0, len(rules))
+	addRule := func(rule *RuleTemplateField) int {
+		if idx, ok := ruleIndices[rule.Name]; ok {
+			return idx
+		}
+		idx := len(s.Runs[0].Tool.Driver.Rules)
+		ruleIndices[rule.Name] = idx
+		s.Runs[0].Tool.Driver.Rules = append(s.Runs[0].Tool.Driver.Rules, toReportingDescriptor(rule))
+		ruleProps = append(ruleProps, descriptorProperties(rule))
+		return idx
+	}
+	for _, rule := range rules {
+		addRule(rule)
+	}
+
+	resultProps := make([]map[string]interface{}, 0, len(fields))
 	for _, f := range fields {
-		s.Runs[0].Results = append(s.Runs[0].Results, toResult(f))
+		// 登録されていないルール(構文エラーなど)のエラーはその場でルールを追加する
+		idx := addRule(&RuleTemplateField{Name: f.Type, Severity: templateFieldsSeverity(f)})
+		s.Runs[0].Results = append(s.Runs[0].Results, toResult(f, idx))
+		resultProps = append(resultProps, resultProperties(f))
+	}
+	encoded, err := s.Marshal()
+	if err != nil {
+		return "", err
 	}
-	json, err := s.Marshal()
+	out, err := mergeSARIFProperties(encoded, ruleProps, resultProps)
 	if err != nil {
 		return "", err
 	}
-	return string(json), nil
+	return string(out), nil
 }
@@ -0,0 +1,230 @@
+package core
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type sarifTestDoc struct {
+	Runs []struct {
+		Tool struct {
+			Driver struct {
+				Rules []struct {
+					ID               string `json:"id"`
+					HelpURI          string `json:"helpUri"`
+					ShortDescription struct {
+						Text string `json:"text"`
+					} `json:"shortDescription"`
+					FullDescription struct {
+						Text string `json:"text"`
+					} `json:"fullDescription"`
+					Properties map[string]interface{} `json:"properties"`
+				} `json:"rules"`
+			} `json:"driver"`
+		} `json:"tool"`
+		Results []struct {
+			RuleID              string            `json:"ruleId"`
+			RuleIndex           *int              `json:"ruleIndex"`
+			PartialFingerprints map[string]string `json:"partialFingerprints"`
+			Fixes               []struct {
+				ArtifactChanges []struct {
+					Replacements []struct {
+						DeletedRegion struct {
+							StartLine int `json:"startLine"`
+							EndLine   int `json:"endLine"`
+						} `json:"deletedRegion"`
+						InsertedContent struct {
+							Text string `json:"text"`
+						} `json:"insertedContent"`
+					} `json:"replacements"`
+				} `json:"artifactChanges"`
+			} `json:"fixes"`
+		} `json:"results"`
+	} `json:"runs"`
+}
+
+func TestToSARIF_RuleMetadata(t *testing.T) {
+	rules := []*RuleTemplateField{
+		{Name: "code-injection-critical", Description: "Checks for code injection. More details follow.", Severity: SeverityCritical},
+		{Name: "artipacked", Description: "Detects credential leakage", Severity: SeverityMedium},
+	}
+	fields := []*TemplateFields{
+		{Message: "m", Filepath: "w.yml", Line: 3, Column: 5, Type: "artipacked", Severity: "medium", Snippet: "  - uses: actions/checkout@v4"},
+		{Message: "m", Filepath: "w.yml", Line: 1, Column: 1, Type: "syntax", Severity: "medium"},
+	}
+
+	out, err := toSARIF(fields, rules)
+	if err != nil {
+		t.Fatalf("toSARIF() returned error: %v", err)
+	}
+	var doc sarifTestDoc
+	if err := json.Unmarshal([]byte(out), &doc); err != nil {
+		t.Fatalf("failed to decode SARIF output: %v", err)
+	}
+
+	descs := doc.Runs[0].Tool.Driver.Rules
+	if len(descs) != 3 {
+		t.Fatalf("got %d rules, want 3 (2 registered + syntax)", len(descs))
+	}
+	if descs[0].HelpURI != "https://sisaku-security.github.io/lint/docs/codeinjectioncritical/" {
+		t.Errorf("helpUri = %q", descs[0].HelpURI)
+	}
+	if descs[0].ShortDescription.Text != "Checks for code injection." {
+		t.Errorf("shortDescription = %q", descs[0].ShortDescription.Text)
+	}
+	if descs[0].FullDescription.Text != rules[0].Description {
+		t.Errorf("fullDescription = %q", descs[0].FullDescription.Text)
+	}
+	if descs[0].Properties["precision"] != "high" || descs[0].Properties["security-severity"] != "9.5" {
+		t.Errorf("properties of code-injection-critical = %v", descs[0].Properties)
+	}
+	if descs[1].Properties["precision"] != "medium" {
+		t.Errorf("precision of artipacked = %v, want medium", descs[1].Properties["precision"])
+	}
+	if descs[2].ID != "syntax" {
+		t.Errorf("rules[2].id = %q, want syntax", descs[2].ID)
+	}
+
+	results := doc.Runs[0].Results
+	if results[0].RuleIndex == nil || *results[0].RuleIndex != 1 {
+		t.Errorf("results[0].ruleIndex = %v, want 1", results[0].RuleIndex)
+	}
+	if results[1].RuleIndex == nil || *results[1].RuleIndex != 2 {
+		t.Errorf("results[1].ruleIndex = %v, want 2", results[1].RuleIndex)
+	}
+	if results[0].PartialFingerprints[fingerprintKey] == "" {
+		t.Error("results[0] has no partial fingerprint")
+	}
+}
+
+func TestToSARIF_FingerprintIgnoresLine(t *testing.T) {
+	a := &TemplateFields{Filepath: "w.yml", Line: 3, Type: "commit-sha", Snippet: "  - uses: actions/checkout@v4"}
+	b := &TemplateFields{Filepath: "w.yml", Line: 10, Type: "commit-sha", Snippet: "      - uses:   actions/checkout@v4"}
+	if sarifFingerprint(a) != sarifFingerprint(b) {
+		t.Error("fingerprints differ for the same snippet at different lines")
+	}
+	c := &TemplateFields{Filepath: "w.yml", Line: 3, Type: "commit-sha", Snippet: "  - uses: actions/setup-go@v5"}
+	if sarifFingerprint(a) == sarifFingerprint(c) {
+		t.Error("fingerprints are equal for different snippets")
+	}
+}
+
+func TestToSARIF_Fixes(t *testing.T) {
+	before := []byte("on: push\njobs:\n  test:\n    runs-on: ubuntu-latest\n")
+	after := []byte("on: push\njobs:\n  test:\n    runs-on: ubuntu-latest\n    timeout-minutes: 5\n")
+	fix := NewFixSuggestion("missing-timeout-minutes", before, after)
+	if fix == nil {
+		t.Fatal("NewFixSuggestion() returned nil")
+	}
+	if fix.StartLine != 5 || fix.EndLine != 4 || fix.Replacement != "    timeout-minutes: 5\n" {
+		t.Fatalf("unexpected fix suggestion: %+v", fix)
+	}
+
+	fields := []*TemplateFields{
+		{Message: "m", Filepath: "w.yml", Line: 3, Column: 3, Type: "missing-timeout-minutes", Severity: "low", Fix: fix},
+	}
+	out, err := toSARIF(fields, nil)
+	if err != nil {
+		t.Fatalf("toSARIF() returned error: %v", err)
+	}
+	var doc sarifTestDoc
+	if err := json.Unmarshal([]byte(out), &doc); err != nil {
+		t.Fatalf("failed to decode SARIF output: %v", err)
+	}
+	fixes := doc.Runs[0].Results[0].Fixes
+	if len(fixes) != 1 {
+		t.Fatalf("got %d fixes, want 1", len(fixes))
+	}
+	r := fixes[0].ArtifactChanges[0].Replacements[0]
+	if r.DeletedRegion.StartLine != 5 || r.DeletedRegion.EndLine != 5 {
+		t.Errorf("deletedRegion = %+v, want empty region at line 5", r.DeletedRegion)
+	}
+	if r.InsertedContent.Text != fix.Replacement {
+		t.Errorf("insertedContent = %q, want %q", r.InsertedContent.Text, fix.Replacement)
+	}
+
+	if NewFixSuggestion("x", before, before) != nil {
+		t.Error("NewFixSuggestion() should return nil when nothing changed")
+	}
+}
+
+func TestLinter_FixSuggestionsPerFinding(t *testing.T) {
+	src := `on: push
+permissions: {}
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    timeout-minutes: 5
+    steps:
+      - run: echo a
+      - run: echo b
+`
+	l, err := NewLinter(io.Discard, &LinterOptions{CustomErrorMessageFormat: "{{sarif .}}"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := l.Lint("w.yml", []byte(src), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 各エラーには、そのステップを修正するAutoFixerの修正内容だけが添付される
+	want := map[int]string{
+		8: "      - timeout-minutes: 5\n        run: echo a\n",
+		9: "      - timeout-minutes: 5\n        run: echo b\n",
+	}
+	for _, e := range res.Errors {
+		if e.Type != "missing-timeout-minutes" {
+			continue
+		}
+		if e.Fix == nil {
+			t.Errorf("no fix suggestion for line %d", e.LineNumber)
+			continue
+		}
+		if e.Fix.StartLine != e.LineNumber || e.Fix.EndLine != e.LineNumber || e.Fix.Replacement != want[e.LineNumber] {
+			t.Errorf("unexpected fix suggestion for line %d: %+v", e.LineNumber, e.Fix)
+		}
+		delete(want, e.LineNumber)
+	}
+	if len(want) > 0 {
+		t.Errorf("no error at lines %v", want)
+	}
+
+	// 修正内容を求めた後も構文木は変更されておらず、AutoFixerをもう一度適用できる
+	for _, s := range res.ParsedWorkflow.Jobs["build"].Steps {
+		if s.TimeoutMinutes != nil {
+			t.Errorf("step at line %d is modified by the preview", s.Pos.Line)
+		}
+	}
+	var fixers []AutoFixer
+	for _, f := range res.AutoFixers {
+		if f.RuleName() == "missing-timeout-minutes" {
+			fixers = append(fixers, f)
+		}
+	}
+	fixed := ApplyAutoFixers(res.Source, res.ParsedWorkflow, fixers, nil)
+	if got := strings.Count(string(fixed.Source), "timeout-minutes: 5"); got != 3 {
+		t.Errorf("got %d timeout-minutes after the fix, want 3:\n%s", got, fixed.Source)
+	}
+}
+
+func TestRuleDocPagesExist(t *testing.T) {
+	for rule, page := range ruleDocPages {
+		path := filepath.Join("..", "..", "docs", page+".md")
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("documentation page for rule %q does not exist: %s", rule, path)
+		}
+	}
+}
+
+func TestRuleDocPagesCoverAllRules(t *testing.T) {
+	rules := append(makeRules("", nil, nil, nil, nil), newShellcheckRule(nil))
+	for _, r := range rules {
+		if _, ok := ruleDocPages[r.RuleNames()]; !ok {
+			t.Errorf("rule %q has no documentation page in ruleDocPages. add docs/<page>.md and map it", r.RuleNames())
+		}
+	}
+}
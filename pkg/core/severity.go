@@ -0,0 +1,130 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Severityはlinting errorの深刻度を表す型
+// 値が大きいほど深刻度が高い。0値のSeverityNoneは深刻度が未設定であることを示す
+type Severity int
+
+const (
+	// SeverityNoneは深刻度が未設定であることを示す
+	SeverityNone Severity = iota
+	// SeverityInfoは情報提供のみを目的とした指摘
+	SeverityInfo
+	// SeverityLowはベストプラクティス違反など影響の小さい指摘
+	SeverityLow
+	// SeverityMediumは条件次第で悪用され得る指摘
+	SeverityMedium
+	// SeverityHighは悪用される可能性が高い指摘
+	SeverityHigh
+	// SeverityCriticalは特権のあるコンテキストで直接悪用され得る指摘
+	SeverityCritical
+)
+
+// severityNamesはSeverityと文字列表現の対応
+var severityNames = map[Severity]string{
+	SeverityNone:     "none",
+	SeverityInfo:     "info",
+	SeverityLow:      "low",
+	SeverityMedium:   "medium",
+	SeverityHigh:     "high",
+	SeverityCritical: "critical",
+}
+
+// Stringは深刻度の文字列表現を返す
+func (s Severity) String() string {
+	if name, ok := severityNames[s]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// ParseSeverityは文字列を深刻度に変換する。大文字小文字は区別しない
+func ParseSeverity(s string) (Severity, error) {
+	v := strings.ToLower(strings.TrimSpace(s))
+	for sev, name := range severityNames {
+		if sev != SeverityNone && name == v {
+			return sev, nil
+		}
+	}
+	return SeverityNone, fmt.Errorf("invalid severity %q. available severities are critical, high, medium, low and info", s)
+}
+
+// SARIFLevelはSARIFのresult.levelに対応する文字列を返す
+// * https://docs.oasis-open.org/sarif/sarif/v2.1.0/os/sarif-v2.1.0-os.html#_Toc34317648
+func (s Severity) SARIFLevel() string {
+	switch s {
+	case SeverityCritical, SeverityHigh:
+		return "error"
+	case SeverityLow, SeverityInfo:
+		return "note"
+	default:
+		return "warning"
+	}
+}
+
+// SecurityScoreはGitHub code scanningのsecurity-severityプロパティに設定するスコアを返す
+// * https://docs.github.com/en/code-security/code-scanning/integrating-with-code-scanning/sarif-support-for-code-scanning#reportingdescriptor-object
+func (s Severity) SecurityScore() string {
+	switch s {
+	case SeverityCritical:
+		return "9.5"
+	case SeverityHigh:
+		return "8.0"
+	case SeverityMedium:
+		return "5.5"
+	case SeverityLow:
+		return "2.0"
+	default:
+		return "0.0"
+	}
+}
+
+// defaultRuleSeveritiesはルール名から深刻度を推定できないルールの既定の深刻度
+var defaultRuleSeverities = map[string]Severity{
+	"syntax":                          SeverityMedium,
+	"syntax-check":                    SeverityMedium,
+	"credentials":                     SeverityHigh,
+	"commit-sha":                      SeverityMedium,
+	"permissions":                     SeverityMedium,
+	"action-list":                     SeverityMedium,
+	"action-advisory":                 SeverityHigh,
+	"image-digest":                    SeverityMedium,
+	"action-metadata":                 SeverityMedium,
+	"shellcheck":                      SeverityLow,
+	"untrusted-checkout":              SeverityHigh,
+	"cache-poisoning":                 SeverityHigh,
+	"cache-poisoning-poisonable-step": SeverityHigh,
+	"secret-exposure":                 SeverityHigh,
+	"unmasked-secret-exposure":        SeverityHigh,
+	"improper-access-control":         SeverityHigh,
+	"bot-conditions":                  SeverityHigh,
+	"artipacked":                      SeverityMedium,
+	"unsound-contains":                SeverityMedium,
+	"workflow-call":                   SeverityLow,
+	"expression":                      SeverityLow,
+	"deprecated-commands":             SeverityLow,
+	"missing-timeout-minutes":         SeverityLow,
+	"env-var":                         SeverityLow,
+	"id":                              SeverityLow,
+	"needs":                           SeverityLow,
+	"cond":                            SeverityLow,
+	SuppressionRuleName:               SeverityLow,
+}
+
+// severityOfRuleはルール名から既定の深刻度を返す
+// "code-injection-critical"や"untrusted-checkout-toctou/high"のように名前の末尾に深刻度を含むルールはそれを優先する
+func severityOfRule(ruleName string) Severity {
+	if i := strings.LastIndexAny(ruleName, "-/"); i >= 0 {
+		if sev, err := ParseSeverity(ruleName[i+1:]); err == nil {
+			return sev
+		}
+	}
+	if sev, ok := defaultRuleSeverities[ruleName]; ok {
+		return sev
+	}
+	return SeverityMedium
+}
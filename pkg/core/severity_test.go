@@ -0,0 +1,166 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/sisaku-security/sisakulint/pkg/ast"
+)
+
+func TestParseSeverity(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Severity
+		wantErr bool
+	}{
+		{input: "critical", want: SeverityCritical},
+		{input: "HIGH", want: SeverityHigh},
+		{input: " medium ", want: SeverityMedium},
+		{input: "low", want: SeverityLow},
+		{input: "info", want: SeverityInfo},
+		{input: "none", wantErr: true},
+		{input: "urgent", wantErr: true},
+		{input: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseSeverity(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSeverity(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseSeverity(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSeverityOfRule(t *testing.T) {
+	tests := []struct {
+		rule string
+		want Severity
+	}{
+		{rule: "code-injection-critical", want: SeverityCritical},
+		{rule: "envvar-injection-medium", want: SeverityMedium},
+		{rule: "untrusted-checkout-toctou/high", want: SeverityHigh},
+		{rule: "credentials", want: SeverityHigh},
+		{rule: "missing-timeout-minutes", want: SeverityLow},
+		{rule: "syntax", want: SeverityMedium},
+		{rule: "unknown-rule", want: SeverityMedium},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			if got := severityOfRule(tt.rule); got != tt.want {
+				t.Errorf("severityOfRule(%q) = %v, want %v", tt.rule, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBaseRule_ErrorCarriesSeverity(t *testing.T) {
+	pos := &ast.Position{Line: 1, Col: 1}
+
+	rule := CodeInjectionCriticalRule()
+	rule.Errorf(pos, "injection")
+	if got := rule.Errors()[0].Severity; got != SeverityCritical {
+		t.Errorf("severity of code-injection-critical error = %v, want %v", got, SeverityCritical)
+	}
+
+	custom := BaseRule{RuleName: "custom", RuleSeverity: SeverityInfo}
+	custom.Error(pos, "note")
+	custom.ErrorfWithSeverity(pos, SeverityHigh, "escalated")
+	errs := custom.Errors()
+	if errs[0].Severity != SeverityInfo {
+		t.Errorf("severity of error = %v, want %v", errs[0].Severity, SeverityInfo)
+	}
+	if errs[1].Severity != SeverityHigh {
+		t.Errorf("severity of error = %v, want %v", errs[1].Severity, SeverityHigh)
+	}
+}
+
+func TestDisplayError_ShowsSeverity(t *testing.T) {
+	err := NewError(&ast.Position{Line: 1, Col: 1}, "code-injection-medium", "injection")
+	err.FilePath = "workflow.yml"
+
+	var buf bytes.Buffer
+	err.DisplayError(&buf, nil)
+	if !strings.Contains(buf.String(), "[code-injection-medium] (medium)") {
+		t.Errorf("DisplayError output %q does not contain the severity", buf.String())
+	}
+}
+
+func TestToSARIF_SeverityLevels(t *testing.T) {
+	fields := []*TemplateFields{
+		{Message: "a", Filepath: "w.yml", Line: 1, Column: 1, Type: "code-injection-critical", Severity: "critical"},
+		{Message: "b", Filepath: "w.yml", Line: 2, Column: 1, Type: "commit-sha", Severity: "medium"},
+		{Message: "c", Filepath: "w.yml", Line: 3, Column: 1, Type: "missing-timeout-minutes", Severity: "low"},
+	}
+
+	out, err := toSARIF(fields, nil)
+	if err != nil {
+		t.Fatalf("toSARIF() returned error: %v", err)
+	}
+
+	var doc struct {
+		Runs []struct {
+			Results []struct {
+				Level      string            `json:"level"`
+				Properties map[string]string `json:"properties"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	if err := json.Unmarshal([]byte(out), &doc); err != nil {
+		t.Fatalf("failed to decode SARIF output: %v", err)
+	}
+
+	wantLevels := []string{"error", "warning", "note"}
+	wantScores := []string{"9.5", "5.5", "2.0"}
+	results := doc.Runs[0].Results
+	if len(results) != len(wantLevels) {
+		t.Fatalf("got %d results, want %d", len(results), len(wantLevels))
+	}
+	for i, r := range results {
+		if r.Level != wantLevels[i] {
+			t.Errorf("results[%d].level = %q, want %q", i, r.Level, wantLevels[i])
+		}
+		if r.Properties["security-severity"] != wantScores[i] {
+			t.Errorf("results[%d].properties.security-severity = %q, want %q", i, r.Properties["security-severity"], wantScores[i])
+		}
+	}
+}
+
+func TestHasFailingError(t *testing.T) {
+	results := []*ValidateResult{
+		{Errors: []*LintingError{
+			{Type: "missing-timeout-minutes", Severity: SeverityLow},
+			{Type: "commit-sha", Severity: SeverityMedium},
+		}},
+	}
+
+	tests := []struct {
+		failOn Severity
+		want   bool
+	}{
+		{failOn: SeverityNone, want: true},
+		{failOn: SeverityLow, want: true},
+		{failOn: SeverityMedium, want: true},
+		{failOn: SeverityHigh, want: false},
+		{failOn: SeverityCritical, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.failOn.String(), func(t *testing.T) {
+			if got := hasFailingError(results, tt.failOn); got != tt.want {
+				t.Errorf("hasFailingError(%v) = %v, want %v", tt.failOn, got, tt.want)
+			}
+		})
+	}
+
+	if hasFailingError([]*ValidateResult{{}}, SeverityNone) {
+		t.Error("hasFailingError() = true for results without errors")
+	}
+}
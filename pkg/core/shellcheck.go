@@ -0,0 +1,240 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/sisaku-security/sisakulint/pkg/ast"
+)
+
+// shellcheckDisabledCodesは、${{ }}をダミーの文字列に置き換えたことで誤検知になるshellcheckのルール
+// * https://www.shellcheck.net/wiki/SC1091 (sourceされたファイルを追えない)
+// * https://www.shellcheck.net/wiki/SC2050 (ダミーの文字列同士の比較が定数になる)
+// * https://www.shellcheck.net/wiki/SC2154 (env:で設定された環境変数は代入されていないように見える)
+// * https://www.shellcheck.net/wiki/SC2157 (ダミーの文字列は空にならない)
+// * https://www.shellcheck.net/wiki/SC2194 (caseの対象がダミーの文字列で定数になる)
+const shellcheckDisabledCodes = "SC1091,SC2050,SC2154,SC2157,SC2194"
+
+// shellcheckDiagnosticはshellcheck -f jsonが出力する1つの指摘
+type shellcheckDiagnostic struct {
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Level   string `json:"level"`
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// ShellcheckRuleは、bashとshのrun:のスクリプトをshellcheckで検査するルール
+// スクリプトの${{ }}は同じ長さのダミーの文字列に置き換えてからshellcheckに渡し、指摘の位置をワークフローの行と列に戻す
+// shellcheckはConcurrentExecutorで並行に実行し、VisitWorkflowPostで全ての結果を待つ
+// * https://github.com/koalaman/shellcheck
+type ShellcheckRule struct {
+	BaseRule
+	cmd           *ExternalCommandRunner
+	mu            sync.Mutex
+	workflowShell string
+	jobShell      string
+	windows       bool
+}
+
+// NewShellcheckRuleは新しいShellcheckRuleインスタンスを作成する
+// executableはshellcheckの実行ファイルのパスまたはPATHから探す名前。見つからない場合はエラーを返す
+func NewShellcheckRule(executable string, proc *ConcurrentExecutor) (*ShellcheckRule, error) {
+	cmd, err := proc.CommandRunner(executable)
+	if err != nil {
+		return nil, err
+	}
+	return newShellcheckRule(cmd), nil
+}
+
+// newShellcheckRuleはcmdでshellcheckを実行するShellcheckRuleを作成する
+// 設定の検証のようにルールを実行しない場合、cmdはnilにできる
+func newShellcheckRule(cmd *ExternalCommandRunner) *ShellcheckRule {
+	return &ShellcheckRule{
+		BaseRule: BaseRule{
+			RuleName: "shellcheck",
+			RuleDesc: "Checks for shell script sources in \"run:\" using shellcheck",
+		},
+		cmd: cmd,
+	}
+}
+
+// VisitWorkflowPreはWorkflowノードを訪れたときのcallback
+func (rule *ShellcheckRule) VisitWorkflowPre(n *ast.Workflow) error {
+	rule.workflowShell = defaultsShell(n.Defaults)
+	return nil
+}
+
+// VisitJobPreはJobノードを訪れたときのcallback
+func (rule *ShellcheckRule) VisitJobPre(n *ast.Job) error {
+	rule.jobShell = defaultsShell(n.Defaults)
+	rule.windows = false
+	if n.RunsOn != nil {
+		// "runs-on: windows-latest"のような1つのラベルはLabelsExprに入る
+		labels := n.RunsOn.Labels
+		if n.RunsOn.LabelsExpr != nil {
+			labels = append(labels, n.RunsOn.LabelsExpr)
+		}
+		for _, l := range labels {
+			if strings.HasPrefix(strings.ToLower(l.Value), "windows") {
+				rule.windows = true
+			}
+		}
+	}
+	return nil
+}
+
+// VisitJobPostはJobノードを訪れた後のcallback
+func (rule *ShellcheckRule) VisitJobPost(n *ast.Job) error {
+	rule.jobShell = ""
+	rule.windows = false
+	return nil
+}
+
+// VisitWorkflowPostは、実行中の全てのshellcheckの終了を待ってエラーを行と列の順に並べる
+func (rule *ShellcheckRule) VisitWorkflowPost(n *ast.Workflow) error {
+	err := rule.cmd.Wait()
+	sort.SliceStable(rule.ruleErrors, func(i, j int) bool {
+		a, b := rule.ruleErrors[i], rule.ruleErrors[j]
+		if a.LineNumber != b.LineNumber {
+			return a.LineNumber < b.LineNumber
+		}
+		return a.ColNumber < b.ColNumber
+	})
+	return err
+}
+
+// VisitStepはStepノードを訪れたときのcallback
+func (rule *ShellcheckRule) VisitStep(n *ast.Step) error {
+	e, ok := n.Exec.(*ast.ExecRun)
+	if !ok || e.Run == nil {
+		return nil
+	}
+	shell := rule.shellName(e.Shell)
+	if shell == "" {
+		return nil
+	}
+	rule.runShellcheck(e.Run, shell)
+	return nil
+}
+
+// shellNameは、ステップのshell:、ジョブとワークフローのdefaults.run.shellの順にシェルを決めて、shellcheckの--shellに渡す名前を返す
+// bashとsh以外のシェルの場合は空文字列を返す
+// * https://docs.github.com/en/actions/writing-workflows/workflow-syntax-for-github-actions#jobsjob_idstepsshell
+func (rule *ShellcheckRule) shellName(step *ast.String) string {
+	shell := rule.workflowShell
+	if rule.jobShell != "" {
+		shell = rule.jobShell
+	}
+	if step != nil {
+		shell = step.Value
+	}
+	if shell == "" {
+		// Windowsのランナーの既定のシェルはpwsh
+		if rule.windows {
+			return ""
+		}
+		return "bash"
+	}
+	if strings.Contains(shell, "${{") {
+		return ""
+	}
+	// "bash -e {0}"のようなカスタムシェルはコマンドの名前で判断する
+	if fs := strings.Fields(shell); len(fs) > 0 {
+		switch filepath.Base(fs[0]) {
+		case "bash":
+			return "bash"
+		case "sh":
+			return "sh"
+		}
+	}
+	return ""
+}
+
+func defaultsShell(d *ast.Defaults) string {
+	if d == nil || d.Run == nil || d.Run.Shell == nil {
+		return ""
+	}
+	return d.Run.Shell.Value
+}
+
+// runShellcheckはスクリプトをshellcheckに渡す。結果はコールバックで並行に報告される
+func (rule *ShellcheckRule) runShellcheck(script *ast.String, shell string) {
+	src := sanitizeExpressionsInScript(script.Value)
+	args := []string{"--norc", "-f", "json", "-x", "--shell", shell, "-e", shellcheckDisabledCodes, "-"}
+	rule.Debug("%s: running shellcheck with args %v", script.Pos, args)
+	rule.cmd.Execute(args, src, func(stdout []byte, err error) error {
+		if err != nil {
+			// shellcheckは指摘がある場合に終了コード1で終了する
+			var exitErr *exec.ExitError
+			if !errors.As(err, &exitErr) || exitErr.ExitCode() != 1 || len(stdout) == 0 {
+				return fmt.Errorf("shellcheck failed on the script at %s: %w", script.Pos, err)
+			}
+		}
+		var diags []shellcheckDiagnostic
+		if err := json.Unmarshal(stdout, &diags); err != nil {
+			return fmt.Errorf("could not parse output of shellcheck %q: %w", stdout, err)
+		}
+		rule.mu.Lock()
+		defer rule.mu.Unlock()
+		for _, d := range diags {
+			rule.Errorf(
+				shellcheckPosition(script, d.Line, d.Column),
+				"shellcheck reported issue in this script: SC%d:%s:%d:%d: %s",
+				d.Code,
+				d.Level,
+				d.Line,
+				d.Column,
+				d.Message,
+			)
+		}
+		return nil
+	})
+}
+
+// shellcheckPositionは、スクリプトの中の1始まりの行と列をワークフローの位置に変換する
+// |のようなブロックスカラーのスクリプトは次の行から始まり、列は内容の行のインデントから数える
+func shellcheckPosition(script *ast.String, line, col int) *ast.Position {
+	pos := &ast.Position{Line: script.Pos.Line + line - 1, Col: script.Pos.Col + col - 1}
+	if script.Literal {
+		pos.Line++
+		if script.Indent > 0 {
+			pos.Col = script.Indent + col
+		}
+	}
+	return pos
+}
+
+// sanitizeExpressionsInScriptは、スクリプトの${{ }}を同じ長さのダミーの文字列に置き換える
+// 長さと改行を変えないので、shellcheckの指摘の行と列の位置がそのまま使える
+func sanitizeExpressionsInScript(src string) string {
+	var b strings.Builder
+	for {
+		start := strings.Index(src, "${{")
+		if start == -1 {
+			break
+		}
+		end := strings.Index(src[start:], "}}")
+		if end == -1 {
+			break
+		}
+		end += start + len("}}")
+		b.WriteString(src[:start])
+		for _, c := range src[start:end] {
+			if c == '\n' {
+				b.WriteRune(c)
+			} else {
+				b.WriteByte('_')
+			}
+		}
+		src = src[end:]
+	}
+	b.WriteString(src)
+	return b.String()
+}
@@ -0,0 +1,219 @@
+package core
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/sisaku-security/sisakulint/pkg/ast"
+)
+
+// fakeShellcheckは、受け取った引数とスクリプトをdirに記録するshellcheckの代わりのスクリプトを作る
+// "echo $FOO"を含むスクリプトには、2行目の6列目への指摘を出力する
+func fakeShellcheck(t *testing.T) (exe, dir string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake shellcheck is a shell script")
+	}
+	dir = t.TempDir()
+	exe = filepath.Join(dir, "shellcheck")
+	calls := filepath.Join(dir, "calls")
+	if err := os.Mkdir(calls, 0755); err != nil {
+		t.Fatal(err)
+	}
+	script := `#!/bin/sh
+f=$(mktemp "` + calls + `/call.XXXXXX")
+{ echo "$*"; cat; } > "$f"
+if grep -q 'echo $FOO' "$f"; then
+  echo '[{"file":"-","line":2,"endLine":2,"column":6,"endColumn":10,"level":"info","code":2086,"message":"Double quote to prevent globbing and word splitting."}]'
+  exit 1
+fi
+echo '[]'
+`
+	if err := os.WriteFile(exe, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return exe, calls
+}
+
+// shellcheckCallsは、fakeShellcheckが受け取った"--shell"の値とスクリプトの組をソートして返す
+func shellcheckCalls(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := make([]string, 0, len(entries))
+	for _, e := range entries {
+		b, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		args, script, _ := strings.Cut(string(b), "\n")
+		fs := strings.Fields(args)
+		shell := ""
+		for i, a := range fs {
+			if a == "--shell" && i+1 < len(fs) {
+				shell = fs[i+1]
+			}
+		}
+		calls = append(calls, shell+": "+script)
+	}
+	sort.Strings(calls)
+	return calls
+}
+
+func TestSanitizeExpressionsInScript(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"echo hello", "echo hello"},
+		{"echo ${{ github.ref }}", "echo _________________"},
+		{"echo \"${{ a }}\" ${{ b }}", "echo \"________\" ________"},
+		{"echo ${{ a\n || b }} x", "echo _____\n________ x"},
+		{"echo ${{ broken", "echo ${{ broken"},
+	}
+	for _, tt := range tests {
+		if got := sanitizeExpressionsInScript(tt.in); got != tt.want {
+			t.Errorf("sanitizeExpressionsInScript(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestShellcheckRule(t *testing.T) {
+	exe, dir := fakeShellcheck(t)
+	srcs := []string{`on: push
+defaults:
+  run:
+    shell: sh
+jobs:
+  unix:
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo workflow default
+      - run: |
+          echo ok
+          echo $FOO ${{ github.ref }}
+        shell: bash
+      - run: Write-Output pwsh
+        shell: pwsh
+      - run: echo custom
+        shell: bash -e {0}
+  job-default:
+    runs-on: ubuntu-latest
+    defaults:
+      run:
+        shell: bash
+    steps:
+      - run: echo job default
+`, `on: push
+jobs:
+  linux:
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo linux
+  windows:
+    runs-on: windows-latest
+    steps:
+      - run: echo windows
+      - run: echo windows bash
+        shell: bash
+`}
+
+	rule, err := NewShellcheckRule(exe, NewConcurrentExecutor(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, src := range srcs {
+		w, errs := Parse([]byte(src))
+		if len(errs) > 0 {
+			t.Fatal(errs)
+		}
+		v := NewSyntaxTreeVisitor()
+		v.AddVisitor(rule)
+		if err := v.VisitTree(w); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := []string{
+		"bash: echo custom",
+		"bash: echo job default",
+		"bash: echo linux",
+		"bash: echo ok\necho $FOO _________________\n",
+		"bash: echo windows bash",
+		"sh: echo workflow default",
+	}
+	got := shellcheckCalls(t, dir)
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("shellcheck was called with\n%q\nwant\n%q", got, want)
+	}
+
+	es := rule.Errors()
+	if len(es) != 1 {
+		t.Fatalf("want 1 error but got %v", es)
+	}
+	// "echo $FOO"の"$FOO"の位置。"run: |"の次の行から2行目で、列は内容の行のインデントからの相対位置
+	if e := es[0]; e.LineNumber != 12 || e.ColNumber != 16 || !strings.Contains(e.Description, "SC2086:info:2:6: Double quote") {
+		t.Errorf("unexpected error: %v", e)
+	}
+}
+
+func TestShellcheckPosition(t *testing.T) {
+	for _, tt := range []struct {
+		literal   bool
+		indent    int
+		line, col int
+	}{
+		{false, 0, 5, 20},
+		{true, 0, 6, 20},
+		{true, 10, 6, 16},
+	} {
+		s := &ast.String{Pos: &ast.Position{Line: 5, Col: 15}, Literal: tt.literal, Indent: tt.indent}
+		if pos := shellcheckPosition(s, 1, 6); pos.Line != tt.line || pos.Col != tt.col {
+			t.Errorf("literal=%v indent=%d: got %d:%d, want %d:%d", tt.literal, tt.indent, pos.Line, pos.Col, tt.line, tt.col)
+		}
+	}
+}
+
+func TestLinter_Shellcheck(t *testing.T) {
+	exe, dir := fakeShellcheck(t)
+	src := []byte("on: push\njobs:\n  test:\n    runs-on: ubuntu-latest\n    steps:\n      - run: |\n          echo ok\n          echo $FOO\n")
+
+	for _, tt := range []struct {
+		name string
+		exe  string
+		want int
+	}{
+		{"enabled", exe, 1},
+		{"executable not found", filepath.Join(dir, "missing"), 0},
+		{"disabled", "", 0},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := NewLinter(io.Discard, &LinterOptions{ShellcheckExecutable: tt.exe})
+			if err != nil {
+				t.Fatal(err)
+			}
+			res, err := l.Lint("test.yml", src, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			n := 0
+			for _, e := range res.Errors {
+				if e.Type == "shellcheck" {
+					n++
+					if e.LineNumber != 8 {
+						t.Errorf("unexpected position: %v", e)
+					}
+				}
+			}
+			if n != tt.want {
+				t.Errorf("got %d shellcheck errors, want %d: %v", n, tt.want, res.Errors)
+			}
+		})
+	}
+}
@@ -0,0 +1,860 @@
+package core
+
+import (
+	"path"
+	"sort"
+	"strings"
+)
+
+// shellQuoteは、シェルスクリプトの中のある位置がどのような引用の中にあるかを表す
+type shellQuote int
+
+const (
+	// shellQuoteNoneは引用の外
+	shellQuoteNone shellQuote = iota
+	// shellQuoteSingleは'...'または$'...'の中。変数は展開されない
+	shellQuoteSingle
+	// shellQuoteDoubleは"..."の中
+	shellQuoteDouble
+	// shellQuoteHeredocは、区切り文字が引用されていないヒアドキュメントの本文。変数は展開される
+	shellQuoteHeredoc
+	// shellQuoteQuotedHeredocは、<<'EOF'のように区切り文字が引用されたヒアドキュメントの本文。変数は展開されない
+	shellQuoteQuotedHeredoc
+	// shellQuoteCommentは#から行末までのコメント
+	shellQuoteComment
+)
+
+// expandsVariablesは、その位置に書いた$VARがシェルによって展開されるかを返す
+func (q shellQuote) expandsVariables() bool {
+	return q == shellQuoteNone || q == shellQuoteDouble || q == shellQuoteHeredoc
+}
+
+// shellExpansionは、スクリプトの中の${{ }}または$VARや${VAR}の出現
+// ${{ }}はシェルが実行される前にGitHub Actionsが文字列として置き換えるので、引用やコメントの中でも展開される
+type shellExpansion struct {
+	// nameは、${{ }}の場合は前後の空白を取り除いた中身、変数の場合は変数名
+	name string
+	// startとendはスクリプトの中のバイトオフセット。endは出現の直後を指す
+	start, end int
+	quote      shellQuote
+	// substは、$( )や``のコマンド置換の中に書かれているか
+	subst bool
+	// cmdは出現を含むコマンド。コメントの中ではnil
+	cmd *shellCommand
+}
+
+// shellWordはコマンドの引数やリダイレクト先の1つの単語
+type shellWord struct {
+	start, end int
+	// valueは引用とエスケープを取り除いた単語の値。${{ }}や$VARはそのまま残る
+	value string
+	// quotedは単語の一部でも引用やエスケープされているか
+	quoted bool
+	// varsは単語の中で展開される変数。コマンド置換の中の変数は含まない
+	vars []*shellExpansion
+}
+
+// referencesVarは、単語が変数nameを展開するかを返す
+func (w *shellWord) referencesVar(name string) bool {
+	for _, v := range w.vars {
+		if v.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// shellHeredocはヒアドキュメント。startとendは本文の範囲で、終わりの区切り文字の行は含まない
+type shellHeredoc struct {
+	delim      string
+	quoted     bool
+	stripTabs  bool
+	start, end int
+	cmd        *shellCommand
+}
+
+// shellRedirectはコマンドのリダイレクト
+type shellRedirect struct {
+	// opは">", ">>", "&>", "<", "<<", "<<-", "<<<"のようなリダイレクトの演算子。ファイルディスクリプタの番号は含まない
+	op      string
+	target  *shellWord
+	heredoc *shellHeredoc
+}
+
+// isOutputは、リダイレクトが対象のファイルに書き込むかを返す
+func (r *shellRedirect) isOutput() bool {
+	switch r.op {
+	case ">", ">>", ">|", "&>", "&>>", "<>":
+		return true
+	default:
+		return false
+	}
+}
+
+// shellPipelineは|でつながったコマンドの並び
+type shellPipeline struct {
+	commands []*shellCommand
+}
+
+// shellCommandは1つの単純コマンド、または{ }や( )、if ... fiのような複合コマンドでまとめられたコマンドのグループ
+type shellCommand struct {
+	words     []*shellWord
+	redirects []*shellRedirect
+	// groupはグループか。グループのredirectsは中の全てのコマンドに適用される
+	group bool
+	// closerはグループを閉じる"}"、")"、"fi"、"done"、"esac"のような単語
+	closer   string
+	parent   *shellCommand
+	pipeline *shellPipeline
+}
+
+// shellReservedWordsは、コマンドの前に置かれて実行するコマンドの名前にならない予約語
+var shellReservedWords = map[string]struct{}{
+	"!": {}, "if": {}, "then": {}, "elif": {}, "else": {}, "while": {}, "until": {}, "do": {}, "time": {},
+}
+
+// shellCompoundCommandsは、複合コマンドを始める予約語と、それを閉じる予約語
+// 閉じる予約語の後のリダイレクトやパイプは、then、do、caseのパターンの後の全てのコマンドに適用される
+var shellCompoundCommands = map[string]string{
+	"if": "fi", "for": "done", "select": "done", "while": "done", "until": "done", "case": "esac",
+}
+
+// onlyReservedWordsは、コマンドにまだ予約語しか書かれていないかを返す
+// 予約語の後はコマンドの先頭として扱うので、"then if"や"do {"も複合コマンドやグループを始める
+func (c *shellCommand) onlyReservedWords() bool {
+	if c.group {
+		return false
+	}
+	for _, w := range c.words {
+		if _, ok := shellReservedWords[w.value]; !ok || w.quoted {
+			return false
+		}
+	}
+	return true
+}
+
+// nameは実行されるコマンドの名前を返す。先頭の予約語と変数の代入は読み飛ばす
+func (c *shellCommand) name() string {
+	for _, w := range c.words {
+		if _, ok := shellReservedWords[w.value]; ok {
+			continue
+		}
+		if i := strings.IndexByte(w.value, '='); i > 0 && isShellName(w.value[:i]) {
+			continue
+		}
+		return path.Base(w.value)
+	}
+	return ""
+}
+
+// writesToVarFileは、コマンド自身がリダイレクトかteeで変数nameが指すファイルに書き込むかを返す
+func (c *shellCommand) writesToVarFile(name string) bool {
+	for _, r := range c.redirects {
+		if r.isOutput() && r.target != nil && r.target.referencesVar(name) {
+			return true
+		}
+	}
+	if c.name() == "tee" {
+		for _, w := range c.words[1:] {
+			if w.referencesVar(name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// shellScriptは、run:のスクリプトをシェルの文法に沿って解析した結果
+// 完全なシェルの構文解析ではなく、インジェクションの検査に必要なコマンド、リダイレクト、
+// ヒアドキュメント、引用の文脈、変数と${{ }}の出現だけを扱う
+type shellScript struct {
+	src      string
+	commands []*shellCommand
+	exprs    []*shellExpansion
+	vars     []*shellExpansion
+}
+
+// exprAtは、オフセットoffsetから始まる${{ }}の出現を返す。見つからない場合はnilを返す
+func (s *shellScript) exprAt(offset int) *shellExpansion {
+	i := sort.Search(len(s.exprs), func(i int) bool { return s.exprs[i].start >= offset })
+	if i < len(s.exprs) && s.exprs[i].start == offset {
+		return s.exprs[i]
+	}
+	return nil
+}
+
+// writesToVarFileは、出現eの値が変数nameが指すファイル($GITHUB_ENVなど)に書き込まれるかを返す
+// eを含むコマンド、パイプラインでその後に続くコマンド、eを囲むグループのどれかがファイルに書き込む場合にtrueになる
+func (s *shellScript) writesToVarFile(e *shellExpansion, name string) bool {
+	if e == nil {
+		return false
+	}
+	for c := e.cmd; c != nil; c = c.parent {
+		after := false
+		for _, p := range c.pipeline.commands {
+			if p == c {
+				after = true
+			}
+			if after && p.writesToVarFile(name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parseShellScriptはbashやshのスクリプトを解析する
+// 文法の誤りがあってもエラーにはせず、解析できたところまでの結果を返す
+func parseShellScript(src string) *shellScript {
+	p := &shellParser{src: src, limit: len(src), script: &shellScript{src: src}}
+	p.parse()
+	sort.Slice(p.script.exprs, func(i, j int) bool { return p.script.exprs[i].start < p.script.exprs[j].start })
+	return p.script
+}
+
+type shellParser struct {
+	src    string
+	pos    int
+	limit  int
+	script *shellScript
+	// cmdは解析中のコマンド、pipelineは解析中のパイプライン、groupは最も内側の開いているグループ
+	cmd      *shellCommand
+	pipeline *shellPipeline
+	group    *shellCommand
+	// wordは解析中の単語。コマンド置換の中ではnil
+	word *shellWord
+	// pendingは、次の改行の後から本文が始まるヒアドキュメント
+	pending []*shellHeredoc
+}
+
+func (p *shellParser) peek(s string) bool {
+	return strings.HasPrefix(p.src[p.pos:p.limit], s)
+}
+
+func (p *shellParser) startCommand() *shellCommand {
+	if p.cmd == nil {
+		if p.pipeline == nil {
+			p.pipeline = &shellPipeline{}
+		}
+		p.cmd = &shellCommand{parent: p.group, pipeline: p.pipeline}
+		p.pipeline.commands = append(p.pipeline.commands, p.cmd)
+		p.script.commands = append(p.script.commands, p.cmd)
+	}
+	return p.cmd
+}
+
+// endCommandはコマンドを終える。pipeがfalseならパイプラインも終える
+func (p *shellParser) endCommand(pipe bool) {
+	p.cmd = nil
+	if !pipe {
+		p.pipeline = nil
+	}
+}
+
+func (p *shellParser) parse() {
+	for p.pos < p.limit {
+		c := p.src[p.pos]
+		switch {
+		case p.peek("${{"):
+			p.parseWord()
+		case c == ' ' || c == '\t' || c == '\r':
+			p.pos++
+		case p.peek("\\\n"):
+			p.pos += 2
+		case c == '\n':
+			p.pos++
+			p.endCommand(false)
+			p.parseHeredocBodies()
+		case c == '#':
+			p.parseComment()
+		case c == ';':
+			p.pos++
+			p.endCommand(false)
+		case p.peek("&&") || p.peek("||"):
+			p.pos += 2
+			p.endCommand(false)
+		case p.peek("&>"):
+			p.parseRedirect()
+		case c == '&':
+			p.pos++
+			p.endCommand(false)
+		case p.peek("|&"):
+			p.pos += 2
+			p.endCommand(true)
+		case c == '|':
+			p.pos++
+			p.endCommand(true)
+		case c == '<' || c == '>':
+			p.parseRedirect()
+		case c == '(':
+			p.pos++
+			if p.cmd != nil && !p.cmd.onlyReservedWords() {
+				// f() { ... } のような関数の定義の()は読み飛ばす
+				if i := strings.IndexByte(p.src[p.pos:p.limit], ')'); i >= 0 && strings.TrimSpace(p.src[p.pos:p.pos+i]) == "" {
+					p.pos += i + 1
+				}
+				p.endCommand(false)
+				continue
+			}
+			p.openGroup(")")
+		case c == ')':
+			p.pos++
+			p.closeGroup(")")
+		case (c == '{' || c == '}') && p.isStandaloneBrace():
+			p.pos++
+			if c == '{' {
+				p.openGroup("}")
+			} else {
+				p.closeGroup("}")
+			}
+		default:
+			if !p.parseKeyword() {
+				p.parseWord()
+			}
+		}
+	}
+}
+
+// isStandaloneBraceは、現在の位置の{や}がグループの始まりや終わりを表す単独の単語かを返す
+// {a,b}のようなブレース展開や、単語の途中の}はグループとして扱わない
+func (p *shellParser) isStandaloneBrace() bool {
+	if p.cmd != nil && !p.cmd.group && !p.cmd.onlyReservedWords() {
+		return false
+	}
+	return p.endsWord(p.pos + 1)
+}
+
+// endsWordは、オフセットiが単語の終わりかを返す
+func (p *shellParser) endsWord(i int) bool {
+	if i >= p.limit {
+		return true
+	}
+	switch p.src[i] {
+	case ' ', '\t', '\n', '\r', ';', '&', '|', '<', '>', ')':
+		return true
+	default:
+		return false
+	}
+}
+
+// parseKeywordは、コマンドの先頭にある複合コマンドの予約語を読む
+// if、for、while、until、case、selectは、{ }と同じくグループを始め、対応するfi、done、esacはグループを閉じる
+// 予約語でない場合は何も読まずにfalseを返す
+func (p *shellParser) parseKeyword() bool {
+	if p.cmd != nil && !p.cmd.onlyReservedWords() {
+		return false
+	}
+	end := p.pos
+	for end < p.limit && 'a' <= p.src[end] && p.src[end] <= 'z' {
+		end++
+	}
+	if end == p.pos || !p.endsWord(end) {
+		return false
+	}
+	kw := p.src[p.pos:end]
+	if closer, ok := shellCompoundCommands[kw]; ok {
+		p.parseWord()
+		p.openGroup(closer)
+		return true
+	}
+	switch kw {
+	case "fi", "done", "esac":
+		if p.cmd != nil {
+			return false
+		}
+		p.pos = end
+		p.closeGroup(kw)
+		return true
+	}
+	return false
+}
+
+// openGroupは、closerで閉じるグループを始める
+func (p *shellParser) openGroup(closer string) {
+	g := p.startCommand()
+	g.group = true
+	g.closer = closer
+	p.group = g
+	p.cmd = nil
+	p.pipeline = nil
+}
+
+// closeGroupはcloserで閉じるグループを閉じる。閉じた後のリダイレクトやパイプはグループ全体に適用される
+// 対応するグループが開いていない場合、caseのパターンの")"などはコマンドの区切りとして扱う
+// fiやdoneのような予約語と}は、閉じ忘れた内側のグループもまとめて閉じる
+func (p *shellParser) closeGroup(closer string) {
+	g := p.group
+	if closer != ")" {
+		for g != nil && g.closer != closer {
+			g = g.parent
+		}
+	}
+	if g == nil || g.closer != closer {
+		p.endCommand(false)
+		return
+	}
+	p.group = g.parent
+	p.cmd = g
+	p.pipeline = g.pipeline
+}
+
+func (p *shellParser) parseComment() {
+	end := strings.IndexByte(p.src[p.pos:p.limit], '\n')
+	if end == -1 {
+		end = p.limit
+	} else {
+		end += p.pos
+	}
+	saved := p.cmd
+	p.cmd = nil
+	for p.pos < end {
+		if p.peek("${{") && p.parseExpr(shellQuoteComment, false) {
+			continue
+		}
+		p.pos++
+	}
+	p.cmd = saved
+}
+
+// parseExprは現在の位置の${{ }}を記録する。閉じる}}がない場合はfalseを返して何もしない
+func (p *shellParser) parseExpr(quote shellQuote, subst bool) bool {
+	end := strings.Index(p.src[p.pos:p.limit], "}}")
+	if end == -1 {
+		return false
+	}
+	end += p.pos + len("}}")
+	p.script.exprs = append(p.script.exprs, &shellExpansion{
+		name:  strings.TrimSpace(p.src[p.pos+len("${{") : end-len("}}")]),
+		start: p.pos,
+		end:   end,
+		quote: quote,
+		subst: subst,
+		cmd:   p.cmd,
+	})
+	if p.word != nil {
+		p.word.value += p.src[p.pos:end]
+	}
+	p.pos = end
+	return true
+}
+
+func (p *shellParser) addVar(name string, start int, quote shellQuote, subst bool) {
+	v := &shellExpansion{name: name, start: start, end: p.pos, quote: quote, subst: subst, cmd: p.cmd}
+	p.script.vars = append(p.script.vars, v)
+	if p.word != nil && !subst {
+		p.word.vars = append(p.word.vars, v)
+		p.word.value += p.src[start:p.pos]
+	}
+}
+
+// parseWordは1つの単語を解析して現在のコマンドに加える
+// 数字だけの単語の直後に<や>が続く場合は、ファイルディスクリプタの番号としてリダイレクトを解析する
+func (p *shellParser) parseWord() {
+	cmd := p.startCommand()
+	w := p.scanWord()
+	if w.end > w.start && !w.quoted && isShellDigits(w.value) && p.pos < p.limit && (p.src[p.pos] == '<' || p.src[p.pos] == '>') {
+		p.parseRedirect()
+		return
+	}
+	cmd.words = append(cmd.words, w)
+}
+
+// scanWordは引用の外の空白や演算子までを1つの単語として読む
+func (p *shellParser) scanWord() *shellWord {
+	w := &shellWord{start: p.pos}
+	saved := p.word
+	p.word = w
+	defer func() { p.word = saved }()
+
+	for p.pos < p.limit {
+		c := p.src[p.pos]
+		switch {
+		case p.peek("${{"):
+			if !p.parseExpr(shellQuoteNone, false) {
+				w.value += "$"
+				p.pos++
+			}
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == ';' || c == '&' || c == '|' || c == '<' || c == '>' || c == '(' || c == ')':
+			w.end = p.pos
+			return w
+		case c == '\\':
+			w.quoted = true
+			p.pos++
+			if p.peek("${{") {
+				continue
+			}
+			if p.pos < p.limit {
+				if p.src[p.pos] != '\n' {
+					w.value += p.src[p.pos : p.pos+1]
+				}
+				p.pos++
+			}
+		case c == '\'':
+			w.quoted = true
+			p.pos++
+			p.scanSingleQuoted(false)
+		case p.peek("$'"):
+			w.quoted = true
+			p.pos += 2
+			p.scanSingleQuoted(true)
+		case c == '"':
+			w.quoted = true
+			p.pos++
+			p.scanDoubleQuoted(false)
+		case c == '$':
+			p.scanDollar(shellQuoteNone, false)
+		case c == '`':
+			p.scanBackquote()
+		default:
+			w.value += p.src[p.pos : p.pos+1]
+			p.pos++
+		}
+	}
+	w.end = p.pos
+	return w
+}
+
+func (p *shellParser) appendWord(s string) {
+	if p.word != nil {
+		p.word.value += s
+	}
+}
+
+// scanSingleQuotedは開いた'の直後から閉じる'までを読む
+// ansiがtrueの場合は$'...'として、エスケープされた\'を閉じる引用符として扱わない
+func (p *shellParser) scanSingleQuoted(ansi bool) {
+	for p.pos < p.limit {
+		if p.peek("${{") && p.parseExpr(shellQuoteSingle, false) {
+			continue
+		}
+		c := p.src[p.pos]
+		p.pos++
+		if c == '\'' {
+			return
+		}
+		if ansi && c == '\\' && p.pos < p.limit {
+			p.appendWord(p.src[p.pos-1 : p.pos+1])
+			p.pos++
+			continue
+		}
+		p.appendWord(string(c))
+	}
+}
+
+// scanDoubleQuotedは開いた"の直後から閉じる"までを読む
+func (p *shellParser) scanDoubleQuoted(subst bool) {
+	for p.pos < p.limit {
+		if p.peek("${{") && p.parseExpr(shellQuoteDouble, subst) {
+			continue
+		}
+		c := p.src[p.pos]
+		switch c {
+		case '"':
+			p.pos++
+			return
+		case '\\':
+			p.pos++
+			if p.pos < p.limit && !p.peek("${{") {
+				p.appendWord(p.src[p.pos : p.pos+1])
+				p.pos++
+			}
+		case '$':
+			p.scanDollar(shellQuoteDouble, subst)
+		case '`':
+			p.scanBackquote()
+		default:
+			p.appendWord(string(c))
+			p.pos++
+		}
+	}
+}
+
+// scanDollarは$から始まる変数の展開、パラメータ展開、コマンド置換を読む
+func (p *shellParser) scanDollar(quote shellQuote, subst bool) {
+	start := p.pos
+	p.pos++
+	if p.pos >= p.limit {
+		p.appendWord("$")
+		return
+	}
+	c := p.src[p.pos]
+	switch {
+	case c == '{':
+		p.pos++
+		i := p.pos
+		for i < p.limit && (p.src[i] == '#' || p.src[i] == '!') {
+			i++
+		}
+		j := i
+		for j < p.limit && isShellNameChar(p.src[j]) {
+			j++
+		}
+		name := p.src[i:j]
+		p.skipNested('{', '}')
+		if name != "" {
+			p.addVar(name, start, quote, subst)
+		} else {
+			p.appendWord(p.src[start:p.pos])
+		}
+	case c == '(':
+		p.pos++
+		p.scanSubst(')')
+		p.appendWord(p.src[start:p.pos])
+	case isShellNameChar(c) && (c < '0' || c > '9'):
+		for p.pos < p.limit && isShellNameChar(p.src[p.pos]) {
+			p.pos++
+		}
+		p.addVar(p.src[start+1:p.pos], start, quote, subst)
+	case strings.IndexByte("0123456789@*#?$!-", c) >= 0:
+		p.pos++
+		p.appendWord(p.src[start:p.pos])
+	default:
+		p.appendWord("$")
+	}
+}
+
+// skipNestedは、パラメータ展開の${の直後から対応する}までを読み飛ばす
+// 中の引用、${{ }}、変数の展開も記録する
+func (p *shellParser) skipNested(open, close byte) {
+	saved := p.word
+	p.word = nil
+	defer func() { p.word = saved }()
+	depth := 1
+	for p.pos < p.limit {
+		if p.peek("${{") && p.parseExpr(shellQuoteNone, true) {
+			continue
+		}
+		c := p.src[p.pos]
+		switch c {
+		case open:
+			depth++
+			p.pos++
+		case close:
+			p.pos++
+			depth--
+			if depth == 0 {
+				return
+			}
+		case '\\':
+			p.pos += 2
+		case '\'':
+			p.pos++
+			p.scanSingleQuoted(false)
+		case '"':
+			p.pos++
+			p.scanDoubleQuoted(true)
+		case '$':
+			p.scanDollar(shellQuoteNone, true)
+		default:
+			p.pos++
+		}
+	}
+	p.pos = min(p.pos, p.limit)
+}
+
+// scanSubstは、$( )の中を対応する)まで読む。中の${{ }}や変数はコマンド置換の中として記録する
+func (p *shellParser) scanSubst(close byte) {
+	saved := p.word
+	p.word = nil
+	defer func() { p.word = saved }()
+	depth := 1
+	for p.pos < p.limit {
+		if p.peek("${{") && p.parseExpr(shellQuoteNone, true) {
+			continue
+		}
+		c := p.src[p.pos]
+		switch {
+		case c == '(':
+			depth++
+			p.pos++
+		case c == close:
+			p.pos++
+			depth--
+			if depth == 0 {
+				return
+			}
+		case c == '\\':
+			p.pos += 2
+		case c == '\'':
+			p.pos++
+			p.scanSingleQuoted(false)
+		case c == '"':
+			p.pos++
+			p.scanDoubleQuoted(true)
+		case c == '$':
+			p.scanDollar(shellQuoteNone, true)
+		case c == '`':
+			p.scanBackquote()
+		default:
+			p.pos++
+		}
+	}
+	p.pos = min(p.pos, p.limit)
+}
+
+// scanBackquoteはバッククォートによるコマンド置換を読む
+func (p *shellParser) scanBackquote() {
+	start := p.pos
+	saved := p.word
+	p.word = nil
+	p.pos++
+	for p.pos < p.limit {
+		if p.peek("${{") && p.parseExpr(shellQuoteNone, true) {
+			continue
+		}
+		c := p.src[p.pos]
+		if c == '`' {
+			p.pos++
+			break
+		}
+		if c == '\\' {
+			p.pos += 2
+			continue
+		}
+		if c == '$' {
+			p.scanDollar(shellQuoteNone, true)
+			continue
+		}
+		p.pos++
+	}
+	p.pos = min(p.pos, p.limit)
+	p.word = saved
+	p.appendWord(p.src[start:p.pos])
+}
+
+// shellRedirectOpsは、長いものから順に並べたリダイレクトの演算子
+var shellRedirectOps = []string{"&>>", "<<<", "<<-", "&>", ">>", ">|", ">&", "<&", "<<", "<>", ">", "<"}
+
+// parseRedirectはリダイレクトの演算子と対象の単語を読む。<<と<<-の場合はヒアドキュメントとして記録する
+func (p *shellParser) parseRedirect() {
+	cmd := p.startCommand()
+	r := &shellRedirect{}
+	for _, op := range shellRedirectOps {
+		if p.peek(op) {
+			r.op = op
+			break
+		}
+	}
+	p.pos += len(r.op)
+	for p.pos < p.limit && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t') {
+		p.pos++
+	}
+	if p.pos < p.limit && strings.IndexByte("\n;&|<>()", p.src[p.pos]) == -1 {
+		r.target = p.scanWord()
+	}
+	if (r.op == "<<" || r.op == "<<-") && r.target != nil {
+		r.heredoc = &shellHeredoc{
+			delim:     r.target.value,
+			quoted:    r.target.quoted,
+			stripTabs: r.op == "<<-",
+			cmd:       cmd,
+		}
+		p.pending = append(p.pending, r.heredoc)
+	}
+	cmd.redirects = append(cmd.redirects, r)
+}
+
+// parseHeredocBodiesは、改行の直後から待っているヒアドキュメントの本文を順に読む
+func (p *shellParser) parseHeredocBodies() {
+	pending := p.pending
+	p.pending = nil
+	for _, h := range pending {
+		h.start = p.pos
+		h.end = p.limit
+		next := p.limit
+		for i := p.pos; i < p.limit; {
+			eol := strings.IndexByte(p.src[i:p.limit], '\n')
+			lineEnd := p.limit
+			if eol >= 0 {
+				lineEnd = i + eol
+			}
+			line := strings.TrimSuffix(p.src[i:lineEnd], "\r")
+			if h.stripTabs {
+				line = strings.TrimLeft(line, "\t")
+			}
+			if line == h.delim {
+				h.end = i
+				next = min(lineEnd+1, p.limit)
+				break
+			}
+			i = lineEnd + 1
+		}
+		p.parseHeredocBody(h)
+		p.pos = next
+	}
+}
+
+// parseHeredocBodyは、ヒアドキュメントの本文の${{ }}と変数を記録する
+// 本文の中では引用符は特別な意味を持たない。区切り文字が引用されている場合は変数も展開されない
+func (p *shellParser) parseHeredocBody(h *shellHeredoc) {
+	savedLimit, savedCmd, savedWord := p.limit, p.cmd, p.word
+	p.pos, p.limit, p.cmd, p.word = h.start, h.end, h.cmd, nil
+	quote := shellQuoteHeredoc
+	if h.quoted {
+		quote = shellQuoteQuotedHeredoc
+	}
+	for p.pos < p.limit {
+		if p.peek("${{") && p.parseExpr(quote, false) {
+			continue
+		}
+		if !h.quoted {
+			switch p.src[p.pos] {
+			case '\\':
+				p.pos += 2
+				continue
+			case '$':
+				p.scanDollar(quote, false)
+				continue
+			case '`':
+				p.scanBackquote()
+				continue
+			}
+		}
+		p.pos++
+	}
+	p.limit, p.cmd, p.word = savedLimit, savedCmd, savedWord
+}
+
+// shellEditは、スクリプトのstartからendまでをtextに置き換える編集
+type shellEdit struct {
+	start, end int
+	text       string
+}
+
+// applyShellEditsは、重ならない編集をまとめてスクリプトに適用する
+func applyShellEdits(src string, edits []shellEdit) string {
+	sort.Slice(edits, func(i, j int) bool { return edits[i].start > edits[j].start })
+	for _, e := range edits {
+		src = src[:e.start] + e.text + src[e.end:]
+	}
+	return src
+}
+
+func isShellNameChar(c byte) bool {
+	return c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+}
+
+// isShellNameは、sが変数名として使える名前かを返す
+func isShellName(s string) bool {
+	if s == "" || ('0' <= s[0] && s[0] <= '9') {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isShellNameChar(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isShellDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
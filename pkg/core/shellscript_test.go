@@ -0,0 +1,133 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseShellScript_ExprQuote(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   []shellQuote
+	}{
+		{"unquoted", `echo ${{ a }}`, []shellQuote{shellQuoteNone}},
+		{"double quoted", `echo "x ${{ a }} y"`, []shellQuote{shellQuoteDouble}},
+		{"single quoted", `echo 'x ${{ a }}' "${{ b }}"`, []shellQuote{shellQuoteSingle, shellQuoteDouble}},
+		{"ansi-c quoted", `echo $'\'${{ a }}'`, []shellQuote{shellQuoteSingle}},
+		{"comment", "echo ok # ${{ a }}\necho ${{ b }}", []shellQuote{shellQuoteComment, shellQuoteNone}},
+		{"hash in word is not comment", `echo a#${{ a }}`, []shellQuote{shellQuoteNone}},
+		{"heredoc", "cat <<EOF\n${{ a }}\nEOF\necho ${{ b }}", []shellQuote{shellQuoteHeredoc, shellQuoteNone}},
+		{"quoted heredoc", "cat <<'EOF'\n'${{ a }}'\nEOF", []shellQuote{shellQuoteQuotedHeredoc}},
+		{"heredoc with tabs", "cat <<-\"EOF\"\n\t${{ a }}\n\tEOF\n${{ b }}", []shellQuote{shellQuoteQuotedHeredoc, shellQuoteNone}},
+		{"expression containing operators", `echo ${{ a || 'b' }} "${{ c }}"`, []shellQuote{shellQuoteNone, shellQuoteDouble}},
+		{"command substitution", `echo "$(echo '${{ a }}')"`, []shellQuote{shellQuoteSingle}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := parseShellScript(tt.script)
+			var got []shellQuote
+			for _, e := range s.exprs {
+				got = append(got, e.quote)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("quote of expression #%d: got %v, want %v", i, got[i], tt.want[i])
+				}
+				if e := s.exprs[i]; s.exprAt(e.start) != e || !strings.HasPrefix(tt.script[e.start:e.end], "${{") {
+					t.Errorf("wrong offsets of expression #%d: %d-%d", i, e.start, e.end)
+				}
+			}
+		})
+	}
+}
+
+func TestParseShellScript_WritesToVarFile(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   []bool
+	}{
+		{"append", `echo "A=${{ a }}" >> "$GITHUB_ENV"`, []bool{true}},
+		{"overwrite with braces", `echo "A=${{ a }}" >${GITHUB_ENV}`, []bool{true}},
+		{"redirect before arguments", `>> $GITHUB_ENV echo "A=${{ a }}"`, []bool{true}},
+		{"other file", `echo "A=${{ a }}" >> out.txt`, []bool{false}},
+		{"single quoted target is literal", `echo "A=${{ a }}" >> '$GITHUB_ENV'`, []bool{false}},
+		{"other command on the same line", `echo "${{ a }}"; echo "A=b" >> "$GITHUB_ENV"`, []bool{false}},
+		{"and list", `test -n "${{ a }}" && echo "A=${{ b }}" >> "$GITHUB_ENV"`, []bool{false, true}},
+		{"redirect in string", `echo "A=${{ a }} >> $GITHUB_ENV"`, []bool{false}},
+		{"comment", "echo A=b >> \"$GITHUB_ENV\" # ${{ a }}", []bool{false}},
+		{"line continuation", "echo \"A=${{ a }}\" \\\n  >> \"$GITHUB_ENV\"", []bool{true}},
+		{"pipeline to tee", `echo "A=${{ a }}" | tr -d '\r' | tee -a "$GITHUB_ENV"`, []bool{true}},
+		{"tee of other file", `echo "A=${{ a }}" | tee out.txt; cat out.txt`, []bool{false}},
+		{"group", "{\n  echo \"A=${{ a }}\"\n  echo \"B=${{ b }}\"\n} >> \"$GITHUB_ENV\"\necho ${{ c }}", []bool{true, true, false}},
+		{"subshell", `(echo "A=${{ a }}"; echo) >> $GITHUB_ENV`, []bool{true}},
+		{"heredoc", "cat <<EOF >> \"$GITHUB_ENV\"\nA=${{ a }}\nEOF\necho ${{ b }}", []bool{true, false}},
+		{"quoted heredoc", "cat >> $GITHUB_ENV << 'EOF'\nA=${{ a }}\nEOF", []bool{true}},
+		{"heredoc to other file", "cat <<EOF > out.txt\nA=${{ a }}\nEOF\necho A=b >> $GITHUB_ENV", []bool{false}},
+		{"command substitution", `echo "A=$(echo ${{ a }})" >> "$GITHUB_ENV"`, []bool{true}},
+		{"fd number", `echo "A=${{ a }}" 1>>"$GITHUB_ENV" 2>&1`, []bool{true}},
+		{"if", "if [ -n \"${{ a }}\" ]; then\n  echo \"A=${{ b }}\" >> $GITHUB_ENV\nfi", []bool{false, true}},
+		{"redirect after fi", "if [ -n \"${{ a }}\" ]; then echo \"A=${{ b }}\"; else echo \"A=${{ c }}\"; fi >> \"$GITHUB_ENV\"\necho ${{ d }}", []bool{true, true, true, false}},
+		{"redirect after done", "for f in ${{ a }}; do\n  echo \"A=$f\"\ndone >> \"$GITHUB_ENV\"", []bool{true}},
+		{"while loop with input and output", "while read -r l; do echo \"${{ a }}\"; done < in.txt >> \"$GITHUB_ENV\"", []bool{true}},
+		{"until loop piped to tee", "until false; do echo \"${{ a }}\"; break; done | tee -a \"$GITHUB_ENV\"", []bool{true}},
+		{"redirect after esac", "case \"$X\" in\n  a) echo \"A=${{ a }}\" ;;\n  *) echo \"B=${{ b }}\" ;;\nesac >> \"$GITHUB_ENV\"", []bool{true, true}},
+		{"nested compound commands", "if true; then\n  for x in 1; do echo \"${{ a }}\"; done\nfi >> $GITHUB_ENV", []bool{true}},
+		{"redirect inside loop only", "for x in 1; do echo \"${{ a }}\" >> out.txt; done\necho A=b >> $GITHUB_ENV", []bool{false}},
+		{"keywords as arguments", "echo done fi; echo \"${{ a }}\"; echo A=b >> $GITHUB_ENV", []bool{false}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := parseShellScript(tt.script)
+			if len(s.exprs) != len(tt.want) {
+				t.Fatalf("got %d expressions, want %d", len(s.exprs), len(tt.want))
+			}
+			for i, e := range s.exprs {
+				if got := s.writesToVarFile(e, "GITHUB_ENV"); got != tt.want[i] {
+					t.Errorf("expression %q: got %v, want %v", e.name, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseShellScript_Vars(t *testing.T) {
+	s := parseShellScript("echo \"$A\" '$B' ${C:-x} $(echo $D)\ncat <<EOF\n$E\nEOF\ncat <<'EOF'\n$F\nEOF\n# $G")
+	var got []string
+	for _, v := range s.vars {
+		n := v.name
+		if v.subst {
+			n += "(subst)"
+		}
+		got = append(got, n+":"+s.src[v.start:v.end])
+	}
+	want := []string{"A:$A", "C:${C:-x}", "D(subst):$D", "E:$E"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if n := s.commands[0].name(); n != "echo" {
+		t.Errorf("command name is %q", n)
+	}
+	if n := parseShellScript(`FOO=1 /usr/bin/tee -a x`).commands[0].name(); n != "tee" {
+		t.Errorf("command name is %q", n)
+	}
+}
+
+func TestApplyShellEdits(t *testing.T) {
+	src := `echo '${{ a }}' "${{ b }}"`
+	s := parseShellScript(src)
+	got := applyShellEdits(src, []shellEdit{
+		{s.exprs[0].start, s.exprs[0].end, `'"$A"'`},
+		{s.exprs[1].start, s.exprs[1].end, "$B"},
+	})
+	if want := `echo ''"$A"'' "$B"`; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,194 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/sisaku-security/sisakulint/pkg/ast"
+)
+
+// TextEditは元のソースに対するバイト範囲の編集
+// StartからEndまで(Endを含まない)のバイトをNewTextで置き換える。StartとEndが等しい場合は挿入を表す
+type TextEdit struct {
+	// Startは置換する範囲の先頭のバイトオフセット
+	Start int
+	// Endは置換する範囲の末尾のバイトオフセット(この位置は含まない)
+	End int
+	// NewTextは置換後のテキスト
+	NewText string
+	// RuleNameは編集を生成したルールの名前
+	RuleName string
+
+	seq int
+}
+
+// FixConflictErrorはAutoFixerの編集が、すでに適用が決まった別のAutoFixerの編集と重なっていることを表す
+type FixConflictError struct {
+	// RuleNameは適用できなかったAutoFixerのルール名
+	RuleName string
+	// OtherRuleNameは先に適用されたAutoFixerのルール名
+	OtherRuleName string
+	// Lineは重なっている編集の行番号
+	Line int
+}
+
+func (e *FixConflictError) Error() string {
+	if e.OtherRuleName == "" {
+		return fmt.Sprintf("fix of %q at line %d changes a part of the workflow which was added by another fix. run autofix again to apply it", e.RuleName, e.Line)
+	}
+	return fmt.Sprintf("fix of %q at line %d conflicts with fix of %q. run autofix again to apply it", e.RuleName, e.Line, e.OtherRuleName)
+}
+
+// SourcePatchは元のソースに対する編集の集合
+// 編集はAutoFixerごとにまとめて追加され、既存の編集と重なる場合はそのAutoFixerの編集をすべて拒否する
+type SourcePatch struct {
+	source []byte
+	edits  []*TextEdit
+	seq    int
+}
+
+// NewSourcePatchはsourceに対する空のパッチを作成する
+func NewSourcePatch(source []byte) *SourcePatch {
+	return &SourcePatch{source: source}
+}
+
+// Addは1つのAutoFixerが生成した編集を追加する
+// どれか1つでも既存の編集と重なる場合は何も追加せずに*FixConflictErrorを返す
+// 既存の編集とまったく同じ編集は重複として無視する
+func (p *SourcePatch) Add(edits []*TextEdit) error {
+	var added []*TextEdit
+	for _, e := range edits {
+		if e.Start < 0 || e.End < e.Start || e.End > len(p.source) {
+			return fmt.Errorf("invalid edit range [%d, %d) for source of %d bytes", e.Start, e.End, len(p.source))
+		}
+		duplicate := false
+		for _, o := range p.edits {
+			if o.Start == e.Start && o.End == e.End && o.NewText == e.NewText {
+				duplicate = true
+				break
+			}
+			if overlaps(o, e) {
+				return &FixConflictError{RuleName: e.RuleName, OtherRuleName: o.RuleName, Line: p.lineOf(e.Start)}
+			}
+		}
+		for _, o := range added {
+			if overlaps(o, e) {
+				return fmt.Errorf("fix of %q produced overlapping edits at line %d", e.RuleName, p.lineOf(e.Start))
+			}
+		}
+		if !duplicate {
+			added = append(added, e)
+		}
+	}
+	for _, e := range added {
+		e.seq = p.seq
+		p.seq++
+		p.edits = append(p.edits, e)
+	}
+	return nil
+}
+
+// overlapsは2つの編集の範囲が重なっているかを返す
+// 挿入は置換する範囲の内側にある場合だけ重なっているとみなし、同じ位置への挿入は追加された順に適用する
+func overlaps(a, b *TextEdit) bool {
+	return a.Start < b.End && b.Start < a.End
+}
+
+func (p *SourcePatch) lineOf(offset int) int {
+	return bytes.Count(p.source[:offset], []byte{'\n'}) + 1
+}
+
+// truncateはn番目以降に追加された編集を取り除く
+func (p *SourcePatch) truncate(n int) {
+	p.edits = p.edits[:n]
+}
+
+// Editsはソース上の位置の順に並べた編集を返す
+func (p *SourcePatch) Edits() []*TextEdit {
+	edits := make([]*TextEdit, len(p.edits))
+	copy(edits, p.edits)
+	sort.SliceStable(edits, func(i, j int) bool {
+		a, b := edits[i], edits[j]
+		if a.Start != b.Start {
+			return a.Start < b.Start
+		}
+		// 同じ位置では挿入を範囲の置換より先に適用する
+		if (a.Start == a.End) != (b.Start == b.End) {
+			return a.Start == a.End
+		}
+		return a.seq < b.seq
+	})
+	return edits
+}
+
+// Applyは元のソースに全ての編集を適用した結果を返す
+func (p *SourcePatch) Apply() []byte {
+	var buf bytes.Buffer
+	buf.Grow(len(p.source))
+	prev := 0
+	for _, e := range p.Edits() {
+		buf.Write(p.source[prev:e.Start])
+		buf.WriteString(e.NewText)
+		prev = e.End
+	}
+	buf.Write(p.source[prev:])
+	return buf.Bytes()
+}
+
+// FixFailureは適用できなかったAutoFixerとその理由
+type FixFailure struct {
+	Fixer AutoFixer
+	Err   error
+}
+
+// FixResultはAutoFixerをソースに適用した結果
+type FixResult struct {
+	// Sourceは修正後のソース
+	Source []byte
+	// Patchは元のソースに対する編集
+	Patch *SourcePatch
+	// Appliedは適用されたAutoFixer
+	Applied []AutoFixer
+	// Failedはエラーになった、または別の修正と衝突したため適用されなかったAutoFixer
+	Failed []*FixFailure
+}
+
+// FixVerifierはAutoFixerを適用した後のソースを検証する。エラーを返した場合、そのAutoFixerの修正は取り消される
+type FixVerifier func(fixer AutoFixer, fixed []byte) error
+
+// ApplyAutoFixersはAutoFixerを順番に適用し、それぞれによるyaml.Nodeの変更を元のソースに対するテキストの編集に変換する
+// 変更のあった部分だけを書き換えるため、修正と関係のないインデント、クォート、コメントや空行はそのまま残る
+// エラーになったAutoFixer、先に適用した修正と編集が重なるAutoFixer、verifyがnilでなく検証に失敗したAutoFixerの変更は
+// 取り消され、FixResult.Failedに記録される。取り消した修正はworkflow.BaseNodeと構文木の文字列の両方で元に戻す
+func ApplyAutoFixers(source []byte, workflow *ast.Workflow, fixers []AutoFixer, verify FixVerifier) *FixResult {
+	src := newSourceText(source)
+	patch := NewSourcePatch(source)
+	result := &FixResult{Patch: patch}
+	for _, fixer := range fixers {
+		snapshot := snapshotNode(src, workflow)
+		if err := fixer.Fix(); err != nil {
+			snapshot.restore()
+			result.Failed = append(result.Failed, &FixFailure{Fixer: fixer, Err: err})
+			continue
+		}
+		applied := len(patch.edits)
+		edits, err := snapshot.edits(fixer.RuleName())
+		if err == nil {
+			err = patch.Add(edits)
+		}
+		if err == nil && verify != nil && len(patch.edits) > applied {
+			if err = verify(fixer, patch.Apply()); err != nil {
+				patch.truncate(applied)
+			}
+		}
+		if err != nil {
+			snapshot.restore()
+			result.Failed = append(result.Failed, &FixFailure{Fixer: fixer, Err: err})
+			continue
+		}
+		result.Applied = append(result.Applied, fixer)
+	}
+	result.Source = patch.Apply()
+	return result
+}
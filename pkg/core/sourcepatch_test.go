@@ -0,0 +1,358 @@
+package core
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/sisaku-security/sisakulint/pkg/ast"
+	"gopkg.in/yaml.v3"
+)
+
+func TestSourcePatch_Add(t *testing.T) {
+	src := []byte("a: 1\nb: 2\n")
+	p := NewSourcePatch(src)
+	if err := p.Add([]*TextEdit{{Start: 3, End: 4, NewText: "10", RuleName: "r1"}}); err != nil {
+		t.Fatal(err)
+	}
+	// 同じ位置への挿入と、まったく同じ編集は衝突しない
+	if err := p.Add([]*TextEdit{{Start: 5, End: 5, NewText: "x: 0\n", RuleName: "r2"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Add([]*TextEdit{{Start: 5, End: 5, NewText: "y: 0\n", RuleName: "r3"}, {Start: 3, End: 4, NewText: "10", RuleName: "r3"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	err := p.Add([]*TextEdit{{Start: 8, End: 9, NewText: "3", RuleName: "r4"}, {Start: 2, End: 4, NewText: " 5", RuleName: "r4"}})
+	var conflict *FixConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("want FixConflictError, got %v", err)
+	}
+	if conflict.RuleName != "r4" || conflict.OtherRuleName != "r1" || conflict.Line != 1 {
+		t.Errorf("unexpected conflict: %+v", conflict)
+	}
+
+	want := "a: 10\nx: 0\ny: 0\nb: 2\n"
+	if got := string(p.Apply()); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+// yamlNodeAtはマッピングのキーをたどってノードを返す
+func yamlNodeAt(t *testing.T, root *yaml.Node, path ...string) *yaml.Node {
+	t.Helper()
+	n := root
+	if n.Kind == yaml.DocumentNode {
+		n = n.Content[0]
+	}
+	for _, p := range path {
+		found := false
+		switch n.Kind {
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				if n.Content[i].Value == p {
+					n = n.Content[i+1]
+					found = true
+					break
+				}
+			}
+		case yaml.SequenceNode:
+			for i, c := range n.Content {
+				if p == string(rune('0'+i)) {
+					n = c
+					found = true
+					break
+				}
+			}
+		}
+		if !found {
+			t.Fatalf("%q is not found in path %v", p, path)
+		}
+	}
+	return n
+}
+
+func scalarNode(v string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Value: v}
+}
+
+func TestApplyAutoFixers_PreservesFormatting(t *testing.T) {
+	src := `# workflow
+name: "CI"
+on: { push: { branches: [main] } }
+
+jobs:
+  build:
+    runs-on: 'ubuntu-latest'   # runner
+
+    steps:
+      - uses: actions/checkout@v4
+        with:
+      # build
+      - name: Build
+        run: |
+            echo  "hello"
+            make build
+      - run: echo done
+`
+	tests := []struct {
+		name string
+		fix  func(t *testing.T, root *yaml.Node)
+		want string
+	}{
+		{
+			name: "replace quoted scalar",
+			fix: func(t *testing.T, root *yaml.Node) {
+				yamlNodeAt(t, root, "jobs", "build", "runs-on").Value = "ubuntu-24.04"
+			},
+			want: strings.Replace(src, "'ubuntu-latest'", "'ubuntu-24.04'", 1),
+		},
+		{
+			name: "replace line comment",
+			fix: func(t *testing.T, root *yaml.Node) {
+				n := yamlNodeAt(t, root, "jobs", "build", "steps", "0", "uses")
+				n.Value = "actions/checkout@11bd71901bbe5b1630ceea73d27597364c9af683"
+				n.LineComment = "v4.2.2"
+			},
+			want: strings.Replace(src, "actions/checkout@v4\n", "actions/checkout@11bd71901bbe5b1630ceea73d27597364c9af683 # v4.2.2\n", 1),
+		},
+		{
+			name: "insert key after existing key",
+			fix: func(t *testing.T, root *yaml.Node) {
+				job := yamlNodeAt(t, root, "jobs", "build")
+				job.Content = append(job.Content[:2], append([]*yaml.Node{scalarNode("timeout-minutes"), scalarNode("5")}, job.Content[2:]...)...)
+			},
+			want: strings.Replace(src, "# runner\n", "# runner\n    timeout-minutes: 5\n", 1),
+		},
+		{
+			name: "insert key before first key of sequence item",
+			fix: func(t *testing.T, root *yaml.Node) {
+				step := yamlNodeAt(t, root, "jobs", "build", "steps", "2")
+				step.Content = append([]*yaml.Node{scalarNode("timeout-minutes"), scalarNode("5")}, step.Content...)
+			},
+			want: strings.Replace(src, "      - run: echo done\n", "      - timeout-minutes: 5\n        run: echo done\n", 1),
+		},
+		{
+			name: "set empty value to mapping",
+			fix: func(t *testing.T, root *yaml.Node) {
+				step := yamlNodeAt(t, root, "jobs", "build", "steps", "0")
+				step.Content[3] = &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{scalarNode("persist-credentials"), scalarNode("false")}}
+			},
+			want: strings.Replace(src, "        with:\n", "        with:\n          persist-credentials: false\n", 1),
+		},
+		{
+			name: "remove key",
+			fix: func(t *testing.T, root *yaml.Node) {
+				step := yamlNodeAt(t, root, "jobs", "build", "steps", "0")
+				step.Content = step.Content[:2]
+			},
+			want: strings.Replace(src, "        with:\n", "", 1),
+		},
+		{
+			name: "remove first key of sequence item",
+			fix: func(t *testing.T, root *yaml.Node) {
+				step := yamlNodeAt(t, root, "jobs", "build", "steps", "1")
+				step.Content = step.Content[2:]
+			},
+			want: strings.Replace(src, "      - name: Build\n        run: |", "      - run: |", 1),
+		},
+		{
+			name: "remove sequence item",
+			fix: func(t *testing.T, root *yaml.Node) {
+				steps := yamlNodeAt(t, root, "jobs", "build", "steps")
+				steps.Content = steps.Content[:2]
+			},
+			want: strings.Replace(src, "      - run: echo done\n", "", 1),
+		},
+		{
+			name: "replace block scalar keeping its indentation",
+			fix: func(t *testing.T, root *yaml.Node) {
+				n := yamlNodeAt(t, root, "jobs", "build", "steps", "1", "run")
+				n.Value = strings.Replace(n.Value, "hello", "$GREETING", 1)
+			},
+			want: strings.Replace(src, `echo  "hello"`, `echo  "$GREETING"`, 1),
+		},
+		{
+			name: "modify flow collection",
+			fix: func(t *testing.T, root *yaml.Node) {
+				branches := yamlNodeAt(t, root, "on", "push", "branches")
+				branches.Content = append(branches.Content, scalarNode("release/*"))
+			},
+			want: strings.Replace(src, "on: { push: { branches: [main] } }", "on: {push: {branches: [main, release/*]}}", 1),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var root yaml.Node
+			if err := yaml.Unmarshal([]byte(src), &root); err != nil {
+				t.Fatal(err)
+			}
+			fixer := NewFuncFixer("test", func() error {
+				tt.fix(t, &root)
+				return nil
+			})
+			res := ApplyAutoFixers([]byte(src), &ast.Workflow{BaseNode: &root}, []AutoFixer{fixer}, nil)
+			if len(res.Failed) > 0 {
+				t.Fatal(res.Failed[0].Err)
+			}
+			if got := string(res.Source); got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+			var parsed yaml.Node
+			if err := yaml.Unmarshal(res.Source, &parsed); err != nil {
+				t.Errorf("fixed source is not valid YAML: %v", err)
+			}
+		})
+	}
+}
+
+func TestApplyAutoFixers_ConflictRollsBack(t *testing.T) {
+	src := "jobs:\n  build:\n    runs-on: ubuntu-latest\n"
+	var root yaml.Node
+	if err := yaml.Unmarshal([]byte(src), &root); err != nil {
+		t.Fatal(err)
+	}
+	runsOn := yamlNodeAt(t, &root, "jobs", "build", "runs-on")
+	first := NewFuncFixer("first", func() error {
+		runsOn.Value = "ubuntu-24.04"
+		return nil
+	})
+	second := NewFuncFixer("second", func() error {
+		runsOn.Value = "ubuntu-22.04"
+		return nil
+	})
+	failing := NewFuncFixer("failing", func() error {
+		runsOn.Value = "windows-latest"
+		return errors.New("failed")
+	})
+
+	res := ApplyAutoFixers([]byte(src), &ast.Workflow{BaseNode: &root}, []AutoFixer{first, second, failing}, nil)
+	if want := "jobs:\n  build:\n    runs-on: ubuntu-24.04\n"; string(res.Source) != want {
+		t.Errorf("got %q, want %q", res.Source, want)
+	}
+	if len(res.Applied) != 1 || res.Applied[0] != first {
+		t.Errorf("only the first fixer should be applied: %v", res.Applied)
+	}
+	if len(res.Failed) != 2 {
+		t.Fatalf("got %d failures, want 2", len(res.Failed))
+	}
+	var conflict *FixConflictError
+	if !errors.As(res.Failed[0].Err, &conflict) || conflict.OtherRuleName != "first" {
+		t.Errorf("want conflict with first fixer, got %v", res.Failed[0].Err)
+	}
+	if runsOn.Value != "ubuntu-24.04" {
+		t.Errorf("changes of rejected fixers are not rolled back: %q", runsOn.Value)
+	}
+}
+
+func TestApplyAutoFixers_Workflow(t *testing.T) {
+	src := `# CI
+on:
+  pull_request_target:
+    branches: [ main ]   # only main
+
+jobs:
+  build:
+    runs-on: ubuntu-latest
+
+    steps:
+      - uses: actions/checkout@v4
+        with:
+          ref: ${{ github.event.pull_request.head.sha }}
+      - name: Build
+        run: |
+          echo "${{ github.event.pull_request.title }}"
+          make   build
+`
+	l, err := NewLinter(io.Discard, &LinterOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := l.Lint("ci.yml", []byte(src), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.AutoFixers) == 0 {
+		t.Fatal("no autofixer for the test workflow")
+	}
+	var fixers []AutoFixer
+	for _, f := range res.AutoFixers {
+		if !needsNetwork(f) {
+			fixers = append(fixers, f)
+		}
+	}
+	fixed := ApplyAutoFixers(res.Source, res.ParsedWorkflow, fixers, nil)
+	for _, f := range fixed.Failed {
+		// 同じwith.refを変更する修正の衝突は想定どおり
+		var conflict *FixConflictError
+		if !errors.As(f.Err, &conflict) {
+			t.Errorf("fix of %s failed: %v", f.Fixer.RuleName(), f.Err)
+		}
+	}
+	got := string(fixed.Source)
+
+	// 修正と関係のない行はそのまま残る
+	for _, line := range []string{"# CI\n", "    branches: [ main ]   # only main\n", "\njobs:\n", "    runs-on: ubuntu-latest\n", "\n    steps:\n", "          make   build\n"} {
+		if !strings.Contains(got, line) {
+			t.Errorf("line %q is not preserved in:\n%s", line, got)
+		}
+	}
+	for _, fix := range []string{"    permissions:\n      contents: read\n", "persist-credentials: false", "PR_TITLE: ${{ github.event.pull_request.title }}", "echo \"$PR_TITLE\""} {
+		if !strings.Contains(got, fix) {
+			t.Errorf("fix %q is not applied:\n%s", fix, got)
+		}
+	}
+	if _, errs := Parse(fixed.Source); len(errs) > 0 {
+		t.Errorf("fixed workflow does not parse: %v", errs)
+	}
+}
+
+func TestApplyAutoFixers_RollsBackASTStrings(t *testing.T) {
+	src := `on: pull_request_target
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo "::set-output name=title::${{ github.event.pull_request.title }}"
+`
+	l, err := NewLinter(io.Discard, &LinterOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := l.Lint("ci.yml", []byte(src), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var deprecated, injection AutoFixer
+	for _, f := range res.AutoFixers {
+		switch f.RuleName() {
+		case "deprecated-commands":
+			deprecated = f
+		case "code-injection-critical":
+			injection = f
+		}
+	}
+	if deprecated == nil || injection == nil {
+		t.Fatalf("autofixers are missing: %v", res.AutoFixers)
+	}
+	// deprecated-commandsの修正を取り消した後、code-injectionの修正が書き換え前のスクリプトを読むこと
+	verify := func(fixer AutoFixer, fixed []byte) error {
+		if fixer == deprecated {
+			return errors.New("rejected")
+		}
+		return nil
+	}
+	fixed := ApplyAutoFixers(res.Source, res.ParsedWorkflow, []AutoFixer{deprecated, injection}, verify)
+	if len(fixed.Applied) != 1 || fixed.Applied[0] != injection {
+		t.Fatalf("only code-injection-critical should be applied: %v", fixed.Applied)
+	}
+	got := string(fixed.Source)
+	if want := `echo "::set-output name=title::$PR_TITLE"`; !strings.Contains(got, want) {
+		t.Errorf("%q is not in the fixed workflow:\n%s", want, got)
+	}
+	if strings.Contains(got, "GITHUB_OUTPUT") {
+		t.Errorf("rolled back fix of deprecated-commands is applied:\n%s", got)
+	}
+}
@@ -0,0 +1,100 @@
+package core
+
+import (
+	"github.com/sisaku-security/sisakulint/pkg/ast"
+)
+
+// applySuppressionsはインライン抑制コメントをエラーに適用する
+// 抑制されなかったエラー、抑制されたエラー、使われなかった抑制コメントに対するエラーを返す
+// 抑制されたエラーはSuppressionフィールドに抑制したコメントが設定される
+func applySuppressions(suppressions []*ast.Suppression, errs []*LintingError) (kept, suppressed, unused []*LintingError) {
+	if len(suppressions) == 0 {
+		return errs, nil, nil
+	}
+
+	type usage struct {
+		s    *ast.Suppression
+		rule string
+	}
+	used := map[usage]bool{}
+
+	kept = make([]*LintingError, 0, len(errs))
+	for _, err := range errs {
+		var by *ast.Suppression
+		for _, s := range suppressions {
+			if s.Covers(err.Type, err.LineNumber) {
+				by = s
+				used[usage{s, err.Type}] = true
+				break
+			}
+		}
+		if by == nil {
+			kept = append(kept, err)
+			continue
+		}
+		err.Suppression = by
+		suppressed = append(suppressed, err)
+	}
+
+	for _, s := range suppressions {
+		for _, r := range s.Rules {
+			if used[usage{s, r}] {
+				continue
+			}
+			unused = append(unused, FormattedError(
+				s.Pos,
+				SuppressionRuleName,
+				"unused suppression: rule %q is not reported in the scope of this %q comment. remove it from the comment",
+				r,
+				suppressionDirectiveName(s.Kind),
+			))
+		}
+	}
+
+	return kept, suppressed, unused
+}
+
+// suppressionDirectiveNameは抑制コメントの種類に対応するディレクティブ名を返す
+func suppressionDirectiveName(kind ast.SuppressionKind) string {
+	switch kind {
+	case ast.SuppressionKindNextLine:
+		return suppressionDirectivePrefix + "-next-line"
+	case ast.SuppressionKindFile:
+		return suppressionDirectivePrefix + "-file"
+	default:
+		return suppressionDirectivePrefix
+	}
+}
+
+// filterRemovedAutoFixersは、修正するエラーが全て抑制またはベースラインによって取り除かれたAutoFixerを取り除く
+// 同じルールでも、報告されるエラーを修正するAutoFixerは残す
+func filterRemovedAutoFixers(fixers []AutoFixer, kept, removed []*LintingError) []AutoFixer {
+	if len(removed) == 0 {
+		return fixers
+	}
+	all := make([]*LintingError, 0, len(kept)+len(removed))
+	all = append(all, kept...)
+	all = append(all, removed...)
+	fixes := errorFixers(all, fixers)
+	reported := map[AutoFixer]bool{}
+	for _, err := range kept {
+		for _, f := range fixes[err] {
+			reported[f] = true
+		}
+	}
+	onlyRemoved := map[AutoFixer]bool{}
+	for _, err := range removed {
+		for _, f := range fixes[err] {
+			if !reported[f] {
+				onlyRemoved[f] = true
+			}
+		}
+	}
+	filtered := make([]AutoFixer, 0, len(fixers))
+	for _, f := range fixers {
+		if !onlyRemoved[f] {
+			filtered = append(filtered, f)
+		}
+	}
+	return filtered
+}
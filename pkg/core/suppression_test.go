@@ -0,0 +1,220 @@
+package core
+
+import (
+	"encoding/json"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/sisaku-security/sisakulint/pkg/ast"
+)
+
+const suppressionTestWorkflow = `on: push
+permissions: {}
+jobs:
+  # sisakulint-disable missing-timeout-minutes -- short job
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      # sisakulint-disable-next-line commit-sha -- pinned by renovate
+      - uses: actions/setup-go@v5
+      # sisakulint-disable-next-line id
+      - run: echo hi
+  test:
+    runs-on: ubuntu-latest
+    # sisakulint-disable-next-line cond -- nothing to see
+    steps:
+      - run: echo
+`
+
+func TestParse_Suppressions(t *testing.T) {
+	workflow, errs := Parse([]byte(suppressionTestWorkflow))
+	if workflow == nil {
+		t.Fatal("Parse() returned nil workflow")
+	}
+
+	want := []struct {
+		kind       ast.SuppressionKind
+		rules      []string
+		reason     string
+		start, end int
+	}{
+		{kind: ast.SuppressionKindBlock, rules: []string{"missing-timeout-minutes"}, reason: "short job", start: 5, end: 11},
+		{kind: ast.SuppressionKindNextLine, rules: []string{"commit-sha"}, reason: "pinned by renovate", start: 9, end: 9},
+		{kind: ast.SuppressionKindNextLine, rules: []string{"cond"}, reason: "nothing to see", start: 15, end: 15},
+	}
+	if len(workflow.Suppressions) != len(want) {
+		t.Fatalf("got %d suppressions, want %d: %#v", len(workflow.Suppressions), len(want), workflow.Suppressions)
+	}
+	for i, w := range want {
+		s := workflow.Suppressions[i]
+		if s.Kind != w.kind || strings.Join(s.Rules, ",") != strings.Join(w.rules, ",") || s.Reason != w.reason || s.StartLine != w.start || s.EndLine != w.end {
+			t.Errorf("suppressions[%d] = %+v, want %+v", i, *s, w)
+		}
+	}
+
+	if len(errs) != 1 || errs[0].Type != SuppressionRuleName || errs[0].LineNumber != 10 {
+		t.Fatalf("want one suppression error at line 10 for the missing reason, got %v", errs)
+	}
+	if !strings.Contains(errs[0].Description, "must have a reason") {
+		t.Errorf("unexpected error message: %q", errs[0].Description)
+	}
+}
+
+func TestParseSuppressionDirective(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		kind    ast.SuppressionKind
+		rules   []string
+		wantErr string
+	}{
+		{name: "next line", text: "sisakulint-disable-next-line commit-sha -- pinned", kind: ast.SuppressionKindNextLine, rules: []string{"commit-sha"}},
+		{name: "multiple rules", text: "sisakulint-disable a, b,c -- reason", kind: ast.SuppressionKindBlock, rules: []string{"a", "b", "c"}},
+		{name: "file", text: "sisakulint-disable-file permissions -- reusable", kind: ast.SuppressionKindFile, rules: []string{"permissions"}},
+		{name: "unknown directive", text: "sisakulint-disable-line id -- reason", wantErr: "unknown suppression directive"},
+		{name: "no rules", text: "sisakulint-disable -- reason", wantErr: "at least one rule"},
+		{name: "empty reason", text: "sisakulint-disable id --  ", wantErr: "must have a reason"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &parser{}
+			s := p.parseSuppressionDirective(tt.text, &ast.Position{Line: 1, Col: 1})
+			if tt.wantErr != "" {
+				if s != nil {
+					t.Fatalf("want nil suppression, got %+v", *s)
+				}
+				if len(p.errors) != 1 || !strings.Contains(p.errors[0].Description, tt.wantErr) {
+					t.Fatalf("want error containing %q, got %v", tt.wantErr, p.errors)
+				}
+				return
+			}
+			if len(p.errors) != 0 {
+				t.Fatalf("unexpected errors: %v", p.errors)
+			}
+			if s.Kind != tt.kind || strings.Join(s.Rules, ",") != strings.Join(tt.rules, ",") {
+				t.Errorf("got %+v, want kind %v rules %v", *s, tt.kind, tt.rules)
+			}
+		})
+	}
+}
+
+func TestApplySuppressions(t *testing.T) {
+	suppressions := []*ast.Suppression{
+		{Kind: ast.SuppressionKindBlock, Rules: []string{"missing-timeout-minutes"}, Reason: "short", StartLine: 3, EndLine: 10, Pos: &ast.Position{Line: 2, Col: 1}},
+		{Kind: ast.SuppressionKindNextLine, Rules: []string{"commit-sha", "artipacked"}, Reason: "ok", StartLine: 5, EndLine: 5, Pos: &ast.Position{Line: 4, Col: 1}},
+		{Kind: ast.SuppressionKindFile, Rules: []string{"permissions"}, Reason: "reusable", Pos: &ast.Position{Line: 1, Col: 1}},
+	}
+	errs := []*LintingError{
+		{Type: "missing-timeout-minutes", LineNumber: 4},
+		{Type: "missing-timeout-minutes", LineNumber: 12},
+		{Type: "commit-sha", LineNumber: 5},
+		{Type: "commit-sha", LineNumber: 6},
+		{Type: "permissions", LineNumber: 30},
+	}
+
+	kept, suppressed, unused := applySuppressions(suppressions, errs)
+
+	if len(kept) != 2 || kept[0].LineNumber != 12 || kept[1].LineNumber != 6 {
+		t.Errorf("unexpected kept errors: %v", kept)
+	}
+	if len(suppressed) != 3 {
+		t.Fatalf("got %d suppressed errors, want 3", len(suppressed))
+	}
+	for _, err := range suppressed {
+		if err.Suppression == nil {
+			t.Errorf("suppressed error %v does not have its suppression", err)
+		}
+	}
+	if len(unused) != 1 || unused[0].LineNumber != 4 || !strings.Contains(unused[0].Description, `"artipacked"`) {
+		t.Errorf("want one unused suppression error for artipacked at line 4, got %v", unused)
+	}
+}
+
+func TestLinter_SuppressedErrorsInSARIF(t *testing.T) {
+	opts := &LinterOptions{CustomErrorMessageFormat: "{{sarif .}}"}
+	var out strings.Builder
+	l, err := NewLinter(&out, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := l.Lint("<stdin>", []byte(suppressionTestWorkflow), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range res.Errors {
+		if e.Type == "commit-sha" && e.LineNumber == 9 {
+			t.Errorf("suppressed commit-sha error is reported: %v", e)
+		}
+	}
+	if len(res.Suppressed) == 0 {
+		t.Fatal("no suppressed errors in the result")
+	}
+
+	var doc struct {
+		Runs []struct {
+			Results []struct {
+				RuleID       string `json:"ruleId"`
+				Suppressions []struct {
+					Kind          string `json:"kind"`
+					Justification string `json:"justification"`
+				} `json:"suppressions"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	if err := json.Unmarshal([]byte(out.String()), &doc); err != nil {
+		t.Fatalf("failed to decode SARIF output: %v\n%s", err, out.String())
+	}
+	found := false
+	for _, r := range doc.Runs[0].Results {
+		if r.RuleID == "commit-sha" && len(r.Suppressions) == 1 {
+			found = true
+			if r.Suppressions[0].Kind != "inSource" || r.Suppressions[0].Justification != "pinned by renovate" {
+				t.Errorf("unexpected suppression: %+v", r.Suppressions[0])
+			}
+		}
+	}
+	if !found {
+		t.Errorf("suppressed commit-sha result is not in the SARIF output:\n%s", out.String())
+	}
+}
+
+func TestLinter_SuppressionRemovesOnlyFixersOfSuppressedErrors(t *testing.T) {
+	src := `on: push
+permissions: {}
+jobs:
+  # sisakulint-disable-next-line missing-timeout-minutes -- short job
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo build
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo test
+        timeout-minutes: 5
+`
+	l, err := NewLinter(io.Discard, &LinterOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := l.Lint("workflow.yml", []byte(src), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 抑制したジョブのエラーの修正だけを取り除き、同じルールのステップと別のジョブの修正は残す
+	var lines []int
+	for _, f := range res.AutoFixers {
+		if f.RuleName() == "missing-timeout-minutes" {
+			lines = append(lines, fixerLine(f))
+		}
+	}
+	sort.Ints(lines)
+	if want := []int{8, 9}; !slices.Equal(lines, want) {
+		t.Errorf("got fixers at lines %v, want %v", lines, want)
+	}
+}
@@ -0,0 +1,494 @@
+package core
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/sisaku-security/sisakulint/pkg/ast"
+	"github.com/sisaku-security/sisakulint/pkg/expressions"
+)
+
+// workflowTaintは、ワークフローの中で信頼できない入力の値が流れる先を求めるデータフロー解析
+// 信頼できない入力はenv:、マトリックスの値、$GITHUB_OUTPUTや::set-outputによるステップの出力、
+// $GITHUB_ENVへの書き込み、ジョブの出力とneeds.<job>.outputsを通って伝わる
+// 例えば次のワークフローではsteps.meta.outputs.titleも信頼できない入力になる
+//
+//   - id: meta
+//     run: echo "title=${{ github.event.issue.title }}" >> "$GITHUB_OUTPUT"
+//   - run: echo "${{ steps.meta.outputs.title }}"
+//
+// インジェクションのルールは、ステップごとの検索ツリー(taintScope)を使って信頼できない入力を検出する
+// ジョブはneeds:の依存をたどって必要になった時に解析する
+type workflowTaint struct {
+	workflow *ast.Workflow
+	config   *Config
+	// inputsは、再利用可能なワークフローの呼び出し元からwith:で信頼できない値を受け取る入力
+	inputs map[string]*callerInput
+	jobs   map[*ast.Job]*jobTaint
+	// visitingは解析中のジョブ。needs:の循環をたどらないために使う
+	visiting map[*ast.Job]bool
+}
+
+// jobTaintはジョブの解析の結果
+type jobTaint struct {
+	// scopeはジョブのレベルの検索ツリー。env:とステップの出力は含まない。再利用可能なワークフローを呼び出すジョブのwith:の検査に使う
+	scope *taintScope
+	// stepsは各ステップが実行される時点の検索ツリー
+	steps []*taintScope
+	// outputsはジョブの出力の名前から、値の元になった信頼できない入力のパスへの対応
+	outputs map[string][]string
+}
+
+// taintScopeは、ある時点で信頼できない入力として扱うプロパティの検索ツリーと、解析で見つけたプロパティの由来
+type taintScope struct {
+	roots expressions.ContextPropertySearchRoots
+	// viaは、信頼できない入力から値を受け取ったプロパティのパスから、元の信頼できない入力のパスへの対応
+	via map[string][]string
+	// traceは、再利用可能なワークフローの呼び出し元から信頼できない値を受け取るプロパティのパスから、呼び出し元での値の由来への対応
+	trace map[string][]string
+	// ownedは、rootsがこのスコープだけのものか。他のスコープと共有している場合はパスを加える前にコピーする
+	owned bool
+}
+
+// newWorkflowTaintは新しいworkflowTaintインスタンスを作成する。ジョブの解析はjobを呼んだ時に行う
+// inputsは呼び出し元から信頼できない値を受け取る入力で、nilにできる
+func newWorkflowTaint(w *ast.Workflow, cfg *Config, inputs map[string]*callerInput) *workflowTaint {
+	return &workflowTaint{
+		workflow: w,
+		config:   cfg,
+		inputs:   inputs,
+		jobs:     map[*ast.Job]*jobTaint{},
+		visiting: map[*ast.Job]bool{},
+	}
+}
+
+// jobはジョブの解析の結果を返す。needs:の依存のジョブも必要に応じて解析する
+func (t *workflowTaint) job(j *ast.Job) *jobTaint {
+	if r, ok := t.jobs[j]; ok {
+		return r
+	}
+	if t.visiting[j] {
+		return nil
+	}
+	t.visiting[j] = true
+	r := t.analyzeJob(j)
+	delete(t.visiting, j)
+	t.jobs[j] = r
+	return r
+}
+
+// jobOutputsはIDがidのジョブの出力のうち、信頼できない値を持つものを返す
+func (t *workflowTaint) jobOutputs(id string) map[string][]string {
+	if t.workflow == nil {
+		return nil
+	}
+	j, ok := t.workflow.Jobs[strings.ToLower(id)]
+	if !ok || j == nil {
+		return nil
+	}
+	if r := t.job(j); r != nil {
+		return r.outputs
+	}
+	return nil
+}
+
+func (t *workflowTaint) analyzeJob(j *ast.Job) *jobTaint {
+	base := &taintScope{roots: untrustedInputRoots(t.workflow, t.config, j), via: map[string][]string{}, trace: map[string][]string{}}
+
+	for name, in := range t.inputs {
+		base.addTraced("inputs."+name, in.events, in.trace)
+	}
+
+	for _, n := range j.Needs {
+		if n == nil {
+			continue
+		}
+		for name, srcs := range t.jobOutputs(n.Value) {
+			base.add("needs."+n.Value+".outputs."+name, srcs)
+		}
+	}
+
+	if j.Strategy != nil && j.Strategy.Matrix != nil {
+		m := j.Strategy.Matrix
+		for name, row := range m.Rows {
+			for _, v := range row.Values {
+				if paths := base.taintedPathsInYAML(v); len(paths) > 0 {
+					base.add("matrix."+name, base.origins(paths))
+				}
+			}
+		}
+		if m.Include != nil {
+			for _, c := range m.Include.Combinations {
+				for name, a := range c.Assigns {
+					if paths := base.taintedPathsInYAML(a.Value); len(paths) > 0 {
+						base.add("matrix."+name, base.origins(paths))
+					}
+				}
+			}
+		}
+	}
+
+	// envの値はステップのenv:で上書きされると信頼できる値に戻ることがあるので、検索ツリーとは別に持つ
+	// ワークフローとジョブのenv:ではenvコンテキストを使えない
+	env := map[string][]string{}
+	if t.workflow != nil {
+		base.applyEnv(env, t.workflow.Env)
+	}
+	base.applyEnv(env, j.Env)
+
+	r := &jobTaint{scope: base.clone(), steps: make([]*taintScope, 0, len(j.Steps))}
+	for _, s := range j.Steps {
+		stepEnv := make(map[string][]string, len(env))
+		for k, v := range env {
+			stepEnv[k] = v
+		}
+		if s != nil {
+			base.withEnv(env).applyEnv(stepEnv, s.Env)
+		}
+		scope := base.withEnv(stepEnv)
+		r.steps = append(r.steps, scope)
+		if s == nil {
+			continue
+		}
+
+		run, ok := s.Exec.(*ast.ExecRun)
+		if !ok || run.Run == nil {
+			continue
+		}
+		for _, w := range scope.scriptWrites(run.Run.Value, stepEnv) {
+			switch w.file {
+			case "GITHUB_OUTPUT":
+				if s.ID != nil && s.ID.Value != "" {
+					base.add("steps."+s.ID.Value+".outputs."+w.key, w.sources)
+				}
+			case "GITHUB_ENV":
+				env[w.key] = w.sources
+			}
+		}
+	}
+
+	end := base.withEnv(env)
+	for name, o := range j.Outputs {
+		if o == nil || o.Value == nil {
+			continue
+		}
+		if paths := end.taintedPaths(o.Value.Value); len(paths) > 0 {
+			if r.outputs == nil {
+				r.outputs = map[string][]string{}
+			}
+			r.outputs[name] = end.origins(paths)
+		}
+	}
+	return r
+}
+
+// addは、信頼できない入力sourcesから値を受け取ったプロパティpathを検索ツリーに加える
+// pathのイベントはsourcesのイベントを合わせたもの
+func (s *taintScope) add(path string, sources []string) {
+	if !s.owned {
+		s.roots = s.roots.Copy()
+		s.owned = true
+	}
+	var events []string
+	for _, src := range sources {
+		if m := s.roots.Find(src); m != nil {
+			events = append(events, m.Events...)
+		}
+	}
+	s.roots.AddPath(path, events...)
+	s.via[strings.ToLower(path)] = sources
+}
+
+// addTracedは、再利用可能なワークフローの呼び出し元から信頼できない値を受け取るプロパティpathを検索ツリーに加える
+func (s *taintScope) addTraced(path string, events, trace []string) {
+	if !s.owned {
+		s.roots = s.roots.Copy()
+		s.owned = true
+	}
+	s.roots.AddPath(path, events...)
+	s.trace[strings.ToLower(path)] = trace
+}
+
+// cloneはスコープのコピーを返す。検索ツリーは次にパスを加える時までコピーせずに共有する
+func (s *taintScope) clone() *taintScope {
+	s.owned = false
+	via := make(map[string][]string, len(s.via))
+	for k, v := range s.via {
+		via[k] = v
+	}
+	return &taintScope{roots: s.roots, via: via, trace: s.trace}
+}
+
+// withEnvは、env.<name>として環境変数envを加えたスコープのコピーを返す
+func (s *taintScope) withEnv(env map[string][]string) *taintScope {
+	c := s.clone()
+	for name, srcs := range env {
+		c.add("env."+name, srcs)
+	}
+	return c
+}
+
+// applyEnvはenv:の定義をenvに反映する。信頼できない値を持つ環境変数を加え、信頼できる値で上書きされた環境変数を取り除く
+func (s *taintScope) applyEnv(env map[string][]string, e *ast.Env) {
+	if e == nil {
+		return
+	}
+	for _, v := range e.Vars {
+		if v == nil || v.Name == nil {
+			continue
+		}
+		var paths []string
+		if v.Value != nil {
+			paths = s.taintedPaths(v.Value.Value)
+		}
+		if len(paths) > 0 {
+			env[v.Name.Value] = s.origins(paths)
+		} else {
+			delete(env, v.Name.Value)
+		}
+	}
+}
+
+// originsは、信頼できない入力のパスを、解析で値を受け取ったプロパティの場合は元の信頼できない入力のパスに置き換える
+func (s *taintScope) origins(paths []string) []string {
+	seen := map[string]struct{}{}
+	var srcs []string
+	for _, p := range paths {
+		vs, ok := s.via[strings.ToLower(p)]
+		if !ok {
+			vs = []string{p}
+		}
+		for _, v := range vs {
+			if _, ok := seen[v]; !ok {
+				seen[v] = struct{}{}
+				srcs = append(srcs, v)
+			}
+		}
+	}
+	sort.Strings(srcs)
+	return srcs
+}
+
+// taintedPathsは、文字列の中の${{ }}が参照する信頼できない入力のパスを返す
+func (s *taintScope) taintedPaths(str string) []string {
+	var paths []string
+	for _, raw := range extractExpressionsFromString(str) {
+		paths = append(paths, s.taintedPathsInExpr(raw)...)
+	}
+	return paths
+}
+
+// taintedPathsInExprは、${{ }}の中身の式が参照する信頼できない入力のパスを返す
+func (s *taintScope) taintedPathsInExpr(raw string) []string {
+	p := expressions.NewMiniParser()
+	node, err := p.Parse(expressions.NewTokenizer(raw + "}}"))
+	if err != nil || node == nil {
+		return nil
+	}
+	return untrustedPathsInExpr(node, s.roots)
+}
+
+// taintedPathsInYAMLは、マトリックスの値の中の文字列が参照する信頼できない入力のパスを返す
+func (s *taintScope) taintedPathsInYAML(v ast.RawYAMLValue) []string {
+	switch v := v.(type) {
+	case *ast.RawYAMLString:
+		return s.taintedPaths(v.Value)
+	case *ast.RawYAMLArray:
+		var paths []string
+		for _, e := range v.Elems {
+			paths = append(paths, s.taintedPathsInYAML(e)...)
+		}
+		return paths
+	case *ast.RawYAMLObject:
+		var paths []string
+		for _, p := range v.Props {
+			paths = append(paths, s.taintedPathsInYAML(p)...)
+		}
+		return paths
+	}
+	return nil
+}
+
+// taintWriteは、run:のスクリプトが信頼できない値を$GITHUB_OUTPUTや$GITHUB_ENVに書き込むこと
+type taintWrite struct {
+	// fileは"GITHUB_OUTPUT"または"GITHUB_ENV"
+	file string
+	// keyは書き込まれる出力や環境変数の名前
+	key     string
+	sources []string
+}
+
+// setOutputCommandは、非推奨の::set-outputワークフローコマンド
+var setOutputCommand = regexp.MustCompile(`::set-output\s+name=([\w-]+)::`)
+
+// shellDeclarationCommandsは、引数で変数に代入するシェルの組み込みコマンド
+var shellDeclarationCommands = map[string]struct{}{
+	"export": {}, "local": {}, "declare": {}, "typeset": {}, "readonly": {},
+}
+
+// scriptWritesは、スクリプトが信頼できない値を書き込む出力と環境変数を返す
+// 値は${{ }}として直接書かれるか、信頼できない値を持つ環境変数envやスクリプトの中で代入されたシェルの変数として展開される
+func (s *taintScope) scriptWrites(src string, env map[string][]string) []taintWrite {
+	if !strings.Contains(src, "GITHUB_OUTPUT") && !strings.Contains(src, "GITHUB_ENV") && !strings.Contains(src, "::set-output") {
+		return nil
+	}
+	script := parseShellScript(src)
+
+	exprs := make(map[*shellExpansion][]string, len(script.exprs))
+	for _, e := range script.exprs {
+		if paths := s.taintedPathsInExpr(e.name); len(paths) > 0 {
+			exprs[e] = s.origins(paths)
+		}
+	}
+	vars := make(map[string][]string, len(env))
+	for k, v := range env {
+		vars[k] = v
+	}
+	// シェルの変数の代入をたどる。代入の順序は考えず、信頼できない値が代入される変数がなくなるまで繰り返す
+	for changed := true; changed; {
+		changed = false
+		for _, c := range script.commands {
+			for _, w := range shellAssignments(c) {
+				name, _, _ := strings.Cut(w.value, "=")
+				if _, ok := vars[name]; ok {
+					continue
+				}
+				if srcs := script.taintedIn(w.start, w.end, exprs, vars); len(srcs) > 0 {
+					vars[name] = srcs
+					changed = true
+				}
+			}
+		}
+	}
+
+	var writes []taintWrite
+	check := func(e *shellExpansion, srcs []string) {
+		line := src[strings.LastIndexByte(src[:e.start], '\n')+1 : e.start]
+		if m := setOutputCommand.FindAllStringSubmatch(line, -1); m != nil {
+			writes = append(writes, taintWrite{"GITHUB_OUTPUT", m[len(m)-1][1], srcs})
+			return
+		}
+		for _, file := range []string{"GITHUB_OUTPUT", "GITHUB_ENV"} {
+			if !script.writesToVarFile(e, file) {
+				continue
+			}
+			if key := writtenKey(src, e.start); key != "" {
+				writes = append(writes, taintWrite{file, key, srcs})
+			}
+		}
+	}
+	for _, e := range script.exprs {
+		if srcs, ok := exprs[e]; ok {
+			check(e, srcs)
+		}
+	}
+	for _, v := range script.vars {
+		if srcs, ok := vars[v.name]; ok && v.quote.expandsVariables() {
+			check(v, srcs)
+		}
+	}
+	return writes
+}
+
+// taintedInは、スクリプトのstartからendまでの範囲で展開される信頼できない値の元になった入力のパスを返す
+func (s *shellScript) taintedIn(start, end int, exprs map[*shellExpansion][]string, vars map[string][]string) []string {
+	var srcs []string
+	for _, e := range s.exprs {
+		if start <= e.start && e.start < end {
+			srcs = append(srcs, exprs[e]...)
+		}
+	}
+	for _, v := range s.vars {
+		if start <= v.start && v.start < end && v.quote.expandsVariables() {
+			srcs = append(srcs, vars[v.name]...)
+		}
+	}
+	return srcs
+}
+
+// shellAssignmentsは、コマンドの中でNAME=valueの形で変数に代入する単語を返す
+// コマンドの名前の前の代入と、exportやlocalのような宣言のコマンドの引数を対象にする
+func shellAssignments(c *shellCommand) []*shellWord {
+	var ws []*shellWord
+	decl := false
+	for _, w := range c.words {
+		if n, _, ok := strings.Cut(w.value, "="); ok && isShellName(n) {
+			ws = append(ws, w)
+			continue
+		}
+		if _, ok := shellReservedWords[w.value]; ok {
+			continue
+		}
+		if decl {
+			continue
+		}
+		if _, ok := shellDeclarationCommands[w.value]; !ok {
+			break
+		}
+		decl = true
+	}
+	return ws
+}
+
+// writtenKeyは、オフセットstartの値が書き込まれる出力や環境変数の名前を返す
+// 同じ行のname=valueやname<<DELIMITERの形、または複数行の値を書き込む前の行のname<<DELIMITERから名前を見つける
+// 名前が見つからない場合は空文字列を返す
+func writtenKey(src string, start int) string {
+	lineStart := strings.LastIndexByte(src[:start], '\n') + 1
+	if key := lastKey(src[lineStart:start], true); key != "" {
+		return key
+	}
+	return lastKey(src[:lineStart], false)
+}
+
+// lastKeyは、sの最後のname=またはname<<のnameを返す。assignがfalseの場合はname<<だけを探す
+// nameは行の先頭、空白、引用符の直後から始まるものだけを対象にする
+func lastKey(s string, assign bool) string {
+	for i := len(s) - 1; i > 0; i-- {
+		end := -1
+		switch {
+		case assign && s[i] == '=':
+			end = i
+		case s[i] == '<' && s[i-1] == '<':
+			end = i - 1
+		}
+		if end <= 0 {
+			continue
+		}
+		begin := end
+		for begin > 0 && (isShellNameChar(s[begin-1]) || s[begin-1] == '-') {
+			begin--
+		}
+		// title=a=bのような値の中の=は読み飛ばす
+		if begin < end && (begin == 0 || strings.IndexByte(" \t\"'", s[begin-1]) >= 0) {
+			return s[begin:end]
+		}
+		if end < i {
+			i = end
+		}
+	}
+	return ""
+}
+
+// untrustedPathsInExprは、検索ツリーrootsを使って式の構文木から信頼できない入力のパスを取り出す
+func untrustedPathsInExpr(node expressions.ExprNode, roots expressions.ContextPropertySearchRoots) []string {
+	checker := expressions.NewExprSemanticsChecker(true, nil)
+	checker.UpdateUntrustedInputs(roots)
+	_, errs := checker.Check(node)
+
+	var paths []string
+	for _, err := range errs {
+		msg := err.Message
+		if strings.Contains(msg, "potentially untrusted") {
+			if idx := strings.Index(msg, "\""); idx != -1 {
+				endIdx := strings.Index(msg[idx+1:], "\"")
+				if endIdx != -1 {
+					path := msg[idx+1 : idx+1+endIdx]
+					paths = append(paths, path)
+				}
+			}
+		}
+	}
+	return paths
+}
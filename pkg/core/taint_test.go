@@ -0,0 +1,322 @@
+package core
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sisaku-security/sisakulint/pkg/ast"
+)
+
+func TestWorkflowTaint(t *testing.T) {
+	tests := []struct {
+		name string
+		jobs string
+		job  string
+		step int
+		expr string
+		want string
+	}{
+		{
+			name: "step output written with expression",
+			jobs: `
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      - id: meta
+        run: echo "title=${{ github.event.issue.title }}" >> "$GITHUB_OUTPUT"
+      - run: echo`,
+			job:  "test",
+			step: 1,
+			expr: "steps.meta.outputs.title",
+			want: `"steps.meta.outputs.title" (from issues event via "github.event.issue.title")`,
+		},
+		{
+			name: "step output written with environment variable",
+			jobs: `
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      - id: meta
+        env:
+          TITLE: ${{ github.event.issue.title }}
+        run: |
+          echo "title=$TITLE" >> "$GITHUB_OUTPUT"
+          echo "ignored='$TITLE'" >> "$GITHUB_OUTPUT"
+      - run: echo`,
+			job:  "test",
+			step: 1,
+			expr: "steps.meta.outputs.title",
+			want: `"steps.meta.outputs.title" (from issues event via "github.event.issue.title")`,
+		},
+		{
+			name: "step output written with shell variable",
+			jobs: `
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      - id: meta
+        run: |
+          export T="${{ github.event.issue.title }}"
+          BODY=$(echo "$T" | head -n 1)
+          echo "body=$BODY" | tee -a "$GITHUB_OUTPUT"
+      - run: echo`,
+			job:  "test",
+			step: 1,
+			expr: "steps.meta.outputs.body",
+			want: `"steps.meta.outputs.body" (from issues event via "github.event.issue.title")`,
+		},
+		{
+			name: "single quoted variable is not expanded",
+			jobs: `
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      - id: meta
+        env:
+          TITLE: ${{ github.event.issue.title }}
+        run: echo 'title=$TITLE' >> "$GITHUB_OUTPUT"
+      - run: echo`,
+			job:  "test",
+			step: 1,
+			expr: "steps.meta.outputs.title",
+		},
+		{
+			name: "multiline step output",
+			jobs: `
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      - id: meta
+        run: |
+          {
+            echo "body<<EOF"
+            echo "${{ github.event.issue.body }}"
+            echo "EOF"
+          } >> "$GITHUB_OUTPUT"
+      - run: echo`,
+			job:  "test",
+			step: 1,
+			expr: "steps.meta.outputs.body",
+			want: `"steps.meta.outputs.body" (from issues event via "github.event.issue.body")`,
+		},
+		{
+			name: "step output written with heredoc",
+			jobs: `
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      - id: meta
+        run: |
+          cat <<EOF >> "$GITHUB_OUTPUT"
+          title=${{ github.event.issue.title }}
+          EOF
+      - run: echo`,
+			job:  "test",
+			step: 1,
+			expr: "steps.meta.outputs.title",
+			want: `"steps.meta.outputs.title" (from issues event via "github.event.issue.title")`,
+		},
+		{
+			name: "step output set with workflow command",
+			jobs: `
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      - id: meta
+        run: echo "::set-output name=title::${{ github.event.issue.title }}"
+      - run: echo`,
+			job:  "test",
+			step: 1,
+			expr: "steps.meta.outputs.title",
+			want: `"steps.meta.outputs.title" (from issues event via "github.event.issue.title")`,
+		},
+		{
+			name: "step output written by compound command",
+			jobs: `
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      - id: meta
+        run: |
+          if true; then
+            echo "title=${{ github.event.issue.title }}"
+          fi >> "$GITHUB_OUTPUT"
+      - run: echo`,
+			job:  "test",
+			step: 1,
+			expr: "steps.meta.outputs.title",
+			want: `"steps.meta.outputs.title" (from issues event via "github.event.issue.title")`,
+		},
+		{
+			name: "trusted step output",
+			jobs: `
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      - id: meta
+        run: |
+          echo "number=${{ github.event.issue.number }}" >> "$GITHUB_OUTPUT"
+          echo "${{ github.event.issue.title }}" > title.txt
+      - run: echo`,
+			job:  "test",
+			step: 1,
+			expr: "steps.meta.outputs.number",
+		},
+		{
+			name: "environment variable written to GITHUB_ENV",
+			jobs: `
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo "TITLE=${{ github.event.issue.title }}" >> "$GITHUB_ENV"
+      - run: echo`,
+			job:  "test",
+			step: 1,
+			expr: "env.TITLE",
+			want: `"env.title" (from issues event via "github.event.issue.title")`,
+		},
+		{
+			name: "GITHUB_ENV is not visible in the same step",
+			jobs: `
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo "TITLE=${{ github.event.issue.title }}" >> "$GITHUB_ENV"`,
+			job:  "test",
+			step: 0,
+			expr: "env.TITLE",
+		},
+		{
+			name: "workflow and job env",
+			jobs: `
+  test:
+    runs-on: ubuntu-latest
+    env:
+      BODY: ${{ github.event.issue.body }}
+    steps:
+      - run: echo`,
+			job:  "test",
+			step: 0,
+			expr: "env.BODY",
+			want: `"env.body" (from issues event via "github.event.issue.body")`,
+		},
+		{
+			name: "env overridden by step env",
+			jobs: `
+  test:
+    runs-on: ubuntu-latest
+    env:
+      BODY: ${{ github.event.issue.body }}
+    steps:
+      - env:
+          BODY: fixed
+        run: echo`,
+			job:  "test",
+			step: 0,
+			expr: "env.BODY",
+		},
+		{
+			name: "job outputs through needs",
+			jobs: `
+  first:
+    runs-on: ubuntu-latest
+    outputs:
+      title: ${{ steps.meta.outputs.title }}
+    steps:
+      - id: meta
+        run: echo "title=${{ github.event.issue.title }}" >> "$GITHUB_OUTPUT"
+  second:
+    needs: first
+    runs-on: ubuntu-latest
+    outputs:
+      title: ${{ needs.first.outputs.title }}
+    steps:
+      - run: echo
+  third:
+    needs: [second]
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo`,
+			job:  "third",
+			step: 0,
+			expr: "needs.second.outputs.title",
+			want: `"needs.second.outputs.title" (from issues event via "github.event.issue.title")`,
+		},
+		{
+			name: "matrix values",
+			jobs: `
+  test:
+    runs-on: ubuntu-latest
+    strategy:
+      matrix:
+        os: [ubuntu-latest]
+        include:
+          - title: ${{ github.event.issue.title }}
+    steps:
+      - run: echo`,
+			job:  "test",
+			step: 0,
+			expr: "matrix.title",
+			want: `"matrix.title" (from issues event via "github.event.issue.title")`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, errs := Parse([]byte("on: issues\njobs:" + tt.jobs + "\n"))
+			if len(errs) > 0 {
+				t.Fatal(errs)
+			}
+			job := newWorkflowTaint(w, nil, nil).job(w.Jobs[tt.job])
+			scope := job.steps[tt.step]
+			paths := untrustedPathsIn(t, scope.roots, tt.expr)
+			if got := describeUntrustedInputs(scope, paths, w); got != tt.want {
+				t.Errorf("untrusted inputs in %q: got %q, want %q", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInjectionRules_Taint(t *testing.T) {
+	src := `on: pull_request_target
+jobs:
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      - id: meta
+        env:
+          TITLE: ${{ github.event.pull_request.title }}
+        run: echo "title=$TITLE" >> "$GITHUB_OUTPUT"
+      - run: echo "${{ steps.meta.outputs.title }}"
+      - run: echo "TITLE=${{ steps.meta.outputs.title }}" >> "$GITHUB_ENV"
+      - run: echo "${{ steps.meta.outputs.title }}" >> "$GITHUB_PATH"
+`
+	w, errs := Parse([]byte(src))
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	rules := []interface {
+		VisitWorkflowPre(*ast.Workflow) error
+		VisitJobPre(*ast.Job) error
+		Errors() []*LintingError
+	}{CodeInjectionCriticalRule(), EnvVarInjectionCriticalRule(), EnvPathInjectionCriticalRule()}
+	wants := []int{3, 1, 1}
+	for i, r := range rules {
+		if err := r.VisitWorkflowPre(w); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.VisitJobPre(w.Jobs["test"]); err != nil {
+			t.Fatal(err)
+		}
+		got := r.Errors()
+		if len(got) != wants[i] {
+			t.Errorf("rule #%d: got %d errors, want %d: %v", i, len(got), wants[i], got)
+			continue
+		}
+		for _, e := range got {
+			if !strings.Contains(e.Description, `"steps.meta.outputs.title" (from pull_request_target event via "github.event.pull_request.title")`) {
+				t.Errorf("rule #%d: unexpected error: %s", i, e.Description)
+			}
+		}
+	}
+}
@@ -1,12 +1,99 @@
 package core
 
 import (
+	"errors"
+	"fmt"
+	"strconv"
+
 	"github.com/sisaku-security/sisakulint/pkg/ast"
 	"gopkg.in/yaml.v3"
 )
 
+// defaultTimeoutMinutesはautofixで追加するtimeout-minutesの既定値
+const defaultTimeoutMinutes = 5
+
 type TimeoutMinutesRule struct {
 	BaseRule
+	// minMinutesとmaxMinutesは設定で許可されたtimeout-minutesの範囲。nilの場合は制限しない
+	minMinutes *float64
+	maxMinutes *float64
+	// defaultMinutesはautofixで追加するtimeout-minutesの値
+	defaultMinutes float64
+}
+
+// timeoutMinutesOptionsは設定ファイルのrules.missing-timeout-minutes.optionsの値
+type timeoutMinutesOptions struct {
+	Min     *float64 `yaml:"min"`
+	Max     *float64 `yaml:"max"`
+	Default *float64 `yaml:"default"`
+}
+
+// ConfigureOptionsは許可するtimeout-minutesの範囲とautofixで追加する値を設定する
+func (rule *TimeoutMinutesRule) ConfigureOptions(options *yaml.Node) error {
+	var opts timeoutMinutesOptions
+	if err := options.Decode(&opts); err != nil {
+		return err
+	}
+	if opts.Min != nil && *opts.Min <= 0 {
+		return errors.New("\"min\" must be greater than 0")
+	}
+	if opts.Min != nil && opts.Max != nil && *opts.Min > *opts.Max {
+		return fmt.Errorf("\"min\" (%g) must not be greater than \"max\" (%g)", *opts.Min, *opts.Max)
+	}
+	rule.minMinutes = opts.Min
+	rule.maxMinutes = opts.Max
+	if opts.Default != nil {
+		rule.defaultMinutes = *opts.Default
+	} else if !rule.inRange(rule.defaultMinutes) {
+		// 既定値が範囲外の場合は範囲の端に寄せる
+		if rule.minMinutes != nil && rule.defaultMinutes < *rule.minMinutes {
+			rule.defaultMinutes = *rule.minMinutes
+		} else if rule.maxMinutes != nil {
+			rule.defaultMinutes = *rule.maxMinutes
+		}
+	}
+	if !rule.inRange(rule.defaultMinutes) {
+		return fmt.Errorf("\"default\" (%g) must be in the allowed range %s", rule.defaultMinutes, rule.rangeString())
+	}
+	return nil
+}
+
+// inRangeはtimeout-minutesの値が設定で許可された範囲内かどうかを返す
+func (rule *TimeoutMinutesRule) inRange(v float64) bool {
+	if rule.minMinutes != nil && v < *rule.minMinutes {
+		return false
+	}
+	if rule.maxMinutes != nil && v > *rule.maxMinutes {
+		return false
+	}
+	return true
+}
+
+// rangeStringは許可された範囲をエラーメッセージ用の文字列にする
+func (rule *TimeoutMinutesRule) rangeString() string {
+	lo, hi := "0", "∞"
+	if rule.minMinutes != nil {
+		lo = strconv.FormatFloat(*rule.minMinutes, 'g', -1, 64)
+	}
+	if rule.maxMinutes != nil {
+		hi = strconv.FormatFloat(*rule.maxMinutes, 'g', -1, 64)
+	}
+	return "[" + lo + ", " + hi + "]"
+}
+
+// checkRangeはtimeout-minutesの値が設定された範囲外の場合にエラーを報告する
+// 式で指定された値は実行時まで分からないため検査しない
+func (rule *TimeoutMinutesRule) checkRange(timeout *ast.Float, kind, name string) {
+	if timeout.Expression != nil || rule.inRange(timeout.Value) {
+		return
+	}
+	pos := timeout.Pos
+	if pos == nil {
+		return
+	}
+	rule.Errorf(pos,
+		"timeout-minutes %g for %s %s is out of the range %s allowed by the configuration",
+		timeout.Value, kind, name, rule.rangeString())
 }
 
 func TimeoutMinuteRule() *TimeoutMinutesRule {
@@ -15,6 +102,7 @@ func TimeoutMinuteRule() *TimeoutMinutesRule {
 			RuleName: "missing-timeout-minutes",
 			RuleDesc: "This rule checks missing timeout-minutes in job level.",
 		},
+		defaultMinutes: defaultTimeoutMinutes,
 	}
 }
 
@@ -24,6 +112,8 @@ func (rule *TimeoutMinutesRule) VisitJobPre(node *ast.Job) error {
 			"timeout-minutes is not set for job %s; see https://docs.github.com/en/actions/using-workflows/workflow-syntax-for-github-actions#jobsjob_idtimeout-minutes for more details.",
 			node.ID.Value)
 		rule.AddAutoFixer(NewJobFixer(node, rule))
+	} else {
+		rule.checkRange(node.TimeoutMinutes, "job", node.ID.Value)
 	}
 	return nil
 }
@@ -34,11 +124,18 @@ func (rule *TimeoutMinutesRule) VisitStep(node *ast.Step) error {
 			"timeout-minutes is not set for step %s; see https://docs.github.com/en/actions/writing-workflows/workflow-syntax-for-github-actions#jobsjob_idstepstimeout-minutes for more details.",
 			node.String())
 		rule.AddAutoFixer(NewStepFixer(node, rule))
+	} else {
+		rule.checkRange(node.TimeoutMinutes, "step", node.String())
 	}
 	return nil
 }
 
 func addTimeoutMinutes(node *yaml.Node, candidate1, candidate2 string) {
+	addTimeoutMinutesValue(node, defaultTimeoutMinutes, candidate1, candidate2)
+}
+
+// addTimeoutMinutesValueは指定された値のtimeout-minutesをcandidateのキーの前に追加する
+func addTimeoutMinutesValue(node *yaml.Node, minutes float64, candidate1, candidate2 string) {
 	// best effort to add timeout-minutes before run or uses
 	appendKey := func(i int) {
 		node.Content = append(node.Content[:i], append([]*yaml.Node{
@@ -48,7 +145,7 @@ func addTimeoutMinutes(node *yaml.Node, candidate1, candidate2 string) {
 			},
 			{
 				Kind:  yaml.ScalarNode,
-				Value: "5",
+				Value: strconv.FormatFloat(minutes, 'g', -1, 64),
 			},
 		}, node.Content[i:]...)...)
 	}
@@ -63,11 +160,11 @@ func addTimeoutMinutes(node *yaml.Node, candidate1, candidate2 string) {
 }
 
 func (rule *TimeoutMinutesRule) FixStep(node *ast.Step) error {
-	addTimeoutMinutes(node.BaseNode, "run", "with")
+	addTimeoutMinutesValue(node.BaseNode, rule.defaultMinutes, "run", "with")
 	return nil
 }
 
 func (rule *TimeoutMinutesRule) FixJob(node *ast.Job) error {
-	addTimeoutMinutes(node.BaseNode, "steps", "runs-on")
+	addTimeoutMinutesValue(node.BaseNode, rule.defaultMinutes, "steps", "runs-on")
 	return nil
 }
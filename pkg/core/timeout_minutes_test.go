@@ -545,3 +545,70 @@ name: Test
 		})
 	}
 }
+
+func TestTimeoutMinutesRule_ConfigureOptions(t *testing.T) {
+	tests := []struct {
+		name        string
+		options     string
+		wantErr     bool
+		wantDefault float64
+		inRange     []float64
+		outOfRange  []float64
+	}{
+		{
+			name:        "range with default",
+			options:     "min: 1\nmax: 60\ndefault: 10",
+			wantDefault: 10,
+			inRange:     []float64{1, 30, 60},
+			outOfRange:  []float64{0.5, 61},
+		},
+		{
+			name:        "default is clamped into the range",
+			options:     "min: 10",
+			wantDefault: 10,
+			inRange:     []float64{10, 360},
+			outOfRange:  []float64{5},
+		},
+		{
+			name:        "only max",
+			options:     "max: 3",
+			wantDefault: 3,
+			inRange:     []float64{1, 3},
+			outOfRange:  []float64{4},
+		},
+		{name: "min greater than max", options: "min: 10\nmax: 5", wantErr: true},
+		{name: "default out of range", options: "max: 10\ndefault: 20", wantErr: true},
+		{name: "non positive min", options: "min: 0", wantErr: true},
+		{name: "invalid type", options: "min: foo", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var node yaml.Node
+			if err := yaml.Unmarshal([]byte(tt.options), &node); err != nil {
+				t.Fatal(err)
+			}
+			rule := TimeoutMinuteRule()
+			err := rule.ConfigureOptions(node.Content[0])
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ConfigureOptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if rule.defaultMinutes != tt.wantDefault {
+				t.Errorf("defaultMinutes = %v, want %v", rule.defaultMinutes, tt.wantDefault)
+			}
+			for _, v := range tt.inRange {
+				if !rule.inRange(v) {
+					t.Errorf("%v should be in range %s", v, rule.rangeString())
+				}
+			}
+			for _, v := range tt.outOfRange {
+				if rule.inRange(v) {
+					t.Errorf("%v should be out of range %s", v, rule.rangeString())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,476 @@
+package core
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/sisaku-security/sisakulint/pkg/ast"
+	"github.com/sisaku-security/sisakulint/pkg/expressions"
+	"gopkg.in/yaml.v3"
+)
+
+// TriggerTrustはワークフローを起動するトリガーの信頼度
+// セキュリティのルールはこの分類によって検査の対象や深刻度を決める
+type TriggerTrust int
+
+const (
+	// TriggerUnprivilegedは、ベースリポジトリのシークレットや書き込み権限を持たないか、外部のユーザーが起動できないトリガー
+	TriggerUnprivileged TriggerTrust = iota
+	// TriggerInheritedは、呼び出し元のワークフローの権限を引き継ぐトリガー(workflow_call)
+	TriggerInherited
+	// TriggerPrivilegedは、外部のユーザーが起動でき、ベースリポジトリのシークレットや書き込み権限を持つトリガー
+	TriggerPrivileged
+)
+
+func (t TriggerTrust) String() string {
+	switch t {
+	case TriggerInherited:
+		return "inherited"
+	case TriggerPrivileged:
+		return "privileged"
+	default:
+		return "unprivileged"
+	}
+}
+
+// defaultPrivilegedTriggersは、既定で特権トリガーとして扱うイベント
+// どれもベースリポジトリのコンテキストで実行され、イベントのペイロードには外部のユーザーが書いた内容が含まれる
+// labeledのようにメンテナしか起こせないアクティビティタイプでも、ペイロードのタイトルや本文は外部のユーザーが書いたものなので、既定ではtypes:で特権を下げない
+// * https://securitylab.github.com/resources/github-actions-preventing-pwn-requests/
+var defaultPrivilegedTriggers = map[string]struct{}{
+	"pull_request_target": {},
+	"workflow_run":        {},
+	"issue_comment":       {},
+	"issues":              {},
+	"discussion_comment":  {},
+}
+
+// forkPullRequestEventsは、フォークからのプルリクエストで起動されるイベント
+var forkPullRequestEvents = map[string]struct{}{
+	"pull_request":                {},
+	"pull_request_target":         {},
+	"pull_request_review":         {},
+	"pull_request_review_comment": {},
+}
+
+// externallyTriggerableEventsは、リポジトリへの書き込み権限を持たないユーザーが起動できるイベント
+// workflow_runのworkflows:に書かれたワークフローがこれらのイベントで起動される場合、workflow_runのワークフローは信頼できない入力を受け取る
+// workflow_runは起動元をたどれないので、安全側に倒して含める
+var externallyTriggerableEvents = map[string]struct{}{
+	"pull_request":                {},
+	"pull_request_target":         {},
+	"pull_request_review":         {},
+	"pull_request_review_comment": {},
+	"issue_comment":               {},
+	"issues":                      {},
+	"discussion":                  {},
+	"discussion_comment":          {},
+	"workflow_run":                {},
+}
+
+// TriggerTrustConfigは設定ファイルのtrigger-trustセクション
+// 既定の分類に対して、イベント(とアクティビティタイプ)を特権または非特権として追加で指定する
+type TriggerTrustConfig struct {
+	// Privilegedは特権トリガーとして扱うイベント。例えば組織の方針でworkflow_dispatchを特権として扱う場合に使う
+	Privileged []*TriggerTrustEntry `yaml:"privileged"`
+	// Unprivilegedは特権トリガーとして扱わないイベント。privilegedより優先される
+	Unprivileged []*TriggerTrustEntry `yaml:"unprivileged"`
+}
+
+// TriggerTrustEntryはtrigger-trustセクションの1つのイベント
+// 文字列だけを書いた場合はeventの省略形として扱う
+type TriggerTrustEntry struct {
+	// Eventはイベントの名前
+	Event string `yaml:"event"`
+	// Typesはアクティビティタイプ。空の場合はイベントの全てのアクティビティタイプに適用される
+	Types []string `yaml:"types"`
+	// Branchesはブランチのフィルタ。空でない場合は、branches:がこれらのブランチだけに絞られているイベントにだけ適用される
+	Branches []string `yaml:"branches"`
+}
+
+// UnmarshalYAMLはeventの省略形である文字列を受け付ける
+func (e *TriggerTrustEntry) UnmarshalYAML(n *yaml.Node) error {
+	if n.Kind == yaml.ScalarNode {
+		return n.Decode(&e.Event)
+	}
+	type plain TriggerTrustEntry
+	return n.Decode((*plain)(e))
+}
+
+// matchesは、エントリがイベントwのnameのアクティビティタイプtyに適用されるかを返す
+// tyが空の場合はイベントの全てのアクティビティタイプを表し、typesを持たないエントリだけがマッチする
+func (e *TriggerTrustEntry) matches(name, ty string, w *ast.WebhookEvent) bool {
+	if e.Event != name || !e.coversBranches(w) {
+		return false
+	}
+	if len(e.Types) == 0 {
+		return true
+	}
+	for _, t := range e.Types {
+		if t == ty {
+			return true
+		}
+	}
+	return false
+}
+
+// coversBranchesは、イベントwのbranches:がエントリのbranchesだけに絞られているかを返す。branchesを持たないエントリは全てのイベントに適用される
+// branches-ignore:は起動されるブランチを列挙できないので、エントリのbranchesにはマッチしない
+func (e *TriggerTrustEntry) coversBranches(w *ast.WebhookEvent) bool {
+	if len(e.Branches) == 0 {
+		return true
+	}
+	if w == nil || w.Branches == nil || len(w.Branches.Values) == 0 || w.BranchesIgnore != nil {
+		return false
+	}
+	for _, b := range w.Branches.Values {
+		if !slices.Contains(e.Branches, b.Value) {
+			return false
+		}
+	}
+	return true
+}
+
+// validateはイベントの名前を小文字に揃え、privilegedとunprivilegedの両方に書かれたイベントをエラーにする
+func (c *TriggerTrustConfig) validate() error {
+	for _, es := range [][]*TriggerTrustEntry{c.Privileged, c.Unprivileged} {
+		for _, e := range es {
+			if e == nil || e.Event == "" {
+				return fmt.Errorf("every entry in trigger-trust section must have an event name")
+			}
+			e.Event = strings.ToLower(e.Event)
+			for i, t := range e.Types {
+				e.Types[i] = strings.ToLower(t)
+			}
+		}
+	}
+	for _, p := range c.Privileged {
+		for _, u := range c.Unprivileged {
+			if p.Event == u.Event && overlapActivityTypes(p.Types, u.Types) && overlapActivityTypes(p.Branches, u.Branches) {
+				return fmt.Errorf("event %q is both privileged and unprivileged in trigger-trust section", p.Event)
+			}
+		}
+	}
+	return nil
+}
+
+// overlapActivityTypesは2つのアクティビティタイプ(またはブランチ)のリストが共通の値を持つかを返す。空のリストは全ての値を表す
+func overlapActivityTypes(a, b []string) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return true
+	}
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// TriggerTrustModelは、ワークフローのトリガーを特権、呼び出し元から継承、非特権に分類する
+// 全てのセキュリティのルールはこのモデルに問い合わせて、同じトリガーに同じ深刻度を付ける
+// 分類は次の順に決まる
+//   - 設定のunprivilegedにマッチするイベントは非特権
+//   - 設定のprivilegedにマッチするイベントは特権
+//   - workflow_callは呼び出し元から継承。ただしプロジェクトの中に特権トリガーで起動される呼び出し元がある場合は特権
+//   - defaultPrivilegedTriggersのイベントは特権。ただしworkflow_runは、workflows:の全てのワークフローがリポジトリにあり、
+//     外部のユーザーが起動できない場合は非特権
+//   - pull_request_targetは、全てのジョブがgithub.event.pull_request.mergedを条件にする場合は非特権。ジョブはtypes: [closed]の
+//     マージされたプルリクエストでしか実行されず、マージにはベースリポジトリへの書き込み権限が必要なので、外部のユーザーはジョブを実行できない
+//   - それ以外は非特権
+//
+// types:でアクティビティタイプが絞られている場合は、タイプごとに分類して最も高い信頼度を使う
+// branches:のようなブランチのフィルタは既定では信頼度を下げない。pull_request_targetのbranches:はベースブランチにマッチし、
+// workflow_runのbranches:は起動元のヘッドブランチにマッチするので、どちらもフォークからのプルリクエストを除外できない
+// 設定のエントリにbranchesを書くと、branches:がそれらのブランチだけに絞られているイベントにだけエントリが適用される
+type TriggerTrustModel struct {
+	config  *TriggerTrustConfig
+	project *Project
+	// pathは検査するワークフローのリポジトリのルートからの相対パス。空の場合は呼び出し元をたどらない
+	path string
+	// baseは呼び出し元のワークフローに適用する設定。overridesは呼び出し元のパスで解決する
+	base *Config
+	// workflowは検査するワークフロー。ジョブの条件でトリガーを分類するために使い、nilにできる
+	workflow *ast.Workflow
+
+	callersOnce sync.Once
+	callers     []*workflowCaller
+}
+
+// defaultTriggerTrustは設定とプロジェクトのない既定のモデル
+var defaultTriggerTrust = &TriggerTrustModel{}
+
+// NewTriggerTrustModelは新しいTriggerTrustModelインスタンスを作成する
+// configとprojectはnilにできる。projectはworkflow_runのworkflows:に書かれたワークフローを探すために使う
+func NewTriggerTrustModel(config *TriggerTrustConfig, project *Project) *TriggerTrustModel {
+	return &TriggerTrustModel{config: config, project: project}
+}
+
+// forWorkflowは、リポジトリのルートからの相対パスがpathのワークフローwを検査するためのモデルを返す
+// 再利用可能なワークフローのworkflow_callは、プロジェクトの中の呼び出し元のトリガーから分類される
+// baseは呼び出し元のワークフローに適用する設定で、baseとwはnilにできる
+func (m *TriggerTrustModel) forWorkflow(path string, base *Config, w *ast.Workflow) *TriggerTrustModel {
+	return &TriggerTrustModel{config: m.config, project: m.project, path: path, base: base, workflow: w}
+}
+
+// Classifyはイベントの信頼度を返す
+func (m *TriggerTrustModel) Classify(e ast.Event) TriggerTrust {
+	return m.classify(e, m.workflow, m.path, nil)
+}
+
+// classifyは、リポジトリのルートからの相対パスがpathのワークフローwfのイベントeの信頼度を返す
+// visitingは呼び出し元をたどっている途中のワークフローのパス
+func (m *TriggerTrustModel) classify(e ast.Event, wf *ast.Workflow, path string, visiting map[string]bool) TriggerTrust {
+	name := strings.ToLower(e.EventName())
+	w, ok := e.(*ast.WebhookEvent)
+	if !ok || len(w.Types) == 0 {
+		return m.classifyType(name, "", w, wf, path, visiting)
+	}
+	trust := TriggerUnprivileged
+	for _, t := range w.Types {
+		trust = max(trust, m.classifyType(name, strings.ToLower(t.Value), w, wf, path, visiting))
+	}
+	return trust
+}
+
+// classifyTypeはイベントnameのアクティビティタイプtyの信頼度を返す。tyが空の場合は全てのアクティビティタイプを表す
+func (m *TriggerTrustModel) classifyType(name, ty string, w *ast.WebhookEvent, wf *ast.Workflow, path string, visiting map[string]bool) TriggerTrust {
+	if m.config != nil {
+		for _, e := range m.config.Unprivileged {
+			if e.matches(name, ty, w) {
+				return TriggerUnprivileged
+			}
+		}
+		for _, e := range m.config.Privileged {
+			// アクティビティタイプを絞っていないイベントは、特権として指定されたタイプでも起動される
+			if e.Event == name && e.coversBranches(w) && (ty == "" || e.matches(name, ty, w)) {
+				return TriggerPrivileged
+			}
+		}
+	}
+	if name == "workflow_call" {
+		for _, c := range m.callersOf(path, visiting) {
+			if c.trust == TriggerPrivileged {
+				return TriggerPrivileged
+			}
+		}
+		return TriggerInherited
+	}
+	if _, ok := defaultPrivilegedTriggers[name]; !ok {
+		return TriggerUnprivileged
+	}
+	if name == "workflow_run" && w != nil && m.trustedWorkflowRun(w) {
+		return TriggerUnprivileged
+	}
+	// mergedはマージされたプルリクエストのclosedでしか真にならないので、他のアクティビティタイプではジョブが実行されない
+	if name == "pull_request_target" && mergedOnly(wf) {
+		return TriggerUnprivileged
+	}
+	return TriggerPrivileged
+}
+
+// mergedOnlyは、ワークフローの全てのジョブがプルリクエストがマージされた場合にだけ実行されるかを返す
+// if:を持たないジョブは、needs:のいずれかのジョブがマージされた場合にだけ実行されるなら、同じくスキップされる
+func mergedOnly(w *ast.Workflow) bool {
+	if w == nil || len(w.Jobs) == 0 {
+		return false
+	}
+	memo := map[string]bool{}
+	var gated func(id string, visiting map[string]bool) bool
+	gated = func(id string, visiting map[string]bool) bool {
+		if v, ok := memo[id]; ok {
+			return v
+		}
+		j := w.Jobs[id]
+		if j == nil || visiting[id] {
+			return false
+		}
+		visiting[id] = true
+		defer delete(visiting, id)
+		v := false
+		if j.If != nil && strings.TrimSpace(j.If.Value) != "" {
+			v = requiresMerged(j.If.Value)
+		} else {
+			for _, n := range j.Needs {
+				if n != nil && gated(strings.ToLower(n.Value), visiting) {
+					v = true
+					break
+				}
+			}
+		}
+		memo[id] = v
+		return v
+	}
+	for id := range w.Jobs {
+		if !gated(id, map[string]bool{}) {
+			return false
+		}
+	}
+	return true
+}
+
+// requiresMergedは、if:の条件condがgithub.event.pull_request.mergedが真であることを&&で要求するかを返す
+func requiresMerged(cond string) bool {
+	src := strings.TrimSpace(cond)
+	if strings.HasPrefix(src, "${{") && strings.HasSuffix(src, "}}") {
+		src = src[len("${{") : len(src)-len("}}")]
+	}
+	if strings.Contains(src, "${{") {
+		// ${{ }}の外に文字列がある条件は常に真になる
+		return false
+	}
+	node, err := expressions.NewMiniParser().Parse(expressions.NewTokenizer(src + "}}"))
+	if err != nil {
+		return false
+	}
+	return requiresTrue(node, "github.event.pull_request.merged")
+}
+
+// requiresTrueは、式nが真になるためにプロパティpathが真である必要があるかを返す
+func requiresTrue(n expressions.ExprNode, path string) bool {
+	switch n := n.(type) {
+	case *expressions.LogicalOpNode:
+		return n.Kind == expressions.LogicalOpNodeKindAnd && (requiresTrue(n.Left, path) || requiresTrue(n.Right, path))
+	case *expressions.CompareOpNode:
+		if n.Kind != expressions.CompareOpNodeKindEq {
+			return false
+		}
+		return propertyPath(n.Left) == path && isBoolLiteral(n.Right, true) ||
+			propertyPath(n.Right) == path && isBoolLiteral(n.Left, true)
+	default:
+		return propertyPath(n) == path
+	}
+}
+
+// propertyPathは、github.event.pull_requestのようなプロパティの参照を小文字のパスで返す。それ以外の式は空文字列を返す
+func propertyPath(n expressions.ExprNode) string {
+	switch n := n.(type) {
+	case *expressions.VariableNode:
+		return strings.ToLower(n.Name)
+	case *expressions.ObjectDerefNode:
+		if r := propertyPath(n.Receiver); r != "" {
+			return r + "." + strings.ToLower(n.Property)
+		}
+	}
+	return ""
+}
+
+func isBoolLiteral(n expressions.ExprNode, v bool) bool {
+	b, ok := n.(*expressions.BoolNode)
+	return ok && b.Value == v
+}
+
+// ForkTriggersは、ワークフローのトリガーのうちフォークからのプルリクエストで起動できるものを返す
+// workflow_callは、プロジェクトの中にフォークからのプルリクエストで起動される呼び出し元がある場合に含める
+// 設定のunprivilegedは外部のユーザーが起動できないイベントも表すので、マッチするアクティビティタイプでしか起動されないイベントは含めない
+func (m *TriggerTrustModel) ForkTriggers(w *ast.Workflow) []ast.Event {
+	if w == nil {
+		return nil
+	}
+	var events []ast.Event
+	for _, e := range w.On {
+		if m.forkTriggered(e, m.path, nil) {
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+// forkTriggeredは、リポジトリのルートからの相対パスがpathのワークフローのイベントeが、フォークからのプルリクエストで起動できるかを返す
+func (m *TriggerTrustModel) forkTriggered(e ast.Event, path string, visiting map[string]bool) bool {
+	name := strings.ToLower(e.EventName())
+	if name == "workflow_call" {
+		for _, c := range m.callersOf(path, visiting) {
+			if c.fork {
+				return true
+			}
+		}
+		return false
+	}
+	if _, ok := forkPullRequestEvents[name]; !ok {
+		return false
+	}
+	if m.config == nil {
+		return true
+	}
+	types := []string{""}
+	w, _ := e.(*ast.WebhookEvent)
+	if w != nil && len(w.Types) > 0 {
+		types = types[:0]
+		for _, t := range w.Types {
+			types = append(types, strings.ToLower(t.Value))
+		}
+	}
+	for _, ty := range types {
+		trusted := false
+		for _, u := range m.config.Unprivileged {
+			if u.matches(name, ty, w) {
+				trusted = true
+				break
+			}
+		}
+		if !trusted {
+			return true
+		}
+	}
+	return false
+}
+
+// trustedWorkflowRunは、workflow_runのworkflows:の全てのワークフローがリポジトリにあり、
+// どれも外部のユーザーが起動できない場合にtrueを返す
+func (m *TriggerTrustModel) trustedWorkflowRun(w *ast.WebhookEvent) bool {
+	if len(w.Workflows) == 0 || m.project == nil {
+		return false
+	}
+	for _, name := range w.Workflows {
+		events, ok := m.project.workflowEvents(name.Value)
+		if !ok {
+			return false
+		}
+		for _, e := range events {
+			if _, ok := externallyTriggerableEvents[strings.ToLower(e.EventName())]; ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// IsPrivilegedはワークフローが特権トリガーを持つかどうかを返す
+func (m *TriggerTrustModel) IsPrivileged(w *ast.Workflow) bool {
+	return m.FirstPrivileged(w) != nil
+}
+
+// FirstPrivilegedはワークフローの最初の特権トリガーを返す。特権トリガーがない場合はnilを返す
+func (m *TriggerTrustModel) FirstPrivileged(w *ast.Workflow) ast.Event {
+	if w == nil {
+		return nil
+	}
+	for _, e := range w.On {
+		if m.classify(e, w, m.path, nil) == TriggerPrivileged {
+			return e
+		}
+	}
+	return nil
+}
+
+// EventPositionはイベントのソース上の位置を返す
+func EventPosition(e ast.Event) *ast.Position {
+	switch e := e.(type) {
+	case *ast.WebhookEvent:
+		return e.Pos
+	case *ast.ScheduledEvent:
+		return e.Pos
+	case *ast.WorkflowDispatchEvent:
+		return e.Pos
+	case *ast.RepositoryDispatchEvent:
+		return e.Pos
+	case *ast.WorkflowCallEvent:
+		return e.Pos
+	}
+	return nil
+}
@@ -0,0 +1,363 @@
+package core
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTriggerTrustModel_Classify(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		on     string
+		want   []TriggerTrust
+	}{
+		{
+			name: "default",
+			on:   "[push, pull_request, pull_request_target, issue_comment, issues, discussion_comment, workflow_run, workflow_call, workflow_dispatch]",
+			want: []TriggerTrust{TriggerUnprivileged, TriggerUnprivileged, TriggerPrivileged, TriggerPrivileged, TriggerPrivileged, TriggerPrivileged, TriggerPrivileged, TriggerInherited, TriggerUnprivileged},
+		},
+		{
+			name: "activity types do not lower trust by default",
+			on:   "{issues: {types: [labeled]}, pull_request_target: {types: [closed]}}",
+			want: []TriggerTrust{TriggerPrivileged, TriggerPrivileged},
+		},
+		{
+			name: "branch filters do not lower trust",
+			on:   "{pull_request_target: {branches: [main]}, workflow_run: {workflows: [CI], branches: [main]}}",
+			want: []TriggerTrust{TriggerPrivileged, TriggerPrivileged},
+		},
+		{
+			name:   "branch filter covered by config",
+			config: "unprivileged: [{event: pull_request_target, branches: [main, release]}]",
+			on:     "{pull_request_target: {branches: [main]}}",
+			want:   []TriggerTrust{TriggerUnprivileged},
+		},
+		{
+			name:   "branch filter not covered by config",
+			config: "unprivileged: [{event: pull_request_target, branches: [main]}]",
+			on:     "{pull_request_target: {branches: [main, dev]}}",
+			want:   []TriggerTrust{TriggerPrivileged},
+		},
+		{
+			name:   "config with branches does not match event without branch filter",
+			config: "unprivileged: [{event: workflow_run, branches: [main]}]",
+			on:     "{workflow_run: {workflows: [CI], branches-ignore: [dev]}, pull_request_target: null}",
+			want:   []TriggerTrust{TriggerPrivileged, TriggerPrivileged},
+		},
+		{
+			name:   "privileged by config on branches",
+			config: "privileged: [{event: push, branches: [main]}]",
+			on:     "{push: {branches: [main]}, pull_request: {branches: [main]}}",
+			want:   []TriggerTrust{TriggerPrivileged, TriggerUnprivileged},
+		},
+		{
+			name:   "extended by config",
+			config: "privileged: [workflow_dispatch, {event: pull_request, types: [labeled]}]",
+			on:     "{workflow_dispatch: null, pull_request: {types: [opened, labeled]}, push: null}",
+			want:   []TriggerTrust{TriggerPrivileged, TriggerPrivileged, TriggerUnprivileged},
+		},
+		{
+			name:   "privileged activity type does not match other types",
+			config: "privileged: [{event: pull_request, types: [labeled]}]",
+			on:     "{pull_request: {types: [opened]}}",
+			want:   []TriggerTrust{TriggerUnprivileged},
+		},
+		{
+			name:   "privileged activity type matches event without types",
+			config: "privileged: [{event: pull_request, types: [labeled]}]",
+			on:     "[pull_request]",
+			want:   []TriggerTrust{TriggerPrivileged},
+		},
+		{
+			name:   "overridden by config",
+			config: "unprivileged: [issue_comment]",
+			on:     "[issue_comment, issues]",
+			want:   []TriggerTrust{TriggerUnprivileged, TriggerPrivileged},
+		},
+		{
+			name:   "unprivileged activity types",
+			config: "unprivileged: [{event: issues, types: [labeled, assigned]}]",
+			on:     "{issues: {types: [labeled, assigned]}, issue_comment: null}",
+			want:   []TriggerTrust{TriggerUnprivileged, TriggerPrivileged},
+		},
+		{
+			name:   "unprivileged activity types do not cover other types",
+			config: "unprivileged: [{event: issues, types: [labeled]}]",
+			on:     "{issues: {types: [labeled, opened]}}",
+			want:   []TriggerTrust{TriggerPrivileged},
+		},
+		{
+			name:   "unprivileged activity types do not cover event without types",
+			config: "unprivileged: [{event: issues, types: [labeled]}]",
+			on:     "[issues]",
+			want:   []TriggerTrust{TriggerPrivileged},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			model := defaultTriggerTrust
+			if tt.config != "" {
+				c, err := parseConfig([]byte("trigger-trust: {"+tt.config+"}"), "sisakulint.yaml")
+				if err != nil {
+					t.Fatal(err)
+				}
+				model = NewTriggerTrustModel(&c.TriggerTrust, nil)
+			}
+			w, errs := Parse([]byte("on: " + tt.on + "\njobs:\n  test:\n    runs-on: ubuntu-latest\n    steps:\n      - run: echo\n"))
+			if len(errs) > 0 {
+				t.Fatal(errs)
+			}
+			if len(w.On) != len(tt.want) {
+				t.Fatalf("got %d events, want %d", len(w.On), len(tt.want))
+			}
+			for i, e := range w.On {
+				if got := model.Classify(e); got != tt.want[i] {
+					t.Errorf("%s is %s, want %s", e.EventName(), got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestTriggerTrustModel_MergedPullRequest(t *testing.T) {
+	tests := []struct {
+		name string
+		on   string
+		jobs string
+		want TriggerTrust
+	}{
+		{
+			name: "closed and merged",
+			on:   "{pull_request_target: {types: [closed]}}",
+			jobs: "  release:\n    if: github.event.pull_request.merged == true\n",
+			want: TriggerUnprivileged,
+		},
+		{
+			name: "merged without types",
+			on:   "pull_request_target",
+			jobs: "  release:\n    if: ${{ github.event.pull_request.merged && github.base_ref == 'main' }}\n",
+			want: TriggerUnprivileged,
+		},
+		{
+			name: "job needing merged job",
+			on:   "{pull_request_target: {types: [closed]}}",
+			jobs: "  build:\n    if: github.event.pull_request.merged\n  publish:\n    needs: [build]\n",
+			want: TriggerUnprivileged,
+		},
+		{
+			name: "closed without merged check",
+			on:   "{pull_request_target: {types: [closed]}}",
+			jobs: "  release:\n    if: github.event.action == 'closed'\n",
+			want: TriggerPrivileged,
+		},
+		{
+			name: "merged check in disjunction",
+			on:   "{pull_request_target: {types: [closed]}}",
+			jobs: "  release:\n    if: github.event.pull_request.merged || github.event.pull_request.draft\n",
+			want: TriggerPrivileged,
+		},
+		{
+			name: "not merged",
+			on:   "{pull_request_target: {types: [closed]}}",
+			jobs: "  cleanup:\n    if: github.event.pull_request.merged == false\n",
+			want: TriggerPrivileged,
+		},
+		{
+			name: "job without merged check",
+			on:   "{pull_request_target: {types: [closed]}}",
+			jobs: "  release:\n    if: github.event.pull_request.merged\n  label:\n",
+			want: TriggerPrivileged,
+		},
+		{
+			name: "always() runs after skipped job",
+			on:   "{pull_request_target: {types: [closed]}}",
+			jobs: "  build:\n    if: github.event.pull_request.merged\n  notify:\n    needs: [build]\n    if: always()\n",
+			want: TriggerPrivileged,
+		},
+		{
+			name: "merged check is not applied to other events",
+			on:   "issue_comment",
+			jobs: "  release:\n    if: github.event.pull_request.merged\n",
+			want: TriggerPrivileged,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b strings.Builder
+			b.WriteString("on: " + tt.on + "\njobs:\n")
+			for _, l := range strings.SplitAfter(tt.jobs, "\n") {
+				b.WriteString(l)
+				if !strings.HasPrefix(l, "   ") && strings.HasSuffix(l, ":\n") {
+					b.WriteString("    runs-on: ubuntu-latest\n    steps: [{run: echo}]\n")
+				}
+			}
+			w, errs := Parse([]byte(b.String()))
+			if len(errs) > 0 {
+				t.Fatal(errs)
+			}
+			model := NewTriggerTrustModel(nil, nil).forWorkflow("", nil, w)
+			if got := model.Classify(w.On[0]); got != tt.want {
+				t.Errorf("%s is %s, want %s", w.On[0].EventName(), got, tt.want)
+			}
+			if got := defaultTriggerTrust.Classify(w.On[0]); got != TriggerPrivileged {
+				t.Errorf("%s without workflow is %s, want privileged", w.On[0].EventName(), got)
+			}
+		})
+	}
+}
+
+func TestTriggerTrustModel_WorkflowRun(t *testing.T) {
+	root := t.TempDir()
+	workflows := filepath.Join(root, ".github", "workflows")
+	if err := os.MkdirAll(workflows, 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"build.yml":   "name: Build\non:\n  push:\n    branches: [main]\njobs: {}\n",
+		"nightly.yml": "on:\n  schedule:\n    - cron: '0 0 * * *'\njobs: {}\n",
+		"pr.yml":      "name: PR\non: [push, pull_request]\njobs: {}\n",
+		// 同じ名前のワークフローのどちらかが外部からトリガーできれば、名前全体を信頼しない
+		"ci-a.yml": "name: CI\non: pull_request\njobs: {}\n",
+		"ci-b.yml": "name: CI\non: push\njobs: {}\n",
+	}
+	for name, src := range files {
+		if err := os.WriteFile(filepath.Join(workflows, name), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	project, err := NewProject(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	model := NewTriggerTrustModel(nil, project)
+
+	tests := []struct {
+		workflows string
+		want      TriggerTrust
+	}{
+		{"[Build]", TriggerUnprivileged},
+		{"[Build, .github/workflows/nightly.yml]", TriggerUnprivileged},
+		{"[Build, PR]", TriggerPrivileged},
+		{"[CI]", TriggerPrivileged},
+		{"[Unknown]", TriggerPrivileged},
+		{"[]", TriggerPrivileged},
+	}
+	for _, tt := range tests {
+		w, errs := Parse([]byte("on:\n  workflow_run:\n    workflows: " + tt.workflows + "\n    types: [completed]\njobs: {}\n"))
+		if w == nil {
+			t.Fatal(errs)
+		}
+		if got := model.Classify(w.On[0]); got != tt.want {
+			t.Errorf("workflow_run of %s is %s, want %s", tt.workflows, got, tt.want)
+		}
+		// プロジェクトがない場合はworkflows:をたどれないので特権として扱う
+		if got := defaultTriggerTrust.Classify(w.On[0]); got != TriggerPrivileged {
+			t.Errorf("workflow_run of %s without project is %s", tt.workflows, got)
+		}
+	}
+}
+
+func TestLinter_TriggerTrustConfig(t *testing.T) {
+	root := t.TempDir()
+	workflows := filepath.Join(root, ".github", "workflows")
+	if err := os.MkdirAll(workflows, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(root, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	workflow := `on:
+  workflow_dispatch:
+  issues:
+    types: [labeled]
+jobs:
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo "${{ github.event.issue.title }}"
+`
+	file := filepath.Join(workflows, "test.yml")
+	if err := os.WriteFile(file, []byte(workflow), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		config string
+		want   string
+	}{
+		{"default", "", "code-injection-critical"},
+		{"unprivileged activity type", "trigger-trust:\n  unprivileged:\n    - event: issues\n      types: [labeled]\n", "code-injection-medium"},
+		{"privileged workflow_dispatch", "trigger-trust:\n  privileged: [workflow_dispatch]\n  unprivileged: [issues]\n", "code-injection-critical"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := os.WriteFile(filepath.Join(root, ".github", "sisakulint.yaml"), []byte(tt.config), 0644); err != nil {
+				t.Fatal(err)
+			}
+			project, err := NewProject(root)
+			if err != nil {
+				t.Fatal(err)
+			}
+			l, err := NewLinter(io.Discard, &LinterOptions{CurrentWorkingDirectoryPath: root})
+			if err != nil {
+				t.Fatal(err)
+			}
+			results, err := l.LintFiles([]string{file}, project)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, e := range results[0].Errors {
+				if e.Type == "code-injection-critical" || e.Type == "code-injection-medium" {
+					got = append(got, e.Type)
+				}
+			}
+			if len(got) != 1 || got[0] != tt.want {
+				t.Errorf("got %v, want [%s]", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLinter_MergedPullRequestTarget(t *testing.T) {
+	root := writeProject(t, map[string]string{
+		"release.yml": `on:
+  pull_request_target:
+    types: [closed]
+jobs:
+  release:
+    if: github.event.pull_request.merged == true
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo "${{ github.event.pull_request.title }}"
+`,
+	})
+	project, err := NewProject(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := NewLinter(io.Discard, &LinterOptions{CurrentWorkingDirectoryPath: root})
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := l.LintFiles([]string{filepath.Join(root, ".github", "workflows", "release.yml")}, project)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range results[0].Errors {
+		if strings.HasPrefix(e.Type, "code-injection-") {
+			got = append(got, e.Type)
+		}
+	}
+	if len(got) != 1 || got[0] != "code-injection-medium" {
+		t.Errorf("got %v, want [code-injection-medium]", got)
+	}
+}
@@ -0,0 +1,267 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// diffContextLinesはunified diffの各変更の前後に出力する行数
+const diffContextLines = 3
+
+// lineChangeは元のソースのoldStart行目(0から始まる)からoldLen行をnewLinesで置き換える変更
+type lineChange struct {
+	oldStart int
+	oldLen   int
+	newLines []string
+	rules    []string
+}
+
+// diffHunkはunified diffの1つのハンク
+type diffHunk struct {
+	oldStart, oldLen int
+	newStart, newLen int
+	lines            []string
+	rules            []string
+}
+
+// lineChangesは編集を行単位の変更に変換する
+// 同じ行や隣接する行を変更する編集はまとめて適用してから、変更前後の行の差分を求める
+func lineChanges(source []byte, edits []*TextEdit) []*lineChange {
+	src := newSourceText(source)
+	lineIndex := func(off int) int {
+		return sort.Search(len(src.lines), func(i int) bool { return src.lines[i] > off }) - 1
+	}
+	lineOffset := func(idx int) int {
+		if idx >= len(src.lines) {
+			return len(source)
+		}
+		return src.lines[idx]
+	}
+
+	sorted := make([]*TextEdit, len(edits))
+	copy(sorted, edits)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Start < sorted[j].Start })
+
+	var changes []*lineChange
+	for i := 0; i < len(sorted); {
+		// 編集を含む行の範囲[first, last)を求め、範囲が重なるか隣接する編集をまとめる
+		first := lineIndex(sorted[i].Start)
+		last := first
+		j := i
+		for ; j < len(sorted); j++ {
+			e := sorted[j]
+			if lineIndex(e.Start) > last {
+				break
+			}
+			end := lineIndex(e.End)
+			if e.End > lineOffset(end) {
+				end++
+			}
+			last = max(last, end)
+		}
+
+		start := lineOffset(first)
+		var b strings.Builder
+		prev := start
+		for _, e := range sorted[i:j] {
+			b.Write(source[prev:e.Start])
+			b.WriteString(e.NewText)
+			prev = e.End
+		}
+		b.Write(source[prev:lineOffset(last)])
+
+		for _, c := range diffLines(splitLines(source[start:lineOffset(last)]), splitLines([]byte(b.String()))) {
+			c.oldStart += first
+			// 変更された行に重なる編集のルールを変更に関連付ける
+			for _, e := range sorted[i:j] {
+				from, to := lineIndex(e.Start), lineIndex(e.End)
+				if e.End > lineOffset(to) {
+					to++
+				}
+				if from <= c.oldStart+c.oldLen && c.oldStart <= to {
+					c.rules = appendRule(c.rules, e.RuleName)
+				}
+			}
+			changes = append(changes, c)
+		}
+		i = j
+	}
+	return changes
+}
+
+// diffLinesは最長共通部分列を使って2つの行のスライスの差分を求める
+func diffLines(a, b []string) []*lineChange {
+	// lcs[i][j]はa[i:]とb[j:]の最長共通部分列の長さ
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var changes []*lineChange
+	var cur *lineChange
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		if i < len(a) && j < len(b) && a[i] == b[j] {
+			cur = nil
+			i++
+			j++
+			continue
+		}
+		if cur == nil {
+			cur = &lineChange{oldStart: i}
+			changes = append(changes, cur)
+		}
+		if j < len(b) && (i == len(a) || lcs[i][j+1] >= lcs[i+1][j]) {
+			cur.newLines = append(cur.newLines, b[j])
+			j++
+		} else {
+			cur.oldLen++
+			i++
+		}
+	}
+	return changes
+}
+
+func appendRule(rules []string, rule string) []string {
+	for _, r := range rules {
+		if r == rule {
+			return rules
+		}
+	}
+	return append(rules, rule)
+}
+
+// diffHunksは行単位の変更を前後の行を含むハンクにまとめる
+// 前後の行が重なる変更は1つのハンクにまとめる
+func diffHunks(source []byte, changes []*lineChange) []*diffHunk {
+	lines := splitLines(source)
+	var hunks []*diffHunk
+	delta := 0
+	for i := 0; i < len(changes); {
+		j := i + 1
+		for j < len(changes) && changes[j].oldStart-(changes[j-1].oldStart+changes[j-1].oldLen) <= 2*diffContextLines {
+			j++
+		}
+		first, last := changes[i], changes[j-1]
+		start := max(0, first.oldStart-diffContextLines)
+		end := min(len(lines), last.oldStart+last.oldLen+diffContextLines)
+
+		h := &diffHunk{oldStart: start, newStart: start + delta}
+		pos := start
+		for _, c := range changes[i:j] {
+			for ; pos < c.oldStart; pos++ {
+				h.lines = append(h.lines, " "+lines[pos])
+			}
+			for k := 0; k < c.oldLen; k++ {
+				h.lines = append(h.lines, "-"+lines[pos+k])
+			}
+			pos += c.oldLen
+			for _, l := range c.newLines {
+				h.lines = append(h.lines, "+"+l)
+			}
+			delta += len(c.newLines) - c.oldLen
+			h.newLen += len(c.newLines) - c.oldLen
+			for _, r := range c.rules {
+				h.rules = appendRule(h.rules, r)
+			}
+		}
+		for ; pos < end; pos++ {
+			h.lines = append(h.lines, " "+lines[pos])
+		}
+		h.oldLen = end - start
+		h.newLen += h.oldLen
+		hunks = append(hunks, h)
+		i = j
+	}
+	return hunks
+}
+
+// hunkRangeはハンクのヘッダーの範囲を"開始行,行数"の形式で返す
+// 行数が0の場合、開始行は変更の直前の行を指す
+func hunkRange(start, length int) string {
+	if length == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	return fmt.Sprintf("%d,%d", start+1, length)
+}
+
+func writeHunk(w io.Writer, h *diffHunk) error {
+	if _, err := fmt.Fprintf(w, "@@ -%s +%s @@ %s\n", hunkRange(h.oldStart, h.oldLen), hunkRange(h.newStart, h.newLen), strings.Join(h.rules, ", ")); err != nil {
+		return err
+	}
+	for _, l := range h.lines {
+		if !strings.HasSuffix(l, "\n") {
+			l += "\n\\ No newline at end of file\n"
+		}
+		if _, err := io.WriteString(w, l); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeDiffHeader(w io.Writer, path string) error {
+	path = strings.TrimPrefix(path, "./")
+	_, err := fmt.Fprintf(w, "diff --git a/%s b/%s\n--- a/%s\n+++ b/%s\n", path, path, path, path)
+	return err
+}
+
+// WriteUnifiedDiffは元のソースに編集を適用した結果をgit applyで適用できるunified diffとして書き込む
+// 各ハンクのヘッダーには、そのハンクの変更を生成したルールの名前を出力する
+// 変更がない場合は何も書き込まない
+func WriteUnifiedDiff(w io.Writer, path string, source []byte, edits []*TextEdit) error {
+	hunks := diffHunks(source, lineChanges(source, edits))
+	if len(hunks) == 0 {
+		return nil
+	}
+	if err := writeDiffHeader(w, path); err != nil {
+		return err
+	}
+	for _, h := range hunks {
+		if err := writeHunk(w, h); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteUnifiedDiffByRuleは編集をルールごとにまとめたunified diffを書き込む
+// 各ルールのハンクはそのルールの編集だけを元のソースに適用した差分なので、全体をgit applyで適用することはできない
+func WriteUnifiedDiffByRule(w io.Writer, path string, source []byte, edits []*TextEdit) error {
+	var rules []string
+	byRule := map[string][]*TextEdit{}
+	for _, e := range edits {
+		rules = appendRule(rules, e.RuleName)
+		byRule[e.RuleName] = append(byRule[e.RuleName], e)
+	}
+	header := false
+	for _, rule := range rules {
+		hunks := diffHunks(source, lineChanges(source, byRule[rule]))
+		if len(hunks) == 0 {
+			continue
+		}
+		if !header {
+			if err := writeDiffHeader(w, path); err != nil {
+				return err
+			}
+			header = true
+		}
+		for _, h := range hunks {
+			if err := writeHunk(w, h); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package core
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteUnifiedDiff(t *testing.T) {
+	src := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm"
+	edits := []*TextEdit{
+		// bの次の行への挿入
+		{Start: 4, End: 4, NewText: "b2\n", RuleName: "r1"},
+		// dの置換
+		{Start: 6, End: 7, NewText: "D", RuleName: "r2"},
+		// 最後の行の置換(改行がない)
+		{Start: 24, End: 25, NewText: "M\n", RuleName: "r1"},
+	}
+
+	tests := []struct {
+		name  string
+		write func(b *bytes.Buffer) error
+		want  string
+	}{
+		{
+			name: "combined",
+			write: func(b *bytes.Buffer) error {
+				return WriteUnifiedDiff(b, "./w.yml", []byte(src), edits)
+			},
+			want: `diff --git a/w.yml b/w.yml
+--- a/w.yml
++++ b/w.yml
+@@ -1,7 +1,8 @@ r1, r2
+ a
+ b
++b2
+ c
+-d
++D
+ e
+ f
+ g
+@@ -10,4 +11,4 @@ r1
+ j
+ k
+ l
+-m
+\ No newline at end of file
++M
+`,
+		},
+		{
+			name: "by rule",
+			write: func(b *bytes.Buffer) error {
+				return WriteUnifiedDiffByRule(b, "w.yml", []byte(src), edits)
+			},
+			want: `diff --git a/w.yml b/w.yml
+--- a/w.yml
++++ b/w.yml
+@@ -1,5 +1,6 @@ r1
+ a
+ b
++b2
+ c
+ d
+ e
+@@ -10,4 +11,4 @@ r1
+ j
+ k
+ l
+-m
+\ No newline at end of file
++M
+@@ -1,7 +1,7 @@ r2
+ a
+ b
+ c
+-d
++D
+ e
+ f
+ g
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b bytes.Buffer
+			if err := tt.write(&b); err != nil {
+				t.Fatal(err)
+			}
+			if got := b.String(); got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteUnifiedDiff_NoChange(t *testing.T) {
+	var b bytes.Buffer
+	src := []byte("a: 1\n")
+	if err := WriteUnifiedDiff(&b, "w.yml", src, []*TextEdit{{Start: 3, End: 4, NewText: "1", RuleName: "r"}}); err != nil {
+		t.Fatal(err)
+	}
+	if b.Len() != 0 {
+		t.Errorf("diff is written for edits without change: %q", b.String())
+	}
+}
+
+func TestCommand_FixOutputPatch(t *testing.T) {
+	root := t.TempDir()
+	workflows := filepath.Join(root, ".github", "workflows")
+	if err := os.MkdirAll(workflows, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(root, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	workflow := "on: push\npermissions: {}\njobs:\n  build:\n    runs-on: ubuntu-latest  # runner\n    timeout-minutes: 5\n    steps:\n      - run: echo hi\n"
+	path := filepath.Join(workflows, "ci.yml")
+	if err := os.WriteFile(path, []byte(workflow), 0644); err != nil {
+		t.Fatal(err)
+	}
+	patchFile := filepath.Join(root, "fix.patch")
+
+	var stdout, stderr bytes.Buffer
+	cmd := &Command{Stdout: &stdout, Stderr: &stderr}
+	cmd.Main([]string{"sisakulint", "-fix", "on", "-fix-output", "patch", "-fix-patch-file", patchFile, path})
+
+	if data, err := os.ReadFile(path); err != nil || string(data) != workflow {
+		t.Errorf("workflow file is modified in patch mode: %q, %v", data, err)
+	}
+	patch, err := os.ReadFile(patchFile)
+	if err != nil {
+		t.Fatalf("patch file is not written: %v\nstderr: %s", err, stderr.String())
+	}
+	want := "diff --git a/.github/workflows/ci.yml b/.github/workflows/ci.yml\n--- a/.github/workflows/ci.yml\n+++ b/.github/workflows/ci.yml\n" +
+		"@@ -5,4 +5,5 @@ missing-timeout-minutes\n     runs-on: ubuntu-latest  # runner\n     timeout-minutes: 5\n     steps:\n-      - run: echo hi\n+      - timeout-minutes: 5\n+        run: echo hi\n"
+	if string(patch) != want {
+		t.Errorf("got patch:\n%s\nwant:\n%s", patch, want)
+	}
+
+	if code := cmd.Main([]string{"sisakulint", "-fix", "dry-run", "-fix-output", "patch", path}); code != ExitStatusInvalidCommandOption {
+		t.Errorf("-fix-output patch with -fix dry-run should be rejected, got exit status %d", code)
+	}
+}
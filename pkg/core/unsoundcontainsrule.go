@@ -139,14 +139,16 @@ func (rule *UnsoundContainsRule) visitExprNode(node expressions.ExprNode, pos *a
 		isHighSeverity := rule.isUserControllableContext(userControlledContext)
 
 		var severity string
+		var level Severity
 		if isHighSeverity {
-			severity = "HIGH"
+			severity, level = "HIGH", SeverityHigh
 		} else {
-			severity = "INFORMATIONAL"
+			severity, level = "INFORMATIONAL", SeverityInfo
 		}
 
-		rule.Errorf(
+		rule.ErrorfWithSeverity(
 			pos,
+			level,
 			"[%s] Unsound use of contains() in %s condition. The first argument '%s' is a string literal and the second argument '%s' is user-controllable. An attacker could create a branch named '%s' to bypass this condition. Use fromJSON() with an array instead: contains(fromJSON('%s'), %s)",
 			severity,
 			context,
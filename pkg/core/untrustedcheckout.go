@@ -66,33 +66,17 @@ func (rule *UntrustedCheckoutRule) VisitWorkflowPre(n *ast.Workflow) error {
 
 	// Check all workflow triggers
 	for _, event := range n.On {
-		var triggerName string
-		var triggerPos *ast.Position
-
-		// Check for WebhookEvent (pull_request_target, issue_comment, workflow_run)
-		if webhookEvent, ok := event.(*ast.WebhookEvent); ok {
-			triggerName = webhookEvent.EventName()
-			triggerPos = webhookEvent.Pos
-		} else if workflowCallEvent, ok := event.(*ast.WorkflowCallEvent); ok {
-			// Check for WorkflowCallEvent (workflow_call)
-			triggerName = workflowCallEvent.EventName()
-			triggerPos = workflowCallEvent.Pos
-		}
-
-		if triggerName != "" {
-			// Check if this is a dangerous trigger
-			// pull_request_target: Runs in base repo context with write permissions and secrets
-			// issue_comment: Runs in base repo context, can be triggered by external contributors
-			// workflow_run: Runs in base repo context with access to secrets
-			// workflow_call: Inherits security context from caller, can be privileged if called from privileged workflow
-			switch triggerName {
-			case EventPullRequestTarget, "issue_comment", "workflow_run", "workflow_call":
-				rule.hasDangerousTrigger = true
-				rule.dangerousTriggerPos = triggerPos
-				rule.dangerousTriggerName = triggerName
-				rule.Debug("Found dangerous trigger '%s' at %s", triggerName, triggerPos)
-				// Don't break - keep checking remaining triggers (though we only need to find one)
-			}
+		// Check if this is a dangerous trigger according to the trigger trust model
+		// Privileged triggers (e.g. pull_request_target, issue_comment, workflow_run) run in base repo context with secrets
+		// workflow_call: Inherits security context from caller, can be privileged if called from privileged workflow
+		if rule.TriggerTrust().Classify(event) != TriggerUnprivileged {
+			triggerName := event.EventName()
+			triggerPos := EventPosition(event)
+			rule.hasDangerousTrigger = true
+			rule.dangerousTriggerPos = triggerPos
+			rule.dangerousTriggerName = triggerName
+			rule.Debug("Found dangerous trigger '%s' at %s", triggerName, triggerPos)
+			// Don't break - keep checking remaining triggers (though we only need to find one)
 		}
 	}
 
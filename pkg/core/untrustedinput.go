@@ -0,0 +1,182 @@
+package core
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/sisaku-security/sisakulint/pkg/ast"
+	"github.com/sisaku-security/sisakulint/pkg/expressions"
+	"gopkg.in/yaml.v3"
+)
+
+// UntrustedInputConfigは設定ファイルのuntrusted-inputsセクションの1つのソース
+// 組み込みのカタログ(expressions.BuiltinUntrustedInputSources)に加えて、セキュリティのルールが信頼できない入力として扱う
+// 文字列だけを書いた場合はpathの省略形として扱う
+type UntrustedInputConfig struct {
+	// Pathはプロパティのパス。例えば"github.event.client_payload.title"や"inputs.title"。配列の要素は"*"で表す
+	Path string `yaml:"path"`
+	// Actionは出力が信頼できない入力になるアクション。"my-org/fetch-issue"のようにバージョンなしで書き、"*"を使える
+	Action string `yaml:"action"`
+	// OutputsはActionの出力のうち信頼できない入力になるものの名前
+	Outputs []string `yaml:"outputs"`
+	// Eventsは入力をペイロードに含むイベント。エラーメッセージに表示される
+	Events []string `yaml:"events"`
+
+	actionRegex *regexp.Regexp
+}
+
+// UnmarshalYAMLはpathの省略形である文字列を受け付ける
+func (c *UntrustedInputConfig) UnmarshalYAML(n *yaml.Node) error {
+	if n.Kind == yaml.ScalarNode {
+		return n.Decode(&c.Path)
+	}
+	type plain UntrustedInputConfig
+	return n.Decode((*plain)(c))
+}
+
+// untrustedInputPathはプロパティのパスの形式。式の中と同じく大文字と小文字は区別しない
+var untrustedInputPath = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_-]*(\.([a-zA-Z_][a-zA-Z0-9_-]*|\*))+$`)
+
+// compileはソースを検証し、アクションのパターンをコンパイルする
+func (c *UntrustedInputConfig) compile() error {
+	if c.Path != "" && c.Action != "" {
+		return fmt.Errorf("untrusted input must have either \"path\" or \"action\", not both")
+	}
+	if c.Action != "" {
+		if len(c.Outputs) == 0 {
+			return fmt.Errorf("untrusted input of action %q must have at least one name in \"outputs\"", c.Action)
+		}
+		re, err := compileActionPattern(c.Action)
+		if err != nil {
+			return fmt.Errorf("failed to compile pattern of action %q: %w", c.Action, err)
+		}
+		c.actionRegex = re
+		return nil
+	}
+	if !untrustedInputPath.MatchString(c.Path) {
+		return fmt.Errorf("invalid path of untrusted input %q. it must be a property path such as \"github.event.client_payload.title\"", c.Path)
+	}
+	return nil
+}
+
+// matchesActionは、ステップのuses:のアクションがソースのアクションにマッチするかを返す
+func (c *UntrustedInputConfig) matchesAction(uses string) bool {
+	if c.actionRegex == nil {
+		return false
+	}
+	name, _, _ := strings.Cut(uses, "@")
+	return c.actionRegex.MatchString(name)
+}
+
+// compileUntrustedInputsはuntrusted-inputsセクションのソースを検証する
+func compileUntrustedInputs(inputs []*UntrustedInputConfig, section string) error {
+	for i, c := range inputs {
+		if c == nil {
+			return fmt.Errorf("%s[%d] must have \"path\" or \"action\"", section, i)
+		}
+		if err := c.compile(); err != nil {
+			return fmt.Errorf("%s[%d]: %w", section, i, err)
+		}
+	}
+	return nil
+}
+
+// untrustedInputRootsは、ジョブのスクリプトで信頼できない入力を検出するための検索ツリーを返す
+// 組み込みのカタログに設定のソースを加える。アクションのソースは、ジョブでそのアクションを使うステップの出力(steps.<id>.outputs.<name>)になる
+// アクションのメタデータファイルのinputs.*は、アクションの呼び出し元が決める値なので信頼できない入力として扱う
+func untrustedInputRoots(w *ast.Workflow, cfg *Config, job *ast.Job) expressions.ContextPropertySearchRoots {
+	var inputs []*UntrustedInputConfig
+	if cfg != nil {
+		inputs = cfg.UntrustedInputs
+	}
+	hasActionInputs := w != nil && w.Action != nil && len(w.Action.Inputs) > 0
+	if len(inputs) == 0 && !hasActionInputs {
+		return expressions.BuiltinUntrustedInputs
+	}
+
+	roots := expressions.BuiltinUntrustedInputs.Copy()
+	if hasActionInputs {
+		for id := range w.Action.Inputs {
+			roots.AddPath("inputs." + id)
+		}
+	}
+	for _, c := range inputs {
+		if c.Path != "" {
+			roots.AddPath(c.Path, c.Events...)
+			continue
+		}
+		if job == nil {
+			continue
+		}
+		for _, s := range job.Steps {
+			a, ok := s.Exec.(*ast.ExecAction)
+			if !ok || a.Uses == nil || s.ID == nil || s.ID.Value == "" || !c.matchesAction(a.Uses.Value) {
+				continue
+			}
+			for _, o := range c.Outputs {
+				roots.AddPath("steps."+s.ID.Value+".outputs."+o, c.Events...)
+			}
+		}
+	}
+	return roots
+}
+
+// describeUntrustedInputsは、エラーメッセージのために信頼できない入力のパスとそのイベントを
+// "\"github.event.issue.title\" (from issue_comment event)"のような形式で並べる
+// イベントはワークフローのトリガーのうちペイロードにその入力を含むもの。どのトリガーも含まない場合はカタログのイベントを全て示す
+// データフロー解析で見つけたプロパティは"\"steps.meta.outputs.title\" (from issues event via \"github.event.issue.title\")"のように元の入力を示し、
+// 再利用可能なワークフローの呼び出し元から受け取った入力は、呼び出し元での値とその位置を示す
+func describeUntrustedInputs(scope *taintScope, paths []string, w *ast.Workflow) string {
+	triggers := map[string]struct{}{}
+	if w != nil {
+		for _, e := range w.On {
+			triggers[strings.ToLower(e.EventName())] = struct{}{}
+		}
+	}
+	descs := make([]string, 0, len(paths))
+	for _, p := range paths {
+		var events []string
+		if m := scope.roots.Find(p); m != nil {
+			for _, e := range m.Events {
+				if _, ok := triggers[e]; ok {
+					events = append(events, e)
+				}
+			}
+			if len(events) == 0 {
+				events = append(events, m.Events...)
+			}
+		}
+		var notes []string
+		if len(events) > 0 {
+			sort.Strings(events)
+			noun := "event"
+			if len(events) > 1 {
+				noun = "events"
+			}
+			notes = append(notes, fmt.Sprintf("from %s %s", strings.Join(events, ", "), noun))
+		}
+		var froms []string
+		if srcs := scope.via[strings.ToLower(p)]; len(srcs) > 0 {
+			for _, src := range srcs {
+				from := fmt.Sprintf("%q", src)
+				if trace := scope.trace[strings.ToLower(src)]; len(trace) > 0 {
+					from += " from " + strings.Join(trace, ", ")
+				}
+				froms = append(froms, from)
+			}
+		} else {
+			froms = scope.trace[strings.ToLower(p)]
+		}
+		if len(froms) > 0 {
+			notes = append(notes, "via "+strings.Join(froms, ", "))
+		}
+		desc := fmt.Sprintf("%q", p)
+		if len(notes) > 0 {
+			desc += " (" + strings.Join(notes, " ") + ")"
+		}
+		descs = append(descs, desc)
+	}
+	return strings.Join(descs, ", ")
+}
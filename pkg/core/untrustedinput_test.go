@@ -0,0 +1,181 @@
+package core
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sisaku-security/sisakulint/pkg/ast"
+	"github.com/sisaku-security/sisakulint/pkg/expressions"
+)
+
+// untrustedPathsInは、検索ツリーrootsを使って式srcから信頼できない入力のパスを取り出す
+func untrustedPathsIn(t *testing.T, roots expressions.ContextPropertySearchRoots, src string) []string {
+	t.Helper()
+	rule := CodeInjectionMediumRule()
+	rule.untrusted = &taintScope{roots: roots}
+	node, err := rule.parseExpression(src)
+	if err != nil {
+		t.Fatalf("could not parse %q: %v", src, err)
+	}
+	return rule.checkUntrustedInput(parsedExpression{raw: src, node: node})
+}
+
+func TestBuiltinUntrustedInputs(t *testing.T) {
+	tests := []struct {
+		expr   string
+		path   string
+		events []string
+	}{
+		{"github.event.issue.title", "github.event.issue.title", []string{"issues", "issue_comment"}},
+		{"github.event.discussion.title", "github.event.discussion.title", []string{"discussion", "discussion_comment"}},
+		{"github.event.release.body", "github.event.release.body", []string{"release"}},
+		{"github.event.workflow_run.head_branch", "github.event.workflow_run.head_branch", []string{"workflow_run"}},
+		{"github.event.workflow_run.pull_requests.*.head.ref", "github.event.workflow_run.pull_requests.*.head.ref", []string{"workflow_run"}},
+		{"github.event.head_commit.committer.name", "github.event.head_commit.committer.name", []string{"push", "merge_group"}},
+		{"github.event.pull_request.head.repo.description", "github.event.pull_request.head.repo.description", []string{"pull_request", "pull_request_target", "pull_request_review", "pull_request_review_comment"}},
+		{"github.event.commits.*.committer.email", "github.event.commits.*.committer.email", []string{"push"}},
+		{"github.event.changes.body.from", "github.event.changes.body.from", nil},
+		{"github.head_ref", "github.head_ref", []string{"pull_request", "pull_request_target"}},
+		{"github.event.pull_request.head.repo.full_name", "", nil},
+		{"github.event.release.tag_name", "", nil},
+		{"github.sha", "", nil},
+	}
+	for _, tt := range tests {
+		paths := untrustedPathsIn(t, expressions.BuiltinUntrustedInputs, tt.expr)
+		if tt.path == "" {
+			if len(paths) > 0 {
+				t.Errorf("%q should be trusted but got %v", tt.expr, paths)
+			}
+			continue
+		}
+		if len(paths) != 1 || paths[0] != tt.path {
+			t.Errorf("untrusted inputs in %q: got %v, want %q", tt.expr, paths, tt.path)
+			continue
+		}
+		if tt.events == nil {
+			continue
+		}
+		m := expressions.BuiltinUntrustedInputs.Find(tt.path)
+		if m == nil || strings.Join(m.Events, ",") != strings.Join(tt.events, ",") {
+			t.Errorf("events of %q: got %v, want %v", tt.path, m, tt.events)
+		}
+	}
+}
+
+func TestUntrustedInputRoots_Config(t *testing.T) {
+	cfg, err := parseConfig([]byte(`
+untrusted-inputs:
+  - github.event.client_payload.title
+  - path: needs.fetch.outputs.body
+    events: [issues]
+  - action: my-org/fetch-*
+    outputs: [title]
+    events: [issue_comment]
+`), "sisakulint.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	job := &ast.Job{Steps: []*ast.Step{
+		{ID: &ast.String{Value: "meta"}, Exec: &ast.ExecAction{Uses: &ast.String{Value: "my-org/fetch-issue@v1"}}},
+		{ID: &ast.String{Value: "other"}, Exec: &ast.ExecAction{Uses: &ast.String{Value: "actions/github-script@v7"}}},
+	}}
+	w := &ast.Workflow{On: []ast.Event{&ast.WebhookEvent{Hook: &ast.String{Value: "issue_comment"}}}}
+	roots := untrustedInputRoots(w, cfg, job)
+
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"github.event.client_payload.title", `"github.event.client_payload.title"`},
+		{"needs.fetch.outputs.body", `"needs.fetch.outputs.body" (from issues event)`},
+		{"steps.meta.outputs.title", `"steps.meta.outputs.title" (from issue_comment event)`},
+		{"steps.other.outputs.title", ""},
+		{"steps.meta.outputs.body", ""},
+		{"github.event.issue.title", `"github.event.issue.title" (from issue_comment event)`},
+	}
+	for _, tt := range tests {
+		paths := untrustedPathsIn(t, roots, tt.expr)
+		if got := describeUntrustedInputs(&taintScope{roots: roots}, paths, w); got != tt.want {
+			t.Errorf("untrusted inputs in %q: got %q, want %q", tt.expr, got, tt.want)
+		}
+	}
+
+	// 設定のソースは組み込みのカタログを変更しない
+	if expressions.BuiltinUntrustedInputs.Find("github.event.client_payload.title") != nil {
+		t.Error("builtin untrusted inputs were modified")
+	}
+	if got := untrustedInputRoots(w, nil, job); got["steps"] != nil || got["needs"] != nil {
+		t.Errorf("roots without configuration have extra sources: %v", got)
+	}
+}
+
+func TestLinter_UntrustedInputsConfig(t *testing.T) {
+	root := t.TempDir()
+	workflows := filepath.Join(root, ".github", "workflows")
+	if err := os.MkdirAll(workflows, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(root, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	config := `
+overrides:
+  - paths: [".github/workflows/comment.yml"]
+    untrusted-inputs:
+      - path: inputs.comment
+        events: [issue_comment]
+`
+	if err := os.WriteFile(filepath.Join(root, ".github", "sisakulint.yaml"), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	workflow := `on:
+  workflow_call:
+    inputs:
+      comment:
+        type: string
+jobs:
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo "${{ inputs.comment }}"
+`
+	files := []string{filepath.Join(workflows, "comment.yml"), filepath.Join(workflows, "other.yml")}
+	for _, f := range files {
+		if err := os.WriteFile(f, []byte(workflow), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	project, err := NewProject(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := NewLinter(io.Discard, &LinterOptions{CurrentWorkingDirectoryPath: root})
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := l.LintFiles(files, project)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, res := range results {
+		var msgs []string
+		for _, e := range res.Errors {
+			if e.Type == "code-injection-medium" {
+				msgs = append(msgs, e.Description)
+			}
+		}
+		if filepath.Base(res.FilePath) == "other.yml" {
+			if len(msgs) > 0 {
+				t.Errorf("inputs of %s should be trusted: %v", res.FilePath, msgs)
+			}
+			continue
+		}
+		if len(msgs) != 1 || !strings.Contains(msgs[0], `"inputs.comment" (from issue_comment event) is potentially untrusted`) {
+			t.Errorf("unexpected errors in %s: %v", res.FilePath, msgs)
+		}
+	}
+}
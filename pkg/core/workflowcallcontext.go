@@ -0,0 +1,186 @@
+package core
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/sisaku-security/sisakulint/pkg/ast"
+)
+
+// workflowCallerは、ローカルの再利用可能なワークフローを呼び出すジョブ
+// 再利用可能なワークフローは呼び出し元のトリガーのコンテキストで実行され、呼び出し元がwith:で渡した値を入力として受け取る
+type workflowCaller struct {
+	// pathは呼び出し元のワークフローのリポジトリのルートからの相対パス
+	path string
+	job  *ast.Job
+	// trustは呼び出し元のワークフローのトリガーのうち最も高い信頼度
+	trust TriggerTrust
+	// forkは呼び出し元のワークフローがフォークからのプルリクエストで起動できるかどうか
+	fork bool
+	// inputsは、呼び出し元がwith:で信頼できない値を渡す入力
+	inputs map[string]*callerInput
+}
+
+// callerInputは、呼び出し元からwith:で信頼できない値を受け取る再利用可能なワークフローの入力
+type callerInput struct {
+	// eventsは値の元になった信頼できない入力をペイロードに含む呼び出し元のトリガー
+	events []string
+	// traceは呼び出し元での値の由来。"\"github.event.pull_request.title\" in .github/workflows/caller.yml:12:16"のように
+	// 元の信頼できない入力と、with:に書かれた位置を示す。呼び出しが連鎖する場合は" -> "で位置をつなぐ
+	trace []string
+}
+
+// localWorkflowPathは、ジョブのuses:がローカルの再利用可能なワークフローを指す場合、リポジトリのルートからの相対パスを返す
+func localWorkflowPath(uses string) (string, bool) {
+	if !strings.HasPrefix(uses, "./") {
+		return "", false
+	}
+	return path.Clean(uses), true
+}
+
+// callerInputsは、検査するワークフローが呼び出し元からwith:で信頼できない値を受け取る入力を返す
+func (m *TriggerTrustModel) callerInputs() map[string]*callerInput {
+	return mergeCallerInputs(m.callersOf(m.path, nil))
+}
+
+// callersOfは、プロジェクトの中でリポジトリのルートからの相対パスがpの再利用可能なワークフローを呼び出すジョブを返す
+// visitingは呼び出しの連鎖の途中にあるワークフローのパスで、循環する呼び出しをたどらないために使う
+// 検査するワークフローの呼び出し元は一度だけ求めてキャッシュする
+func (m *TriggerTrustModel) callersOf(p string, visiting map[string]bool) []*workflowCaller {
+	if m.project == nil || p == "" {
+		return nil
+	}
+	if p == m.path && visiting == nil {
+		m.callersOnce.Do(func() {
+			m.callers = m.findCallers(p, map[string]bool{})
+		})
+		return m.callers
+	}
+	if visiting[p] {
+		return nil
+	}
+	return m.findCallers(p, visiting)
+}
+
+func (m *TriggerTrustModel) findCallers(p string, visiting map[string]bool) []*workflowCaller {
+	visiting[p] = true
+	defer delete(visiting, p)
+
+	var callers []*workflowCaller
+	for _, pw := range m.project.parsedWorkflows() {
+		ids := make([]string, 0, len(pw.workflow.Jobs))
+		for id := range pw.workflow.Jobs {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			j := pw.workflow.Jobs[id]
+			if j == nil || j.WorkflowCall == nil || j.WorkflowCall.Uses == nil {
+				continue
+			}
+			if callee, ok := localWorkflowPath(j.WorkflowCall.Uses.Value); !ok || callee != p {
+				continue
+			}
+			c := &workflowCaller{path: pw.path, job: j}
+			for _, e := range pw.workflow.On {
+				c.trust = max(c.trust, m.classify(e, pw.workflow, pw.path, visiting))
+				c.fork = c.fork || m.forkTriggered(e, pw.path, visiting)
+			}
+			c.inputs = m.taintedWithInputs(pw, j, visiting)
+			callers = append(callers, c)
+		}
+	}
+	return callers
+}
+
+// taintedWithInputsは、ジョブjがwith:で信頼できない値を渡す入力を返す
+// 呼び出し元のワークフローもデータフロー解析するので、needs.<job>.outputsやマトリックスの値、呼び出し元自身の入力を通った値もたどれる
+func (m *TriggerTrustModel) taintedWithInputs(pw *projectWorkflow, j *ast.Job, visiting map[string]bool) map[string]*callerInput {
+	if len(j.WorkflowCall.Inputs) == 0 {
+		return nil
+	}
+	var cfg *Config
+	if m.base != nil {
+		cfg = m.base.ForPath(pw.path)
+	}
+	inputs := mergeCallerInputs(m.callersOf(pw.path, visiting))
+	scope := newWorkflowTaint(pw.workflow, cfg, inputs).job(j).scope
+
+	triggers := map[string]struct{}{}
+	for _, e := range pw.workflow.On {
+		triggers[strings.ToLower(e.EventName())] = struct{}{}
+	}
+
+	tainted := map[string]*callerInput{}
+	for name, in := range j.WorkflowCall.Inputs {
+		if in == nil || in.Value == nil {
+			continue
+		}
+		paths := scope.taintedPaths(in.Value.Value)
+		if len(paths) == 0 {
+			continue
+		}
+		loc := pw.path
+		if pos := in.Value.Pos; pos != nil {
+			loc = fmt.Sprintf("%s:%d:%d", pw.path, pos.Line, pos.Col)
+		}
+		ci := &callerInput{}
+		var events []string
+		for _, src := range scope.origins(paths) {
+			if n := scope.roots.Find(src); n != nil {
+				events = append(events, n.Events...)
+			}
+			if trace := scope.trace[strings.ToLower(src)]; len(trace) > 0 {
+				for _, t := range trace {
+					ci.trace = append(ci.trace, t+" -> "+loc)
+				}
+				continue
+			}
+			ci.trace = append(ci.trace, fmt.Sprintf("%q in %s", src, loc))
+		}
+		// 呼び出し元のトリガーで起こるイベントだけを示す。どのトリガーも該当しない場合は全てのイベントを示す
+		for _, e := range events {
+			if _, ok := triggers[e]; ok {
+				ci.events = appendUnique(ci.events, e)
+			}
+		}
+		if len(ci.events) == 0 {
+			for _, e := range events {
+				ci.events = appendUnique(ci.events, e)
+			}
+		}
+		tainted[name] = ci
+	}
+	return tainted
+}
+
+// mergeCallerInputsは、全ての呼び出し元から信頼できない値を受け取る入力をまとめる
+func mergeCallerInputs(callers []*workflowCaller) map[string]*callerInput {
+	var merged map[string]*callerInput
+	for _, c := range callers {
+		for name, in := range c.inputs {
+			if merged == nil {
+				merged = map[string]*callerInput{}
+			}
+			m, ok := merged[name]
+			if !ok {
+				m = &callerInput{}
+				merged[name] = m
+			}
+			for _, e := range in.events {
+				m.events = appendUnique(m.events, e)
+			}
+			m.trace = append(m.trace, in.trace...)
+		}
+	}
+	return merged
+}
+
+func appendUnique(ss []string, s string) []string {
+	if contains(ss, s) {
+		return ss
+	}
+	return append(ss, s)
+}
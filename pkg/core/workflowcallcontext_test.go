@@ -0,0 +1,211 @@
+package core
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeProjectは、ワークフローのファイルを持つプロジェクトを一時ディレクトリに作成する
+func writeProject(t *testing.T, workflows map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	dir := filepath.Join(root, ".github", "workflows")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(root, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, src := range workflows {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+const calleeWorkflow = `on:
+  workflow_call:
+    inputs:
+      title:
+        type: string
+      sha:
+        type: string
+jobs:
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo "${{ inputs.title }} ${{ inputs.sha }}"
+`
+
+func TestTriggerTrustModel_WorkflowCallers(t *testing.T) {
+	tests := []struct {
+		name      string
+		callers   map[string]string
+		want      TriggerTrust
+		wantTrace map[string]string
+	}{
+		{
+			name: "no caller",
+			want: TriggerInherited,
+		},
+		{
+			name: "privileged caller",
+			callers: map[string]string{
+				"caller.yml": `on: pull_request_target
+jobs:
+  call:
+    uses: ./.github/workflows/callee.yml
+    with:
+      title: ${{ github.event.pull_request.title }}
+      sha: ${{ github.sha }}
+`,
+			},
+			want:      TriggerPrivileged,
+			wantTrace: map[string]string{"title": `"github.event.pull_request.title" in .github/workflows/caller.yml:6:14`},
+		},
+		{
+			name: "unprivileged caller",
+			callers: map[string]string{
+				"caller.yml": `on: pull_request
+jobs:
+  meta:
+    runs-on: ubuntu-latest
+    outputs:
+      title: ${{ steps.m.outputs.title }}
+    steps:
+      - id: m
+        run: echo "title=${{ github.event.pull_request.title }}" >> "$GITHUB_OUTPUT"
+  call:
+    needs: meta
+    uses: ./.github/workflows/callee.yml
+    with:
+      title: ${{ needs.meta.outputs.title }}
+`,
+			},
+			want:      TriggerInherited,
+			wantTrace: map[string]string{"title": `"github.event.pull_request.title" in .github/workflows/caller.yml:14:14`},
+		},
+		{
+			name: "chain of reusable workflows",
+			callers: map[string]string{
+				"top.yml": `on: issue_comment
+jobs:
+  call:
+    uses: ./.github/workflows/mid.yml
+    with:
+      body: ${{ github.event.comment.body }}
+`,
+				"mid.yml": `on:
+  workflow_call:
+    inputs:
+      body:
+        type: string
+jobs:
+  call:
+    uses: ./.github/workflows/callee.yml
+    with:
+      title: ${{ inputs.body }}
+`,
+			},
+			want:      TriggerPrivileged,
+			wantTrace: map[string]string{"title": `"github.event.comment.body" in .github/workflows/top.yml:6:13 -> .github/workflows/mid.yml:10:14`},
+		},
+		{
+			name: "recursive call",
+			callers: map[string]string{
+				"caller.yml": `on:
+  workflow_call:
+    inputs:
+      title:
+        type: string
+jobs:
+  call:
+    uses: ./.github/workflows/callee.yml
+    with:
+      title: ${{ inputs.title }}
+  self:
+    uses: ./.github/workflows/caller.yml
+    with:
+      title: ${{ inputs.title }}
+`,
+			},
+			want: TriggerInherited,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := map[string]string{"callee.yml": calleeWorkflow}
+			for name, src := range tt.callers {
+				files[name] = src
+			}
+			project, err := NewProject(writeProject(t, files))
+			if err != nil {
+				t.Fatal(err)
+			}
+			model := NewTriggerTrustModel(nil, project).forWorkflow(".github/workflows/callee.yml", nil, nil)
+			w, errs := Parse([]byte(calleeWorkflow))
+			if len(errs) > 0 {
+				t.Fatal(errs)
+			}
+			if got := model.Classify(w.On[0]); got != tt.want {
+				t.Errorf("workflow_call is %s, want %s", got, tt.want)
+			}
+			inputs := model.callerInputs()
+			if len(inputs) != len(tt.wantTrace) {
+				t.Fatalf("got tainted inputs %v, want %v", inputs, tt.wantTrace)
+			}
+			for name, want := range tt.wantTrace {
+				in, ok := inputs[name]
+				if !ok {
+					t.Errorf("input %q is not tainted", name)
+					continue
+				}
+				if got := strings.Join(in.trace, ", "); got != want {
+					t.Errorf("trace of input %q: got %q, want %q", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLinter_ReusableWorkflowTaint(t *testing.T) {
+	root := writeProject(t, map[string]string{
+		"callee.yml": calleeWorkflow,
+		"caller.yml": `on: pull_request_target
+jobs:
+  call:
+    uses: ./.github/workflows/callee.yml
+    with:
+      title: ${{ github.event.pull_request.title }}
+      sha: ${{ github.sha }}
+`,
+	})
+	project, err := NewProject(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := NewLinter(io.Discard, &LinterOptions{CurrentWorkingDirectoryPath: root})
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(root, ".github", "workflows", "callee.yml")
+	results, err := l.LintFiles([]string{file}, project)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var msgs []string
+	for _, e := range results[0].Errors {
+		if strings.HasPrefix(e.Type, "code-injection-") {
+			msgs = append(msgs, e.Type+": "+e.Description)
+		}
+	}
+	want := `code-injection-critical: code injection (critical): "inputs.title" (from pull_request_target event via "github.event.pull_request.title" in .github/workflows/caller.yml:6:14) is potentially untrusted`
+	if len(msgs) != 1 || !strings.HasPrefix(msgs[0], want) {
+		t.Errorf("got %v, want a message starting with %q", msgs, want)
+	}
+}
@@ -11,6 +11,8 @@ type ContextPropertyMap struct {
 	Name     string
 	Parent   *ContextPropertyMap
 	Children map[string]*ContextPropertyMap
+	// Events はこのプロパティをペイロードに含むイベントの名前です。葉のノードにのみ設定されます。空の場合はイベントを特定しません。
+	Events []string
 }
 
 func (m *ContextPropertyMap) String() string {
@@ -75,93 +77,171 @@ func (ms ContextPropertySearchRoots) AddRoot(m *ContextPropertyMap) {
 	ms[m.Name] = m
 }
 
-// BuiltinUntrustedInputs は untrusted inputsのリストです。
-// これらの入力は `run:` スクリプトで""untrusted!""として検出されます。
-//* https://securitylab.github.com/research/github-actions-untrusted-input/
-//* https://docs.github.com/en/actions/security-guides/security-hardening-for-github-actions
-//* https://github.com/github/codeql/blob/main/javascript/ql/src/experimental/Security/CWE-094/examples/pull_request_target_bad.yml
-/*
-github.event.issue.title
-github.event.issue.body
-github.event.pull_request.title
-github.event.pull_request.body
-github.event.comment.body
-github.event.review.body
-github.event.pages.*.page_name
-github.event.commits.*.message
-github.event.head_commit.message
-github.event.head_commit.author.email
-github.event.head_commit.author.name
-github.event.commits.*.author.email
-github.event.commits.*.author.name
-github.event.pull_request.head.ref
-github.event.pull_request.head.label
-github.event.pull_request.head.repo.default_branch
-github.head_ref
-*/
-var BuiltinUntrustedInputs = ContextPropertySearchRoots{
-	//todo: github.event.issue.title , github.event.issue.body
-	"github": NewContextPropertyMap("github",
-		NewContextPropertyMap("event",
-			NewContextPropertyMap("issue",
-				NewContextPropertyMap("title"),
-				NewContextPropertyMap("body"),
-			),
-			//todo: github.event.pull_request.title , github.event.pull_request.body
-			NewContextPropertyMap("pull_request",
-				NewContextPropertyMap("title"),
-				NewContextPropertyMap("body"),
-				NewContextPropertyMap("head",
-					NewContextPropertyMap("ref"),
-					NewContextPropertyMap("label"),
-					NewContextPropertyMap("sha"),
-					NewContextPropertyMap("repo",
-						NewContextPropertyMap("default_branch"),
-					),
-				),
-			),
-			//todo: github.event.comment.body
-			NewContextPropertyMap("comment",
-				NewContextPropertyMap("body"),
-			),
-			//todo: github.event.review.body
-			NewContextPropertyMap("review",
-				NewContextPropertyMap("body"),
-			),
-			//todo: github.event.pages.*.page_name
-			NewContextPropertyMap("review_comment",
-				NewContextPropertyMap("body"),
-			),
-			NewContextPropertyMap("pages",
-				NewContextPropertyMap("*",
-					NewContextPropertyMap("page_name"),
-				),
-			),
-			//todo: github.event.commits.*.message
-			NewContextPropertyMap("commits",
-				NewContextPropertyMap("*",
-					NewContextPropertyMap("message"),
-					NewContextPropertyMap("author",
-						NewContextPropertyMap("email"),
-						NewContextPropertyMap("name"),
-					),
-				),
-			),
-			//todo: github.event.head_commit.message , github.event.head_commit.author.email , github.event.head_commit.author.name
-			NewContextPropertyMap("head_commit",
-				NewContextPropertyMap("message"),
-				NewContextPropertyMap("author",
-					NewContextPropertyMap("email"),
-					NewContextPropertyMap("name"),
-				),
-			),
-			//todo: github.event.discussion.title , github.event.discussion.body
-			NewContextPropertyMap("discussion",
-				NewContextPropertyMap("title"),
-				NewContextPropertyMap("body"),
-			),
-		),
-		//todo: github.head_ref
-		NewContextPropertyMap("head_ref"),
-	),
+// AddPath は `github.event.commits.*.message` のようなプロパティのパスを検索ツリーに追加します。
+// 配列の要素は `*` で表します。既に同じパスがある場合は、eventsをそのパスのイベントに追加します。
+func (ms ContextPropertySearchRoots) AddPath(path string, events ...string) {
+	names := strings.Split(strings.ToLower(path), ".")
+	m, ok := ms[names[0]]
+	if !ok {
+		m = NewContextPropertyMap(names[0])
+		ms.AddRoot(m)
+	}
+	for _, name := range names[1:] {
+		c, ok := m.findObjectProp(name)
+		if !ok {
+			c = NewContextPropertyMap(name)
+			c.Parent = m
+			if m.Children == nil {
+				m.Children = map[string]*ContextPropertyMap{}
+			}
+			m.Children[name] = c
+		}
+		m = c
+	}
+	for _, e := range events {
+		if !containsString(m.Events, e) {
+			m.Events = append(m.Events, e)
+		}
+	}
+}
+
+// Find はパスに対応するノードを返します。見つからない場合はnilを返します。
+func (ms ContextPropertySearchRoots) Find(path string) *ContextPropertyMap {
+	names := strings.Split(strings.ToLower(path), ".")
+	m, ok := ms[names[0]]
+	if !ok {
+		return nil
+	}
+	for _, name := range names[1:] {
+		if m, ok = m.findObjectProp(name); !ok {
+			return nil
+		}
+	}
+	return m
 }
+
+// Copy は検索ツリーの深いコピーを返します。コピーにAddPathでパスを追加しても元のツリーは変更されません。
+func (ms ContextPropertySearchRoots) Copy() ContextPropertySearchRoots {
+	copied := make(ContextPropertySearchRoots, len(ms))
+	for name, m := range ms {
+		copied[name] = m.copy(nil)
+	}
+	return copied
+}
+
+func (m *ContextPropertyMap) copy(parent *ContextPropertyMap) *ContextPropertyMap {
+	c := &ContextPropertyMap{Name: m.Name, Parent: parent, Events: m.Events}
+	if m.Children != nil {
+		c.Children = make(map[string]*ContextPropertyMap, len(m.Children))
+		for name, child := range m.Children {
+			c.Children[name] = child.copy(c)
+		}
+	}
+	return c
+}
+
+func containsString(ss []string, s string) bool {
+	for _, x := range ss {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+// UntrustedInputSource は信頼できない入力の1つのソースです。
+type UntrustedInputSource struct {
+	// Path はプロパティのパスです。配列の要素は `*` で表します。
+	Path string
+	// Events はこのプロパティをペイロードに含むイベントです。
+	Events []string
+}
+
+// NewUntrustedInputRoots はソースのリストから信頼できない入力の検索ツリーを作成します。
+func NewUntrustedInputRoots(sources []UntrustedInputSource) ContextPropertySearchRoots {
+	roots := ContextPropertySearchRoots{}
+	for _, s := range sources {
+		roots.AddPath(s.Path, s.Events...)
+	}
+	return roots
+}
+
+// 信頼できない入力を含むペイロードを持つイベントのグループです。
+var (
+	untrustedPullRequestEvents = []string{"pull_request", "pull_request_target", "pull_request_review", "pull_request_review_comment"}
+	untrustedIssueEvents       = []string{"issues", "issue_comment"}
+	untrustedCommentEvents     = []string{"issue_comment", "pull_request_review_comment", "discussion_comment", "commit_comment"}
+	untrustedDiscussionEvents  = []string{"discussion", "discussion_comment"}
+	untrustedEditedEvents      = []string{"issues", "issue_comment", "pull_request", "pull_request_target", "discussion", "discussion_comment", "pull_request_review_comment"}
+)
+
+// BuiltinUntrustedInputSources は、webhookのペイロードのうち外部のユーザーが自由に書ける文字列のカタログです。
+// 各ソースにはそのプロパティをペイロードに含むイベントが付いています。
+// ログイン名やリポジトリ名のように使える文字が限られた値は、シェルの構文を注入できないので含めません。
+// * https://securitylab.github.com/research/github-actions-untrusted-input/
+// * https://docs.github.com/en/actions/security-guides/security-hardening-for-github-actions
+// * https://docs.github.com/en/webhooks/webhook-events-and-payloads
+var BuiltinUntrustedInputSources = []UntrustedInputSource{
+	// issues, issue_comment
+	{"github.event.issue.title", untrustedIssueEvents},
+	{"github.event.issue.body", untrustedIssueEvents},
+	// pull_request, pull_request_target, pull_request_review, pull_request_review_comment
+	{"github.event.pull_request.title", untrustedPullRequestEvents},
+	{"github.event.pull_request.body", untrustedPullRequestEvents},
+	{"github.event.pull_request.head.ref", untrustedPullRequestEvents},
+	{"github.event.pull_request.head.label", untrustedPullRequestEvents},
+	{"github.event.pull_request.head.sha", untrustedPullRequestEvents},
+	{"github.event.pull_request.head.repo.default_branch", untrustedPullRequestEvents},
+	{"github.event.pull_request.head.repo.description", untrustedPullRequestEvents},
+	{"github.event.pull_request.head.repo.homepage", untrustedPullRequestEvents},
+	{"github.head_ref", []string{"pull_request", "pull_request_target"}},
+	{"github.event.review.body", []string{"pull_request_review"}},
+	{"github.event.review_comment.body", []string{"pull_request_review_comment"}},
+	// コメント
+	{"github.event.comment.body", untrustedCommentEvents},
+	// discussion, discussion_comment
+	{"github.event.discussion.title", untrustedDiscussionEvents},
+	{"github.event.discussion.body", untrustedDiscussionEvents},
+	// "edited"のアクティビティタイプで変更前の値を持つ
+	{"github.event.changes.title.from", untrustedEditedEvents},
+	{"github.event.changes.body.from", untrustedEditedEvents},
+	// gollum
+	{"github.event.pages.*.page_name", []string{"gollum"}},
+	{"github.event.pages.*.title", []string{"gollum"}},
+	{"github.event.pages.*.summary", []string{"gollum"}},
+	// push
+	{"github.event.commits.*.message", []string{"push"}},
+	{"github.event.commits.*.author.email", []string{"push"}},
+	{"github.event.commits.*.author.name", []string{"push"}},
+	{"github.event.commits.*.committer.email", []string{"push"}},
+	{"github.event.commits.*.committer.name", []string{"push"}},
+	{"github.event.head_commit.message", []string{"push", "merge_group"}},
+	{"github.event.head_commit.author.email", []string{"push", "merge_group"}},
+	{"github.event.head_commit.author.name", []string{"push", "merge_group"}},
+	{"github.event.head_commit.committer.email", []string{"push", "merge_group"}},
+	{"github.event.head_commit.committer.name", []string{"push", "merge_group"}},
+	{"github.event.merge_group.head_commit.message", []string{"merge_group"}},
+	{"github.event.merge_group.head_commit.author.email", []string{"merge_group"}},
+	{"github.event.merge_group.head_commit.author.name", []string{"merge_group"}},
+	// release
+	{"github.event.release.name", []string{"release"}},
+	{"github.event.release.body", []string{"release"}},
+	// workflow_run: 起動元のワークフローがフォークからのプルリクエストで実行された場合、ヘッドブランチやコミットは外部のユーザーが決める
+	{"github.event.workflow_run.head_branch", []string{"workflow_run"}},
+	{"github.event.workflow_run.display_title", []string{"workflow_run"}},
+	{"github.event.workflow_run.head_commit.message", []string{"workflow_run"}},
+	{"github.event.workflow_run.head_commit.author.email", []string{"workflow_run"}},
+	{"github.event.workflow_run.head_commit.author.name", []string{"workflow_run"}},
+	{"github.event.workflow_run.head_commit.committer.email", []string{"workflow_run"}},
+	{"github.event.workflow_run.head_commit.committer.name", []string{"workflow_run"}},
+	{"github.event.workflow_run.head_repository.description", []string{"workflow_run"}},
+	{"github.event.workflow_run.pull_requests.*.head.ref", []string{"workflow_run"}},
+	// check_suite, check_run
+	{"github.event.check_suite.head_branch", []string{"check_suite"}},
+	{"github.event.check_suite.head_commit.message", []string{"check_suite"}},
+	{"github.event.check_run.check_suite.head_branch", []string{"check_run"}},
+}
+
+// BuiltinUntrustedInputs は BuiltinUntrustedInputSources から作成した検索ツリーです。
+// これらの入力は `run:` スクリプトで""untrusted!""として検出されます。
+var BuiltinUntrustedInputs = NewUntrustedInputRoots(BuiltinUntrustedInputSources)
@@ -324,6 +324,14 @@ func NewExprSemanticsChecker(checkUntrustedInput bool, configVars []string) *Exp
 	return c
 }
 
+// UpdateUntrustedInputsは信頼できない入力を検出するためのルートを置き換えます。
+// checkUntrustedInputがfalseで作成されたチェッカーでは何もしません。
+func (sema *ExprSemanticsChecker) UpdateUntrustedInputs(roots ContextPropertySearchRoots) {
+	if sema.untrusted != nil {
+		sema.untrusted = NewUntiChecker(roots)
+	}
+}
+
 func errorAtExpr(e ExprNode, msg string) *ExprError {
 	t := e.Token()
 	return &ExprError{
@@ -0,0 +1,222 @@
+// generate-popular-actionsは、よく使われるアクションのメタデータのデータセットを生成するツール
+// アクションのリポジトリをgit cloneしたローカルのミラーのディレクトリから、各メジャーバージョンの
+// 最新のタグのaction.ymlを読み込み、pkg/core/popular_actions.jsonに書き出す
+//
+//	go run ./script/generate-popular-actions -mirror /path/to/mirror -list script/generate-popular-actions/actions.yaml -out pkg/core/popular_actions.json
+//
+// ミラーのディレクトリは"<mirror>/<owner>/<repo>"にそれぞれのリポジトリのクローンを置く
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sisaku-security/sisakulint/pkg/core"
+	"gopkg.in/yaml.v3"
+)
+
+// actionListはデータセットに含めるアクションのリスト
+type actionList struct {
+	Actions []*actionEntry `yaml:"actions"`
+}
+
+// actionEntryはデータセットに含める1つのリポジトリ
+type actionEntry struct {
+	// Repoは"owner/repo"
+	Repo string `yaml:"repo"`
+	// Pathsはリポジトリの中のアクションのパス。省略した場合はリポジトリのルートのアクションだけを含める
+	Paths []string `yaml:"paths"`
+	// Majorsはデータセットに含めるメジャーバージョン。省略した場合はすべてのメジャーバージョンを含める
+	Majors []string `yaml:"majors"`
+	// Deprecatedはメジャーバージョンごとの非推奨の理由。"*"はすべてのバージョンに適用される
+	Deprecated map[string]string `yaml:"deprecated"`
+	// SkipInputsは、アクションが任意の入力を受け付ける場合にtrueにする
+	SkipInputs bool `yaml:"skip-inputs"`
+	// SkipOutputsは、アクションが動的に出力を設定する場合にtrueにする
+	SkipOutputs bool `yaml:"skip-outputs"`
+}
+
+var versionTag = regexp.MustCompile(`^v(\d+)(?:\.(\d+))?(?:\.(\d+))?$`)
+
+// semverは"vX.Y.Z"のタグのバージョン
+type semver struct {
+	tag                 string
+	major, minor, patch int
+}
+
+func (v semver) less(o semver) bool {
+	if v.major != o.major {
+		return v.major < o.major
+	}
+	if v.minor != o.minor {
+		return v.minor < o.minor
+	}
+	if v.patch != o.patch {
+		return v.patch < o.patch
+	}
+	// "v4"より"v4.0.0"のような詳しいタグを優先する
+	return len(v.tag) < len(o.tag)
+}
+
+func parseVersionTag(tag string) (semver, bool) {
+	m := versionTag.FindStringSubmatch(tag)
+	if m == nil {
+		return semver{}, false
+	}
+	v := semver{tag: tag}
+	v.major, _ = strconv.Atoi(m[1])
+	v.minor, _ = strconv.Atoi(m[2])
+	v.patch, _ = strconv.Atoi(m[3])
+	return v, true
+}
+
+// latestTagsは、メジャーバージョンごとに最新のタグを返す
+func latestTags(tags []string) []semver {
+	latest := map[int]semver{}
+	for _, t := range tags {
+		v, ok := parseVersionTag(strings.TrimSpace(t))
+		if !ok {
+			continue
+		}
+		if cur, ok := latest[v.major]; !ok || cur.less(v) {
+			latest[v.major] = v
+		}
+	}
+	vs := make([]semver, 0, len(latest))
+	for _, v := range latest {
+		vs = append(vs, v)
+	}
+	sort.Slice(vs, func(i, j int) bool { return vs[i].major < vs[j].major })
+	return vs
+}
+
+func git(dir string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git %s in %s: %w: %s", strings.Join(args, " "), dir, err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+// readMetadataは、タグの時点のaction.ymlまたはaction.yamlを読み込む
+func readMetadata(dir, tag, actionPath string) (*core.ActionMetadata, error) {
+	var lastErr error
+	for _, name := range []string{"action.yml", "action.yaml"} {
+		b, err := git(dir, "show", tag+":"+path.Join(actionPath, name))
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		var meta core.ActionMetadata
+		if err := yaml.Unmarshal(b, &meta); err != nil {
+			return nil, fmt.Errorf("could not parse %s at %s in %s: %w", name, tag, dir, err)
+		}
+		return &meta, nil
+	}
+	return nil, lastErr
+}
+
+// generateは、リストのアクションのメタデータをミラーから読み込んでデータセットを作る
+func generate(mirror string, list *actionList, log io.Writer) (map[string]*core.ActionMetadata, error) {
+	dataset := map[string]*core.ActionMetadata{}
+	for _, a := range list.Actions {
+		dir := filepath.Join(mirror, filepath.FromSlash(a.Repo))
+		out, err := git(dir, "tag", "--list", "v*")
+		if err != nil {
+			return nil, err
+		}
+		tags := latestTags(strings.Split(string(out), "\n"))
+		if len(tags) == 0 {
+			return nil, fmt.Errorf("no version tag was found in %s", dir)
+		}
+		paths := a.Paths
+		if len(paths) == 0 {
+			paths = []string{""}
+		}
+		for _, v := range tags {
+			major := fmt.Sprintf("v%d", v.major)
+			if len(a.Majors) > 0 && !slices.Contains(a.Majors, major) {
+				continue
+			}
+			for _, p := range paths {
+				spec := a.Repo
+				if p != "" {
+					spec += "/" + p
+				}
+				spec += "@" + major
+				meta, err := readMetadata(dir, v.tag, p)
+				if err != nil {
+					// 古いバージョンには存在しないサブアクションもある
+					fmt.Fprintf(log, "skip %s: %v\n", spec, err)
+					continue
+				}
+				meta.SkipInputs = a.SkipInputs
+				meta.SkipOutputs = a.SkipOutputs
+				if msg, ok := a.Deprecated[major]; ok {
+					meta.Deprecated = msg
+				} else if msg, ok := a.Deprecated["*"]; ok {
+					meta.Deprecated = msg
+				}
+				dataset[spec] = meta
+				fmt.Fprintf(log, "%s: %s\n", spec, v.tag)
+			}
+		}
+	}
+	return dataset, nil
+}
+
+func run(args []string, stderr io.Writer) error {
+	flags := flag.NewFlagSet("generate-popular-actions", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	mirror := flags.String("mirror", "", "directory which contains clones of action repositories as <owner>/<repo>")
+	listPath := flags.String("list", "", "YAML file which lists actions to include in the dataset")
+	out := flags.String("out", "", "path to write the dataset JSON")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *mirror == "" || *listPath == "" || *out == "" {
+		return errors.New("-mirror, -list and -out are required. set SISAKULINT_ACTIONS_MIRROR to run go generate")
+	}
+
+	b, err := os.ReadFile(*listPath)
+	if err != nil {
+		return err
+	}
+	var list actionList
+	if err := yaml.Unmarshal(b, &list); err != nil {
+		return fmt.Errorf("could not parse %s: %w", *listPath, err)
+	}
+
+	dataset, err := generate(*mirror, &list, stderr)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(dataset, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(*out, append(data, '\n'), 0644)
+}
+
+func main() {
+	if err := run(os.Args[1:], os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
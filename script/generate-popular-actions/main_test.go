@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/sisaku-security/sisakulint/pkg/core"
+)
+
+func TestLatestTags(t *testing.T) {
+	got := latestTags([]string{"v1", "v1.0.0", "v1.2.0", "v1.10.1", "v2", "v2.0.0-beta", "v3.0", "release", ""})
+	want := []string{"v1.10.1", "v2", "v3.0"}
+	if len(got) != len(want) {
+		t.Fatalf("got %v", got)
+	}
+	for i, v := range got {
+		if v.tag != want[i] {
+			t.Errorf("got %q at %d, want %q", v.tag, i, want[i])
+		}
+	}
+}
+
+// gitRepoは、tagsの順にaction.ymlをコミットしてタグを付けたリポジトリを作る
+func gitRepo(t *testing.T, dir string, tags []string, files map[string]map[string]string) {
+	t.Helper()
+	run := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=t", "-c", "user.email=t@example.com"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	run("init", "-q")
+	for _, tag := range tags {
+		for path, content := range files[tag] {
+			p := filepath.Join(dir, filepath.FromSlash(path))
+			if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		run("add", "-A")
+		run("commit", "-q", "-m", tag)
+		run("tag", tag)
+	}
+}
+
+func TestRun(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	mirror := t.TempDir()
+	gitRepo(t, filepath.Join(mirror, "owner", "repo"), []string{"v1.0.0", "v1.1.0", "v2.0.0"}, map[string]map[string]string{
+		"v1.0.0": {"action.yml": "name: Old\nruns:\n  using: node12\n"},
+		"v1.1.0": {"action.yml": "name: One\ninputs:\n  Token:\n    required: true\noutputs:\n  result:\n    description: r\nruns:\n  using: node16\n"},
+		"v2.0.0": {
+			"action.yml":     "name: Two\ninputs:\n  token:\n    required: true\n    default: x\n  old:\n    deprecationMessage: do not use\nruns:\n  using: node20\n",
+			"sub/action.yml": "name: Sub\nruns:\n  using: docker\n  image: Dockerfile\n",
+		},
+	})
+
+	dir := t.TempDir()
+	list := filepath.Join(dir, "actions.yaml")
+	if err := os.WriteFile(list, []byte("actions:\n  - repo: owner/repo\n    paths: [\"\", sub]\n    skip-outputs: true\n    deprecated:\n      v1: use v2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "popular_actions.json")
+	if err := run([]string{"-mirror", mirror, "-list", list, "-out", out}, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]*core.ActionMetadata
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]*core.ActionMetadata{
+		"owner/repo@v1": {
+			Name:        "One",
+			Inputs:      core.ActionInputsMetadata{"token": {Name: "Token", Required: true}},
+			Outputs:     core.ActionOutputsMetadata{"result": {Name: "result"}},
+			SkipOutputs: true,
+			Runs:        core.ActionRunsMetadata{Using: "node16"},
+			Deprecated:  "use v2",
+		},
+		"owner/repo@v2": {
+			Name: "Two",
+			Inputs: core.ActionInputsMetadata{
+				"token": {Name: "token"},
+				"old":   {Name: "old", Deprecated: "do not use"},
+			},
+			SkipOutputs: true,
+			Runs:        core.ActionRunsMetadata{Using: "node20"},
+		},
+		"owner/repo/sub@v2": {
+			Name:        "Sub",
+			SkipOutputs: true,
+			Runs:        core.ActionRunsMetadata{Using: "docker"},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		g, _ := json.MarshalIndent(got, "", "  ")
+		t.Errorf("got:\n%s", g)
+	}
+}
+
+func TestRun_Majors(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	mirror := t.TempDir()
+	gitRepo(t, filepath.Join(mirror, "owner", "repo"), []string{"v1.0.0", "v2.0.0", "v3.0.0"}, map[string]map[string]string{
+		"v1.0.0": {"action.yml": "name: One\nruns:\n  using: node12\n"},
+		"v2.0.0": {"action.yml": "name: Two\nruns:\n  using: node16\n"},
+		"v3.0.0": {"action.yml": "name: Three\nruns:\n  using: node20\n"},
+	})
+
+	dir := t.TempDir()
+	list := filepath.Join(dir, "actions.yaml")
+	if err := os.WriteFile(list, []byte("actions:\n  - repo: owner/repo\n    majors: [v1, v3]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "popular_actions.json")
+	if err := run([]string{"-mirror", mirror, "-list", list, "-out", out}, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]*core.ActionMetadata
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got["owner/repo@v1"] == nil || got["owner/repo@v3"] == nil {
+		t.Errorf("only v1 and v3 should be generated: %v", got)
+	}
+}
+
+func TestRun_MissingFlags(t *testing.T) {
+	if err := run([]string{"-list", "actions.yaml"}, io.Discard); err == nil {
+		t.Error("error should be returned without -mirror")
+	}
+}
+
+// TestDatasetIsUpToDateは、コミットされたpkg/core/popular_actions.jsonが生成結果と一致することを確認する
+// SISAKULINT_ACTIONS_MIRRORにactions.yamlのリポジトリのクローンがある場合だけ実行する
+func TestDatasetIsUpToDate(t *testing.T) {
+	mirror := os.Getenv("SISAKULINT_ACTIONS_MIRROR")
+	if mirror == "" {
+		t.Skip("SISAKULINT_ACTIONS_MIRROR is not set")
+	}
+	out := filepath.Join(t.TempDir(), "popular_actions.json")
+	if err := run([]string{"-mirror", mirror, "-list", "actions.yaml", "-out", out}, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	want, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(filepath.Join("..", "..", "pkg", "core", "popular_actions.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Error("pkg/core/popular_actions.json is outdated. run `go generate ./pkg/core` with SISAKULINT_ACTIONS_MIRROR to update it")
+	}
+}